@@ -0,0 +1,216 @@
+package agentloop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+// DefaultAuditMaxArgBytes caps the arguments recorded per audit entry.
+const DefaultAuditMaxArgBytes = 4096
+
+// AuditOptions configures an AuditLog.
+type AuditOptions struct {
+	// MaxArgBytes truncates recorded arguments. Default:
+	// DefaultAuditMaxArgBytes.
+	MaxArgBytes int
+
+	// IncludeReads also records read-only tools (file_read, git_diff, ...).
+	// By default only tools that can change something are recorded.
+	IncludeReads bool
+}
+
+// AuditLog records what an Executor did to its worktree as one JSON line per
+// tool call, in the order the calls finished, for post-mortems.
+type AuditLog struct {
+	opts AuditOptions
+
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor,omitempty"`
+	Tool      string    `json:"tool"`
+	CallID    string    `json:"call_id,omitempty"`
+	Arguments string    `json:"arguments"`
+	Truncated bool      `json:"truncated,omitempty"`
+
+	// Paths are the absolute paths the call touched: files written, edited,
+	// moved or deleted, or the directory a command ran in.
+	Paths []string `json:"paths,omitempty"`
+
+	// SHA256 maps each file the call wrote to the hash of its new content.
+	SHA256 map[string]string `json:"sha256,omitempty"`
+
+	DryRun     bool   `json:"dry_run,omitempty"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// NewAuditLog writes audit entries to w.
+func NewAuditLog(w io.Writer, opts AuditOptions) *AuditLog {
+	if opts.MaxArgBytes <= 0 {
+		opts.MaxArgBytes = DefaultAuditMaxArgBytes
+	}
+	return &AuditLog{opts: opts, w: w}
+}
+
+// OpenAuditLog opens path for appending, creating it if needed.
+func OpenAuditLog(path string, opts AuditOptions) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	a := NewAuditLog(f, opts)
+	a.closer = f
+	return a, nil
+}
+
+// Close closes the underlying file, if the log opened one.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// SetAuditLog makes the executor record tool calls to audit. Pass nil to
+// stop recording.
+func (e *Executor) SetAuditLog(audit *AuditLog) {
+	e.audit = audit
+}
+
+// record writes the entry for one finished call. Write failures are logged;
+// they never fail the call.
+func (a *AuditLog) record(e *Executor, call llm.ToolCall, start time.Time, callErr error) {
+	if !a.opts.IncludeReads && isReadOnlyTool(call.Name) {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:       start.UTC(),
+		Actor:      e.actor,
+		Tool:       call.Name,
+		CallID:     call.ID,
+		DryRun:     e.readOnly && !runsInDryRun(call),
+		OK:         callErr == nil,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+	entry.Arguments, entry.Truncated = a.arguments(call.Args)
+	entry.Paths = e.auditPaths(call)
+	if entry.OK && !entry.DryRun && writesFiles[call.Name] {
+		entry.SHA256 = hashFiles(entry.Paths)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("[agentloop] Encoding audit entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(line); err != nil {
+		log.Printf("[agentloop] Writing audit log: %v", err)
+	}
+}
+
+// arguments renders args for the log, capped at MaxArgBytes.
+func (a *AuditLog) arguments(args json.RawMessage) (string, bool) {
+	if len(args) > a.opts.MaxArgBytes {
+		return string(args[:a.opts.MaxArgBytes]), true
+	}
+	return string(args), false
+}
+
+// writesFiles are the tools whose successful calls leave new file content
+// at the paths they touch.
+var writesFiles = map[string]bool{
+	"file_write":  true,
+	"file_edit":   true,
+	"multi_edit":  true,
+	"apply_patch": true,
+	"file_move":   true,
+}
+
+// auditPaths resolves the worktree paths a call names. Paths that fail to
+// resolve are left out; the call itself will have failed on them.
+func (e *Executor) auditPaths(call llm.ToolCall) []string {
+	var params struct {
+		Path  string `json:"path"`
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Cwd   string `json:"cwd"`
+		Patch string `json:"patch"`
+	}
+	_ = json.Unmarshal(call.Args, &params)
+
+	var names []string
+	switch call.Name {
+	case "file_move":
+		names = []string{params.From, params.To}
+	case "apply_patch":
+		files, _ := parseUnifiedDiff(params.Patch)
+		for _, f := range files {
+			if f.oldPath != "" && f.oldPath != f.newPath {
+				names = append(names, f.oldPath)
+			}
+			names = append(names, f.newPath)
+		}
+	case "shell_exec":
+		if params.Cwd == "" {
+			return []string{e.workDir}
+		}
+		names = []string{params.Cwd}
+	default:
+		names = []string{params.Path}
+	}
+
+	var paths []string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if abs, err := e.safePath(name); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+	return paths
+}
+
+// hashFiles returns the SHA-256 of each path that is now a regular file.
+// Paths that no longer exist (the source of a move) are skipped.
+func hashFiles(paths []string) map[string]string {
+	hashes := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		hashes[path] = hex.EncodeToString(sum[:])
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	return hashes
+}
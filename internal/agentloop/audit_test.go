@@ -0,0 +1,111 @@
+package agentloop
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readAuditEntries(t *testing.T, buf *bytes.Buffer) []AuditEntry {
+	t.Helper()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("bad audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestExecutorAuditLogRecordsMutatingCalls(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"old.txt": "old\n"})
+	var buf bytes.Buffer
+	e.SetAuditLog(NewAuditLog(&buf, AuditOptions{MaxArgBytes: 64}))
+	dir := e.WorkDir()
+
+	big := strings.Repeat("x", 100)
+	if _, err := execTool(t, e, "file_write", map[string]string{"path": "a.txt", "content": big}); err != nil {
+		t.Fatalf("file_write: %v", err)
+	}
+	if _, err := execTool(t, e, "file_read", map[string]string{"path": "a.txt"}); err != nil {
+		t.Fatalf("file_read: %v", err)
+	}
+	if _, err := execTool(t, e, "file_move", map[string]string{"from": "old.txt", "to": "new.txt"}); err != nil {
+		t.Fatalf("file_move: %v", err)
+	}
+	if _, err := execTool(t, e, "file_delete", map[string]string{"path": "missing.txt"}); err == nil {
+		t.Fatal("file_delete of a missing file should fail")
+	}
+
+	entries := readAuditEntries(t, &buf)
+	var tools []string
+	for _, entry := range entries {
+		tools = append(tools, entry.Tool)
+	}
+	if got := strings.Join(tools, ","); got != "file_write,file_move,file_delete" {
+		t.Fatalf("audited tools = %s, want file_write,file_move,file_delete (reads omitted)", got)
+	}
+
+	write := entries[0]
+	aPath := filepath.Join(dir, "a.txt")
+	sum := sha256.Sum256([]byte(big))
+	if !write.OK || write.Actor != "rig/polecats/Test" || write.Time.IsZero() {
+		t.Errorf("write entry = %+v", write)
+	}
+	if len(write.Paths) != 1 || write.Paths[0] != aPath {
+		t.Errorf("write paths = %v, want [%s]", write.Paths, aPath)
+	}
+	if write.SHA256[aPath] != hex.EncodeToString(sum[:]) {
+		t.Errorf("write sha256 = %v, want hash of the written content", write.SHA256)
+	}
+	if !write.Truncated || len(write.Arguments) != 64 {
+		t.Errorf("write arguments = %d bytes (truncated=%v), want capped at 64", len(write.Arguments), write.Truncated)
+	}
+
+	move := entries[1]
+	newPath := filepath.Join(dir, "new.txt")
+	if len(move.Paths) != 2 || move.Paths[0] != filepath.Join(dir, "old.txt") || move.Paths[1] != newPath {
+		t.Errorf("move paths = %v", move.Paths)
+	}
+	if _, ok := move.SHA256[newPath]; !ok || len(move.SHA256) != 1 {
+		t.Errorf("move sha256 = %v, want only the destination", move.SHA256)
+	}
+
+	del := entries[2]
+	if del.OK || del.Error == "" || del.SHA256 != nil {
+		t.Errorf("failed delete entry = %+v", del)
+	}
+}
+
+func TestExecutorAuditLogDryRunAndReads(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"a.txt": "a\n"})
+	e.SetReadOnly(true)
+	var buf bytes.Buffer
+	e.SetAuditLog(NewAuditLog(&buf, AuditOptions{IncludeReads: true}))
+
+	if _, err := execTool(t, e, "file_read", map[string]string{"path": "a.txt"}); err != nil {
+		t.Fatalf("file_read: %v", err)
+	}
+	if _, err := execTool(t, e, "file_write", map[string]string{"path": "a.txt", "content": "b"}); err != nil {
+		t.Fatalf("file_write: %v", err)
+	}
+
+	entries := readAuditEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if entries[0].Tool != "file_read" || entries[0].DryRun {
+		t.Errorf("read entry = %+v", entries[0])
+	}
+	if entries[1].Tool != "file_write" || !entries[1].DryRun || entries[1].SHA256 != nil {
+		t.Errorf("dry-run write entry = %+v, want dry_run and no hash", entries[1])
+	}
+}
@@ -0,0 +1,65 @@
+package agentloop
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffLen is how much of a file is examined to decide whether it is binary,
+// matching what http.DetectContentType considers.
+const sniffLen = 512
+
+// binaryContentType reports whether data looks like a binary file rather than
+// text, and if so its sniffed MIME type. A NUL byte or invalid UTF-8 in the
+// first sniffLen bytes marks a file as binary, as does a recognized
+// non-text signature such as image/png or application/pdf.
+func binaryContentType(data []byte) (string, bool) {
+	sniff := data[:min(len(data), sniffLen)]
+	ctype := http.DetectContentType(sniff)
+	if i := strings.IndexByte(ctype, ';'); i >= 0 {
+		ctype = ctype[:i]
+	}
+
+	if bytes.IndexByte(sniff, 0) >= 0 {
+		return ctype, true
+	}
+	if !utf8.Valid(trimPartialRune(sniff, len(data))) {
+		return ctype, true
+	}
+	if !strings.HasPrefix(ctype, "text/") && ctype != "application/octet-stream" {
+		return ctype, true
+	}
+	return "", false
+}
+
+// trimPartialRune drops a multi-byte character cut off at the end of the
+// sniffed prefix, so a valid UTF-8 file isn't mistaken for binary.
+func trimPartialRune(sniff []byte, total int) []byte {
+	if len(sniff) == total {
+		return sniff
+	}
+	for i := 1; i <= utf8.UTFMax-1 && i <= len(sniff); i++ {
+		if utf8.RuneStart(sniff[len(sniff)-i]) {
+			if !utf8.FullRune(sniff[len(sniff)-i:]) {
+				return sniff[:len(sniff)-i]
+			}
+			break
+		}
+	}
+	return sniff
+}
+
+// formatBytes renders a size like "240KB" for messages to the model.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%dKB", n>>10)
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
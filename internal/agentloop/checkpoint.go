@@ -0,0 +1,108 @@
+package agentloop
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/atomicfile"
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+// checkpointVersion is bumped when the checkpoint format changes incompatibly.
+const checkpointVersion = 1
+
+// maxCheckpointLine bounds one JSONL line of a checkpoint.
+const maxCheckpointLine = 64 * 1024 * 1024
+
+// checkpointHeader is the first line of a checkpoint file. Each following
+// line is one llm.Message of the conversation, in order.
+type checkpointHeader struct {
+	Version     int       `json:"version"`
+	Task        string    `json:"task"`
+	Iteration   int       `json:"iteration"`
+	TotalTokens int       `json:"total_tokens"`
+	CostUSD     float64   `json:"cost_usd,omitempty"`
+	Actor       string    `json:"actor,omitempty"`
+	SavedAt     time.Time `json:"saved_at"`
+}
+
+// checkpoint is a task's resumable state.
+type checkpoint struct {
+	checkpointHeader
+	Messages []llm.Message
+}
+
+// writeCheckpoint saves the conversation as JSONL. The write goes to a temp
+// file that is renamed over path, so a crash mid-write leaves the previous
+// checkpoint intact.
+func writeCheckpoint(path string, cp *checkpoint) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	cp.Version = checkpointVersion
+	if err := enc.Encode(cp.checkpointHeader); err != nil {
+		return fmt.Errorf("encoding checkpoint header: %w", err)
+	}
+	for _, m := range cp.Messages {
+		if err := enc.Encode(m); err != nil {
+			return fmt.Errorf("encoding checkpoint message: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating checkpoint directory: %w", err)
+	}
+	if err := atomicfile.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("writing checkpoint: %w", err)
+	}
+	return nil
+}
+
+// loadCheckpoint reads a checkpoint written by writeCheckpoint.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening checkpoint: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	// Tool results can be large (up to the executor's output limit,
+	// JSON-escaped).
+	scanner.Buffer(make([]byte, 0, 64*1024), maxCheckpointLine)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("reading checkpoint: %w", err)
+		}
+		return nil, fmt.Errorf("checkpoint %s is empty", path)
+	}
+
+	cp := &checkpoint{}
+	if err := json.Unmarshal(scanner.Bytes(), &cp.checkpointHeader); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint header: %w", err)
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d", cp.Version)
+	}
+
+	for line := 2; scanner.Scan(); line++ {
+		var m llm.Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			return nil, fmt.Errorf("parsing checkpoint line %d: %w", line, err)
+		}
+		cp.Messages = append(cp.Messages, m)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	if len(cp.Messages) == 0 {
+		return nil, fmt.Errorf("checkpoint %s has no messages", path)
+	}
+	return cp, nil
+}
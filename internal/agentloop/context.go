@@ -1,8 +1,11 @@
 package agentloop
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"strings"
+	"time"
 
 	"github.com/steveyegge/gastown/internal/llm"
 )
@@ -17,6 +20,8 @@ const (
 	TokensPerChar = 0.28
 	// SummaryMaxTokens is the max tokens for a summarized message.
 	SummaryMaxTokens = 500
+	// SummaryTimeout bounds the LLM call used to summarize dropped messages.
+	SummaryTimeout = 60 * time.Second
 )
 
 // ContextManager tracks conversation size and manages context window limits.
@@ -25,6 +30,10 @@ type ContextManager struct {
 	contextWindow int // model's total context window
 	maxTokens     int // usable tokens (after response reserve)
 	totalTokens   int // estimated current total
+
+	// summarizer, if set, writes summaries of dropped messages.
+	// nil uses the statistical summary.
+	summarizer llm.Client
 }
 
 // NewContextManager creates a context manager for the given context window size.
@@ -39,6 +48,15 @@ func NewContextManager(contextWindow int) *ContextManager {
 	}
 }
 
+// NewContextManagerWithSummarizer creates a context manager that uses client
+// to summarize messages dropped during truncation, falling back to the
+// statistical summary if the call fails.
+func NewContextManagerWithSummarizer(contextWindow int, client llm.Client) *ContextManager {
+	cm := NewContextManager(contextWindow)
+	cm.summarizer = client
+	return cm
+}
+
 // EstimateTokens returns a rough token count for a string.
 func EstimateTokens(s string) int {
 	return int(float64(len(s)) * TokensPerChar)
@@ -82,6 +100,11 @@ func (cm *ContextManager) NeedsTruncation(messages []llm.Message) bool {
 // 3. Summarize or drop middle messages
 // 4. Truncate long tool results
 func (cm *ContextManager) Truncate(messages []llm.Message) []llm.Message {
+	return cm.TruncateContext(context.Background(), messages)
+}
+
+// TruncateContext is Truncate with a context for the summarization call.
+func (cm *ContextManager) TruncateContext(ctx context.Context, messages []llm.Message) []llm.Message {
 	if !cm.NeedsTruncation(messages) {
 		return messages
 	}
@@ -112,7 +135,7 @@ func (cm *ContextManager) Truncate(messages []llm.Message) []llm.Message {
 	if keepFrom > startIdx {
 		droppedCount := keepFrom - startIdx
 		summary := fmt.Sprintf("[%d earlier messages summarized]\n", droppedCount)
-		summary += cm.summarizeMessages(messages[startIdx:keepFrom])
+		summary += cm.summarize(ctx, messages[startIdx:keepFrom])
 		result = append(result, llm.Message{
 			Role:    "user",
 			Content: summary,
@@ -147,7 +170,82 @@ func (cm *ContextManager) trimToolResults(messages []llm.Message) []llm.Message
 	return result
 }
 
-// summarizeMessages creates a brief summary of dropped messages.
+// summarize returns a model-written summary of dropped messages when a
+// summarizer is configured, and the statistical summary otherwise.
+func (cm *ContextManager) summarize(ctx context.Context, messages []llm.Message) string {
+	if cm.summarizer == nil {
+		return cm.summarizeMessages(messages)
+	}
+	summary, err := cm.summarizeWithLLM(ctx, messages)
+	if err != nil {
+		log.Printf("[agentloop] LLM summarization failed, using statistical summary: %v", err)
+		return cm.summarizeMessages(messages)
+	}
+	return summary
+}
+
+// summarizeWithLLM asks the summarizer model to condense a conversation segment.
+func (cm *ContextManager) summarizeWithLLM(ctx context.Context, messages []llm.Message) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, SummaryTimeout)
+	defer cancel()
+
+	resp, err := cm.summarizer.Chat(ctx, &llm.ChatRequest{
+		Messages: []llm.Message{
+			{
+				Role: "system",
+				Content: "You are summarizing a segment of an autonomous coding agent's working session " +
+					"so the agent can continue without it. Preserve facts the agent will need: files read " +
+					"or changed and what was found or done in them, commands run and their outcomes, " +
+					"decisions made, errors hit, and work still outstanding. Be concise and factual; " +
+					"use short bullet points.",
+			},
+			{
+				Role:    "user",
+				Content: "Summarize this conversation segment:\n\n" + renderTranscript(messages),
+			},
+		},
+		MaxTokens: SummaryMaxTokens,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary + "\n", nil
+}
+
+// renderTranscript flattens messages into plain text for summarization,
+// clipping long tool output.
+func renderTranscript(messages []llm.Message) string {
+	const maxContent = 2000
+
+	clip := func(s string) string {
+		if len(s) > maxContent {
+			return s[:maxContent] + "\n... (truncated)"
+		}
+		return s
+	}
+
+	var sb strings.Builder
+	for _, msg := range messages {
+		switch msg.Role {
+		case "tool":
+			fmt.Fprintf(&sb, "[tool result: %s]\n%s\n\n", msg.Name, clip(msg.Content))
+		default:
+			if msg.Content != "" {
+				fmt.Fprintf(&sb, "[%s]\n%s\n\n", msg.Role, clip(msg.Content))
+			}
+			for _, tc := range msg.ToolCalls {
+				fmt.Fprintf(&sb, "[%s called %s] %s\n\n", msg.Role, tc.Name, clip(string(tc.Args)))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// summarizeMessages creates a brief statistical summary of dropped messages.
 func (cm *ContextManager) summarizeMessages(messages []llm.Message) string {
 	var sb strings.Builder
 
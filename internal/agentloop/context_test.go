@@ -0,0 +1,47 @@
+package agentloop
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+func longConversation() []llm.Message {
+	msgs := []llm.Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "task"}}
+	for i := 0; i < 10; i++ {
+		msgs = append(msgs,
+			llm.Message{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "c", Name: "file_read", Args: []byte(`{"path":"a.go"}`)}}},
+			llm.Message{Role: "tool", Name: "file_read", ToolCallID: "c", Content: strings.Repeat("x", 400)},
+		)
+	}
+	return msgs
+}
+
+func TestTruncateUsesLLMSummary(t *testing.T) {
+	client := &scriptedLLM{responses: []*llm.ChatResponse{{Content: "- read a.go ten times"}}}
+	cm := NewContextManagerWithSummarizer(1000, client)
+
+	out := cm.TruncateContext(context.Background(), longConversation())
+	if out[0].Role != "system" || !strings.Contains(out[1].Content, "- read a.go ten times") {
+		t.Fatalf("summary message = %q", out[1].Content)
+	}
+	if len(out) != 8 {
+		t.Errorf("len = %d, want system + summary + 6 recent", len(out))
+	}
+	req := client.requests[0]
+	if req.MaxTokens != SummaryMaxTokens || !strings.Contains(req.Messages[1].Content, "[assistant called file_read]") {
+		t.Errorf("summarization request = %+v", req)
+	}
+}
+
+func TestTruncateFallsBackToStatisticalSummary(t *testing.T) {
+	client := &scriptedLLM{} // every call fails
+	cm := NewContextManagerWithSummarizer(1000, client)
+
+	out := cm.TruncateContext(context.Background(), longConversation())
+	if !strings.Contains(out[1].Content, "tool calls: file_read(") {
+		t.Errorf("summary message = %q, want statistical fallback", out[1].Content)
+	}
+}
@@ -0,0 +1,333 @@
+package agentloop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+// dryRunPrefix starts every simulated tool result.
+const dryRunPrefix = "[dry-run] "
+
+// SetReadOnly puts the executor in dry-run mode: read-only tools still run,
+// but tools that would change the worktree or any outside state return a
+// description of what they would have done instead of doing it.
+func (e *Executor) SetReadOnly(readOnly bool) {
+	e.readOnly = readOnly
+}
+
+// ReadOnly reports whether the executor is in dry-run mode.
+func (e *Executor) ReadOnly() bool {
+	return e.readOnly
+}
+
+// runsInDryRun reports whether a call executes for real in dry-run mode.
+// gt_prime only loads role context, and git_branch without a name only
+// lists branches.
+func runsInDryRun(call llm.ToolCall) bool {
+	switch call.Name {
+	case "gt_prime":
+		return true
+	case "git_branch":
+		var params struct {
+			Name string `json:"name"`
+		}
+		_ = json.Unmarshal(call.Args, &params)
+		return params.Name == ""
+	}
+	return isReadOnlyTool(call.Name)
+}
+
+// dryRun describes the effect call would have had. Arguments are validated
+// as the real tool would, so a call that would fail still fails.
+func (e *Executor) dryRun(ctx context.Context, call llm.ToolCall) (string, error) {
+	switch call.Name {
+	case "file_write":
+		return e.dryRunFileWrite(call.Args)
+	case "file_edit":
+		return e.dryRunFileEdit(call.Args)
+	case "multi_edit":
+		return e.dryRunMultiEdit(call.Args)
+	case "apply_patch":
+		return e.dryRunApplyPatch(call.Args)
+	case "file_delete":
+		return e.dryRunFileDelete(call.Args)
+	case "file_move":
+		return e.dryRunFileMove(call.Args)
+	case "git_commit":
+		return e.dryRunGitCommit(ctx, call.Args)
+	case "shell_exec":
+		var params struct {
+			Command string `json:"command"`
+			Cwd     string `json:"cwd"`
+		}
+		if err := json.Unmarshal(call.Args, &params); err != nil {
+			return "", fmt.Errorf("parsing shell_exec args: %w", err)
+		}
+		if params.Command == "" {
+			return "", fmt.Errorf("shell_exec requires command")
+		}
+		where := "the working directory"
+		if params.Cwd != "" {
+			where = params.Cwd
+		}
+		return fmt.Sprintf("%swould have run in %s:\n%s", dryRunPrefix, where, params.Command), nil
+	}
+
+	// Everything else runs a gt/bd/git command; its arguments say enough.
+	args := string(call.Args)
+	if strings.TrimSpace(args) == "" {
+		args = "{}"
+	}
+	return fmt.Sprintf("%swould have called %s with %s", dryRunPrefix, call.Name, args), nil
+}
+
+func (e *Executor) dryRunFileWrite(args json.RawMessage) (string, error) {
+	var params struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing file_write args: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("file_write requires path")
+	}
+	absPath, err := e.safePath(params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(absPath); err == nil {
+		return fmt.Sprintf("%swould have replaced %s (%d bytes) with %d bytes", dryRunPrefix, params.Path, info.Size(), len(params.Content)), nil
+	}
+	return fmt.Sprintf("%swould have created %s with %d bytes", dryRunPrefix, params.Path, len(params.Content)), nil
+}
+
+func (e *Executor) dryRunFileEdit(args json.RawMessage) (string, error) {
+	var params struct {
+		Path          string `json:"path"`
+		Search        string `json:"search"`
+		Replace       string `json:"replace"`
+		ReplaceAll    bool   `json:"replace_all"`
+		ExpectedCount int    `json:"expected_count"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing file_edit args: %w", err)
+	}
+	if params.Path == "" || params.Search == "" {
+		return "", fmt.Errorf("file_edit requires path and search")
+	}
+	if params.ExpectedCount > 1 && !params.ReplaceAll {
+		return "", fmt.Errorf("file_edit expected_count %d requires replace_all, since only the first occurrence is replaced otherwise", params.ExpectedCount)
+	}
+	content, err := e.readForDryRun(params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	count := strings.Count(content, params.Search)
+	if count == 0 {
+		return "", fmt.Errorf("search text not found in %s", params.Path)
+	}
+	if params.ExpectedCount > 0 && count != params.ExpectedCount {
+		return "", fmt.Errorf("search text found %d times in %s, expected %d", count, params.Path, params.ExpectedCount)
+	}
+	if !params.ReplaceAll {
+		count = 1
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%swould have edited %s (%d replacement(s)):\n", dryRunPrefix, params.Path, count)
+	writeEditDiff(&sb, params.Search, params.Replace)
+	return sb.String(), nil
+}
+
+func (e *Executor) dryRunMultiEdit(args json.RawMessage) (string, error) {
+	var params struct {
+		Path  string `json:"path"`
+		Edits []struct {
+			Search  string `json:"search"`
+			Replace string `json:"replace"`
+		} `json:"edits"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing multi_edit args: %w", err)
+	}
+	if params.Path == "" || len(params.Edits) == 0 {
+		return "", fmt.Errorf("multi_edit requires path and at least one edit")
+	}
+	content, err := e.readForDryRun(params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%swould have applied %d edits to %s:\n", dryRunPrefix, len(params.Edits), params.Path)
+	for i, edit := range params.Edits {
+		if edit.Search == "" {
+			return "", fmt.Errorf("edit %d: search is empty; no changes written", i+1)
+		}
+		if !strings.Contains(content, edit.Search) {
+			return "", fmt.Errorf("edit %d: search text not found in %s; no changes written", i+1, params.Path)
+		}
+		content = strings.Replace(content, edit.Search, edit.Replace, 1)
+		writeEditDiff(&sb, edit.Search, edit.Replace)
+	}
+	return sb.String(), nil
+}
+
+func (e *Executor) dryRunApplyPatch(args json.RawMessage) (string, error) {
+	var params struct {
+		Patch string `json:"patch"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing apply_patch args: %w", err)
+	}
+	if strings.TrimSpace(params.Patch) == "" {
+		return "", fmt.Errorf("apply_patch requires patch")
+	}
+	files, err := parseUnifiedDiff(params.Patch)
+	if err != nil {
+		return "", fmt.Errorf("parsing patch: %w", err)
+	}
+	changes, err := e.planPatch(files)
+	if err != nil {
+		return "", fmt.Errorf("patch rejected, no files changed: %w", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%swould have applied patch to %d file(s):\n", dryRunPrefix, len(changes))
+	for _, ch := range changes {
+		fmt.Fprintf(&sb, "  %c %s (+%d -%d)\n", ch.kind, ch.display, ch.added, ch.removed)
+	}
+	return sb.String(), nil
+}
+
+func (e *Executor) dryRunFileDelete(args json.RawMessage) (string, error) {
+	var params struct {
+		Path      string `json:"path"`
+		Recursive bool   `json:"recursive"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing file_delete args: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("file_delete requires path")
+	}
+	absPath, err := e.safePath(params.Path)
+	if err != nil {
+		return "", err
+	}
+	if e.isWorkDir(absPath) {
+		return "", fmt.Errorf("refusing to delete the working directory")
+	}
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return "", fmt.Errorf("stat %s: %w", params.Path, err)
+	}
+	if info.IsDir() {
+		if !params.Recursive {
+			return "", fmt.Errorf("%s is a directory; set recursive to delete it", params.Path)
+		}
+		return fmt.Sprintf("%swould have deleted directory %s", dryRunPrefix, params.Path), nil
+	}
+	return fmt.Sprintf("%swould have deleted %s", dryRunPrefix, params.Path), nil
+}
+
+func (e *Executor) dryRunFileMove(args json.RawMessage) (string, error) {
+	var params struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing file_move args: %w", err)
+	}
+	if params.From == "" || params.To == "" {
+		return "", fmt.Errorf("file_move requires from and to")
+	}
+	fromPath, err := e.safePath(params.From)
+	if err != nil {
+		return "", err
+	}
+	toPath, err := e.safePath(params.To)
+	if err != nil {
+		return "", err
+	}
+	if e.isWorkDir(fromPath) {
+		return "", fmt.Errorf("refusing to move the working directory")
+	}
+	if _, err := os.Lstat(fromPath); err != nil {
+		return "", fmt.Errorf("stat %s: %w", params.From, err)
+	}
+	if _, err := os.Lstat(toPath); err == nil {
+		return "", fmt.Errorf("destination %s already exists", params.To)
+	}
+	return fmt.Sprintf("%swould have moved %s to %s", dryRunPrefix, params.From, params.To), nil
+}
+
+// dryRunGitCommit shows the pending changes the commit would have taken.
+func (e *Executor) dryRunGitCommit(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Message string   `json:"message"`
+		Paths   []string `json:"paths"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing git_commit args: %w", err)
+	}
+	if params.Message == "" {
+		return "", fmt.Errorf("git_commit requires a message")
+	}
+
+	statusArgs := []string{"status", "--short"}
+	if len(params.Paths) > 0 {
+		statusArgs = append(statusArgs, "--")
+		for _, p := range params.Paths {
+			safePath, err := e.safePath(p)
+			if err != nil {
+				return "", err
+			}
+			statusArgs = append(statusArgs, safePath)
+		}
+	}
+	status, err := e.runCommand(ctx, "git", statusArgs, e.shellTimeout())
+	if err != nil {
+		return "", fmt.Errorf("git status failed: %w", err)
+	}
+
+	paths := "all changes"
+	if len(params.Paths) > 0 {
+		paths = strings.Join(params.Paths, ", ")
+	}
+	return fmt.Sprintf("%swould have committed %s with message %q:\n%s", dryRunPrefix, paths, params.Message, status), nil
+}
+
+// readForDryRun reads a file an edit tool would modify.
+func (e *Executor) readForDryRun(path string) (string, error) {
+	absPath, err := e.safePath(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+	return string(data), nil
+}
+
+// writeEditDiff renders a search/replace pair as removed and added lines.
+func writeEditDiff(sb *strings.Builder, search, replace string) {
+	for _, line := range strings.Split(strings.TrimSuffix(search, "\n"), "\n") {
+		fmt.Fprintf(sb, "-%s\n", line)
+	}
+	if replace == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(replace, "\n"), "\n") {
+		fmt.Fprintf(sb, "+%s\n", line)
+	}
+}
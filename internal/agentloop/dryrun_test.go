@@ -0,0 +1,49 @@
+package agentloop
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyExecutorSimulatesMutations(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"a.txt": "alpha\nbeta\n"})
+	e.SetReadOnly(true)
+
+	out, err := execTool(t, e, "file_edit", map[string]string{"path": "a.txt", "search": "beta", "replace": "BETA"})
+	if err != nil {
+		t.Fatalf("file_edit: %v", err)
+	}
+	if !strings.HasPrefix(out, dryRunPrefix) || !strings.Contains(out, "-beta\n+BETA") {
+		t.Errorf("file_edit dry run:\n%s", out)
+	}
+
+	if out, err := execTool(t, e, "file_write", map[string]string{"path": "new.txt", "content": "x"}); err != nil || !strings.Contains(out, "would have created new.txt") {
+		t.Errorf("file_write dry run = %q, %v", out, err)
+	}
+	if out, err := execTool(t, e, "shell_exec", map[string]string{"command": "touch ran"}); err != nil || !strings.Contains(out, "touch ran") {
+		t.Errorf("shell_exec dry run = %q, %v", out, err)
+	}
+	if out, err := execTool(t, e, "file_delete", map[string]string{"path": "a.txt"}); err != nil || !strings.Contains(out, "would have deleted a.txt") {
+		t.Errorf("file_delete dry run = %q, %v", out, err)
+	}
+
+	// Nothing changed on disk.
+	if got := readFile(t, e, "a.txt"); got != "alpha\nbeta\n" {
+		t.Errorf("a.txt = %q", got)
+	}
+	for _, name := range []string{"new.txt", "ran"} {
+		if _, err := readFileErr(e, name); err == nil {
+			t.Errorf("%s was created in dry-run mode", name)
+		}
+	}
+
+	// Calls the real tool would reject still fail.
+	if _, err := execTool(t, e, "file_edit", map[string]string{"path": "a.txt", "search": "gamma", "replace": "x"}); err == nil {
+		t.Error("file_edit dry run should fail when the search text is missing")
+	}
+
+	// Read tools run for real.
+	if out, err := execTool(t, e, "file_read", map[string]string{"path": "a.txt"}); err != nil || !strings.Contains(out, "beta") {
+		t.Errorf("file_read = %q, %v", out, err)
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,6 +25,16 @@ const (
 	MaxFileReadSize = 10 * 1024 * 1024
 	// MaxOutputSize is the maximum tool output size (100KB).
 	MaxOutputSize = 100 * 1024
+	// DefaultGitLogCount is how many commits git_log shows by default.
+	DefaultGitLogCount = 20
+	// DefaultMailReadCount is how many messages gt_mail_read shows by default.
+	DefaultMailReadCount = 10
+	// commandKillGrace is how long a timed-out command's process group has
+	// to exit after SIGTERM before it is sent SIGKILL.
+	commandKillGrace = 2 * time.Second
+	// commandWaitDelay is how long a finished or killed command's output
+	// pipes may stay open before they are closed forcibly.
+	commandWaitDelay = 5 * time.Second
 )
 
 // Executor handles tool call execution in a specific working directory.
@@ -33,6 +46,32 @@ type Executor struct {
 	townRoot string
 	actor    string // e.g., "rig/polecats/Toast"
 	role     string // e.g., "polecat", "witness", "deacon"
+
+	// allowedTools restricts which tools Execute will run.
+	// nil or empty allows all tools.
+	allowedTools map[string]bool
+
+	limits ExecutorLimits
+
+	// readOnly simulates tools with side effects; see SetReadOnly.
+	readOnly bool
+
+	// audit, if set, records tool calls; see SetAuditLog.
+	audit *AuditLog
+
+	// snapshots holds prior file content for revert_last and revert_to.
+	snapshots snapshotHistory
+}
+
+// ExecutorLimits overrides the executor's size and time limits.
+// Zero fields use the package defaults.
+type ExecutorLimits struct {
+	MaxFileReadSize int64         // default MaxFileReadSize
+	MaxOutputSize   int           // default MaxOutputSize
+	ShellTimeout    time.Duration // default DefaultShellTimeout
+
+	SnapshotMaxOps   int   // default DefaultSnapshotMaxOps
+	SnapshotMaxBytes int64 // default DefaultSnapshotMaxBytes
 }
 
 // NewExecutor creates a tool executor for a specific working directory.
@@ -50,9 +89,94 @@ func NewExecutor(workDir, rigName, rigPath, townRoot, actor, role string) *Execu
 	}
 }
 
+// SetAllowedTools restricts the executor to the named tools.
+// Passing nil or an empty list allows all tools. Call this before handing the
+// executor to NewAgentLoop or mcp.Server.RegisterGTTools, which snapshot the
+// permitted tool set.
+func (e *Executor) SetAllowedTools(names []string) {
+	if len(names) == 0 {
+		e.allowedTools = nil
+		return
+	}
+	e.allowedTools = make(map[string]bool, len(names))
+	for _, name := range names {
+		e.allowedTools[name] = true
+	}
+}
+
+// SetLimits overrides the executor's limits. Zero fields keep the defaults.
+func (e *Executor) SetLimits(limits ExecutorLimits) {
+	e.limits = limits
+}
+
+// maxFileReadSize is the largest file file_read and file_search will read.
+func (e *Executor) maxFileReadSize() int64 {
+	if e.limits.MaxFileReadSize > 0 {
+		return e.limits.MaxFileReadSize
+	}
+	return MaxFileReadSize
+}
+
+// maxOutputSize is the most output a tool returns.
+func (e *Executor) maxOutputSize() int {
+	if e.limits.MaxOutputSize > 0 {
+		return e.limits.MaxOutputSize
+	}
+	return MaxOutputSize
+}
+
+// shellTimeout is the default timeout for subprocess tools.
+func (e *Executor) shellTimeout() time.Duration {
+	if e.limits.ShellTimeout > 0 {
+		return e.limits.ShellTimeout
+	}
+	return DefaultShellTimeout
+}
+
+// IsToolAllowed reports whether the executor's allowlist permits the tool.
+func (e *Executor) IsToolAllowed(name string) bool {
+	return len(e.allowedTools) == 0 || e.allowedTools[name]
+}
+
+// Tools returns the GT tool definitions this executor is permitted to run.
+func (e *Executor) Tools() []llm.ToolDef {
+	if len(e.allowedTools) == 0 {
+		return GTTools()
+	}
+	var tools []llm.ToolDef
+	for _, t := range GTTools() {
+		if e.allowedTools[t.Name] {
+			tools = append(tools, t)
+		}
+	}
+	return tools
+}
+
 // Execute runs a tool call and returns the result as a string.
 // Tool execution happens locally regardless of where the LLM runs.
+// ExecuteResult also returns a structured rendering where one exists.
 func (e *Executor) Execute(ctx context.Context, call llm.ToolCall) (string, error) {
+	start := time.Now()
+	snap, mutates := e.snapshotBefore(call)
+	out, err := e.execute(ctx, call)
+	if mutates && err == nil {
+		e.recordSnapshot(snap)
+	}
+	if e.audit != nil {
+		e.audit.record(e, call, start, err)
+	}
+	return out, err
+}
+
+// execute dispatches a tool call to its implementation.
+func (e *Executor) execute(ctx context.Context, call llm.ToolCall) (string, error) {
+	if !e.IsToolAllowed(call.Name) {
+		return "", fmt.Errorf("tool %q not permitted for role %q", call.Name, e.role)
+	}
+	if e.readOnly && !runsInDryRun(call) {
+		return e.dryRun(ctx, call)
+	}
+
 	switch call.Name {
 	case "gt_prime":
 		return e.execGTPrime(ctx)
@@ -70,12 +194,28 @@ func (e *Executor) Execute(ctx context.Context, call llm.ToolCall) (string, erro
 		return e.execGitStatus(ctx)
 	case "git_commit":
 		return e.execGitCommit(ctx, call.Args)
+	case "git_log":
+		return e.execGitLog(ctx, call.Args)
+	case "git_show":
+		return e.execGitShow(ctx, call.Args)
+	case "git_branch":
+		return e.execGitBranch(ctx, call.Args)
+	case "git_checkout":
+		return e.execGitCheckout(ctx, call.Args)
 	case "file_read":
 		return e.execFileRead(ctx, call.Args)
 	case "file_write":
 		return e.execFileWrite(ctx, call.Args)
 	case "file_edit":
 		return e.execFileEdit(ctx, call.Args)
+	case "multi_edit":
+		return e.execMultiEdit(ctx, call.Args)
+	case "apply_patch":
+		return e.execApplyPatch(ctx, call.Args)
+	case "file_delete":
+		return e.execFileDelete(ctx, call.Args)
+	case "file_move":
+		return e.execFileMove(ctx, call.Args)
 	case "file_list":
 		return e.execFileList(ctx, call.Args)
 	case "file_search":
@@ -86,6 +226,10 @@ func (e *Executor) Execute(ctx context.Context, call llm.ToolCall) (string, erro
 		return e.execMailSend(ctx, call.Args)
 	case "gt_mail_read":
 		return e.execMailRead(ctx, call.Args)
+	case "revert_last":
+		return e.execRevertLast()
+	case "revert_to":
+		return e.execRevertTo(call.Args)
 	default:
 		return "", fmt.Errorf("unknown tool: %s", call.Name)
 	}
@@ -95,7 +239,7 @@ func (e *Executor) Execute(ctx context.Context, call llm.ToolCall) (string, erro
 
 func (e *Executor) execGTPrime(ctx context.Context) (string, error) {
 	// Run `gt prime` in the working directory
-	return e.runCommand(ctx, "gt", []string{"prime"}, DefaultShellTimeout)
+	return e.runCommand(ctx, "gt", []string{"prime"}, e.shellTimeout())
 }
 
 func (e *Executor) execGTDone(ctx context.Context, args json.RawMessage) (string, error) {
@@ -108,7 +252,7 @@ func (e *Executor) execGTDone(ctx context.Context, args json.RawMessage) (string
 	if params.Message == "" {
 		return "", fmt.Errorf("gt_done requires a message")
 	}
-	return e.runCommand(ctx, "gt", []string{"done", "-m", params.Message}, DefaultShellTimeout)
+	return e.runCommand(ctx, "gt", []string{"done", "-m", params.Message}, e.shellTimeout())
 }
 
 func (e *Executor) execBDShow(ctx context.Context, args json.RawMessage) (string, error) {
@@ -121,7 +265,7 @@ func (e *Executor) execBDShow(ctx context.Context, args json.RawMessage) (string
 	if params.IssueID == "" {
 		return "", fmt.Errorf("bd_show requires issue_id")
 	}
-	return e.runCommand(ctx, "bd", []string{"show", params.IssueID}, DefaultShellTimeout)
+	return e.runCommand(ctx, "bd", []string{"show", params.IssueID}, e.shellTimeout())
 }
 
 func (e *Executor) execBDList(ctx context.Context, args json.RawMessage) (string, error) {
@@ -140,7 +284,7 @@ func (e *Executor) execBDList(ctx context.Context, args json.RawMessage) (string
 	if params.Label != "" {
 		cmdArgs = append(cmdArgs, "--label", params.Label)
 	}
-	return e.runCommand(ctx, "bd", cmdArgs, DefaultShellTimeout)
+	return e.runCommand(ctx, "bd", cmdArgs, e.shellTimeout())
 }
 
 func (e *Executor) execBDUpdate(ctx context.Context, args json.RawMessage) (string, error) {
@@ -163,7 +307,7 @@ func (e *Executor) execBDUpdate(ctx context.Context, args json.RawMessage) (stri
 	if params.Comment != "" {
 		cmdArgs = append(cmdArgs, "--comment", params.Comment)
 	}
-	return e.runCommand(ctx, "bd", cmdArgs, DefaultShellTimeout)
+	return e.runCommand(ctx, "bd", cmdArgs, e.shellTimeout())
 }
 
 func (e *Executor) execGitDiff(ctx context.Context, args json.RawMessage) (string, error) {
@@ -186,11 +330,11 @@ func (e *Executor) execGitDiff(ctx context.Context, args json.RawMessage) (strin
 		}
 		cmdArgs = append(cmdArgs, "--", safePath)
 	}
-	return e.runCommand(ctx, "git", cmdArgs, DefaultShellTimeout)
+	return e.runCommand(ctx, "git", cmdArgs, e.shellTimeout())
 }
 
 func (e *Executor) execGitStatus(ctx context.Context) (string, error) {
-	return e.runCommand(ctx, "git", []string{"status", "--short"}, DefaultShellTimeout)
+	return e.runCommand(ctx, "git", []string{"status", "--short"}, e.shellTimeout())
 }
 
 func (e *Executor) execGitCommit(ctx context.Context, args json.RawMessage) (string, error) {
@@ -215,24 +359,152 @@ func (e *Executor) execGitCommit(ctx context.Context, args json.RawMessage) (str
 			}
 			addArgs = append(addArgs, safePath)
 		}
-		if _, err := e.runCommand(ctx, "git", addArgs, DefaultShellTimeout); err != nil {
+		if _, err := e.runCommand(ctx, "git", addArgs, e.shellTimeout()); err != nil {
 			return "", fmt.Errorf("git add failed: %w", err)
 		}
 	} else {
-		if _, err := e.runCommand(ctx, "git", []string{"add", "-A"}, DefaultShellTimeout); err != nil {
+		if _, err := e.runCommand(ctx, "git", []string{"add", "-A"}, e.shellTimeout()); err != nil {
 			return "", fmt.Errorf("git add -A failed: %w", err)
 		}
 	}
 
 	// Commit
-	return e.runCommand(ctx, "git", []string{"commit", "-m", params.Message}, DefaultShellTimeout)
+	return e.runCommand(ctx, "git", []string{"commit", "-m", params.Message}, e.shellTimeout())
+}
+
+func (e *Executor) execGitLog(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Path     string `json:"path"`
+		MaxCount int    `json:"max_count"`
+		Oneline  bool   `json:"oneline"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("parsing git_log args: %w", err)
+		}
+	}
+	if params.MaxCount <= 0 {
+		params.MaxCount = DefaultGitLogCount
+	}
+
+	cmdArgs := []string{"log", fmt.Sprintf("--max-count=%d", params.MaxCount)}
+	if params.Oneline {
+		cmdArgs = append(cmdArgs, "--oneline")
+	}
+	if params.Path != "" {
+		safePath, err := e.safePath(params.Path)
+		if err != nil {
+			return "", err
+		}
+		cmdArgs = append(cmdArgs, "--", safePath)
+	}
+	return e.runCommand(ctx, "git", cmdArgs, e.shellTimeout())
+}
+
+func (e *Executor) execGitShow(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Ref string `json:"ref"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("parsing git_show args: %w", err)
+		}
+	}
+	if params.Ref == "" {
+		params.Ref = "HEAD"
+	}
+	// A ref starting with "-" would be parsed as an option (e.g. --output).
+	if strings.HasPrefix(params.Ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", params.Ref)
+	}
+	return e.runCommand(ctx, "git", []string{"show", params.Ref, "--"}, e.shellTimeout())
+}
+
+// branchNamePattern is the charset allowed in git_branch and git_checkout
+// names and bases; git applies its own, stricter ref rules on top.
+var branchNamePattern = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+// validateBranchName rejects names that could be read as options or fall
+// outside branchNamePattern.
+func validateBranchName(name string) error {
+	if !branchNamePattern.MatchString(name) || strings.HasPrefix(name, "-") || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid branch name %q", name)
+	}
+	return nil
+}
+
+func (e *Executor) execGitBranch(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Name string `json:"name"`
+		Base string `json:"base"`
+	}
+	if len(args) > 0 {
+		if err := json.Unmarshal(args, &params); err != nil {
+			return "", fmt.Errorf("parsing git_branch args: %w", err)
+		}
+	}
+	if params.Name == "" {
+		if params.Base != "" {
+			return "", fmt.Errorf("git_branch base requires a name")
+		}
+		return e.gitBranchState(ctx)
+	}
+
+	cmdArgs := []string{"branch", "--"}
+	for _, ref := range []string{params.Name, params.Base} {
+		if ref == "" {
+			continue
+		}
+		if err := validateBranchName(ref); err != nil {
+			return "", err
+		}
+		cmdArgs = append(cmdArgs, ref)
+	}
+	if _, err := e.runCommand(ctx, "git", cmdArgs, e.shellTimeout()); err != nil {
+		return "", fmt.Errorf("git branch failed: %w", err)
+	}
+	return e.gitBranchState(ctx)
+}
+
+func (e *Executor) execGitCheckout(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Branch string `json:"branch"`
+		Create bool   `json:"create"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing git_checkout args: %w", err)
+	}
+	if params.Branch == "" {
+		return "", fmt.Errorf("git_checkout requires a branch")
+	}
+	if err := validateBranchName(params.Branch); err != nil {
+		return "", err
+	}
+
+	// The trailing "--" keeps git from reading the branch as a path.
+	cmdArgs := []string{"checkout", params.Branch, "--"}
+	if params.Create {
+		cmdArgs = []string{"checkout", "-b", params.Branch, "--"}
+	}
+	if _, err := e.runCommand(ctx, "git", cmdArgs, e.shellTimeout()); err != nil {
+		return "", fmt.Errorf("git checkout failed: %w", err)
+	}
+	return e.gitBranchState(ctx)
+}
+
+// gitBranchState lists local branches, marking the current one.
+func (e *Executor) gitBranchState(ctx context.Context) (string, error) {
+	return e.runCommand(ctx, "git", []string{"branch", "--list"}, e.shellTimeout())
 }
 
 func (e *Executor) execFileRead(_ context.Context, args json.RawMessage) (string, error) {
 	var params struct {
-		Path      string `json:"path"`
-		StartLine int    `json:"start_line"`
-		EndLine   int    `json:"end_line"`
+		Path         string `json:"path"`
+		StartLine    int    `json:"start_line"`
+		EndLine      int    `json:"end_line"`
+		Search       string `json:"search"`
+		ContextLines *int   `json:"context_lines"`
+		Force        bool   `json:"force"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("parsing file_read args: %w", err)
@@ -240,6 +512,9 @@ func (e *Executor) execFileRead(_ context.Context, args json.RawMessage) (string
 	if params.Path == "" {
 		return "", fmt.Errorf("file_read requires path")
 	}
+	if params.Search != "" && (params.StartLine > 0 || params.EndLine > 0) {
+		return "", fmt.Errorf("file_read takes either search or start_line/end_line, not both")
+	}
 
 	absPath, err := e.safePath(params.Path)
 	if err != nil {
@@ -250,8 +525,8 @@ func (e *Executor) execFileRead(_ context.Context, args json.RawMessage) (string
 	if err != nil {
 		return "", fmt.Errorf("file not found: %s", params.Path)
 	}
-	if info.Size() > MaxFileReadSize {
-		return "", fmt.Errorf("file too large (%d bytes, max %d)", info.Size(), MaxFileReadSize)
+	if maxSize := e.maxFileReadSize(); info.Size() > maxSize {
+		return "", fmt.Errorf("file too large (%d bytes, max %d)", info.Size(), maxSize)
 	}
 
 	data, err := os.ReadFile(absPath)
@@ -259,8 +534,26 @@ func (e *Executor) execFileRead(_ context.Context, args json.RawMessage) (string
 		return "", fmt.Errorf("reading file: %w", err)
 	}
 
+	// Binary content wastes context and reads as garbage once line-numbered.
+	if ctype, binary := binaryContentType(data); binary && !params.Force {
+		return "", fmt.Errorf("refusing to read binary file %s (type %s, %s); use a different tool, or pass force: true to read it anyway",
+			params.Path, ctype, formatBytes(info.Size()))
+	}
+
 	content := string(data)
 
+	if params.Search != "" {
+		contextLines := defaultReadContextLines
+		if params.ContextLines != nil {
+			contextLines = max(*params.ContextLines, 0)
+		}
+		result := readAround(params.Path, content, params.Search, contextLines)
+		if maxOutput := e.maxOutputSize(); len(result) > maxOutput {
+			return result[:maxOutput] + "\n... (truncated)", nil
+		}
+		return result, nil
+	}
+
 	// Apply line range filter if specified
 	if params.StartLine > 0 || params.EndLine > 0 {
 		lines := strings.Split(content, "\n")
@@ -293,12 +586,55 @@ func (e *Executor) execFileRead(_ context.Context, args json.RawMessage) (string
 	}
 
 	result := sb.String()
-	if len(result) > MaxOutputSize {
-		return result[:MaxOutputSize] + "\n... (truncated)", nil
+	if maxOutput := e.maxOutputSize(); len(result) > maxOutput {
+		return result[:maxOutput] + "\n... (truncated)", nil
 	}
 	return result, nil
 }
 
+// defaultReadContextLines is how many lines file_read shows on each side of
+// a search match.
+const defaultReadContextLines = 10
+
+// readAround returns the first line of content matching search, with
+// contextLines lines either side, numbered like a full file_read. search is
+// a regex, or literal text if it doesn't compile as one. A header notes how
+// many lines matched in total so the model can narrow the search.
+func readAround(path, content, search string, contextLines int) string {
+	re, err := regexp.Compile(search)
+	if err != nil {
+		re = regexp.MustCompile(regexp.QuoteMeta(search))
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	first, matches := -1, 0
+	for i, line := range lines {
+		if re.MatchString(line) {
+			if first < 0 {
+				first = i
+			}
+			matches++
+		}
+	}
+	if first < 0 {
+		return fmt.Sprintf("(no lines in %s match %q)", path, search)
+	}
+
+	start := max(first-contextLines, 0)
+	end := min(first+contextLines+1, len(lines))
+
+	var sb strings.Builder
+	if matches == 1 {
+		fmt.Fprintf(&sb, "(line %d matches %q; showing lines %d-%d of %d)\n", first+1, search, start+1, end, len(lines))
+	} else {
+		fmt.Fprintf(&sb, "(first of %d matching lines is %d; showing lines %d-%d of %d)\n", matches, first+1, start+1, end, len(lines))
+	}
+	for i := start; i < end; i++ {
+		fmt.Fprintf(&sb, "%d: %s\n", i+1, lines[i])
+	}
+	return sb.String()
+}
+
 func (e *Executor) execFileWrite(_ context.Context, args json.RawMessage) (string, error) {
 	var params struct {
 		Path    string `json:"path"`
@@ -331,9 +667,11 @@ func (e *Executor) execFileWrite(_ context.Context, args json.RawMessage) (strin
 
 func (e *Executor) execFileEdit(_ context.Context, args json.RawMessage) (string, error) {
 	var params struct {
-		Path    string `json:"path"`
-		Search  string `json:"search"`
-		Replace string `json:"replace"`
+		Path          string `json:"path"`
+		Search        string `json:"search"`
+		Replace       string `json:"replace"`
+		ReplaceAll    bool   `json:"replace_all"`
+		ExpectedCount int    `json:"expected_count"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("parsing file_edit args: %w", err)
@@ -341,6 +679,9 @@ func (e *Executor) execFileEdit(_ context.Context, args json.RawMessage) (string
 	if params.Path == "" || params.Search == "" {
 		return "", fmt.Errorf("file_edit requires path and search")
 	}
+	if params.ExpectedCount > 1 && !params.ReplaceAll {
+		return "", fmt.Errorf("file_edit expected_count %d requires replace_all, since only the first occurrence is replaced otherwise", params.ExpectedCount)
+	}
 
 	absPath, err := e.safePath(params.Path)
 	if err != nil {
@@ -353,24 +694,197 @@ func (e *Executor) execFileEdit(_ context.Context, args json.RawMessage) (string
 	}
 
 	content := string(data)
-	if !strings.Contains(content, params.Search) {
+	count := strings.Count(content, params.Search)
+	if count == 0 {
 		return "", fmt.Errorf("search text not found in %s", params.Path)
 	}
+	// Guard against ambiguous edits before anything is written.
+	if params.ExpectedCount > 0 && count != params.ExpectedCount {
+		return "", fmt.Errorf("search text found %d times in %s, expected %d", count, params.Path, params.ExpectedCount)
+	}
 
-	// Replace first occurrence
-	newContent := strings.Replace(content, params.Search, params.Replace, 1)
+	if !params.ReplaceAll {
+		// Replace first occurrence
+		count = 1
+	}
+	newContent := strings.Replace(content, params.Search, params.Replace, count)
 	if err := os.WriteFile(absPath, []byte(newContent), 0644); err != nil {
 		return "", fmt.Errorf("writing file: %w", err)
 	}
 
+	if params.ReplaceAll {
+		return fmt.Sprintf("Applied edit to %s (%d replacements)", params.Path, count), nil
+	}
 	return fmt.Sprintf("Applied edit to %s", params.Path), nil
 }
 
-func (e *Executor) execFileList(_ context.Context, args json.RawMessage) (string, error) {
+func (e *Executor) execMultiEdit(_ context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Path  string `json:"path"`
+		Edits []struct {
+			Search  string `json:"search"`
+			Replace string `json:"replace"`
+		} `json:"edits"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing multi_edit args: %w", err)
+	}
+	if params.Path == "" || len(params.Edits) == 0 {
+		return "", fmt.Errorf("multi_edit requires path and at least one edit")
+	}
+
+	absPath, err := e.safePath(params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	// Apply edits in order to an in-memory copy; the file is written only
+	// if every edit succeeds.
+	content := string(data)
+	for i, edit := range params.Edits {
+		if edit.Search == "" {
+			return "", fmt.Errorf("edit %d: search is empty; no changes written", i+1)
+		}
+		if !strings.Contains(content, edit.Search) {
+			return "", fmt.Errorf("edit %d: search text not found in %s; no changes written", i+1, params.Path)
+		}
+		content = strings.Replace(content, edit.Search, edit.Replace, 1)
+	}
+
+	if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("writing file: %w", err)
+	}
+
+	return fmt.Sprintf("Applied %d edits to %s", len(params.Edits), params.Path), nil
+}
+
+func (e *Executor) execApplyPatch(_ context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Patch string `json:"patch"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing apply_patch args: %w", err)
+	}
+	if strings.TrimSpace(params.Patch) == "" {
+		return "", fmt.Errorf("apply_patch requires patch")
+	}
+
+	files, err := parseUnifiedDiff(params.Patch)
+	if err != nil {
+		return "", fmt.Errorf("parsing patch: %w", err)
+	}
+
+	// Apply everything in memory first so a bad hunk means no writes.
+	changes, err := e.planPatch(files)
+	if err != nil {
+		return "", fmt.Errorf("patch rejected, no files changed: %w", err)
+	}
+	if err := commitPatch(changes); err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Applied patch to %d file(s):\n", len(changes))
+	for _, ch := range changes {
+		fmt.Fprintf(&sb, "  %c %s (+%d -%d)\n", ch.kind, ch.display, ch.added, ch.removed)
+	}
+	return sb.String(), nil
+}
+
+func (e *Executor) execFileDelete(_ context.Context, args json.RawMessage) (string, error) {
 	var params struct {
 		Path      string `json:"path"`
 		Recursive bool   `json:"recursive"`
-		Pattern   string `json:"pattern"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing file_delete args: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("file_delete requires path")
+	}
+
+	absPath, err := e.safePath(params.Path)
+	if err != nil {
+		return "", err
+	}
+	if e.isWorkDir(absPath) {
+		return "", fmt.Errorf("refusing to delete the working directory")
+	}
+
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return "", fmt.Errorf("stat %s: %w", params.Path, err)
+	}
+	if info.IsDir() {
+		if !params.Recursive {
+			return "", fmt.Errorf("%s is a directory; set recursive to delete it", params.Path)
+		}
+		if err := os.RemoveAll(absPath); err != nil {
+			return "", fmt.Errorf("deleting directory: %w", err)
+		}
+		return fmt.Sprintf("Deleted directory %s", params.Path), nil
+	}
+
+	if err := os.Remove(absPath); err != nil {
+		return "", fmt.Errorf("deleting file: %w", err)
+	}
+	return fmt.Sprintf("Deleted %s", params.Path), nil
+}
+
+func (e *Executor) execFileMove(_ context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing file_move args: %w", err)
+	}
+	if params.From == "" || params.To == "" {
+		return "", fmt.Errorf("file_move requires from and to")
+	}
+
+	fromPath, err := e.safePath(params.From)
+	if err != nil {
+		return "", err
+	}
+	toPath, err := e.safePath(params.To)
+	if err != nil {
+		return "", err
+	}
+	if e.isWorkDir(fromPath) {
+		return "", fmt.Errorf("refusing to move the working directory")
+	}
+
+	if _, err := os.Lstat(fromPath); err != nil {
+		return "", fmt.Errorf("stat %s: %w", params.From, err)
+	}
+	// os.Rename silently replaces an existing file; make the model delete
+	// the destination explicitly instead.
+	if _, err := os.Lstat(toPath); err == nil {
+		return "", fmt.Errorf("destination %s already exists", params.To)
+	}
+
+	// Create parent directories
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return "", fmt.Errorf("creating directories: %w", err)
+	}
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return "", fmt.Errorf("moving file: %w", err)
+	}
+	return fmt.Sprintf("Moved %s to %s", params.From, params.To), nil
+}
+
+func (e *Executor) execFileList(_ context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Path             string `json:"path"`
+		Recursive        bool   `json:"recursive"`
+		Pattern          string `json:"pattern"`
+		RespectGitignore *bool  `json:"respect_gitignore"`
 	}
 	if len(args) > 0 {
 		_ = json.Unmarshal(args, &params)
@@ -385,6 +899,16 @@ func (e *Executor) execFileList(_ context.Context, args json.RawMessage) (string
 		dir = safePath
 	}
 
+	// Ignore rules are on by default; load those from the worktree root
+	// down to the listed directory.
+	var ignore *gitignoreMatcher
+	if params.RespectGitignore == nil || *params.RespectGitignore {
+		ignore = newGitignoreMatcher()
+		if relDir, err := filepath.Rel(e.workDir, dir); err == nil {
+			ignore.loadAncestors(e.workDir, relDir)
+		}
+	}
+
 	var sb strings.Builder
 	if params.Recursive {
 		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
@@ -398,6 +922,17 @@ func (e *Executor) execFileList(_ context.Context, args json.RawMessage) (string
 				}
 				return nil
 			}
+			if ignore != nil && relPath != "." {
+				if ignore.Ignored(relPath, info.IsDir()) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.IsDir() {
+					ignore.loadDir(e.workDir, relPath)
+				}
+			}
 			if params.Pattern != "" {
 				matched, _ := filepath.Match(params.Pattern, filepath.Base(path))
 				if !matched {
@@ -423,6 +958,11 @@ func (e *Executor) execFileList(_ context.Context, args json.RawMessage) (string
 			if strings.HasPrefix(entry.Name(), ".git") {
 				continue
 			}
+			if ignore != nil {
+				if relPath, err := filepath.Rel(e.workDir, filepath.Join(dir, entry.Name())); err == nil && ignore.Ignored(relPath, entry.IsDir()) {
+					continue
+				}
+			}
 			if params.Pattern != "" {
 				matched, _ := filepath.Match(params.Pattern, entry.Name())
 				if !matched {
@@ -450,9 +990,10 @@ func (e *Executor) execFileList(_ context.Context, args json.RawMessage) (string
 
 func (e *Executor) execFileSearch(ctx context.Context, args json.RawMessage) (string, error) {
 	var params struct {
-		Pattern string `json:"pattern"`
-		Path    string `json:"path"`
-		Include string `json:"include"`
+		Pattern string   `json:"pattern"`
+		Path    string   `json:"path"`
+		Include globList `json:"include"`
+		Exclude globList `json:"exclude"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("parsing file_search args: %w", err)
@@ -461,13 +1002,6 @@ func (e *Executor) execFileSearch(ctx context.Context, args json.RawMessage) (st
 		return "", fmt.Errorf("file_search requires pattern")
 	}
 
-	// Use grep for content search
-	cmdArgs := []string{"-rn", "--color=never"}
-	if params.Include != "" {
-		cmdArgs = append(cmdArgs, "--include="+params.Include)
-	}
-	cmdArgs = append(cmdArgs, params.Pattern)
-
 	searchDir := e.workDir
 	if params.Path != "" {
 		safePath, err := e.safePath(params.Path)
@@ -476,7 +1010,16 @@ func (e *Executor) execFileSearch(ctx context.Context, args json.RawMessage) (st
 		}
 		searchDir = safePath
 	}
-	cmdArgs = append(cmdArgs, searchDir)
+
+	// Minimal images often lack grep; search natively instead.
+	if _, err := exec.LookPath("grep"); err != nil {
+		return e.searchFiles(ctx, params.Pattern, searchDir, params.Include, params.Exclude)
+	}
+
+	// Use grep for content search
+	cmdArgs := []string{"-rn", "--color=never"}
+	cmdArgs = append(cmdArgs, grepFilterArgs(params.Include, params.Exclude)...)
+	cmdArgs = append(cmdArgs, params.Pattern, searchDir)
 
 	cmd := exec.CommandContext(ctx, "grep", cmdArgs...)
 	cmd.Dir = e.workDir
@@ -487,21 +1030,40 @@ func (e *Executor) execFileSearch(ctx context.Context, args json.RawMessage) (st
 	err := cmd.Run()
 	output := stdout.String()
 
+	// grep was found but could not be started (broken binary, exec format
+	// error, ...): fall back to the native search.
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && ctx.Err() == nil {
+		return e.searchFiles(ctx, params.Pattern, searchDir, params.Include, params.Exclude)
+	}
+
 	// grep exits 1 when no matches found — that's not an error
 	if err != nil && output == "" {
 		return "(no matches found)", nil
 	}
 
-	if len(output) > MaxOutputSize {
-		output = output[:MaxOutputSize] + "\n... (truncated)"
+	if maxOutput := e.maxOutputSize(); len(output) > maxOutput {
+		output = output[:maxOutput] + "\n... (truncated)"
 	}
 	return output, nil
 }
 
+// protectedEnv are the identity variables runCommand sets; shell_exec's env
+// parameter may not override them.
+var protectedEnv = map[string]bool{
+	"GT_ROLE":      true,
+	"GT_RIG":       true,
+	"GT_TOWN_ROOT": true,
+	"GT_ROOT":      true,
+	"GT_ACTOR":     true,
+}
+
 func (e *Executor) execShell(ctx context.Context, args json.RawMessage) (string, error) {
 	var params struct {
-		Command        string `json:"command"`
-		TimeoutSeconds int    `json:"timeout_seconds"`
+		Command        string            `json:"command"`
+		TimeoutSeconds int               `json:"timeout_seconds"`
+		Cwd            string            `json:"cwd"`
+		Env            map[string]string `json:"env"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("parsing shell_exec args: %w", err)
@@ -510,19 +1072,52 @@ func (e *Executor) execShell(ctx context.Context, args json.RawMessage) (string,
 		return "", fmt.Errorf("shell_exec requires command")
 	}
 
-	timeout := DefaultShellTimeout
+	timeout := e.shellTimeout()
 	if params.TimeoutSeconds > 0 {
 		timeout = time.Duration(params.TimeoutSeconds) * time.Second
 	}
 
-	return e.runCommand(ctx, "bash", []string{"-c", params.Command}, timeout)
+	dir := e.workDir
+	if params.Cwd != "" {
+		absPath, err := e.safePath(params.Cwd)
+		if err != nil {
+			return "", err
+		}
+		info, err := os.Stat(absPath)
+		if err != nil {
+			return "", fmt.Errorf("cwd %q: %w", params.Cwd, err)
+		}
+		if !info.IsDir() {
+			return "", fmt.Errorf("cwd %q is not a directory", params.Cwd)
+		}
+		dir = absPath
+	}
+
+	keys := make([]string, 0, len(params.Env))
+	for key := range params.Env {
+		if key == "" || strings.ContainsAny(key, "=\x00") {
+			return "", fmt.Errorf("invalid env variable name %q", key)
+		}
+		if protectedEnv[key] {
+			return "", fmt.Errorf("env may not override %s", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+params.Env[key])
+	}
+
+	return e.runCommandIn(ctx, dir, env, "bash", []string{"-c", params.Command}, timeout)
 }
 
 func (e *Executor) execMailSend(ctx context.Context, args json.RawMessage) (string, error) {
 	var params struct {
-		To      string `json:"to"`
-		Subject string `json:"subject"`
-		Body    string `json:"body"`
+		To        string `json:"to"`
+		Subject   string `json:"subject"`
+		Body      string `json:"body"`
+		InReplyTo string `json:"in_reply_to"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("parsing gt_mail_send args: %w", err)
@@ -535,7 +1130,14 @@ func (e *Executor) execMailSend(ctx context.Context, args json.RawMessage) (stri
 	if params.Body != "" {
 		cmdArgs = append(cmdArgs, "--body", params.Body)
 	}
-	return e.runCommand(ctx, "gt", cmdArgs, DefaultShellTimeout)
+	if params.InReplyTo != "" {
+		// gt mail send threads the reply under the original message.
+		if strings.HasPrefix(params.InReplyTo, "-") {
+			return "", fmt.Errorf("invalid message ID %q", params.InReplyTo)
+		}
+		cmdArgs = append(cmdArgs, "--reply-to", params.InReplyTo)
+	}
+	return e.runCommand(ctx, "gt", cmdArgs, e.shellTimeout())
 }
 
 func (e *Executor) execMailRead(ctx context.Context, args json.RawMessage) (string, error) {
@@ -547,28 +1149,91 @@ func (e *Executor) execMailRead(ctx context.Context, args json.RawMessage) (stri
 		_ = json.Unmarshal(args, &params)
 	}
 
-	cmdArgs := []string{"mail", "read"}
-	if params.Count > 0 {
-		cmdArgs = append(cmdArgs, "--count", fmt.Sprintf("%d", params.Count))
+	if params.Count <= 0 {
+		params.Count = DefaultMailReadCount
 	}
+
+	cmdArgs := []string{"mail", "inbox", "--json"}
 	if params.UnreadOnly {
 		cmdArgs = append(cmdArgs, "--unread")
 	}
-	return e.runCommand(ctx, "gt", cmdArgs, DefaultShellTimeout)
+	out, err := e.runCommand(ctx, "gt", cmdArgs, e.shellTimeout())
+	if err != nil {
+		return out, err
+	}
+
+	// Decode stdout only; runCommand appends any stderr after it.
+	out, _, _ = strings.Cut(out, "\nSTDERR: ")
+	var messages []mailMessage
+	if err := json.Unmarshal([]byte(out), &messages); err != nil {
+		return "", fmt.Errorf("parsing gt mail inbox output: %w", err)
+	}
+	return formatMail(messages, params.Count), nil
+}
+
+// mailMessage is the part of a `gt mail inbox --json` message the model sees.
+type mailMessage struct {
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	Timestamp time.Time `json:"timestamp"`
+	Read      bool      `json:"read"`
+	ReplyTo   string    `json:"reply_to"`
+}
+
+// formatMail renders up to limit messages, each headed by its ID so the
+// model can answer it with gt_mail_send's in_reply_to.
+func formatMail(messages []mailMessage, limit int) string {
+	if len(messages) == 0 {
+		return "(no messages)"
+	}
+	var sb strings.Builder
+	for i, msg := range messages {
+		if i == limit {
+			fmt.Fprintf(&sb, "(%d more messages not shown)\n", len(messages)-limit)
+			break
+		}
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		status := "read"
+		if !msg.Read {
+			status = "unread"
+		}
+		fmt.Fprintf(&sb, "ID: %s (%s)\nFrom: %s\nDate: %s\nSubject: %s\n",
+			msg.ID, status, msg.From, msg.Timestamp.UTC().Format(time.RFC3339), msg.Subject)
+		if msg.ReplyTo != "" {
+			fmt.Fprintf(&sb, "In-Reply-To: %s\n", msg.ReplyTo)
+		}
+		if msg.Body != "" {
+			fmt.Fprintf(&sb, "\n%s\n", strings.TrimRight(msg.Body, "\n"))
+		}
+	}
+	return sb.String()
 }
 
 // --- Helpers ---
 
 // runCommand executes a command in the working directory with a timeout.
 func (e *Executor) runCommand(ctx context.Context, name string, args []string, timeout time.Duration) (string, error) {
+	return e.runCommandIn(ctx, e.workDir, nil, name, args, timeout)
+}
+
+// runCommandIn executes a command in dir with a timeout. extraEnv
+// ("KEY=value" entries) is added to the environment beneath the GT
+// variables, which always take precedence.
+func (e *Executor) runCommandIn(ctx context.Context, dir string, extraEnv []string, name string, args []string, timeout time.Duration) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, name, args...)
-	cmd.Dir = e.workDir
+	cmd.Dir = dir
+	setProcessGroup(cmd, commandKillGrace)
 
 	// Set GT environment variables
-	cmd.Env = append(os.Environ(),
+	cmd.Env = append(os.Environ(), extraEnv...)
+	cmd.Env = append(cmd.Env,
 		"GT_ROLE="+e.role,
 		"GT_RIG="+e.rigName,
 		"GT_TOWN_ROOT="+e.townRoot,
@@ -576,21 +1241,31 @@ func (e *Executor) runCommand(ctx context.Context, name string, args []string, t
 		"GT_ACTOR="+e.actor,
 	)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Output is streamed to the context's OutputFunc, if any, as it arrives;
+	// only the first maxOutputSize bytes of each stream are kept.
+	maxOutput := e.maxOutputSize()
+	out := newCommandOutput(maxOutput, outputStreamFrom(ctx))
+	cmd.Stdout = out.stdoutWriter()
+	cmd.Stderr = out.stderrWriter()
+	// Don't let a background child holding the pipes open keep Run from
+	// returning the partial output after a timeout.
+	cmd.WaitDelay = commandWaitDelay
 
 	err := cmd.Run()
-	output := stdout.String()
-	if stderr.Len() > 0 {
+	output := out.stdout.buf.String()
+	if out.stderr.buf.Len() > 0 {
 		if output != "" {
 			output += "\n"
 		}
-		output += "STDERR: " + stderr.String()
+		output += "STDERR: " + out.stderr.buf.String()
 	}
 
-	if len(output) > MaxOutputSize {
-		output = output[:MaxOutputSize] + "\n... (truncated)"
+	if dropped := out.dropped(); len(output) > maxOutput || dropped > 0 {
+		if len(output) > maxOutput {
+			dropped += len(output) - maxOutput
+			output = output[:maxOutput]
+		}
+		output += fmt.Sprintf("\n... (truncated, %d bytes omitted)", dropped)
 	}
 
 	if err != nil {
@@ -650,6 +1325,12 @@ func (e *Executor) safePath(path string) (string, error) {
 	return absPath, nil
 }
 
+// isWorkDir reports whether absPath (as returned by safePath) is the
+// working directory itself.
+func (e *Executor) isWorkDir(absPath string) bool {
+	return absPath == filepath.Clean(e.workDir)
+}
+
 // WorkDir returns the executor's working directory.
 func (e *Executor) WorkDir() string {
 	return e.workDir
@@ -0,0 +1,691 @@
+package agentloop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+func newTestExecutor(t *testing.T, files map[string]string) *Executor {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewExecutor(dir, "rig", dir, dir, "rig/polecats/Test", "polecat")
+}
+
+func execTool(t *testing.T, e *Executor, name string, args interface{}) (string, error) {
+	t.Helper()
+	raw, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e.Execute(context.Background(), llm.ToolCall{ID: "t", Name: name, Args: raw})
+}
+
+func readFile(t *testing.T, e *Executor, name string) string {
+	t.Helper()
+	data, err := readFileErr(e, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func readFileErr(e *Executor, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(e.WorkDir(), name))
+	return string(data), err
+}
+
+func TestFileEditReplaceAllAndExpectedCount(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"f.go": "foo(); foo(); foo()\n"})
+
+	_, err := execTool(t, e, "file_edit", map[string]interface{}{
+		"path": "f.go", "search": "foo", "replace": "bar", "replace_all": true, "expected_count": 2,
+	})
+	if err == nil || !strings.Contains(err.Error(), "found 3 times") {
+		t.Fatalf("err = %v, want expected_count mismatch", err)
+	}
+	if got := readFile(t, e, "f.go"); got != "foo(); foo(); foo()\n" {
+		t.Fatalf("file modified despite count mismatch: %q", got)
+	}
+
+	out, err := execTool(t, e, "file_edit", map[string]interface{}{
+		"path": "f.go", "search": "foo", "replace": "bar", "replace_all": true, "expected_count": 3,
+	})
+	if err != nil {
+		t.Fatalf("file_edit: %v", err)
+	}
+	if !strings.Contains(out, "3 replacements") {
+		t.Errorf("output = %q", out)
+	}
+	if got := readFile(t, e, "f.go"); got != "bar(); bar(); bar()\n" {
+		t.Errorf("f.go = %q", got)
+	}
+
+	// Without replace_all only one occurrence would change, so a count
+	// above 1 can never describe the edit.
+	if _, err := execTool(t, e, "file_edit", map[string]interface{}{
+		"path": "f.go", "search": "bar", "replace": "baz", "expected_count": 3,
+	}); err == nil || !strings.Contains(err.Error(), "requires replace_all") {
+		t.Errorf("err = %v, want expected_count rejected without replace_all", err)
+	}
+	if got := readFile(t, e, "f.go"); got != "bar(); bar(); bar()\n" {
+		t.Errorf("f.go modified by a rejected edit: %q", got)
+	}
+
+	if _, err := execTool(t, e, "file_edit", map[string]interface{}{
+		"path": "f.go", "search": "foo", "replace": "baz",
+	}); err == nil || !strings.Contains(err.Error(), "search text not found") {
+		t.Errorf("err = %v, want not found", err)
+	}
+
+	if _, err := execTool(t, e, "file_edit", map[string]interface{}{
+		"path": "f.go", "search": "bar", "replace": "baz",
+	}); err != nil {
+		t.Fatalf("file_edit: %v", err)
+	}
+	if got := readFile(t, e, "f.go"); got != "baz(); bar(); bar()\n" {
+		t.Errorf("default edit should replace only the first occurrence: %q", got)
+	}
+}
+
+func TestFileReadSearchReturnsMatchWithContext(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 40; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	lines[19] = "func Target() {"
+	lines[29] = "	Target()"
+	e := newTestExecutor(t, map[string]string{"big.go": strings.Join(lines, "\n") + "\n"})
+
+	out, err := execTool(t, e, "file_read", map[string]interface{}{
+		"path": "big.go", "search": `func \w+\(`, "context_lines": 2,
+	})
+	if err != nil {
+		t.Fatalf("file_read: %v", err)
+	}
+	want := "(line 20 matches \"func \\\\w+\\\\(\"; showing lines 18-22 of 40)\n" +
+		"18: line 18\n19: line 19\n20: func Target() {\n21: line 21\n22: line 22\n"
+	if out != want {
+		t.Errorf("file_read =\n%s\nwant\n%s", out, want)
+	}
+
+	// Literal text that isn't a valid regex; several matches are counted.
+	out, err = execTool(t, e, "file_read", map[string]interface{}{
+		"path": "big.go", "search": "Target(", "context_lines": 0,
+	})
+	if err != nil {
+		t.Fatalf("file_read: %v", err)
+	}
+	if want := "(first of 2 matching lines is 20; showing lines 20-20 of 40)\n20: func Target() {\n"; out != want {
+		t.Errorf("file_read =\n%s\nwant\n%s", out, want)
+	}
+
+	// Context is clamped to the file.
+	out, _ = execTool(t, e, "file_read", map[string]interface{}{"path": "big.go", "search": "line 2$"})
+	if !strings.HasPrefix(out, "(line 2 matches") || !strings.Contains(out, "showing lines 1-12 of 40") {
+		t.Errorf("file_read near start = %q", out)
+	}
+
+	out, err = execTool(t, e, "file_read", map[string]interface{}{"path": "big.go", "search": "nowhere"})
+	if err != nil || !strings.Contains(out, "no lines in big.go match") {
+		t.Errorf("file_read no match = %q, %v", out, err)
+	}
+
+	_, err = execTool(t, e, "file_read", map[string]interface{}{"path": "big.go", "search": "x", "start_line": 3})
+	if err == nil {
+		t.Error("file_read with search and start_line should fail")
+	}
+}
+
+func TestFileReadRefusesBinaryFiles(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00\x01", 2000)
+	// A multi-byte character straddles the 512-byte sniff boundary.
+	utf8Text := strings.Repeat("a", 511) + "é\nline two\n"
+	e := newTestExecutor(t, map[string]string{
+		"logo.png":   png,
+		"doc.pdf":    "%PDF-1.7\n%more ascii\n",
+		"blob.bin":   "abc\x00def",
+		"latin1.txt": "caf\xe9\n",
+		"utf8.txt":   utf8Text,
+	})
+
+	for name, ctype := range map[string]string{
+		"logo.png":   "image/png",
+		"doc.pdf":    "application/pdf",
+		"blob.bin":   "application/octet-stream",
+		"latin1.txt": "text/plain",
+	} {
+		_, err := execTool(t, e, "file_read", map[string]interface{}{"path": name})
+		if err == nil || !strings.Contains(err.Error(), "refusing to read binary file") || !strings.Contains(err.Error(), ctype) {
+			t.Errorf("file_read %s err = %v, want a binary refusal naming %s", name, err, ctype)
+		}
+	}
+	if _, err := execTool(t, e, "file_read", map[string]interface{}{"path": "logo.png"}); err == nil || !strings.Contains(err.Error(), "3KB") {
+		t.Errorf("refusal should include the file size, got %v", err)
+	}
+
+	out, err := execTool(t, e, "file_read", map[string]interface{}{"path": "utf8.txt"})
+	if err != nil || !strings.Contains(out, "2: line two") {
+		t.Errorf("file_read utf8.txt = %q, %v", out, err)
+	}
+
+	out, err = execTool(t, e, "file_read", map[string]interface{}{"path": "blob.bin", "force": true})
+	if err != nil || !strings.Contains(out, "1: abc") {
+		t.Errorf("file_read with force = %q, %v", out, err)
+	}
+}
+
+func TestMultiEditIsAtomic(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"f.txt": "one two three\n"})
+
+	_, err := execTool(t, e, "multi_edit", map[string]interface{}{
+		"path": "f.txt",
+		"edits": []map[string]string{
+			{"search": "one", "replace": "1"},
+			{"search": "four", "replace": "4"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "edit 2") {
+		t.Fatalf("err = %v, want failure naming edit 2", err)
+	}
+	if got := readFile(t, e, "f.txt"); got != "one two three\n" {
+		t.Fatalf("file modified after failed multi_edit: %q", got)
+	}
+
+	out, err := execTool(t, e, "multi_edit", map[string]interface{}{
+		"path": "f.txt",
+		"edits": []map[string]string{
+			{"search": "one", "replace": "1"},
+			{"search": "1 two", "replace": "1 2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("multi_edit: %v", err)
+	}
+	if !strings.Contains(out, "Applied 2 edits") {
+		t.Errorf("output = %q", out)
+	}
+	if got := readFile(t, e, "f.txt"); got != "1 2 three\n" {
+		t.Errorf("f.txt = %q", got)
+	}
+}
+
+func TestExecutorToolAllowlist(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"f.txt": "hi\n"})
+	e.SetAllowedTools([]string{"file_read"})
+
+	if _, err := execTool(t, e, "file_read", map[string]string{"path": "f.txt"}); err != nil {
+		t.Fatalf("file_read: %v", err)
+	}
+	_, err := execTool(t, e, "shell_exec", map[string]string{"command": "true"})
+	if err == nil || err.Error() != `tool "shell_exec" not permitted for role "polecat"` {
+		t.Fatalf("err = %v, want not permitted", err)
+	}
+	if tools := e.Tools(); len(tools) != 1 || tools[0].Name != "file_read" {
+		t.Errorf("Tools() = %v, want only file_read", tools)
+	}
+
+	e.SetAllowedTools(nil)
+	if len(e.Tools()) != len(GTTools()) {
+		t.Error("empty allowlist should allow all tools")
+	}
+}
+
+func TestFileSearchIncludeAndExclude(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{
+		"a.go":                "Foo\n",
+		"go.mod":              "Foo\n",
+		"notes.txt":           "Foo\n",
+		"testdata/fixture.go": "Foo\n",
+		"vendor/dep/dep.go":   "Foo\n",
+		"a_test.go":           "Foo\n",
+		".git/HEAD.go":        "Foo\n",
+	})
+	dir := e.WorkDir()
+
+	matchedFiles := func(out string) []string {
+		var files []string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			if path, _, ok := strings.Cut(line, ":"); ok {
+				rel, _ := filepath.Rel(dir, path)
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		sort.Strings(files)
+		return files
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []string
+	}{
+		{"single include string", map[string]interface{}{"include": "*.mod"}, []string{"go.mod"}},
+		{"comma-separated include", map[string]interface{}{"include": "*.go, *.mod", "exclude": "testdata,vendor"},
+			[]string{"a.go", "a_test.go", "go.mod"}},
+		{"array include and exclude", map[string]interface{}{"include": []string{"*.go"}, "exclude": []string{"*_test.go", "vendor", "testdata"}},
+			[]string{"a.go"}},
+		{"exclude only", map[string]interface{}{"exclude": []string{"*.go"}}, []string{"go.mod", "notes.txt"}},
+	}
+	for _, tt := range tests {
+		args := map[string]interface{}{"pattern": "Foo"}
+		for k, v := range tt.args {
+			args[k] = v
+		}
+		out, err := execTool(t, e, "file_search", args)
+		if err != nil {
+			t.Fatalf("%s: file_search: %v", tt.name, err)
+		}
+		if got := matchedFiles(out); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: grep matched %v, want %v", tt.name, got, tt.want)
+		}
+
+		var params struct {
+			Include globList `json:"include"`
+			Exclude globList `json:"exclude"`
+		}
+		raw, _ := json.Marshal(tt.args)
+		if err := json.Unmarshal(raw, &params); err != nil {
+			t.Fatalf("%s: parsing globs: %v", tt.name, err)
+		}
+		out, err = e.searchFiles(context.Background(), "Foo", dir, params.Include, params.Exclude)
+		if err != nil {
+			t.Fatalf("%s: searchFiles: %v", tt.name, err)
+		}
+		if got := matchedFiles(out); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: native search matched %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSearchFilesMatchesGrepFormat(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{
+		"a.go":        "package a\nfunc Foo() {}\n",
+		"sub/b.go":    "// Foo caller\nvar x = Foo\n",
+		"sub/c.txt":   "Foo in text\n",
+		".git/config": "Foo\n",
+		"bin.dat":     "Foo\x00\x01",
+	})
+	dir := e.WorkDir()
+
+	out, err := e.searchFiles(context.Background(), `Fo+\b`, dir, []string{"*.go"}, nil)
+	if err != nil {
+		t.Fatalf("searchFiles: %v", err)
+	}
+	want := filepath.Join(dir, "a.go") + ":2:func Foo() {}\n" +
+		filepath.Join(dir, "sub", "b.go") + ":1:// Foo caller\n" +
+		filepath.Join(dir, "sub", "b.go") + ":2:var x = Foo\n"
+	if out != want {
+		t.Errorf("output =\n%s\nwant\n%s", out, want)
+	}
+
+	out, err = e.searchFiles(context.Background(), "Foo", dir, nil, nil)
+	if err != nil {
+		t.Fatalf("searchFiles: %v", err)
+	}
+	if strings.Contains(out, ".git") || strings.Contains(out, "bin.dat") {
+		t.Errorf("output should skip .git and binary files:\n%s", out)
+	}
+	if !strings.Contains(out, "c.txt:1:Foo in text") {
+		t.Errorf("output missing c.txt match:\n%s", out)
+	}
+
+	if out, _ := e.searchFiles(context.Background(), "nope", dir, nil, nil); out != "(no matches found)" {
+		t.Errorf("output = %q", out)
+	}
+	if _, err := e.searchFiles(context.Background(), "(", dir, nil, nil); err == nil {
+		t.Error("expected invalid pattern error")
+	}
+}
+
+func TestFileListRespectsGitignore(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{
+		".gitignore":              "node_modules/\n*.log\n/build\n!keep.log\n",
+		"main.go":                 "",
+		"debug.log":               "",
+		"keep.log":                "",
+		"build/out":               "",
+		"node_modules/x/index.js": "",
+		"src/build/gen.go":        "",
+		"src/.gitignore":          "gen_*.go\n",
+		"src/gen_a.go":            "",
+		"src/a.go":                "",
+	})
+
+	out, err := execTool(t, e, "file_list", map[string]interface{}{"recursive": true})
+	if err != nil {
+		t.Fatalf("file_list: %v", err)
+	}
+	for _, want := range []string{"main.go", "keep.log", "src/a.go", "src/build/gen.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("listing missing %s:\n%s", want, out)
+		}
+	}
+	for _, hidden := range []string{"debug.log", "node_modules", "build/out", "gen_a.go"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("listing should hide %s:\n%s", hidden, out)
+		}
+	}
+
+	out, err = execTool(t, e, "file_list", map[string]interface{}{"recursive": true, "pattern": "*.go", "respect_gitignore": false})
+	if err != nil {
+		t.Fatalf("file_list: %v", err)
+	}
+	if !strings.Contains(out, "src/gen_a.go") || strings.Contains(out, "debug.log") {
+		t.Errorf("unfiltered *.go listing wrong:\n%s", out)
+	}
+
+	out, err = execTool(t, e, "file_list", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("file_list: %v", err)
+	}
+	if strings.Contains(out, "node_modules") || strings.Contains(out, "debug.log") || !strings.Contains(out, "d src") {
+		t.Errorf("top-level listing wrong:\n%s", out)
+	}
+}
+
+// newGitTestExecutor is newTestExecutor in a git repo with one commit per
+// entry of commits, each writing the given files.
+func newGitTestExecutor(t *testing.T, commits ...map[string]string) *Executor {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	e := newTestExecutor(t, nil)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = e.WorkDir()
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+			"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	for i, files := range commits {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(e.WorkDir(), name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", fmt.Sprintf("commit %d", i+1))
+	}
+	return e
+}
+
+func TestGitLogAndShow(t *testing.T) {
+	e := newGitTestExecutor(t,
+		map[string]string{"a.txt": "a\n"},
+		map[string]string{"b.txt": "b\n"},
+		map[string]string{"a.txt": "a2\n"},
+	)
+
+	out, err := execTool(t, e, "git_log", map[string]interface{}{"oneline": true})
+	if err != nil {
+		t.Fatalf("git_log: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 3 || !strings.HasSuffix(lines[0], "commit 3") {
+		t.Errorf("git_log oneline:\n%s", out)
+	}
+
+	out, err = execTool(t, e, "git_log", map[string]interface{}{"path": "a.txt", "oneline": true})
+	if err != nil {
+		t.Fatalf("git_log path: %v", err)
+	}
+	if !strings.Contains(out, "commit 3") || !strings.Contains(out, "commit 1") || strings.Contains(out, "commit 2") {
+		t.Errorf("git_log path:\n%s", out)
+	}
+
+	out, err = execTool(t, e, "git_log", map[string]interface{}{"max_count": 1, "oneline": true})
+	if err != nil || strings.Count(out, "\n") != 1 {
+		t.Errorf("git_log max_count = %q, %v", out, err)
+	}
+
+	if _, err := execTool(t, e, "git_log", map[string]interface{}{"path": "../outside"}); err == nil {
+		t.Error("git_log should reject paths outside the working directory")
+	}
+
+	out, err = execTool(t, e, "git_show", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("git_show: %v", err)
+	}
+	if !strings.Contains(out, "commit 3") || !strings.Contains(out, "+a2") {
+		t.Errorf("git_show HEAD:\n%s", out)
+	}
+
+	out, err = execTool(t, e, "git_show", map[string]interface{}{"ref": "HEAD~1"})
+	if err != nil || !strings.Contains(out, "+b") {
+		t.Errorf("git_show HEAD~1 = %q, %v", out, err)
+	}
+
+	if _, err := execTool(t, e, "git_show", map[string]interface{}{"ref": "--output=x"}); err == nil {
+		t.Error("git_show should reject option-like refs")
+	}
+}
+
+func TestGitBranchAndCheckout(t *testing.T) {
+	e := newGitTestExecutor(t, map[string]string{"a.txt": "a\n"})
+
+	out, err := execTool(t, e, "git_branch", map[string]interface{}{"name": "feature/x"})
+	if err != nil {
+		t.Fatalf("git_branch create: %v", err)
+	}
+	if !strings.Contains(out, "feature/x") {
+		t.Errorf("git_branch create:\n%s", out)
+	}
+
+	out, err = execTool(t, e, "git_checkout", map[string]interface{}{"branch": "feature/x"})
+	if err != nil {
+		t.Fatalf("git_checkout: %v", err)
+	}
+	if !strings.Contains(out, "* feature/x") {
+		t.Errorf("git_checkout should mark feature/x current:\n%s", out)
+	}
+
+	out, err = execTool(t, e, "git_checkout", map[string]interface{}{"branch": "fix-1", "create": true})
+	if err != nil || !strings.Contains(out, "* fix-1") {
+		t.Errorf("git_checkout create = %q, %v", out, err)
+	}
+
+	out, err = execTool(t, e, "git_branch", map[string]interface{}{})
+	if err != nil || !strings.Contains(out, "feature/x") || !strings.Contains(out, "* fix-1") {
+		t.Errorf("git_branch list = %q, %v", out, err)
+	}
+
+	for _, name := range []string{"--force", "a b", "x;rm -rf", "a..b", "$(id)"} {
+		if _, err := execTool(t, e, "git_checkout", map[string]interface{}{"branch": name}); err == nil {
+			t.Errorf("git_checkout should reject %q", name)
+		}
+		if _, err := execTool(t, e, "git_branch", map[string]interface{}{"name": name}); err == nil {
+			t.Errorf("git_branch should reject %q", name)
+		}
+	}
+}
+
+func TestFileDeleteAndMove(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{
+		"a.txt":     "a",
+		"dir/b.txt": "b",
+		"c.txt":     "c",
+	})
+
+	if _, err := execTool(t, e, "file_delete", map[string]interface{}{"path": "dir"}); err == nil || !strings.Contains(err.Error(), "recursive") {
+		t.Errorf("deleting a directory without recursive: err = %v", err)
+	}
+	if _, err := execTool(t, e, "file_delete", map[string]interface{}{"path": ".", "recursive": true}); err == nil {
+		t.Error("deleting the working directory should fail")
+	}
+	if _, err := execTool(t, e, "file_delete", map[string]interface{}{"path": "../x", "recursive": true}); err == nil {
+		t.Error("deleting outside the working directory should fail")
+	}
+	if out, err := execTool(t, e, "file_delete", map[string]interface{}{"path": "a.txt"}); err != nil || out != "Deleted a.txt" {
+		t.Errorf("file_delete = %q, %v", out, err)
+	}
+	if _, err := execTool(t, e, "file_delete", map[string]interface{}{"path": "dir", "recursive": true}); err != nil {
+		t.Errorf("recursive file_delete: %v", err)
+	}
+	for _, name := range []string{"a.txt", "dir"} {
+		if _, err := os.Stat(filepath.Join(e.WorkDir(), name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be deleted", name)
+		}
+	}
+
+	if out, err := execTool(t, e, "file_move", map[string]string{"from": "c.txt", "to": "new/dir/c.txt"}); err != nil || out != "Moved c.txt to new/dir/c.txt" {
+		t.Fatalf("file_move = %q, %v", out, err)
+	}
+	if got := readFile(t, e, "new/dir/c.txt"); got != "c" {
+		t.Errorf("moved file = %q", got)
+	}
+	if err := os.WriteFile(filepath.Join(e.WorkDir(), "d.txt"), []byte("d"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execTool(t, e, "file_move", map[string]string{"from": "d.txt", "to": "new/dir/c.txt"}); err == nil {
+		t.Error("file_move should not overwrite an existing destination")
+	}
+	if _, err := execTool(t, e, "file_move", map[string]string{"from": "d.txt", "to": "../d.txt"}); err == nil {
+		t.Error("file_move outside the working directory should fail")
+	}
+}
+
+func TestShellExecCwdAndEnv(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"pkg/sub/f.txt": "x"})
+
+	out, err := execTool(t, e, "shell_exec", map[string]interface{}{
+		"command": `echo "$(basename "$PWD") $CGO_ENABLED $GT_ACTOR"`,
+		"cwd":     "pkg/sub",
+		"env":     map[string]string{"CGO_ENABLED": "0"},
+	})
+	if err != nil {
+		t.Fatalf("shell_exec: %v", err)
+	}
+	if got := strings.TrimSpace(out); got != "sub 0 rig/polecats/Test" {
+		t.Errorf("output = %q", got)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"escaping cwd", map[string]interface{}{"command": "true", "cwd": "../"}, "outside working directory"},
+		{"file cwd", map[string]interface{}{"command": "true", "cwd": "pkg/sub/f.txt"}, "not a directory"},
+		{"protected env", map[string]interface{}{"command": "true", "env": map[string]string{"GT_ACTOR": "mayor"}}, "may not override GT_ACTOR"},
+	}
+	for _, tt := range tests {
+		if _, err := execTool(t, e, "shell_exec", tt.args); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestShellExecStreamsAndCapsOutput(t *testing.T) {
+	e := newTestExecutor(t, nil)
+
+	var streamed strings.Builder
+	ctx := WithOutputStream(context.Background(), func(chunk []byte) { streamed.Write(chunk) })
+	raw, _ := json.Marshal(map[string]string{"command": "echo one; echo two >&2"})
+	out, err := e.Execute(ctx, llm.ToolCall{ID: "t", Name: "shell_exec", Args: raw})
+	if err != nil {
+		t.Fatalf("shell_exec: %v", err)
+	}
+	if out != "one\n\nSTDERR: two\n" {
+		t.Errorf("output = %q", out)
+	}
+	if got := streamed.String(); !strings.Contains(got, "one\n") || !strings.Contains(got, "two\n") {
+		t.Errorf("streamed = %q", got)
+	}
+
+	big := MaxOutputSize + 5000
+	out, err = execTool(t, e, "shell_exec", map[string]string{"command": fmt.Sprintf("head -c %d /dev/zero | tr '\\0' x", big)})
+	if err != nil {
+		t.Fatalf("shell_exec: %v", err)
+	}
+	if !strings.HasSuffix(out, "... (truncated, 5000 bytes omitted)") || !strings.HasPrefix(out, strings.Repeat("x", MaxOutputSize)) {
+		t.Errorf("output tail = %q", out[len(out)-60:])
+	}
+}
+
+func TestExecutorLimitsOverrideDefaults(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"big.txt": strings.Repeat("x", 200)})
+	e.SetLimits(ExecutorLimits{MaxFileReadSize: 100, MaxOutputSize: 10})
+
+	if _, err := execTool(t, e, "file_read", map[string]string{"path": "big.txt"}); err == nil || !strings.Contains(err.Error(), "max 100") {
+		t.Errorf("file_read err = %v, want size limit", err)
+	}
+	out, err := execTool(t, e, "shell_exec", map[string]string{"command": "echo 0123456789abcdef"})
+	if err != nil {
+		t.Fatalf("shell_exec: %v", err)
+	}
+	if !strings.HasPrefix(out, "0123456789\n... (truncated") {
+		t.Errorf("output = %q", out)
+	}
+
+	e.SetLimits(ExecutorLimits{})
+	if got := e.maxOutputSize(); got != MaxOutputSize {
+		t.Errorf("zero limits: maxOutputSize = %d, want default", got)
+	}
+}
+
+func TestMailToolsCarryMessageIDs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake gt is a shell script")
+	}
+	bin := t.TempDir()
+	script := `#!/bin/sh
+if [ "$2" = "inbox" ]; then
+  echo '[{"id":"hq-1","from":"rig/witness","subject":"Status?","body":"How is it going?","timestamp":"2026-01-02T03:04:05Z"},{"id":"hq-2","from":"mayor/","subject":"Re: plan","reply_to":"hq-0","read":true}]'
+else
+  echo "$@"
+fi
+`
+	if err := os.WriteFile(filepath.Join(bin, "gt"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	e := newTestExecutor(t, nil)
+
+	out, err := execTool(t, e, "gt_mail_read", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("gt_mail_read: %v", err)
+	}
+	for _, want := range []string{"ID: hq-1 (unread)", "From: rig/witness", "How is it going?", "ID: hq-2 (read)", "In-Reply-To: hq-0"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("gt_mail_read output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, err = execTool(t, e, "gt_mail_read", map[string]interface{}{"count": 1})
+	if err != nil || strings.Contains(out, "hq-2") || !strings.Contains(out, "1 more messages") {
+		t.Errorf("gt_mail_read count 1 = %q, %v", out, err)
+	}
+
+	out, err = execTool(t, e, "gt_mail_send", map[string]string{"to": "rig/witness", "subject": "Re: Status?", "in_reply_to": "hq-1"})
+	if err != nil || !strings.Contains(out, "--reply-to hq-1") {
+		t.Errorf("gt_mail_send = %q, %v", out, err)
+	}
+	if _, err := execTool(t, e, "gt_mail_send", map[string]string{"to": "x", "subject": "y", "in_reply_to": "--help"}); err == nil {
+		t.Error("gt_mail_send should reject option-like message IDs")
+	}
+}
@@ -0,0 +1,157 @@
+package agentloop
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreMatcher evaluates .gitignore rules collected from a worktree.
+// It covers the parts of gitignore(5) that matter for keeping listings
+// small: comments, negation, directory-only rules, anchored patterns, and
+// "**". Global excludes and .git/info/exclude are not consulted.
+type gitignoreMatcher struct {
+	rules  []gitignoreRule
+	loaded map[string]bool // directories whose .gitignore has been read
+}
+
+type gitignoreRule struct {
+	base     string // slash-separated dir of the .gitignore, relative to the root ("" for root)
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // pattern contains a slash, so it matches relative to base
+}
+
+func newGitignoreMatcher() *gitignoreMatcher {
+	return &gitignoreMatcher{loaded: make(map[string]bool)}
+}
+
+// loadDir reads root/relDir/.gitignore, if present. Rules from deeper
+// directories are appended later and so take precedence.
+func (m *gitignoreMatcher) loadDir(root, relDir string) {
+	relDir = filepath.ToSlash(relDir)
+	if relDir == "." {
+		relDir = ""
+	}
+	if m.loaded[relDir] {
+		return
+	}
+	m.loaded[relDir] = true
+
+	f, err := os.Open(filepath.Join(root, filepath.FromSlash(relDir), ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreLine(scanner.Text(), relDir); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+}
+
+// loadAncestors reads the .gitignore files from the root down to relDir
+// (inclusive), so rules from parents apply when listing starts in a subdirectory.
+func (m *gitignoreMatcher) loadAncestors(root, relDir string) {
+	m.loadDir(root, "")
+	relDir = filepath.ToSlash(relDir)
+	if relDir == "" || relDir == "." {
+		return
+	}
+	parts := strings.Split(relDir, "/")
+	for i := range parts {
+		m.loadDir(root, strings.Join(parts[:i+1], "/"))
+	}
+}
+
+func parseGitignoreLine(line, base string) (gitignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return gitignoreRule{}, false
+	}
+
+	rule := gitignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// Ignored reports whether relPath (relative to the root) is ignored.
+// The last matching rule wins, so a later "!pattern" re-includes a path.
+func (m *gitignoreMatcher) Ignored(relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.matches(relPath) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func (r gitignoreRule) matches(relPath string) bool {
+	rel := relPath
+	if r.base != "" {
+		if !strings.HasPrefix(relPath, r.base+"/") {
+			return false
+		}
+		rel = relPath[len(r.base)+1:]
+	}
+	if !r.anchored {
+		// A pattern without a slash matches the name at any depth.
+		ok, _ := path.Match(r.pattern, path.Base(rel))
+		return ok
+	}
+	return matchGlobPath(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchGlobPath matches slash-separated pattern segments against path
+// segments, where a "**" segment matches zero or more path segments.
+func matchGlobPath(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segs); i++ {
+				if matchGlobPath(rest, segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segs[0]); !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
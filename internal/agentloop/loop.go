@@ -2,8 +2,11 @@ package agentloop
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,6 +22,31 @@ const (
 	DefaultIdleTimeout = 5 * time.Minute
 	// DefaultToolTimeout is the max time for a single tool execution.
 	DefaultToolTimeout = 120 * time.Second
+	// DefaultQueueDepth is how many tasks can wait behind the current one.
+	DefaultQueueDepth = 4
+	// DefaultToolConcurrency runs tool calls one at a time.
+	DefaultToolConcurrency = 1
+	// DefaultToolRetryBackoff is the wait before the first retry of a failed
+	// read-only tool call; it doubles for each further retry.
+	DefaultToolRetryBackoff = 500 * time.Millisecond
+	// DefaultMaxConsecutiveErrors is how many iterations in a row may have
+	// every tool call fail before the task is aborted.
+	DefaultMaxConsecutiveErrors = 5
+	// DefaultMaxConsecutiveEmptyTurns is how many empty responses in a row
+	// the model may give before the task is aborted.
+	DefaultMaxConsecutiveEmptyTurns = 3
+)
+
+// emptyTurnPrompt is sent after a response with neither tool calls nor text.
+const emptyTurnPrompt = "Your last response was empty. Continue with the task using the available tools, or reply with a summary of what you did if it is complete."
+
+// Task priorities for AssignWorkWithPriority. Any int works; higher runs first.
+const (
+	// PriorityNormal is the priority of work assigned with AssignWork.
+	PriorityNormal = 0
+	// PriorityUrgent is for interrupts that should jump the queue, such as
+	// a witness nudging a polecat.
+	PriorityUrgent = 10
 )
 
 // LoopState represents the current state of the agent loop.
@@ -29,12 +57,23 @@ const (
 	StateIdle LoopState = "idle"
 	// StateWorking means the loop is processing a task.
 	StateWorking LoopState = "working"
+	// StateSleeping means the loop went idle and released its LLM client's
+	// connections; it still accepts work, and AssignWork wakes it.
+	StateSleeping LoopState = "sleeping"
+	// StateRetiring is reported (via OnHeartbeat) when StopGracefully
+	// starts draining the loop ahead of a planned shutdown.
+	StateRetiring LoopState = "retiring"
 	// StateStopped means the loop has been stopped.
 	StateStopped LoopState = "stopped"
 	// StateError means the loop encountered a fatal error.
 	StateError LoopState = "error"
 )
 
+// ErrDrained is the error a task reports when StopGracefully interrupts it
+// between iterations. With CheckpointPath set, the task can be continued
+// later with ResumeTask.
+var ErrDrained = errors.New("task interrupted by graceful stop")
+
 // AgentLoopConfig controls loop behavior.
 type AgentLoopConfig struct {
 	// SystemPrompt is the system message prepended to every conversation.
@@ -48,14 +87,49 @@ type AgentLoopConfig struct {
 	// Prevents runaway costs. Default: 200000.
 	MaxTokensPerTask int
 
-	// IdleTimeout is how long to wait for work before the loop sleeps.
-	// Default: 5 minutes.
+	// MaxCostUSD limits the estimated spend per task, in dollars.
+	// Default: 0 (no cost limit).
+	MaxCostUSD float64
+
+	// MaxTaskDuration limits the wall-clock time per task, checked between
+	// iterations and after each tool call; a call already running is not
+	// interrupted. A resumed or preempted task's clock restarts when it
+	// resumes. Default: 0 (no time limit).
+	MaxTaskDuration time.Duration
+
+	// Pricing overrides the per-token prices used to estimate cost.
+	// Default: looked up from the llm package by model ID.
+	Pricing *llm.Pricing
+
+	// IdleTimeout is how long to wait for work before the loop consults
+	// OnIdle. Default: 5 minutes.
 	IdleTimeout time.Duration
 
 	// ToolTimeout is the maximum time for a single tool execution.
 	// Default: 120 seconds.
 	ToolTimeout time.Duration
 
+	// ToolConcurrency is how many read-only tool calls from one response
+	// may run at once. Mutating tools always run alone, after the calls
+	// before them finish. Default: 1 (sequential).
+	ToolConcurrency int
+
+	// ToolRetry re-runs failed read-only tool calls (reads, status, diff)
+	// before the error is shown to the model, so a transient failure such
+	// as a held git lock doesn't cost a turn. Tools with side effects are
+	// never retried. Default: no retries.
+	ToolRetry ToolRetryConfig
+
+	// MaxConsecutiveErrors aborts the task after this many iterations in a
+	// row in which every executed tool call failed. Calls denied by
+	// OnToolApproval don't count as failures. Default: 5.
+	MaxConsecutiveErrors int
+
+	// MaxConsecutiveEmptyTurns aborts the task after this many responses in
+	// a row with neither tool calls nor text. An empty response does not
+	// complete the task; the model is asked to continue. Default: 3.
+	MaxConsecutiveEmptyTurns int
+
 	// Role is the agent's role (polecat, witness, refinery, etc.)
 	Role string
 
@@ -65,23 +139,100 @@ type AgentLoopConfig struct {
 	// Actor is the agent's full address (e.g., "rig/polecats/Toast").
 	Actor string
 
-	// OnHeartbeat is called periodically during task execution.
-	// Used to publish Nostr lifecycle events.
+	// QueueDepth is how many assigned tasks can wait while the agent is
+	// working. AssignWork fails once the queue is full. Default: 4.
+	QueueDepth int
+
+	// PreemptPriority enables preemption: a task assigned at or above this
+	// priority makes a running lower-priority task checkpoint and yield
+	// after its current iteration. The yielded task is re-queued at its
+	// original priority and resumes where it left off.
+	// Default: 0 (no preemption).
+	PreemptPriority int
+
+	// SummarizeWithLLM uses the loop's client to summarize messages dropped
+	// when the context window fills, instead of a statistical summary.
+	SummarizeWithLLM bool
+
+	// CheckpointPath, if set, is where the conversation is checkpointed
+	// (as JSONL) after each iteration so an interrupted task can be
+	// continued with ResumeTask. The file is removed when a task completes.
+	CheckpointPath string
+
+	// ExecutorLimits overrides the executor's file read size, tool output
+	// size, and subprocess timeout. Zero fields keep the defaults.
+	ExecutorLimits ExecutorLimits
+
+	// ReadOnly runs the executor in dry-run mode: tools with side effects
+	// report what they would have done instead of doing it.
+	ReadOnly bool
+
+	// OnHeartbeat is called periodically during task execution, and once
+	// when the loop goes to sleep. Used to publish Nostr lifecycle events.
 	OnHeartbeat func(state LoopState, iteration int, totalTokens int)
 
+	// OnIdle is called each time IdleTimeout passes with no work, with how
+	// long the loop has been idle. Returning true puts the loop to sleep:
+	// the LLM client is closed and the idle timer stops until AssignWork
+	// wakes it, so a deployment can reclaim resources from idle agents.
+	// When nil the loop never sleeps.
+	OnIdle func(idleDuration time.Duration) (shouldSleep bool)
+
+	// OnWake is called when work arrives for a sleeping loop, before the
+	// work starts. Use it to reconnect anything released on sleep, such as
+	// relay connections; the LLM client reconnects on its next request.
+	OnWake func()
+
+	// OnIteration is called after every model call with that call's token
+	// usage (zero when the provider reports none), the task's running token
+	// total, and the call's latency. Intended for live cost/latency telemetry.
+	OnIteration func(iteration, promptTokens, completionTokens, totalSoFar int, latency time.Duration)
+
 	// OnTaskComplete is called when a task finishes.
 	OnTaskComplete func(task string, iterations int, totalTokens int, err error)
+
+	// OnToolApproval, if set, gates every tool call before it executes.
+	// iteration is the 1-based loop iteration, so policies can throttle
+	// repeated attempts. A denied call is not executed; the model instead
+	// sees "Tool call denied: <reason>" as the tool result and can adapt.
+	OnToolApproval func(call llm.ToolCall, iteration int) (approved bool, reason string)
+
+	// OnToolOutput, if set, receives subprocess tool output (shell_exec,
+	// git, gt, bd) while the command runs. Calls for concurrent tool calls
+	// may interleave.
+	OnToolOutput func(call llm.ToolCall, chunk []byte)
+}
+
+// ToolRetryConfig controls automatic retries of failed read-only tool calls.
+type ToolRetryConfig struct {
+	// MaxAttempts is the total number of attempts per call, including the
+	// first. Values below 2 disable retries.
+	MaxAttempts int
+
+	// Backoff is the wait before the first retry, doubled for each further
+	// retry. Default: DefaultToolRetryBackoff.
+	Backoff time.Duration
 }
 
 // LoopStatus contains the current status of the agent loop.
 type LoopStatus struct {
 	State       LoopState `json:"state"`
 	CurrentTask string    `json:"current_task,omitempty"`
+	Priority    int       `json:"priority,omitempty"`
 	Iteration   int       `json:"iteration"`
 	TotalTokens int       `json:"total_tokens"`
+	CostUSD     float64   `json:"cost_usd"`
+	QueuedTasks int       `json:"queued_tasks"`
 	StartedAt   time.Time `json:"started_at"`
 	LastActive  time.Time `json:"last_active"`
 	Error       string    `json:"error,omitempty"`
+
+	// ElapsedSeconds is how long the current task has been running.
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+
+	// PreemptedTask is a task that yielded to higher-priority work and is
+	// waiting in the queue to resume.
+	PreemptedTask string `json:"preempted_task,omitempty"`
 }
 
 // AgentLoop orchestrates the think-act-observe cycle for API-mode agents.
@@ -95,19 +246,54 @@ type AgentLoop struct {
 	context  *ContextManager
 
 	mu          sync.Mutex
+	started     bool // Start is running
 	state       LoopState
 	currentTask string
 	iteration   int
 	totalTokens int
+	totalCost   float64
 	startedAt   time.Time
+	taskStarted time.Time // when the current task started running; zero when idle
 	lastActive  time.Time
 	lastError   error
+	queue       []queuedTask
+	running     *queuedTask // task being run from the queue, if any
+	checkpoint  string      // where the running task checkpoints, if not CheckpointPath
+	preempt     bool        // running task should yield after this iteration
+	preempted   string
+	draining    bool          // StopGracefully was called; take no new work
+	drained     chan struct{} // closed when the running task stops while draining
 
-	workCh     chan string
+	workReady  chan struct{} // signaled when a task is enqueued
 	cancelFunc context.CancelFunc
 	done       chan struct{}
 }
 
+// queuedTask is a task waiting for the loop to pick it up.
+type queuedTask struct {
+	task     string
+	priority int
+	resume   *checkpoint // state of a preempted or resumed task, nil for new work
+
+	// checkpointPath overrides CheckpointPath for this task (ResumeTask
+	// keeps checkpointing to the file it resumed from).
+	checkpointPath string
+
+	// done, if set, receives the task's outcome once it finishes or is
+	// dropped without running.
+	done chan error
+}
+
+// preemptedError is returned by runConversation when the task yields to
+// higher-priority work. It carries the state needed to resume.
+type preemptedError struct {
+	cp *checkpoint
+}
+
+func (e *preemptedError) Error() string {
+	return fmt.Sprintf("task preempted at iteration %d", e.cp.Iteration)
+}
+
 // NewAgentLoop creates an agent loop for an API-mode agent.
 func NewAgentLoop(client llm.Client, executor *Executor, cfg *AgentLoopConfig) *AgentLoop {
 	if cfg.MaxIterations <= 0 {
@@ -122,22 +308,61 @@ func NewAgentLoop(client llm.Client, executor *Executor, cfg *AgentLoopConfig) *
 	if cfg.ToolTimeout <= 0 {
 		cfg.ToolTimeout = DefaultToolTimeout
 	}
+	if cfg.QueueDepth <= 0 {
+		cfg.QueueDepth = DefaultQueueDepth
+	}
+	if cfg.ToolConcurrency <= 0 {
+		cfg.ToolConcurrency = DefaultToolConcurrency
+	}
+	if cfg.MaxConsecutiveErrors <= 0 {
+		cfg.MaxConsecutiveErrors = DefaultMaxConsecutiveErrors
+	}
+	if cfg.MaxConsecutiveEmptyTurns <= 0 {
+		cfg.MaxConsecutiveEmptyTurns = DefaultMaxConsecutiveEmptyTurns
+	}
+	if cfg.ToolRetry.Backoff <= 0 {
+		cfg.ToolRetry.Backoff = DefaultToolRetryBackoff
+	}
+	if cfg.ExecutorLimits != (ExecutorLimits{}) {
+		executor.SetLimits(cfg.ExecutorLimits)
+	}
+	if cfg.ReadOnly {
+		executor.SetReadOnly(true)
+	}
 
 	contextWindow := 0
+	modelID := ""
 	if mi := client.ModelInfo(); mi != nil {
 		contextWindow = mi.ContextWindow
+		modelID = mi.ID
+	}
+
+	if cfg.Pricing == nil {
+		if p, ok := llm.LookupPricing(modelID); ok {
+			cfg.Pricing = &p
+		} else if cfg.MaxCostUSD > 0 {
+			log.Printf("[agentloop] No pricing known for model %q; cost budget will not be enforced", modelID)
+		}
 	}
 
 	return &AgentLoop{
-		client:   client,
-		executor: executor,
-		tools:    GTTools(),
-		config:   cfg,
-		context:  NewContextManager(contextWindow),
-		state:    StateStopped,
-		workCh:   make(chan string, 1),
-		done:     make(chan struct{}),
+		client:    client,
+		executor:  executor,
+		tools:     executor.Tools(),
+		config:    cfg,
+		context:   newContextManager(contextWindow, client, cfg.SummarizeWithLLM),
+		state:     StateStopped,
+		workReady: make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+// newContextManager picks the context manager flavor for the loop config.
+func newContextManager(contextWindow int, client llm.Client, summarize bool) *ContextManager {
+	if summarize {
+		return NewContextManagerWithSummarizer(contextWindow, client)
 	}
+	return NewContextManager(contextWindow)
 }
 
 // Start begins the agent loop. It runs until stopped or the context is canceled.
@@ -150,6 +375,12 @@ func (l *AgentLoop) Start(ctx context.Context) error {
 	l.cancelFunc = cancel
 
 	l.mu.Lock()
+	if l.state == StateWorking {
+		l.mu.Unlock()
+		cancel()
+		return fmt.Errorf("agent loop is busy resuming a task")
+	}
+	l.started = true
 	l.state = StateIdle
 	l.startedAt = time.Now()
 	l.lastActive = time.Now()
@@ -157,8 +388,20 @@ func (l *AgentLoop) Start(ctx context.Context) error {
 
 	defer func() {
 		l.mu.Lock()
+		l.started = false
 		l.state = StateStopped
+		dropped := len(l.queue)
+		for _, qt := range l.queue {
+			if qt.done != nil {
+				qt.done <- fmt.Errorf("agent loop stopped before the task ran")
+			}
+		}
+		l.queue = nil
+		l.preempted = ""
 		l.mu.Unlock()
+		if dropped > 0 {
+			log.Printf("[agentloop] Dropped %d queued task(s) on stop", dropped)
+		}
 		close(l.done)
 	}()
 
@@ -174,63 +417,212 @@ func (l *AgentLoop) Start(ctx context.Context) error {
 			return ctx.Err()
 
 		case <-idleTimer.C:
-			log.Printf("[agentloop] Idle timeout reached (%v), sleeping", l.config.IdleTimeout)
-			// Reset and continue - the loop stays alive but logs the idle state.
-			// In production, the deacon may use this signal to scale down.
-			idleTimer.Reset(l.config.IdleTimeout)
-
-		case task := <-l.workCh:
-			idleTimer.Stop()
 			l.mu.Lock()
-			l.state = StateWorking
-			l.currentTask = task
-			l.iteration = 0
-			l.totalTokens = 0
-			l.lastActive = time.Now()
+			idle := time.Since(l.lastActive)
 			l.mu.Unlock()
-
-			err := l.runTask(ctx, task)
-
-			l.mu.Lock()
-			l.state = StateIdle
-			l.currentTask = ""
-			l.lastActive = time.Now()
-			if err != nil {
-				l.lastError = err
-				log.Printf("[agentloop] Task failed: %v", err)
+			if l.config.OnIdle != nil && l.config.OnIdle(idle) {
+				// The timer stays stopped while asleep; only work wakes us.
+				l.sleep(idle)
+				continue
 			}
-			l.mu.Unlock()
+			log.Printf("[agentloop] Idle for %v, staying awake", idle.Round(time.Second))
+			idleTimer.Reset(l.config.IdleTimeout)
 
-			if l.config.OnTaskComplete != nil {
-				l.config.OnTaskComplete(task, l.iteration, l.totalTokens, err)
+		case <-l.workReady:
+			idleTimer.Stop()
+			l.wake()
+			for ctx.Err() == nil {
+				task, ok := l.dequeue()
+				if !ok {
+					break
+				}
+				l.processTask(ctx, task)
 			}
 
-			// Reset idle timer after task completion
+			// Reset idle timer after the queue is drained
 			idleTimer.Reset(l.config.IdleTimeout)
 		}
 	}
 }
 
-// AssignWork sends a new task to the running agent loop.
+// sleep moves an idle loop to StateSleeping and closes the LLM client, which
+// releases its idle connections; the client stays usable and reconnects on
+// the next request.
+func (l *AgentLoop) sleep(idle time.Duration) {
+	l.mu.Lock()
+	l.state = StateSleeping
+	l.mu.Unlock()
+
+	log.Printf("[agentloop] Idle for %v, sleeping until work arrives", idle.Round(time.Second))
+	if err := l.client.Close(); err != nil {
+		log.Printf("[agentloop] Closing LLM client for sleep: %v", err)
+	}
+	if l.config.OnHeartbeat != nil {
+		l.config.OnHeartbeat(StateSleeping, 0, 0)
+	}
+}
+
+// wake returns a sleeping loop to StateIdle ahead of running new work.
+func (l *AgentLoop) wake() {
+	l.mu.Lock()
+	sleeping := l.state == StateSleeping
+	if sleeping {
+		l.state = StateIdle
+		l.lastActive = time.Now()
+	}
+	l.mu.Unlock()
+	if !sleeping {
+		return
+	}
+
+	log.Printf("[agentloop] Woken by new work")
+	if l.config.OnWake != nil {
+		l.config.OnWake()
+	}
+}
+
+// processTask runs one task from the queue and reports its outcome.
+// A preempted task is re-queued instead of being reported as complete.
+func (l *AgentLoop) processTask(ctx context.Context, qt queuedTask) {
+	l.mu.Lock()
+	l.state = StateWorking
+	l.currentTask = qt.task
+	l.running = &qt
+	l.checkpoint = qt.checkpointPath
+	l.preempt = false
+	if qt.task == l.preempted {
+		l.preempted = ""
+	}
+	if qt.resume != nil {
+		l.iteration = qt.resume.Iteration
+		l.totalTokens = qt.resume.TotalTokens
+		l.totalCost = qt.resume.CostUSD
+	} else {
+		l.iteration = 0
+		l.totalTokens = 0
+		l.totalCost = 0
+	}
+	l.lastActive = time.Now()
+	l.mu.Unlock()
+
+	var err error
+	if qt.resume != nil {
+		log.Printf("[agentloop] Resuming task at iteration %d", qt.resume.Iteration)
+		err = l.runConversation(ctx, qt.task, qt.resume.Messages, qt.resume.Iteration)
+	} else {
+		err = l.runTask(ctx, qt.task)
+	}
+
+	var pe *preemptedError
+	preempted := errors.As(err, &pe)
+
+	l.mu.Lock()
+	l.state = StateIdle
+	l.currentTask = ""
+	l.taskStarted = time.Time{}
+	l.running = nil
+	l.checkpoint = ""
+	l.preempt = false
+	l.lastActive = time.Now()
+	if preempted {
+		log.Printf("[agentloop] Task preempted at iteration %d by higher-priority work", pe.cp.Iteration)
+		l.preempted = qt.task
+		qt.resume = pe.cp
+		l.requeueLocked(qt)
+	} else if errors.Is(err, ErrDrained) {
+		l.lastError = err
+		log.Printf("[agentloop] Task stopped at iteration %d for shutdown", l.iteration)
+	} else if err != nil {
+		l.lastError = err
+		log.Printf("[agentloop] Task failed: %v", err)
+	}
+	l.signalDrainedLocked()
+	l.mu.Unlock()
+
+	if preempted {
+		return
+	}
+	if l.config.OnTaskComplete != nil {
+		l.config.OnTaskComplete(qt.task, l.iteration, l.totalTokens, err)
+	}
+	if qt.done != nil {
+		qt.done <- err
+	}
+}
+
+// dequeue pops the highest-priority queued task, if any. Nothing is handed
+// out once the loop is draining.
+func (l *AgentLoop) dequeue() (queuedTask, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.queue) == 0 || l.draining {
+		return queuedTask{}, false
+	}
+	next := l.queue[0]
+	l.queue = l.queue[1:]
+	return next, true
+}
+
+// requeueLocked puts a preempted task back ahead of queued tasks of the same
+// priority, since it started before them. It ignores QueueDepth so the
+// task's progress is never dropped. l.mu must be held.
+func (l *AgentLoop) requeueLocked(qt queuedTask) {
+	i := 0
+	for i < len(l.queue) && l.queue[i].priority > qt.priority {
+		i++
+	}
+	l.queue = append(l.queue[:i], append([]queuedTask{qt}, l.queue[i:]...)...)
+}
+
+// AssignWork queues a task for the running agent loop. If the agent is busy,
+// the task runs after the ones ahead of it finish.
 // This is the API-mode equivalent of tmux NudgeSession.
 func (l *AgentLoop) AssignWork(task string) error {
+	return l.AssignWorkWithPriority(task, PriorityNormal)
+}
+
+// AssignWorkWithPriority queues a task ahead of any lower-priority tasks;
+// tasks of equal priority run in the order they were assigned. With
+// PreemptPriority configured, an urgent task also asks the running task to
+// yield after its current iteration.
+func (l *AgentLoop) AssignWorkWithPriority(task string, priority int) error {
 	l.mu.Lock()
-	state := l.state
-	l.mu.Unlock()
+	defer l.mu.Unlock()
 
-	if state == StateStopped {
+	if l.state == StateStopped {
 		return fmt.Errorf("agent loop is stopped")
 	}
-	if state == StateWorking {
-		return fmt.Errorf("agent is already working on a task")
+	if l.draining {
+		return fmt.Errorf("agent loop is shutting down")
+	}
+	if len(l.queue) >= l.config.QueueDepth {
+		return fmt.Errorf("work queue full (%d tasks)", len(l.queue))
+	}
+
+	i := 0
+	for i < len(l.queue) && l.queue[i].priority >= priority {
+		i++
+	}
+	l.queue = append(l.queue[:i], append([]queuedTask{{task: task, priority: priority}}, l.queue[i:]...)...)
+
+	if l.config.PreemptPriority > 0 && priority >= l.config.PreemptPriority &&
+		l.running != nil && l.running.priority < priority {
+		l.preempt = true
 	}
 
 	select {
-	case l.workCh <- task:
-		return nil
+	case l.workReady <- struct{}{}:
 	default:
-		return fmt.Errorf("work channel full, agent may be busy")
+		// A wakeup is already pending; the loop will see this task too.
 	}
+	return nil
+}
+
+// QueuedTasks returns the number of tasks waiting behind the current one.
+func (l *AgentLoop) QueuedTasks() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.queue)
 }
 
 // Stop gracefully stops the agent loop.
@@ -256,18 +648,90 @@ func (l *AgentLoop) Stop() error {
 	}
 }
 
+// StopGracefully stops the loop without abandoning a task mid-iteration, for
+// planned shutdowns such as deploys. It stops accepting work, reports
+// StateRetiring through OnHeartbeat, and lets the running task finish its
+// current iteration: tool calls already started complete, but no new ones
+// start. The task is checkpointed (when CheckpointPath is set) and reports
+// ErrDrained, then the loop is stopped as by Stop. If the task is still
+// running after timeout, the loop is canceled anyway and an error returned.
+func (l *AgentLoop) StopGracefully(timeout time.Duration) error {
+	l.mu.Lock()
+	if l.state == StateStopped {
+		l.mu.Unlock()
+		return nil
+	}
+	l.draining = true
+	var drained chan struct{}
+	if l.state == StateWorking {
+		if l.drained == nil {
+			l.drained = make(chan struct{})
+		}
+		drained = l.drained
+	}
+	iteration, totalTokens := l.iteration, l.totalTokens
+	l.mu.Unlock()
+
+	log.Printf("[agentloop] Retiring: draining before stop (timeout %v)", timeout)
+	if l.config.OnHeartbeat != nil {
+		l.config.OnHeartbeat(StateRetiring, iteration, totalTokens)
+	}
+
+	var drainErr error
+	if drained != nil {
+		timer := time.NewTimer(timeout)
+		select {
+		case <-drained:
+			timer.Stop()
+		case <-timer.C:
+			log.Printf("[agentloop] Task still running after %v, canceling it", timeout)
+			drainErr = fmt.Errorf("task did not stop within %v; canceled it", timeout)
+		}
+	}
+
+	if err := l.Stop(); err != nil {
+		return err
+	}
+	return drainErr
+}
+
+// isDraining reports whether StopGracefully has been called.
+func (l *AgentLoop) isDraining() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.draining
+}
+
+// signalDrainedLocked wakes StopGracefully once the running task has
+// stopped. l.mu must be held.
+func (l *AgentLoop) signalDrainedLocked() {
+	if l.drained != nil {
+		close(l.drained)
+		l.drained = nil
+	}
+}
+
 // Status returns the current loop status.
 func (l *AgentLoop) Status() *LoopStatus {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	status := &LoopStatus{
-		State:       l.state,
-		CurrentTask: l.currentTask,
-		Iteration:   l.iteration,
-		TotalTokens: l.totalTokens,
-		StartedAt:   l.startedAt,
-		LastActive:  l.lastActive,
+		State:         l.state,
+		CurrentTask:   l.currentTask,
+		PreemptedTask: l.preempted,
+		Iteration:     l.iteration,
+		TotalTokens:   l.totalTokens,
+		CostUSD:       l.totalCost,
+		QueuedTasks:   len(l.queue),
+		StartedAt:     l.startedAt,
+		LastActive:    l.lastActive,
+	}
+	if l.running != nil {
+		status.Priority = l.running.priority
+	}
+	if !l.taskStarted.IsZero() {
+		status.ElapsedSeconds = time.Since(l.taskStarted).Seconds()
 	}
 	if l.lastError != nil {
 		status.Error = l.lastError.Error()
@@ -275,11 +739,12 @@ func (l *AgentLoop) Status() *LoopStatus {
 	return status
 }
 
-// IsRunning returns true if the agent loop is running (idle or working).
+// IsRunning returns true if the agent loop is running (idle, sleeping or
+// working).
 func (l *AgentLoop) IsRunning() bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	return l.state == StateIdle || l.state == StateWorking
+	return l.state == StateIdle || l.state == StateSleeping || l.state == StateWorking
 }
 
 // runTask executes a single task using the think-act-observe cycle.
@@ -299,12 +764,103 @@ func (l *AgentLoop) runTask(ctx context.Context, task string) error {
 		Content: task,
 	})
 
-	for i := 0; i < l.config.MaxIterations; i++ {
+	return l.runConversation(ctx, task, messages, 0)
+}
+
+// ResumeTask reloads a checkpoint written by a previous run and continues its
+// think-act-observe cycle from the saved iteration, blocking until the task
+// finishes. The task keeps checkpointing to checkpointPath, and the file is
+// removed once it completes; later tasks use CheckpointPath as usual.
+//
+// On a started loop the resume is queued like other work (ahead of queued
+// tasks of normal priority) so it never runs alongside another task; if ctx
+// ends while it is still queued, it is withdrawn. On a loop that has not been
+// started it runs immediately, and Start refuses to run until it finishes.
+func (l *AgentLoop) ResumeTask(ctx context.Context, checkpointPath string) error {
+	cp, err := loadCheckpoint(checkpointPath)
+	if err != nil {
+		return err
+	}
+	qt := queuedTask{
+		task:           cp.Task,
+		priority:       PriorityNormal,
+		resume:         cp,
+		checkpointPath: checkpointPath,
+		done:           make(chan error, 1),
+	}
+	log.Printf("[agentloop] Resuming task from %s at iteration %d (%d messages)", checkpointPath, cp.Iteration, len(cp.Messages))
+
+	l.mu.Lock()
+	switch {
+	case l.draining:
+		l.mu.Unlock()
+		return fmt.Errorf("agent loop is shutting down")
+	case !l.started && l.state == StateWorking:
+		l.mu.Unlock()
+		return fmt.Errorf("agent is already working on a task")
+	case !l.started:
+		l.mu.Unlock()
+		l.processTask(ctx, qt)
+		l.mu.Lock()
+		if !l.started {
+			l.state = StateStopped
+		}
+		l.mu.Unlock()
+		select {
+		case err := <-qt.done:
+			return err
+		default:
+			return fmt.Errorf("resumed task was preempted before the loop started")
+		}
+	}
+	l.requeueLocked(qt)
+	l.mu.Unlock()
+
+	select {
+	case l.workReady <- struct{}{}:
+	default:
+	}
+
+	select {
+	case err := <-qt.done:
+		return err
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for i, queued := range l.queue {
+			if queued.done == qt.done {
+				l.queue = append(l.queue[:i], l.queue[i+1:]...)
+				break
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// runConversation runs think-act-observe iterations over messages, starting
+// after iteration start (0 for a new task).
+func (l *AgentLoop) runConversation(ctx context.Context, task string, messages []llm.Message, start int) error {
+	// Iterations in a row where every tool call failed, and responses in a
+	// row with no content at all.
+	var errorStreak, emptyStreak int
+
+	l.mu.Lock()
+	l.taskStarted = time.Now()
+	l.mu.Unlock()
+
+	for i := start; i < l.config.MaxIterations; i++ {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
 		}
+		if l.isDraining() {
+			l.saveCheckpoint(task, messages, i)
+			return ErrDrained
+		}
+		if err := l.checkTaskDuration(); err != nil {
+			return err
+		}
 
 		l.mu.Lock()
 		l.iteration = i + 1
@@ -314,14 +870,16 @@ func (l *AgentLoop) runTask(ctx context.Context, task string) error {
 		// Context window management
 		if l.context.NeedsTruncation(messages) {
 			log.Printf("[agentloop] Context window pressure at iteration %d, truncating", i+1)
-			messages = l.context.Truncate(messages)
+			messages = l.context.TruncateContext(ctx, messages)
 		}
 
 		// Think: call LLM
+		callStart := time.Now()
 		resp, err := l.client.Chat(ctx, &llm.ChatRequest{
 			Messages: messages,
 			Tools:    l.tools,
 		})
+		latency := time.Since(callStart)
 		if err != nil {
 			return fmt.Errorf("LLM call failed at iteration %d: %w", i+1, err)
 		}
@@ -330,12 +888,50 @@ func (l *AgentLoop) runTask(ctx context.Context, task string) error {
 		if resp.Usage != nil {
 			l.mu.Lock()
 			l.totalTokens += resp.Usage.TotalTokens
+			if l.config.Pricing != nil {
+				l.totalCost += l.config.Pricing.Cost(resp.Usage)
+			}
 			l.mu.Unlock()
+		}
+
+		if l.config.OnIteration != nil {
+			var prompt, completion int
+			if resp.Usage != nil {
+				prompt, completion = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+			}
+			l.config.OnIteration(i+1, prompt, completion, l.totalTokens, latency)
+		}
 
+		if resp.Usage != nil {
 			// Check token budget
 			if l.totalTokens > l.config.MaxTokensPerTask {
 				return fmt.Errorf("token budget exceeded: %d > %d", l.totalTokens, l.config.MaxTokensPerTask)
 			}
+
+			// Check cost budget
+			if l.config.MaxCostUSD > 0 && l.totalCost > l.config.MaxCostUSD {
+				return fmt.Errorf("cost budget exceeded: $%.4f > $%.4f", l.totalCost, l.config.MaxCostUSD)
+			}
+		}
+
+		// An empty response isn't an answer; ask the model to go on rather
+		// than treating it as completion.
+		if len(resp.ToolCalls) == 0 && strings.TrimSpace(resp.Content) == "" {
+			emptyStreak++
+			if emptyStreak >= l.config.MaxConsecutiveEmptyTurns {
+				return fmt.Errorf("aborting after %d consecutive empty responses", emptyStreak)
+			}
+			log.Printf("[agentloop] Empty response at iteration %d, prompting to continue", i+1)
+			messages = append(messages, llm.Message{Role: "user", Content: emptyTurnPrompt})
+			continue
+		}
+		emptyStreak = 0
+
+		// A stop began while the model was thinking: drop its tool calls
+		// rather than start them, so the checkpoint resumes cleanly here.
+		if len(resp.ToolCalls) > 0 && l.isDraining() {
+			l.saveCheckpoint(task, messages, i)
+			return ErrDrained
 		}
 
 		// Add assistant response to history
@@ -350,35 +946,241 @@ func (l *AgentLoop) runTask(ctx context.Context, task string) error {
 		if len(resp.ToolCalls) == 0 {
 			log.Printf("[agentloop] Task complete after %d iterations (~%d tokens)",
 				i+1, l.totalTokens)
+			l.clearCheckpoint()
 			return nil
 		}
 
-		// Act: execute each tool call
-		for _, tc := range resp.ToolCalls {
-			toolCtx, toolCancel := context.WithTimeout(ctx, l.config.ToolTimeout)
+		// Act: execute the tool calls
+		results, failed, denied := l.executeToolCalls(ctx, resp.ToolCalls, i+1)
+		messages = append(messages, results...)
+		switch {
+		case denied == len(resp.ToolCalls):
+			// Nothing ran; a denial is feedback, not an error.
+		case failed+denied == len(resp.ToolCalls):
+			errorStreak++
+		default:
+			errorStreak = 0
+		}
 
-			result, err := l.executor.Execute(toolCtx, tc)
-			toolCancel()
+		// Publish heartbeat
+		if l.config.OnHeartbeat != nil && (i+1)%5 == 0 {
+			l.config.OnHeartbeat(StateWorking, i+1, l.totalTokens)
+		}
 
-			if err != nil {
-				result = fmt.Sprintf("Error executing %s: %v", tc.Name, err)
-				log.Printf("[agentloop] Tool error: %s: %v", tc.Name, err)
-			}
+		l.saveCheckpoint(task, messages, i+1)
 
-			// Observe: add tool result to conversation
-			messages = append(messages, llm.Message{
+		if err := l.checkTaskDuration(); err != nil {
+			return err
+		}
+		if errorStreak >= l.config.MaxConsecutiveErrors {
+			return fmt.Errorf("aborting after %d consecutive iterations where every tool call failed", errorStreak)
+		}
+
+		if l.takePreempt() {
+			return &preemptedError{cp: l.snapshot(task, messages, i+1)}
+		}
+	}
+
+	return fmt.Errorf("max iterations (%d) reached without completion", l.config.MaxIterations)
+}
+
+// executeToolCalls runs the calls from one assistant response and returns
+// their tool-result messages in call order, how many of the calls failed and
+// how many OnToolApproval denied. Consecutive read-only calls run concurrently, up to
+// ToolConcurrency at a time; a mutating call waits for everything before it
+// and finishes before anything after it starts.
+func (l *AgentLoop) executeToolCalls(ctx context.Context, calls []llm.ToolCall, iteration int) (results []llm.Message, failed, denied int) {
+	results = make([]llm.Message, len(calls))
+	errs := make([]error, len(calls))
+	sem := make(chan struct{}, l.config.ToolConcurrency)
+	var wg sync.WaitGroup
+
+	for idx, tc := range calls {
+		// Out of time: don't start further calls. runConversation ends the
+		// task once the ones already running finish.
+		if err := l.checkTaskDuration(); err != nil {
+			results[idx] = llm.Message{
 				Role:       "tool",
-				Content:    result,
+				Content:    "Tool call skipped: " + err.Error(),
 				ToolCallID: tc.ID,
 				Name:       tc.Name,
-			})
+			}
+			failed++
+			continue
 		}
 
-		// Publish heartbeat
-		if l.config.OnHeartbeat != nil && (i+1)%5 == 0 {
-			l.config.OnHeartbeat(StateWorking, i+1, l.totalTokens)
+		if l.config.OnToolApproval != nil {
+			if approved, reason := l.config.OnToolApproval(tc, iteration); !approved {
+				log.Printf("[agentloop] Tool call denied: %s: %s", tc.Name, reason)
+				results[idx] = llm.Message{
+					Role:       "tool",
+					Content:    "Tool call denied: " + reason,
+					ToolCallID: tc.ID,
+					Name:       tc.Name,
+				}
+				denied++
+				continue
+			}
+		}
+
+		if l.config.ToolConcurrency == 1 || !isReadOnlyTool(tc.Name) {
+			wg.Wait()
+			results[idx], errs[idx] = l.executeToolCall(ctx, tc)
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(idx int, tc llm.ToolCall) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[idx], errs[idx] = l.executeToolCall(ctx, tc)
+		}(idx, tc)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			failed++
 		}
 	}
+	return results, failed, denied
+}
 
-	return fmt.Errorf("max iterations (%d) reached without completion", l.config.MaxIterations)
+// executeToolCall runs one tool call, retrying it per ToolRetry if it is
+// read-only, and wraps its output as a tool message. The returned error is
+// the tool's final failure, already reflected in the message.
+func (l *AgentLoop) executeToolCall(ctx context.Context, tc llm.ToolCall) (llm.Message, error) {
+	attempts := 1
+	if isReadOnlyTool(tc.Name) && l.config.ToolRetry.MaxAttempts > 1 {
+		attempts = l.config.ToolRetry.MaxAttempts
+	}
+
+	var result string
+	var err error
+	backoff := l.config.ToolRetry.Backoff
+	for attempt := 1; ; attempt++ {
+		var timedOut bool
+		result, timedOut, err = l.runToolOnce(ctx, tc)
+		// A timed-out call would most likely time out again.
+		if err == nil || timedOut || attempt >= attempts {
+			break
+		}
+		log.Printf("[agentloop] Tool %s failed (attempt %d/%d), retrying in %s: %v",
+			tc.Name, attempt, attempts, backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff *= 2
+	}
+
+	if err != nil {
+		result = fmt.Sprintf("Error executing %s: %v", tc.Name, err)
+		log.Printf("[agentloop] Tool error: %s: %v", tc.Name, err)
+	}
+
+	// Observe: the result goes back into the conversation
+	return llm.Message{
+		Role:       "tool",
+		Content:    result,
+		ToolCallID: tc.ID,
+		Name:       tc.Name,
+	}, err
+}
+
+// runToolOnce executes tc under ToolTimeout. timedOut reports whether the
+// call ran out of time.
+func (l *AgentLoop) runToolOnce(ctx context.Context, tc llm.ToolCall) (result string, timedOut bool, err error) {
+	toolCtx, toolCancel := context.WithTimeout(ctx, l.config.ToolTimeout)
+	defer toolCancel()
+	if l.config.OnToolOutput != nil {
+		toolCtx = WithOutputStream(toolCtx, func(chunk []byte) { l.config.OnToolOutput(tc, chunk) })
+	}
+	result, err = l.executor.Execute(toolCtx, tc)
+	return result, toolCtx.Err() == context.DeadlineExceeded, err
+}
+
+// saveCheckpoint writes the conversation to the task's checkpoint file, if
+// there is one. Failures are logged but don't interrupt the task.
+func (l *AgentLoop) saveCheckpoint(task string, messages []llm.Message, iteration int) {
+	path := l.checkpointPath()
+	if path == "" {
+		return
+	}
+	if err := writeCheckpoint(path, l.snapshot(task, messages, iteration)); err != nil {
+		log.Printf("[agentloop] Checkpoint failed: %v", err)
+	}
+}
+
+// checkpointPath returns where the running task checkpoints: the file it
+// was resumed from, else CheckpointPath.
+func (l *AgentLoop) checkpointPath() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.checkpoint != "" {
+		return l.checkpoint
+	}
+	return l.config.CheckpointPath
+}
+
+// snapshot captures the task's resumable state after iteration.
+func (l *AgentLoop) snapshot(task string, messages []llm.Message, iteration int) *checkpoint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return &checkpoint{
+		checkpointHeader: checkpointHeader{
+			Task:        task,
+			Iteration:   iteration,
+			TotalTokens: l.totalTokens,
+			CostUSD:     l.totalCost,
+			Actor:       l.config.Actor,
+			SavedAt:     time.Now().UTC(),
+		},
+		Messages: messages,
+	}
+}
+
+// checkTaskDuration returns an error once the running task has used up
+// MaxTaskDuration.
+func (l *AgentLoop) checkTaskDuration() error {
+	if l.config.MaxTaskDuration <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	started := l.taskStarted
+	l.mu.Unlock()
+	if started.IsZero() {
+		return nil
+	}
+	if elapsed := time.Since(started); elapsed > l.config.MaxTaskDuration {
+		return fmt.Errorf("task exceeded max duration: ran %v > %v",
+			elapsed.Round(time.Millisecond), l.config.MaxTaskDuration)
+	}
+	return nil
+}
+
+// takePreempt reports whether the running task has been asked to yield.
+func (l *AgentLoop) takePreempt() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p := l.preempt
+	l.preempt = false
+	return p
+}
+
+// clearCheckpoint removes the checkpoint of a completed task so it isn't resumed.
+func (l *AgentLoop) clearCheckpoint() {
+	path := l.checkpointPath()
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("[agentloop] Removing checkpoint: %v", err)
+	}
 }
@@ -0,0 +1,835 @@
+package agentloop
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/llm"
+	"github.com/steveyegge/gastown/internal/llm/llmtest"
+)
+
+// scriptedLLM returns its responses in order and records each request.
+type scriptedLLM struct {
+	responses []*llm.ChatResponse
+	requests  []*llm.ChatRequest
+}
+
+func (s *scriptedLLM) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	cp := *req
+	cp.Messages = append([]llm.Message(nil), req.Messages...)
+	s.requests = append(s.requests, &cp)
+	if len(s.responses) == 0 {
+		return nil, errors.New("script exhausted")
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+func (s *scriptedLLM) Stream(ctx context.Context, req *llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+func (s *scriptedLLM) ModelInfo() *llm.ModelInfo      { return &llm.ModelInfo{ID: "scripted"} }
+func (s *scriptedLLM) Ping(ctx context.Context) error { return nil }
+func (s *scriptedLLM) Close() error                   { return nil }
+
+func toolCallResponse(id, name string, args interface{}) *llm.ChatResponse {
+	raw, _ := json.Marshal(args)
+	return &llm.ChatResponse{
+		ToolCalls: []llm.ToolCall{{ID: id, Name: name, Args: raw}},
+		Usage:     &llm.Usage{TotalTokens: 10},
+	}
+}
+
+func TestRunTaskToolApprovalDenialFeedsBackToModel(t *testing.T) {
+	e := newTestExecutor(t, nil)
+	client := &scriptedLLM{responses: []*llm.ChatResponse{
+		toolCallResponse("c1", "file_write", map[string]string{"path": "x.txt", "content": "x"}),
+		{Content: "ok, skipping the write"},
+	}}
+
+	var gotIteration int
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{
+		OnToolApproval: func(call llm.ToolCall, iteration int) (bool, string) {
+			gotIteration = iteration
+			return call.Name != "file_write", "writes need review"
+		},
+	})
+
+	if err := loop.runTask(context.Background(), "write x"); err != nil {
+		t.Fatalf("runTask: %v", err)
+	}
+	if gotIteration != 1 {
+		t.Errorf("iteration = %d, want 1", gotIteration)
+	}
+	if _, err := readFileErr(e, "x.txt"); err == nil {
+		t.Error("denied file_write should not have executed")
+	}
+
+	last := client.requests[1].Messages
+	result := last[len(last)-1]
+	if result.Role != "tool" || result.ToolCallID != "c1" || !strings.Contains(result.Content, "Tool call denied: writes need review") {
+		t.Errorf("tool result = %+v", result)
+	}
+}
+
+func TestRunTaskReportsEachIteration(t *testing.T) {
+	e := newTestExecutor(t, nil)
+	client := &scriptedLLM{responses: []*llm.ChatResponse{
+		toolCallResponse("c1", "file_read", map[string]string{"path": "missing.txt"}),
+		{Content: "done", Usage: &llm.Usage{PromptTokens: 30, CompletionTokens: 2, TotalTokens: 32}},
+	}}
+
+	type call struct{ iter, prompt, completion, total int }
+	var calls []call
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{
+		OnIteration: func(iter, prompt, completion, total int, latency time.Duration) {
+			if latency < 0 {
+				t.Errorf("latency = %s", latency)
+			}
+			calls = append(calls, call{iter, prompt, completion, total})
+		},
+	})
+
+	if err := loop.runTask(context.Background(), "read"); err != nil {
+		t.Fatalf("runTask: %v", err)
+	}
+	want := []call{{1, 0, 0, 10}, {2, 30, 2, 42}}
+	if fmt.Sprint(calls) != fmt.Sprint(want) {
+		t.Errorf("iterations = %v, want %v", calls, want)
+	}
+}
+
+func TestCheckpointAndResumeTask(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"f.txt": "hello\n"})
+	cpPath := filepath.Join(t.TempDir(), "task.jsonl")
+
+	// First run: one tool iteration, then the LLM fails (e.g. a crash).
+	client := &scriptedLLM{responses: []*llm.ChatResponse{
+		toolCallResponse("c1", "file_read", map[string]string{"path": "f.txt"}),
+	}}
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{SystemPrompt: "sys", CheckpointPath: cpPath})
+	if err := loop.runTask(context.Background(), "read f"); err == nil {
+		t.Fatal("expected failure once the script is exhausted")
+	}
+
+	cp, err := loadCheckpoint(cpPath)
+	if err != nil {
+		t.Fatalf("loadCheckpoint: %v", err)
+	}
+	if cp.Task != "read f" || cp.Iteration != 1 || cp.TotalTokens != 10 || len(cp.Messages) != 4 {
+		t.Fatalf("checkpoint = %+v with %d messages", cp.checkpointHeader, len(cp.Messages))
+	}
+
+	// Resume with a fresh loop: the model sees the saved history.
+	client = &scriptedLLM{responses: []*llm.ChatResponse{{Content: "done", Usage: &llm.Usage{TotalTokens: 5}}}}
+	var completedIterations, completedTokens int
+	loop = NewAgentLoop(client, e, &AgentLoopConfig{
+		OnTaskComplete: func(task string, iterations, totalTokens int, err error) {
+			completedIterations, completedTokens = iterations, totalTokens
+		},
+	})
+	if err := loop.ResumeTask(context.Background(), cpPath); err != nil {
+		t.Fatalf("ResumeTask: %v", err)
+	}
+	msgs := client.requests[0].Messages
+	if len(msgs) != 4 || !strings.Contains(msgs[3].Content, "hello") {
+		t.Errorf("resumed conversation = %+v", msgs)
+	}
+	if completedIterations != 2 || completedTokens != 15 {
+		t.Errorf("completed iterations=%d tokens=%d, want 2 and 15", completedIterations, completedTokens)
+	}
+	if _, err := os.Stat(cpPath); !os.IsNotExist(err) {
+		t.Error("checkpoint should be removed after the task completes")
+	}
+}
+
+func TestResumeTaskQueuesBehindRunningTask(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"f.txt": "hello\n"})
+	dir := t.TempDir()
+	resumePath := filepath.Join(dir, "resume.jsonl")
+	configPath := filepath.Join(dir, "config.jsonl")
+
+	first := NewAgentLoop(&scriptedLLM{responses: []*llm.ChatResponse{
+		toolCallResponse("c1", "file_read", map[string]string{"path": "f.txt"}),
+	}}, e, &AgentLoopConfig{CheckpointPath: resumePath})
+	if err := first.runTask(context.Background(), "read f"); err == nil {
+		t.Fatal("expected failure once the script is exhausted")
+	}
+
+	client := newGatedLLM()
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{CheckpointPath: configPath})
+	startTestLoop(t, loop)
+	if err := loop.AssignWork("busy"); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, loop, StateWorking)
+
+	resumed := make(chan error, 1)
+	go func() { resumed <- loop.ResumeTask(context.Background(), resumePath) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for loop.QueuedTasks() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("resume was not queued behind the running task")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client.replies <- &llm.ChatResponse{Content: "done"}
+	waitForRequests(t, client, 2)
+	if msgs := client.lastRequest().Messages; len(msgs) != 3 || !strings.Contains(msgs[2].Content, "hello") {
+		t.Errorf("resumed conversation = %+v", msgs)
+	}
+	client.replies <- &llm.ChatResponse{Content: "done"}
+	if err := <-resumed; err != nil {
+		t.Fatalf("ResumeTask: %v", err)
+	}
+	if _, err := os.Stat(resumePath); !os.IsNotExist(err) {
+		t.Error("resumed checkpoint should be removed after the task completes")
+	}
+
+	// Later tasks checkpoint to the configured path, not the resumed file.
+	if err := loop.AssignWork("read again"); err != nil {
+		t.Fatal(err)
+	}
+	client.replies <- toolCallResponse("c2", "file_read", map[string]string{"path": "f.txt"})
+	waitForRequests(t, client, 4)
+	if _, err := os.Stat(configPath); err != nil {
+		t.Errorf("configured checkpoint not written: %v", err)
+	}
+	if _, err := os.Stat(resumePath); !os.IsNotExist(err) {
+		t.Error("later task wrote to the resumed checkpoint")
+	}
+	client.replies <- &llm.ChatResponse{Content: "done"}
+}
+
+func waitForRequests(t *testing.T, g *gatedLLM, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		g.mu.Lock()
+		got := len(g.requests)
+		g.mu.Unlock()
+		if got >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("saw %d model calls, want %d", got, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// gatedLLM blocks each Chat call until a response is sent on replies.
+type gatedLLM struct {
+	scriptedLLM
+	replies chan *llm.ChatResponse
+
+	mu       sync.Mutex
+	requests []*llm.ChatRequest
+}
+
+func newGatedLLM() *gatedLLM {
+	return &gatedLLM{replies: make(chan *llm.ChatResponse)}
+}
+
+func (g *gatedLLM) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	cp := *req
+	cp.Messages = append([]llm.Message(nil), req.Messages...)
+	g.mu.Lock()
+	g.requests = append(g.requests, &cp)
+	g.mu.Unlock()
+
+	select {
+	case resp := <-g.replies:
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *gatedLLM) lastRequest() *llm.ChatRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.requests[len(g.requests)-1]
+}
+
+// startTestLoop runs loop in the background and waits until it is idle.
+func startTestLoop(t *testing.T, loop *AgentLoop) {
+	t.Helper()
+	go func() { _ = loop.Start(context.Background()) }()
+	t.Cleanup(func() { _ = loop.Stop() })
+	waitForState(t, loop, StateIdle)
+}
+
+func waitForState(t *testing.T, loop *AgentLoop, want LoopState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for loop.Status().State != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("loop state = %s, want %s", loop.Status().State, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAssignWorkQueuesWhileBusy(t *testing.T) {
+	client := newGatedLLM()
+	completed := make(chan string, 4)
+	loop := NewAgentLoop(client, newTestExecutor(t, nil), &AgentLoopConfig{
+		QueueDepth: 2,
+		OnTaskComplete: func(task string, _ int, _ int, err error) {
+			if err != nil {
+				t.Errorf("task %q: %v", task, err)
+			}
+			completed <- task
+		},
+	})
+	startTestLoop(t, loop)
+
+	if err := loop.AssignWork("a"); err != nil {
+		t.Fatalf("AssignWork(a): %v", err)
+	}
+	waitForState(t, loop, StateWorking)
+	for _, task := range []string{"b", "c"} {
+		if err := loop.AssignWork(task); err != nil {
+			t.Fatalf("AssignWork(%s) while busy: %v", task, err)
+		}
+	}
+	if err := loop.AssignWork("d"); err == nil || !strings.Contains(err.Error(), "queue full") {
+		t.Fatalf("AssignWork(d) err = %v, want queue full", err)
+	}
+	if got := loop.Status().QueuedTasks; got != 2 {
+		t.Errorf("QueuedTasks = %d, want 2", got)
+	}
+
+	for _, want := range []string{"a", "b", "c"} {
+		client.replies <- &llm.ChatResponse{Content: "done"}
+		if got := <-completed; got != want {
+			t.Fatalf("completed %q, want %q", got, want)
+		}
+	}
+	waitForState(t, loop, StateIdle)
+	if got := loop.QueuedTasks(); got != 0 {
+		t.Errorf("QueuedTasks after drain = %d, want 0", got)
+	}
+}
+
+func TestStopDrainsQueue(t *testing.T) {
+	client := newGatedLLM()
+	loop := NewAgentLoop(client, newTestExecutor(t, nil), &AgentLoopConfig{})
+	startTestLoop(t, loop)
+
+	if err := loop.AssignWork("a"); err != nil {
+		t.Fatalf("AssignWork(a): %v", err)
+	}
+	waitForState(t, loop, StateWorking)
+	if err := loop.AssignWork("b"); err != nil {
+		t.Fatalf("AssignWork(b): %v", err)
+	}
+
+	if err := loop.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if got := loop.QueuedTasks(); got != 0 {
+		t.Errorf("QueuedTasks after stop = %d, want 0", got)
+	}
+	if err := loop.AssignWork("c"); err == nil {
+		t.Error("AssignWork after stop should fail")
+	}
+}
+
+func TestAssignWorkWithPriorityOrdersQueue(t *testing.T) {
+	client := newGatedLLM()
+	completed := make(chan string, 4)
+	loop := NewAgentLoop(client, newTestExecutor(t, nil), &AgentLoopConfig{
+		OnTaskComplete: func(task string, _ int, _ int, _ error) { completed <- task },
+	})
+	startTestLoop(t, loop)
+
+	if err := loop.AssignWork("first"); err != nil {
+		t.Fatalf("AssignWork: %v", err)
+	}
+	waitForState(t, loop, StateWorking)
+	for _, a := range []struct {
+		task     string
+		priority int
+	}{{"low", -1}, {"normal", PriorityNormal}, {"urgent", PriorityUrgent}} {
+		if err := loop.AssignWorkWithPriority(a.task, a.priority); err != nil {
+			t.Fatalf("AssignWorkWithPriority(%s): %v", a.task, err)
+		}
+	}
+
+	for _, want := range []string{"first", "urgent", "normal", "low"} {
+		client.replies <- &llm.ChatResponse{Content: "done"}
+		if got := <-completed; got != want {
+			t.Fatalf("completed %q, want %q", got, want)
+		}
+	}
+}
+
+func TestUrgentWorkPreemptsRunningTask(t *testing.T) {
+	client := newGatedLLM()
+	completed := make(chan string, 4)
+	loop := NewAgentLoop(client, newTestExecutor(t, map[string]string{"a.txt": "a"}), &AgentLoopConfig{
+		PreemptPriority: PriorityUrgent,
+		OnTaskComplete: func(task string, _ int, _ int, err error) {
+			if err != nil {
+				t.Errorf("task %q: %v", task, err)
+			}
+			completed <- task
+		},
+	})
+	startTestLoop(t, loop)
+
+	if err := loop.AssignWork("long task"); err != nil {
+		t.Fatalf("AssignWork: %v", err)
+	}
+	waitForState(t, loop, StateWorking)
+	if err := loop.AssignWorkWithPriority("nudge", PriorityUrgent); err != nil {
+		t.Fatalf("AssignWorkWithPriority: %v", err)
+	}
+
+	// Finish the long task's first iteration; it should yield afterwards.
+	client.replies <- toolCallResponse("c1", "file_list", map[string]string{})
+	deadline := time.Now().Add(5 * time.Second)
+	for client.lastRequest().Messages[0].Content != "nudge" {
+		if time.Now().After(deadline) {
+			t.Fatal("urgent task did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if st := loop.Status(); st.PreemptedTask != "long task" || st.Priority != PriorityUrgent || st.QueuedTasks != 1 {
+		t.Errorf("status while preempted = %+v", st)
+	}
+	client.replies <- &llm.ChatResponse{Content: "nudged"}
+	if got := <-completed; got != "nudge" {
+		t.Fatalf("completed %q, want nudge", got)
+	}
+
+	// The preempted task resumes with its conversation intact.
+	client.replies <- &llm.ChatResponse{Content: "done"}
+	if got := <-completed; got != "long task" {
+		t.Fatalf("completed %q, want long task", got)
+	}
+	msgs := client.lastRequest().Messages
+	if len(msgs) != 3 || msgs[0].Content != "long task" || msgs[2].ToolCallID != "c1" {
+		t.Errorf("resumed conversation = %+v", msgs)
+	}
+}
+
+func TestExecuteToolCallsConcurrentKeepsOrder(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"})
+	loop := NewAgentLoop(&scriptedLLM{}, e, &AgentLoopConfig{ToolConcurrency: 4})
+
+	call := func(id, name string, args interface{}) llm.ToolCall {
+		return toolCallResponse(id, name, args).ToolCalls[0]
+	}
+	calls := []llm.ToolCall{
+		call("1", "file_read", map[string]string{"path": "a.txt"}),
+		call("2", "file_read", map[string]string{"path": "b.txt"}),
+		call("3", "file_edit", map[string]string{"path": "a.txt", "search": "alpha", "replace": "ALPHA"}),
+		call("4", "file_read", map[string]string{"path": "a.txt"}),
+		call("5", "file_search", map[string]string{"pattern": "beta"}),
+		call("6", "file_read", map[string]string{"path": "a.txt"}),
+	}
+
+	results, _, _ := loop.executeToolCalls(context.Background(), calls, 1)
+	if len(results) != len(calls) {
+		t.Fatalf("got %d results, want %d", len(results), len(calls))
+	}
+	for i, r := range results {
+		if r.ToolCallID != calls[i].ID || r.Name != calls[i].Name {
+			t.Errorf("result %d is for %s/%s, want %s/%s", i, r.ToolCallID, r.Name, calls[i].ID, calls[i].Name)
+		}
+	}
+	// Reads before the edit see the old content, reads after see the new.
+	if !strings.Contains(results[0].Content, "alpha") || !strings.Contains(results[1].Content, "beta") {
+		t.Errorf("reads before edit: %q, %q", results[0].Content, results[1].Content)
+	}
+	for _, i := range []int{3, 5} {
+		if !strings.Contains(results[i].Content, "ALPHA") {
+			t.Errorf("read %d after edit = %q", i, results[i].Content)
+		}
+	}
+	if !strings.Contains(results[4].Content, "b.txt") {
+		t.Errorf("file_search = %q", results[4].Content)
+	}
+}
+
+func TestToolRetryOnlyRetriesReadOnlyTools(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	e := newTestExecutor(t, nil)
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(e.WorkDir()))
+
+	var mu sync.Mutex
+	var output strings.Builder
+	loop := NewAgentLoop(&scriptedLLM{}, e, &AgentLoopConfig{
+		ToolRetry: ToolRetryConfig{MaxAttempts: 3, Backoff: time.Millisecond},
+		OnToolOutput: func(call llm.ToolCall, chunk []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			output.Write(chunk)
+		},
+	})
+	attempts := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := strings.Count(output.String(), "not a git repository")
+		output.Reset()
+		return n
+	}
+
+	// Outside a repository git_status fails every time.
+	msg, _ := loop.executeToolCall(context.Background(), llm.ToolCall{ID: "c1", Name: "git_status", Args: json.RawMessage(`{}`)})
+	if !strings.Contains(msg.Content, "Error executing git_status") {
+		t.Errorf("git_status result = %q", msg.Content)
+	}
+	if n := attempts(); n != 3 {
+		t.Errorf("git_status ran %d times, want 3", n)
+	}
+
+	msg, _ = loop.executeToolCall(context.Background(), llm.ToolCall{ID: "c2", Name: "git_commit", Args: json.RawMessage(`{"message":"x"}`)})
+	if !strings.Contains(msg.Content, "Error executing git_commit") {
+		t.Errorf("git_commit result = %q", msg.Content)
+	}
+	if n := attempts(); n != 1 {
+		t.Errorf("git_commit ran %d times, want 1", n)
+	}
+}
+
+func TestRunTaskAbortsOnConsecutiveToolErrors(t *testing.T) {
+	e := newTestExecutor(t, nil)
+	var responses []*llm.ChatResponse
+	for i := 0; i < 5; i++ {
+		responses = append(responses, toolCallResponse(fmt.Sprintf("c%d", i), "file_read", map[string]string{"path": "missing.txt"}))
+	}
+	client := &scriptedLLM{responses: responses}
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{MaxConsecutiveErrors: 3})
+
+	err := loop.runTask(context.Background(), "read missing.txt")
+	if err == nil || !strings.Contains(err.Error(), "3 consecutive iterations") {
+		t.Fatalf("runTask error = %v", err)
+	}
+	if len(client.requests) != 3 {
+		t.Errorf("made %d model calls, want 3", len(client.requests))
+	}
+}
+
+func TestRunTaskDenialsDoNotCountAsErrors(t *testing.T) {
+	e := newTestExecutor(t, nil)
+	var responses []*llm.ChatResponse
+	for i := 0; i < 4; i++ {
+		responses = append(responses, toolCallResponse(fmt.Sprintf("c%d", i), "file_write", map[string]string{"path": "x.txt", "content": "x"}))
+	}
+	responses = append(responses, &llm.ChatResponse{Content: "giving up on the write"})
+	client := &scriptedLLM{responses: responses}
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{
+		MaxConsecutiveErrors: 3,
+		OnToolApproval: func(llm.ToolCall, int) (bool, string) {
+			return false, "writes need review"
+		},
+	})
+
+	if err := loop.runTask(context.Background(), "write x"); err != nil {
+		t.Fatalf("runTask aborted on denials: %v", err)
+	}
+	if len(client.requests) != 5 {
+		t.Errorf("made %d model calls, want 5", len(client.requests))
+	}
+}
+
+func TestRunTaskEmptyTurns(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"a.txt": "a\n"})
+
+	// A productive turn resets the streak; an empty one doesn't complete.
+	client := &scriptedLLM{responses: []*llm.ChatResponse{
+		{},
+		toolCallResponse("c1", "file_read", map[string]string{"path": "a.txt"}),
+		{Content: " "},
+		{Content: "done"},
+	}}
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{MaxConsecutiveEmptyTurns: 2})
+	if err := loop.runTask(context.Background(), "read a.txt"); err != nil {
+		t.Fatalf("runTask: %v", err)
+	}
+	msgs := client.requests[1].Messages
+	if last := msgs[len(msgs)-1]; last.Role != "user" || last.Content != emptyTurnPrompt {
+		t.Errorf("after an empty turn the model saw %+v", last)
+	}
+
+	client = &scriptedLLM{responses: []*llm.ChatResponse{{}, {}, {Content: "done"}}}
+	loop = NewAgentLoop(client, e, &AgentLoopConfig{MaxConsecutiveEmptyTurns: 2})
+	if err := loop.runTask(context.Background(), "read a.txt"); err == nil || !strings.Contains(err.Error(), "2 consecutive empty responses") {
+		t.Errorf("runTask error = %v", err)
+	}
+}
+
+func TestRunTaskThinkActObserve(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"greet.txt": "hello\n"})
+	client := llmtest.NewFakeClient(
+		llmtest.ToolCall("c1", "file_read", map[string]string{"path": "greet.txt"}).WithUsage(100, 10),
+		llmtest.ToolCall("c2", "file_edit", map[string]string{"path": "greet.txt", "search": "hello", "replace": "hello, world"}).WithUsage(150, 10),
+		llmtest.Reply("Updated the greeting.").WithUsage(200, 5),
+	)
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{SystemPrompt: "You are a polecat."})
+
+	if err := loop.runTask(context.Background(), "Make greet.txt say hello, world"); err != nil {
+		t.Fatalf("runTask: %v", err)
+	}
+	if got := readFile(t, e, "greet.txt"); got != "hello, world\n" {
+		t.Errorf("greet.txt = %q", got)
+	}
+	if client.Remaining() != 0 {
+		t.Errorf("%d scripted turns unused", client.Remaining())
+	}
+
+	// Each request carries the previous tool results back to the model.
+	reqs := client.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("made %d model calls, want 3", len(reqs))
+	}
+	if len(reqs[0].Tools) == 0 || reqs[0].Messages[0].Role != "system" {
+		t.Errorf("first request = %+v", reqs[0])
+	}
+	observed := reqs[1].Messages[len(reqs[1].Messages)-1]
+	if observed.Role != "tool" || observed.ToolCallID != "c1" || !strings.Contains(observed.Content, "hello") {
+		t.Errorf("file_read result = %+v", observed)
+	}
+	if st := loop.Status(); st.TotalTokens != 475 {
+		t.Errorf("total tokens = %d, want 475", st.TotalTokens)
+	}
+}
+
+func TestRunTaskReportsLLMErrors(t *testing.T) {
+	e := newTestExecutor(t, nil)
+	client := llmtest.NewFakeClient(
+		llmtest.ToolCall("c1", "file_list", map[string]string{}),
+		llmtest.Fail(&llm.APIError{StatusCode: 400, Body: "bad request"}),
+	)
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{})
+
+	err := loop.runTask(context.Background(), "list files")
+	var apiErr *llm.APIError
+	if !errors.As(err, &apiErr) || !strings.Contains(err.Error(), "iteration 2") {
+		t.Errorf("runTask error = %v, want the API error at iteration 2", err)
+	}
+}
+
+// closeCountingLLM is a gatedLLM that counts Close calls.
+type closeCountingLLM struct {
+	*gatedLLM
+	closes atomic.Int32
+}
+
+func (c *closeCountingLLM) Close() error {
+	c.closes.Add(1)
+	return nil
+}
+
+func TestIdleLoopSleepsAndWakesOnWork(t *testing.T) {
+	client := &closeCountingLLM{gatedLLM: newGatedLLM()}
+	var idleCalls atomic.Int32
+	heartbeats := make(chan LoopState, 4)
+	woken := make(chan struct{}, 1)
+	completed := make(chan string, 1)
+	loop := NewAgentLoop(client, newTestExecutor(t, nil), &AgentLoopConfig{
+		IdleTimeout: 20 * time.Millisecond,
+		OnIdle: func(idle time.Duration) bool {
+			if idle < 20*time.Millisecond {
+				t.Errorf("OnIdle(%v), want at least the idle timeout", idle)
+			}
+			// Stay awake once to check the timer is re-armed.
+			return idleCalls.Add(1) > 1
+		},
+		OnHeartbeat: func(state LoopState, _, _ int) { heartbeats <- state },
+		OnWake:      func() { woken <- struct{}{} },
+		OnTaskComplete: func(task string, _ int, _ int, err error) {
+			completed <- task
+		},
+	})
+	startTestLoop(t, loop)
+
+	waitForState(t, loop, StateSleeping)
+	if got := idleCalls.Load(); got != 2 {
+		t.Errorf("OnIdle called %d times before sleeping, want 2", got)
+	}
+	if got := client.closes.Load(); got != 1 {
+		t.Errorf("client closed %d times, want 1", got)
+	}
+	if state := <-heartbeats; state != StateSleeping {
+		t.Errorf("heartbeat state = %s, want sleeping", state)
+	}
+	if !loop.IsRunning() {
+		t.Error("sleeping loop should report running")
+	}
+
+	// Asleep, the idle timer is off.
+	time.Sleep(60 * time.Millisecond)
+	if got := idleCalls.Load(); got != 2 {
+		t.Errorf("OnIdle called %d times while asleep, want no more calls", got)
+	}
+
+	if err := loop.AssignWork("wake up"); err != nil {
+		t.Fatalf("AssignWork: %v", err)
+	}
+	select {
+	case <-woken:
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnWake not called")
+	}
+	waitForState(t, loop, StateWorking)
+	client.replies <- &llm.ChatResponse{Content: "done"}
+	if task := <-completed; task != "wake up" {
+		t.Errorf("completed %q, want %q", task, "wake up")
+	}
+}
+
+func TestStopGracefullyDrainsRunningTask(t *testing.T) {
+	client := newGatedLLM()
+	e := newTestExecutor(t, nil)
+	cpPath := filepath.Join(t.TempDir(), "task.ckpt")
+	heartbeats := make(chan LoopState, 4)
+	completed := make(chan error, 1)
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{
+		CheckpointPath: cpPath,
+		OnHeartbeat:    func(state LoopState, _, _ int) { heartbeats <- state },
+		OnTaskComplete: func(_ string, _ int, _ int, err error) { completed <- err },
+	})
+	startTestLoop(t, loop)
+
+	if err := loop.AssignWork("write x"); err != nil {
+		t.Fatalf("AssignWork: %v", err)
+	}
+	waitForState(t, loop, StateWorking)
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- loop.StopGracefully(5 * time.Second) }()
+	if state := <-heartbeats; state != StateRetiring {
+		t.Errorf("heartbeat state = %s, want retiring", state)
+	}
+	if err := loop.AssignWork("more"); err == nil || !strings.Contains(err.Error(), "shutting down") {
+		t.Errorf("AssignWork while draining err = %v, want shutting down", err)
+	}
+
+	// The in-flight model call finishes, but its tool call must not start.
+	client.replies <- toolCallResponse("c1", "file_write", map[string]string{"path": "x.txt", "content": "x"})
+
+	if err := <-stopped; err != nil {
+		t.Fatalf("StopGracefully: %v", err)
+	}
+	if err := <-completed; !errors.Is(err, ErrDrained) {
+		t.Errorf("task err = %v, want ErrDrained", err)
+	}
+	if _, err := readFileErr(e, "x.txt"); err == nil {
+		t.Error("tool call started after graceful stop began")
+	}
+	if loop.IsRunning() {
+		t.Error("loop still running after StopGracefully")
+	}
+	cp, err := loadCheckpoint(cpPath)
+	if err != nil {
+		t.Fatalf("loading checkpoint: %v", err)
+	}
+	if cp.Task != "write x" || cp.Iteration != 0 || len(cp.Messages) != 1 {
+		t.Errorf("checkpoint = task %q iteration %d with %d messages, want the task before its first iteration",
+			cp.Task, cp.Iteration, len(cp.Messages))
+	}
+}
+
+func TestStopGracefullyCancelsAfterTimeout(t *testing.T) {
+	client := newGatedLLM()
+	completed := make(chan error, 1)
+	loop := NewAgentLoop(client, newTestExecutor(t, nil), &AgentLoopConfig{
+		OnTaskComplete: func(_ string, _ int, _ int, err error) { completed <- err },
+	})
+	startTestLoop(t, loop)
+
+	if err := loop.AssignWork("hang"); err != nil {
+		t.Fatalf("AssignWork: %v", err)
+	}
+	waitForState(t, loop, StateWorking)
+
+	err := loop.StopGracefully(20 * time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "did not stop") {
+		t.Errorf("StopGracefully err = %v, want a timeout error", err)
+	}
+	if err := <-completed; !errors.Is(err, context.Canceled) {
+		t.Errorf("task err = %v, want context canceled", err)
+	}
+	if loop.IsRunning() {
+		t.Error("loop still running after StopGracefully")
+	}
+}
+
+func TestRunTaskAbortsAfterMaxTaskDuration(t *testing.T) {
+	e := newTestExecutor(t, nil)
+	var calls []llm.ToolCall
+	client := &scriptedLLM{responses: []*llm.ChatResponse{
+		{
+			ToolCalls: []llm.ToolCall{
+				{ID: "c1", Name: "file_read", Args: json.RawMessage(`{"path":"a.txt"}`)},
+				{ID: "c2", Name: "file_write", Args: json.RawMessage(`{"path":"b.txt","content":"b"}`)},
+			},
+		},
+		{Content: "done"},
+	}}
+	loop := NewAgentLoop(client, e, &AgentLoopConfig{
+		MaxTaskDuration: 30 * time.Millisecond,
+		OnToolApproval: func(call llm.ToolCall, _ int) (bool, string) {
+			calls = append(calls, call)
+			// Simulate a slow tool call that uses up the time budget.
+			time.Sleep(40 * time.Millisecond)
+			return true, ""
+		},
+	})
+
+	err := loop.runTask(context.Background(), "slow")
+	if err == nil || !strings.Contains(err.Error(), "task exceeded max duration") {
+		t.Fatalf("runTask err = %v, want max duration error", err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("started %d tool calls, want 1 before the budget ran out", len(calls))
+	}
+	if _, err := readFileErr(e, "b.txt"); err == nil {
+		t.Error("tool call started after the time budget ran out")
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("made %d model calls, want 1", len(client.requests))
+	}
+}
+
+func TestStatusReportsTaskElapsed(t *testing.T) {
+	client := newGatedLLM()
+	loop := NewAgentLoop(client, newTestExecutor(t, nil), &AgentLoopConfig{})
+	startTestLoop(t, loop)
+
+	if st := loop.Status(); st.ElapsedSeconds != 0 {
+		t.Errorf("idle ElapsedSeconds = %v, want 0", st.ElapsedSeconds)
+	}
+	if err := loop.AssignWork("task"); err != nil {
+		t.Fatalf("AssignWork: %v", err)
+	}
+	waitForState(t, loop, StateWorking)
+	time.Sleep(10 * time.Millisecond)
+	if st := loop.Status(); st.ElapsedSeconds < 0.01 {
+		t.Errorf("working ElapsedSeconds = %v, want at least 0.01", st.ElapsedSeconds)
+	}
+	client.replies <- &llm.ChatResponse{Content: "done"}
+	waitForState(t, loop, StateIdle)
+	if st := loop.Status(); st.ElapsedSeconds != 0 {
+		t.Errorf("ElapsedSeconds after task = %v, want 0", st.ElapsedSeconds)
+	}
+}
@@ -0,0 +1,86 @@
+package agentloop
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// OutputFunc receives a command's output as it is produced. Chunks from
+// stdout and stderr arrive in the order they are written; a call never
+// overlaps another for the same command.
+type OutputFunc func(chunk []byte)
+
+type outputStreamKey struct{}
+
+// WithOutputStream returns a context under which subprocess tools (shell_exec,
+// git, gt, bd) pass their output to fn while they run, so callers can show
+// progress for long commands. The tool result is unchanged.
+func WithOutputStream(ctx context.Context, fn OutputFunc) context.Context {
+	return context.WithValue(ctx, outputStreamKey{}, fn)
+}
+
+func outputStreamFrom(ctx context.Context) OutputFunc {
+	fn, _ := ctx.Value(outputStreamKey{}).(OutputFunc)
+	return fn
+}
+
+// cappedBuffer keeps the first limit bytes written to it and counts the
+// rest, so a chatty command cannot grow memory without bound.
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - b.buf.Len()
+	if len(p) <= room {
+		return b.buf.Write(p)
+	}
+	b.buf.Write(p[:room])
+	b.dropped += len(p) - room
+	return len(p), nil
+}
+
+// commandOutput collects a command's stdout and stderr into capped buffers
+// and tees every write to an optional OutputFunc.
+type commandOutput struct {
+	mu     sync.Mutex
+	stream OutputFunc
+	stdout cappedBuffer
+	stderr cappedBuffer
+}
+
+func newCommandOutput(limit int, stream OutputFunc) *commandOutput {
+	return &commandOutput{
+		stream: stream,
+		stdout: cappedBuffer{limit: limit},
+		stderr: cappedBuffer{limit: limit},
+	}
+}
+
+// streamWriter is one side (stdout or stderr) of a commandOutput.
+type streamWriter struct {
+	out *commandOutput
+	buf *cappedBuffer
+}
+
+func (w streamWriter) Write(p []byte) (int, error) {
+	w.out.mu.Lock()
+	defer w.out.mu.Unlock()
+	if w.out.stream != nil {
+		w.out.stream(bytes.Clone(p))
+	}
+	return w.buf.Write(p)
+}
+
+func (o *commandOutput) stdoutWriter() streamWriter { return streamWriter{o, &o.stdout} }
+func (o *commandOutput) stderrWriter() streamWriter { return streamWriter{o, &o.stderr} }
+
+// dropped returns how many output bytes were discarded by the caps.
+func (o *commandOutput) dropped() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.stdout.dropped + o.stderr.dropped
+}
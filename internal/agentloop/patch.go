@@ -0,0 +1,461 @@
+package agentloop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Unified diff parsing and application for the apply_patch tool.
+//
+// Parsing is deliberately lenient about the things models get wrong (hunk
+// line counts, a missing leading space on blank context lines, line numbers
+// that are slightly off) and strict about the things that matter: every
+// context and removed line must match the file exactly, or nothing is written.
+
+const devNull = "/dev/null"
+
+// filePatch is the set of hunks for a single file.
+type filePatch struct {
+	oldPath string // "" for new files
+	newPath string // "" for deleted files
+	hunks   []patchHunk
+}
+
+type patchHunk struct {
+	oldStart int // 1-based, from the @@ header
+	lines    []patchLine
+
+	oldNoEOL bool // "\ No newline at end of file" after the old side
+	newNoEOL bool // ... after the new side
+}
+
+type patchLine struct {
+	op   byte // ' ', '-', or '+'
+	text string
+}
+
+// parseUnifiedDiff splits a unified diff into per-file patches.
+func parseUnifiedDiff(patch string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []filePatch
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if !strings.HasPrefix(line, "--- ") || i+1 >= len(lines) || !strings.HasPrefix(lines[i+1], "+++ ") {
+			// Skip "diff --git", "index", mode lines and any prose.
+			i++
+			continue
+		}
+
+		fp := filePatch{
+			oldPath: diffPath(strings.TrimPrefix(line, "--- ")),
+			newPath: diffPath(strings.TrimPrefix(lines[i+1], "+++ ")),
+		}
+		if fp.oldPath == "" && fp.newPath == "" {
+			return nil, fmt.Errorf("patch header at line %d has no file path", i+1)
+		}
+		i += 2
+
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			fp.hunks = append(fp.hunks, hunk)
+			i = next
+		}
+		if len(fp.hunks) == 0 {
+			return nil, fmt.Errorf("no hunks for %s", fp.displayPath())
+		}
+		files = append(files, fp)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found (expected unified diff with ---/+++ headers)")
+	}
+	return files, nil
+}
+
+// parseHunk parses the hunk whose "@@" header is at lines[start] and returns
+// the index of the first line after it.
+func parseHunk(lines []string, start int) (patchHunk, int, error) {
+	header := lines[start]
+	var hunk patchHunk
+
+	// @@ -oldStart[,oldCount] +newStart[,newCount] @@ [section]
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+		return hunk, 0, fmt.Errorf("malformed hunk header at line %d: %q", start+1, header)
+	}
+	oldRange := strings.TrimPrefix(fields[1], "-")
+	oldStart, _, _ := strings.Cut(oldRange, ",")
+	n, err := strconv.Atoi(oldStart)
+	if err != nil {
+		return hunk, 0, fmt.Errorf("malformed hunk header at line %d: %q", start+1, header)
+	}
+	hunk.oldStart = n
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") || strings.HasPrefix(line, "diff ") {
+			break
+		}
+		if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			break
+		}
+		if strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file" applies to the preceding line.
+			if len(hunk.lines) > 0 {
+				switch hunk.lines[len(hunk.lines)-1].op {
+				case '-':
+					hunk.oldNoEOL = true
+				case '+':
+					hunk.newNoEOL = true
+				default:
+					hunk.oldNoEOL = true
+					hunk.newNoEOL = true
+				}
+			}
+			continue
+		}
+		if line == "" {
+			// Blank context line whose leading space was stripped.
+			hunk.lines = append(hunk.lines, patchLine{op: ' '})
+			continue
+		}
+		switch line[0] {
+		case ' ', '-', '+':
+			hunk.lines = append(hunk.lines, patchLine{op: line[0], text: line[1:]})
+		default:
+			return hunk, 0, fmt.Errorf("unexpected line %d in hunk: %q", i+1, line)
+		}
+	}
+
+	// A trailing blank line is almost always the end of the patch text,
+	// not an empty context line.
+	for len(hunk.lines) > 0 {
+		last := hunk.lines[len(hunk.lines)-1]
+		if last.op != ' ' || last.text != "" {
+			break
+		}
+		hunk.lines = hunk.lines[:len(hunk.lines)-1]
+	}
+	if len(hunk.lines) == 0 {
+		return hunk, 0, fmt.Errorf("empty hunk at line %d", start+1)
+	}
+	return hunk, i, nil
+}
+
+// diffPath extracts a file path from a ---/+++ header value, dropping any
+// timestamp and the conventional a/ b/ prefixes. Returns "" for /dev/null.
+func diffPath(s string) string {
+	if tab := strings.IndexByte(s, '\t'); tab >= 0 {
+		s = s[:tab]
+	}
+	s = strings.TrimSpace(s)
+	if s == devNull {
+		return ""
+	}
+	if strings.HasPrefix(s, "a/") || strings.HasPrefix(s, "b/") {
+		s = s[2:]
+	}
+	return s
+}
+
+func (fp filePatch) displayPath() string {
+	if fp.newPath != "" {
+		return fp.newPath
+	}
+	return fp.oldPath
+}
+
+// oldLines and newLines return the hunk's lines as they appear on each side.
+func (h patchHunk) oldLines() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l.op != '+' {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
+
+func (h patchHunk) newLines() []string {
+	var out []string
+	for _, l := range h.lines {
+		if l.op != '-' {
+			out = append(out, l.text)
+		}
+	}
+	return out
+}
+
+// fileText is file content split into lines, remembering the final newline.
+type fileText struct {
+	lines []string
+	eol   bool // content ends with "\n"
+}
+
+func splitFileText(content string) fileText {
+	if content == "" {
+		return fileText{eol: true}
+	}
+	eol := strings.HasSuffix(content, "\n")
+	content = strings.TrimSuffix(content, "\n")
+	return fileText{lines: strings.Split(content, "\n"), eol: eol}
+}
+
+func (t fileText) String() string {
+	if len(t.lines) == 0 {
+		return ""
+	}
+	s := strings.Join(t.lines, "\n")
+	if t.eol {
+		s += "\n"
+	}
+	return s
+}
+
+// applyHunks applies hunks in order to text. Each hunk is located at its
+// declared line (adjusted for earlier hunks) or, failing that, at the
+// nearest exact match after the previous hunk.
+func applyHunks(path string, text fileText, hunks []patchHunk) (fileText, error) {
+	lines := text.lines
+	eol := text.eol
+	offset := 0 // line delta introduced by earlier hunks
+	minPos := 0 // hunks must not overlap the previous one
+
+	for n, h := range hunks {
+		old := h.oldLines()
+		want := h.oldStart - 1 + offset
+		if len(old) == 0 {
+			// Pure insertion: oldStart names the line after which to insert.
+			want = h.oldStart + offset
+		}
+
+		pos := findLines(lines, old, want, minPos)
+		if pos < 0 {
+			return text, fmt.Errorf("hunk %d of %s does not apply (context not found near line %d)", n+1, path, h.oldStart)
+		}
+
+		repl := h.newLines()
+		updated := make([]string, 0, len(lines)-len(old)+len(repl))
+		updated = append(updated, lines[:pos]...)
+		updated = append(updated, repl...)
+		updated = append(updated, lines[pos+len(old):]...)
+		lines = updated
+
+		offset += len(repl) - len(old)
+		minPos = pos + len(repl)
+
+		if h.newNoEOL {
+			eol = false
+		} else if h.oldNoEOL {
+			eol = true
+		}
+	}
+
+	return fileText{lines: lines, eol: eol}, nil
+}
+
+// findLines returns the index at which needle occurs in haystack, preferring
+// the position closest to want, and never before minPos. Returns -1 if absent.
+func findLines(haystack, needle []string, want, minPos int) int {
+	matchAt := func(pos int) bool {
+		if pos < minPos || pos+len(needle) > len(haystack) {
+			return false
+		}
+		for i, l := range needle {
+			if haystack[pos+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+
+	if want < minPos {
+		want = minPos
+	}
+	if want > len(haystack) {
+		want = len(haystack)
+	}
+	for d := 0; d <= len(haystack); d++ {
+		if matchAt(want + d) {
+			return want + d
+		}
+		if d > 0 && matchAt(want-d) {
+			return want - d
+		}
+	}
+	return -1
+}
+
+// patchChange is the resolved outcome of a file patch, computed in full
+// before anything touches disk.
+type patchChange struct {
+	display string
+	oldAbs  string // file to remove ("" if none)
+	newAbs  string // file to write ("" if none)
+	content string
+	added   int
+	removed int
+	kind    byte // 'A', 'M', 'D', 'R'
+}
+
+// planPatch resolves and applies every file patch in memory. Any failure
+// rejects the whole patch.
+func (e *Executor) planPatch(files []filePatch) ([]patchChange, error) {
+	// Later patches to the same file see earlier results.
+	pending := make(map[string]*string)
+	read := func(abs string) (string, bool, error) {
+		if c, ok := pending[abs]; ok {
+			if c == nil {
+				return "", false, nil
+			}
+			return *c, true, nil
+		}
+		data, err := os.ReadFile(abs)
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return string(data), true, nil
+	}
+
+	var changes []patchChange
+	for _, fp := range files {
+		ch := patchChange{display: fp.displayPath()}
+		for _, h := range fp.hunks {
+			for _, l := range h.lines {
+				switch l.op {
+				case '+':
+					ch.added++
+				case '-':
+					ch.removed++
+				}
+			}
+		}
+
+		var oldAbs, newAbs string
+		var err error
+		if fp.oldPath != "" {
+			if oldAbs, err = e.safePath(fp.oldPath); err != nil {
+				return nil, err
+			}
+		}
+		if fp.newPath != "" {
+			if newAbs, err = e.safePath(fp.newPath); err != nil {
+				return nil, err
+			}
+		}
+
+		var current fileText
+		if oldAbs != "" {
+			content, exists, err := read(oldAbs)
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", fp.oldPath, err)
+			}
+			if !exists {
+				return nil, fmt.Errorf("%s does not exist", fp.oldPath)
+			}
+			current = splitFileText(content)
+		} else {
+			if _, exists, err := read(newAbs); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", fp.newPath, err)
+			} else if exists {
+				return nil, fmt.Errorf("%s already exists (patch creates it)", fp.newPath)
+			}
+			current = fileText{eol: true}
+		}
+
+		result, err := applyHunks(fp.displayPath(), current, fp.hunks)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case newAbs == "":
+			if len(result.lines) > 0 {
+				return nil, fmt.Errorf("deletion of %s does not remove all of its content", fp.oldPath)
+			}
+			ch.kind = 'D'
+			ch.oldAbs = oldAbs
+			pending[oldAbs] = nil
+		case oldAbs == "":
+			ch.kind = 'A'
+		case oldAbs != newAbs:
+			ch.kind = 'R'
+			ch.oldAbs = oldAbs
+			pending[oldAbs] = nil
+		default:
+			ch.kind = 'M'
+		}
+		if newAbs != "" {
+			ch.newAbs = newAbs
+			ch.content = result.String()
+			content := ch.content
+			pending[newAbs] = &content
+		}
+		changes = append(changes, ch)
+	}
+	return changes, nil
+}
+
+// commitPatch writes planned changes to disk. If a write fails, files
+// already touched are restored to their original content.
+func commitPatch(changes []patchChange) error {
+	type backup struct {
+		path    string
+		content []byte
+		existed bool
+	}
+	var backups []backup
+	saved := make(map[string]bool)
+	save := func(path string) {
+		if path == "" || saved[path] {
+			return
+		}
+		saved[path] = true
+		data, err := os.ReadFile(path)
+		backups = append(backups, backup{path: path, content: data, existed: err == nil})
+	}
+	rollback := func() {
+		for i := len(backups) - 1; i >= 0; i-- {
+			b := backups[i]
+			if b.existed {
+				_ = os.WriteFile(b.path, b.content, 0644)
+			} else {
+				_ = os.Remove(b.path)
+			}
+		}
+	}
+
+	for _, ch := range changes {
+		save(ch.oldAbs)
+		save(ch.newAbs)
+
+		if ch.newAbs != "" {
+			if err := os.MkdirAll(filepath.Dir(ch.newAbs), 0755); err != nil {
+				rollback()
+				return fmt.Errorf("creating directories for %s: %w", ch.display, err)
+			}
+			if err := os.WriteFile(ch.newAbs, []byte(ch.content), 0644); err != nil {
+				rollback()
+				return fmt.Errorf("writing %s: %w", ch.display, err)
+			}
+		}
+		if ch.oldAbs != "" && ch.oldAbs != ch.newAbs {
+			if err := os.Remove(ch.oldAbs); err != nil && !os.IsNotExist(err) {
+				rollback()
+				return fmt.Errorf("removing %s: %w", ch.display, err)
+			}
+		}
+	}
+	return nil
+}
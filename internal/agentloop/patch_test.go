@@ -0,0 +1,100 @@
+package agentloop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatchModifyAddDelete(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{
+		"main.go": "package main\n\nfunc a() {}\n\nfunc b() {}\n",
+		"old.txt": "bye\n",
+	})
+
+	patch := `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,3 @@
+ func a() {}
+
+-func b() {}
++func b() { a() }
+--- /dev/null
++++ b/pkg/new.go
+@@ -0,0 +1,2 @@
++package pkg
++// new
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-bye
+`
+	out, err := execTool(t, e, "apply_patch", map[string]string{"patch": patch})
+	if err != nil {
+		t.Fatalf("apply_patch: %v", err)
+	}
+	for _, want := range []string{"3 file(s)", "M main.go (+1 -1)", "A pkg/new.go (+2 -0)", "D old.txt (+0 -1)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+
+	if got := readFile(t, e, "main.go"); got != "package main\n\nfunc a() {}\n\nfunc b() { a() }\n" {
+		t.Errorf("main.go = %q", got)
+	}
+	if got := readFile(t, e, "pkg/new.go"); got != "package pkg\n// new\n" {
+		t.Errorf("pkg/new.go = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(e.WorkDir(), "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt should be deleted")
+	}
+}
+
+func TestApplyPatchToleratesWrongLineNumbers(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"f.txt": "one\ntwo\nthree\nfour\n"})
+	patch := "--- a/f.txt\n+++ b/f.txt\n@@ -40,2 +40,2 @@\n three\n-four\n+FOUR\n"
+	if _, err := execTool(t, e, "apply_patch", map[string]string{"patch": patch}); err != nil {
+		t.Fatalf("apply_patch: %v", err)
+	}
+	if got := readFile(t, e, "f.txt"); got != "one\ntwo\nthree\nFOUR\n" {
+		t.Errorf("f.txt = %q", got)
+	}
+}
+
+func TestApplyPatchIsAtomic(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{
+		"a.txt": "alpha\n",
+		"b.txt": "beta\n",
+	})
+	// The first file applies cleanly; the second hunk's context is wrong.
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1 +1 @@\n-alpha\n+ALPHA\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -1 +1 @@\n-gamma\n+GAMMA\n"
+	_, err := execTool(t, e, "apply_patch", map[string]string{"patch": patch})
+	if err == nil || !strings.Contains(err.Error(), "no files changed") {
+		t.Fatalf("err = %v, want rejection", err)
+	}
+	if got := readFile(t, e, "a.txt"); got != "alpha\n" {
+		t.Errorf("a.txt was modified: %q", got)
+	}
+}
+
+func TestApplyPatchRejectsPathEscape(t *testing.T) {
+	e := newTestExecutor(t, nil)
+	patch := "--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n"
+	if _, err := execTool(t, e, "apply_patch", map[string]string{"patch": patch}); err == nil {
+		t.Fatal("expected path outside working directory to be rejected")
+	}
+}
+
+func TestApplyPatchNoNewlineAtEOF(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"f.txt": "a\nb"})
+	patch := "--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n"
+	if _, err := execTool(t, e, "apply_patch", map[string]string{"patch": patch}); err != nil {
+		t.Fatalf("apply_patch: %v", err)
+	}
+	if got := readFile(t, e, "f.txt"); got != "a\nc\n" {
+		t.Errorf("f.txt = %q", got)
+	}
+}
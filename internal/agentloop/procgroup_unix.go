@@ -0,0 +1,30 @@
+//go:build !windows
+
+package agentloop
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// setProcessGroup runs cmd in its own process group and makes context
+// cancellation signal the whole group, so children the command spawned
+// (test servers, make -j jobs) don't outlive it. The group gets SIGTERM,
+// then SIGKILL once grace has passed.
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		if err := syscall.Kill(pgid, syscall.SIGTERM); err != nil {
+			if errors.Is(err, syscall.ESRCH) {
+				return os.ErrProcessDone
+			}
+			return err
+		}
+		time.AfterFunc(grace, func() { _ = syscall.Kill(pgid, syscall.SIGKILL) })
+		return nil
+	}
+}
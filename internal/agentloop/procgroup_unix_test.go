@@ -0,0 +1,50 @@
+//go:build !windows
+
+package agentloop
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShellExecTimeoutKillsChildProcesses(t *testing.T) {
+	e := newTestExecutor(t, nil)
+
+	out, err := execTool(t, e, "shell_exec", map[string]interface{}{
+		"command":         "sleep 60 & echo $!; wait",
+		"timeout_seconds": 1,
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	pid, convErr := strconv.Atoi(strings.TrimSpace(out))
+	if convErr != nil {
+		t.Fatalf("child pid from output %q: %v", out, convErr)
+	}
+
+	deadline := time.Now().Add(commandKillGrace + 3*time.Second)
+	for processRunning(pid) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("child process %d still running after timeout", pid)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// processRunning reports whether pid exists and is not a zombie.
+func processRunning(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return true // no procfs; the signal check is all we have
+	}
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
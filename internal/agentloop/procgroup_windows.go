@@ -0,0 +1,12 @@
+//go:build windows
+
+package agentloop
+
+import (
+	"os/exec"
+	"time"
+)
+
+// setProcessGroup is a no-op on Windows, where exec.CommandContext kills
+// only the direct child.
+func setProcessGroup(cmd *exec.Cmd, grace time.Duration) {}
@@ -0,0 +1,85 @@
+package agentloop
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+// ToolResult is the outcome of a tool call. Text is what the model sees;
+// Structured, when set, is a JSON rendering of the same result for programs
+// (a UI, another agent) that would otherwise have to parse Text.
+type ToolResult struct {
+	Text       string
+	Structured json.RawMessage
+}
+
+// structuredResults build a Structured rendering from a tool's text output,
+// for the tools whose output has a stable line format.
+var structuredResults = map[string]func(text string) interface{}{
+	"git_status": parseGitStatus,
+	"file_list":  parseFileList,
+}
+
+// ExecuteResult runs a tool call like Execute and also returns a structured
+// rendering of the result for tools that support one.
+func (e *Executor) ExecuteResult(ctx context.Context, call llm.ToolCall) (ToolResult, error) {
+	text, err := e.Execute(ctx, call)
+	result := ToolResult{Text: text}
+	if err != nil {
+		return result, err
+	}
+	if build := structuredResults[call.Name]; build != nil {
+		if data, err := json.Marshal(build(text)); err == nil {
+			result.Structured = data
+		}
+	}
+	return result, nil
+}
+
+// GitStatusEntry is one changed path in a structured git_status result.
+type GitStatusEntry struct {
+	Index    string `json:"index"`    // staged status code, " " if none
+	Worktree string `json:"worktree"` // unstaged status code, " " if none
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"` // source of a rename or copy
+}
+
+// parseGitStatus parses `git status --short` output.
+func parseGitStatus(text string) interface{} {
+	entries := []GitStatusEntry{}
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) < 4 || line[2] != ' ' || strings.HasPrefix(line, "STDERR: ") {
+			continue
+		}
+		entry := GitStatusEntry{Index: line[:1], Worktree: line[1:2], Path: line[3:]}
+		if from, to, ok := strings.Cut(entry.Path, " -> "); ok {
+			entry.OrigPath, entry.Path = from, to
+		}
+		entries = append(entries, entry)
+	}
+	return map[string]interface{}{"entries": entries}
+}
+
+// FileListEntry is one path in a structured file_list result.
+type FileListEntry struct {
+	Path string `json:"path"`
+	Dir  bool   `json:"dir"`
+}
+
+// parseFileList parses file_list output, where each line is a path prefixed
+// with "d " for directories and "  " for files.
+func parseFileList(text string) interface{} {
+	entries := []FileListEntry{}
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "d "):
+			entries = append(entries, FileListEntry{Path: line[2:], Dir: true})
+		case strings.HasPrefix(line, "  "):
+			entries = append(entries, FileListEntry{Path: line[2:]})
+		}
+	}
+	return map[string]interface{}{"entries": entries}
+}
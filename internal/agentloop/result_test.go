@@ -0,0 +1,51 @@
+package agentloop
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	text := " M a.go\nA  b.go\nR  old.go -> new.go\n?? notes.txt\nSTDERR: warning: something\n"
+	got := parseGitStatus(text).(map[string]interface{})["entries"]
+	want := []GitStatusEntry{
+		{Index: " ", Worktree: "M", Path: "a.go"},
+		{Index: "A", Worktree: " ", Path: "b.go"},
+		{Index: "R", Worktree: " ", Path: "new.go", OrigPath: "old.go"},
+		{Index: "?", Worktree: "?", Path: "notes.txt"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %+v, want %+v", got, want)
+	}
+}
+
+func TestExecuteResultStructured(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"a.txt": "a", "sub/b.txt": "b"})
+
+	result, err := e.ExecuteResult(context.Background(), llm.ToolCall{Name: "file_list", Args: json.RawMessage(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Entries []FileListEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(result.Structured, &list); err != nil {
+		t.Fatalf("structured = %s: %v", result.Structured, err)
+	}
+	want := []FileListEntry{{Path: "a.txt"}, {Path: "sub", Dir: true}}
+	if !reflect.DeepEqual(list.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", list.Entries, want)
+	}
+	if result.Text == "" {
+		t.Error("text rendering missing")
+	}
+
+	result, err = e.ExecuteResult(context.Background(), llm.ToolCall{Name: "file_read", Args: json.RawMessage(`{"path":"a.txt"}`)})
+	if err != nil || result.Structured != nil {
+		t.Errorf("file_read result = %+v, %v; want text only", result, err)
+	}
+}
@@ -0,0 +1,154 @@
+package agentloop
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// binarySniffLen is how much of a file is checked for NUL bytes to decide
+// whether it is binary (the same heuristic grep uses).
+const binarySniffLen = 8000
+
+// globList is a list of file name globs given as a JSON array or as a
+// single comma-separated string, e.g. "*.go,*.mod".
+type globList []string
+
+func (g *globList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return fmt.Errorf("want a glob string or an array of globs")
+		}
+		list = strings.Split(s, ",")
+	}
+	*g = (*g)[:0]
+	for _, glob := range list {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			*g = append(*g, glob)
+		}
+	}
+	return nil
+}
+
+// matchAny reports whether name matches any of globs.
+func matchAny(globs []string, name string) bool {
+	for _, glob := range globs {
+		if matched, _ := filepath.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// grepFilterArgs returns the grep flags for include and exclude globs.
+// Exclude globs apply to directory names as well as file names, and .git is
+// always excluded.
+func grepFilterArgs(include, exclude []string) []string {
+	args := []string{"--exclude-dir=.git"}
+	for _, glob := range include {
+		args = append(args, "--include="+glob)
+	}
+	for _, glob := range exclude {
+		args = append(args, "--exclude="+glob, "--exclude-dir="+glob)
+	}
+	return args
+}
+
+// searchFiles is the pure-Go implementation of file_search, used when grep
+// is not available. It walks dir (skipping .git), matches each line against
+// pattern, and emits "path:lineno:line" like `grep -rn`. Like grep's
+// --include, --exclude and --exclude-dir, include globs select files by base
+// name and exclude globs skip matching files and directories.
+func (e *Executor) searchFiles(ctx context.Context, pattern, dir string, include, exclude []string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid search pattern: %w", err)
+	}
+
+	var sb strings.Builder
+	truncated := false
+
+	err = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip unreadable entries
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".git" || matchAny(exclude, d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if matchAny(exclude, d.Name()) || (len(include) > 0 && !matchAny(include, d.Name())) {
+			return nil
+		}
+
+		if searchFile(path, re, &sb, e.maxFileReadSize(), e.maxOutputSize()) {
+			truncated = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("searching files: %w", err)
+	}
+
+	output := sb.String()
+	if output == "" {
+		return "(no matches found)", nil
+	}
+	if maxOutput := e.maxOutputSize(); truncated || len(output) > maxOutput {
+		if len(output) > maxOutput {
+			output = output[:maxOutput]
+		}
+		output += "\n... (truncated)"
+	}
+	return output, nil
+}
+
+// searchFile appends matching lines from the first maxRead bytes of path to
+// sb. It reports true once the output has reached maxOutput and the search
+// should stop.
+func searchFile(path string, re *regexp.Regexp, sb *strings.Builder, maxRead int64, maxOutput int) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := bufio.NewReader(f)
+	head, _ := reader.Peek(binarySniffLen)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return false
+	}
+
+	scanner := bufio.NewScanner(io.LimitReader(reader, maxRead))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if !re.MatchString(line) {
+			continue
+		}
+		fmt.Fprintf(sb, "%s:%d:%s\n", path, lineNum, line)
+		if sb.Len() >= maxOutput {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package agentloop
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+const (
+	// DefaultSnapshotMaxOps is how many file operations revert_last and
+	// revert_to can undo by default.
+	DefaultSnapshotMaxOps = 50
+	// DefaultSnapshotMaxBytes caps the prior file content kept for undo (16MB).
+	DefaultSnapshotMaxBytes = 16 * 1024 * 1024
+)
+
+// snapshotTools are the tools whose prior file state is recorded before they
+// run, so revert_last and revert_to can restore it. Changes made by
+// shell_exec and the git tools are not recorded.
+var snapshotTools = map[string]bool{
+	"file_write":  true,
+	"file_edit":   true,
+	"multi_edit":  true,
+	"apply_patch": true,
+	"file_delete": true,
+	"file_move":   true,
+}
+
+// fileSnapshot is the state of one file before an operation.
+type fileSnapshot struct {
+	path    string // absolute
+	existed bool
+	content []byte
+	mode    fs.FileMode
+}
+
+// snapshotOp is one undoable file operation.
+type snapshotOp struct {
+	id    int
+	tool  string
+	paths []string // as shown to the model, relative to the worktree
+
+	files []fileSnapshot
+
+	// moveFrom and moveTo are set for file_move, which is undone by renaming
+	// the destination back rather than by copying content.
+	moveFrom, moveTo string
+
+	size int64
+}
+
+// snapshotHistory holds the most recent operations, oldest first.
+type snapshotHistory struct {
+	mu     sync.Mutex
+	nextID int
+	ops    []*snapshotOp
+	bytes  int64
+}
+
+// maxSnapshotOps is how many operations the executor keeps for undo.
+func (e *Executor) maxSnapshotOps() int {
+	if e.limits.SnapshotMaxOps > 0 {
+		return e.limits.SnapshotMaxOps
+	}
+	return DefaultSnapshotMaxOps
+}
+
+// maxSnapshotBytes caps the file content the executor keeps for undo.
+func (e *Executor) maxSnapshotBytes() int64 {
+	if e.limits.SnapshotMaxBytes > 0 {
+		return e.limits.SnapshotMaxBytes
+	}
+	return DefaultSnapshotMaxBytes
+}
+
+// snapshotBefore records what call is about to change. It returns nil for
+// calls that change nothing revertible, and for calls that will not run.
+func (e *Executor) snapshotBefore(call llm.ToolCall) (*snapshotOp, bool) {
+	if !snapshotTools[call.Name] || e.readOnly || !e.IsToolAllowed(call.Name) {
+		return nil, false
+	}
+
+	op := &snapshotOp{tool: call.Name}
+	paths := e.auditPaths(call)
+	for _, p := range paths {
+		op.paths = append(op.paths, e.displayPath(p))
+	}
+
+	if call.Name == "file_move" {
+		if len(paths) != 2 {
+			return nil, false
+		}
+		op.moveFrom, op.moveTo = paths[0], paths[1]
+		return op, true
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		if err := op.capture(p, e.maxSnapshotBytes()); err != nil {
+			log.Printf("[agentloop] Not snapshotting %s: %v", call.Name, err)
+			return nil, true
+		}
+	}
+	return op, true
+}
+
+// capture records path, or every file under it if it is a directory.
+func (op *snapshotOp) capture(path string, maxBytes int64) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		op.files = append(op.files, fileSnapshot{path: path})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return op.captureFile(path, info, maxBytes)
+	}
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return op.captureFile(p, info, maxBytes)
+	})
+}
+
+func (op *snapshotOp) captureFile(path string, info fs.FileInfo, maxBytes int64) error {
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+	if op.size+info.Size() > maxBytes {
+		return fmt.Errorf("prior content exceeds %s", formatBytes(maxBytes))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	op.size += int64(len(data))
+	op.files = append(op.files, fileSnapshot{path: path, existed: true, content: data, mode: info.Mode().Perm()})
+	return nil
+}
+
+// restore puts the files op changed back the way they were.
+func (op *snapshotOp) restore() error {
+	if op.moveTo != "" {
+		if _, err := os.Lstat(op.moveFrom); err == nil {
+			return fmt.Errorf("%s exists again; not moving it back", op.paths[0])
+		}
+		if err := os.MkdirAll(filepath.Dir(op.moveFrom), 0755); err != nil {
+			return fmt.Errorf("creating directories: %w", err)
+		}
+		if err := os.Rename(op.moveTo, op.moveFrom); err != nil {
+			return fmt.Errorf("moving back: %w", err)
+		}
+		return nil
+	}
+
+	for i := len(op.files) - 1; i >= 0; i-- {
+		f := op.files[i]
+		if !f.existed {
+			if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing %s: %w", f.path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+			return fmt.Errorf("creating directories: %w", err)
+		}
+		if err := os.WriteFile(f.path, f.content, f.mode); err != nil {
+			return fmt.Errorf("restoring %s: %w", f.path, err)
+		}
+		if err := os.Chmod(f.path, f.mode); err != nil {
+			return fmt.Errorf("restoring %s: %w", f.path, err)
+		}
+	}
+	return nil
+}
+
+func (op *snapshotOp) String() string {
+	return fmt.Sprintf("op %d (%s %s)", op.id, op.tool, strings.Join(op.paths, ", "))
+}
+
+// recordSnapshot adds a finished operation to the history, evicting the
+// oldest operations past the limits. A nil op means the call changed files
+// that could not be snapshotted; the history is cleared, since undoing
+// anything older would no longer restore a consistent state.
+func (e *Executor) recordSnapshot(op *snapshotOp) {
+	h := &e.snapshots
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.nextID++
+	if op == nil {
+		h.ops, h.bytes = nil, 0
+		return
+	}
+	op.id = h.nextID
+	h.ops = append(h.ops, op)
+	h.bytes += op.size
+	for len(h.ops) > 0 && (len(h.ops) > e.maxSnapshotOps() || h.bytes > e.maxSnapshotBytes()) {
+		h.bytes -= h.ops[0].size
+		h.ops = h.ops[1:]
+	}
+}
+
+func (e *Executor) execRevertLast() (string, error) {
+	h := &e.snapshots
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.ops) == 0 {
+		return "", fmt.Errorf("nothing to revert")
+	}
+	op := h.ops[len(h.ops)-1]
+	if err := op.restore(); err != nil {
+		return "", fmt.Errorf("reverting %s: %w", op, err)
+	}
+	h.ops = h.ops[:len(h.ops)-1]
+	h.bytes -= op.size
+	return fmt.Sprintf("Reverted %s", op), nil
+}
+
+func (e *Executor) execRevertTo(args json.RawMessage) (string, error) {
+	var params struct {
+		OpID int `json:"op_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parsing revert_to args: %w", err)
+	}
+
+	h := &e.snapshots
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if params.OpID <= 0 {
+		if len(h.ops) == 0 {
+			return "No operations recorded.", nil
+		}
+		var sb strings.Builder
+		sb.WriteString("Recorded operations, oldest first:\n")
+		for _, op := range h.ops {
+			fmt.Fprintf(&sb, "  %d: %s %s\n", op.id, op.tool, strings.Join(op.paths, ", "))
+		}
+		return sb.String(), nil
+	}
+
+	if len(h.ops) == 0 || params.OpID < h.ops[0].id || params.OpID > h.ops[len(h.ops)-1].id {
+		return "", fmt.Errorf("no snapshot for op %d; call revert_to without op_id to list them", params.OpID)
+	}
+
+	var reverted []string
+	for len(h.ops) > 0 && h.ops[len(h.ops)-1].id >= params.OpID {
+		op := h.ops[len(h.ops)-1]
+		if err := op.restore(); err != nil {
+			if len(reverted) > 0 {
+				return "", fmt.Errorf("reverting %s: %w (already reverted: %s)", op, err, strings.Join(reverted, "; "))
+			}
+			return "", fmt.Errorf("reverting %s: %w", op, err)
+		}
+		h.ops = h.ops[:len(h.ops)-1]
+		h.bytes -= op.size
+		reverted = append(reverted, op.String())
+	}
+	return "Reverted " + strings.Join(reverted, "\nReverted "), nil
+}
+
+// displayPath renders an absolute worktree path relative to the worktree.
+func (e *Executor) displayPath(abs string) string {
+	if rel, err := filepath.Rel(e.workDir, abs); err == nil {
+		return rel
+	}
+	return abs
+}
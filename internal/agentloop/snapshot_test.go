@@ -0,0 +1,118 @@
+package agentloop
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRevertLastAndRevertTo(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{
+		"a.txt":       "one\n",
+		"dir/b.txt":   "bee\n",
+		"dir/c/d.txt": "dee\n",
+	})
+
+	steps := []struct {
+		tool string
+		args map[string]interface{}
+	}{
+		{"file_edit", map[string]interface{}{"path": "a.txt", "search": "one", "replace": "two"}},
+		{"file_write", map[string]interface{}{"path": "new.txt", "content": "fresh\n"}},
+		{"file_delete", map[string]interface{}{"path": "dir", "recursive": true}},
+		{"file_move", map[string]interface{}{"from": "a.txt", "to": "moved/a.txt"}},
+	}
+	for _, s := range steps {
+		if _, err := execTool(t, e, s.tool, s.args); err != nil {
+			t.Fatalf("%s: %v", s.tool, err)
+		}
+	}
+	// A failed call is not recorded.
+	if _, err := execTool(t, e, "file_delete", map[string]string{"path": "missing.txt"}); err == nil {
+		t.Fatal("file_delete of a missing file should fail")
+	}
+
+	list, err := execTool(t, e, "revert_to", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("revert_to list: %v", err)
+	}
+	for _, want := range []string{"1: file_edit a.txt", "2: file_write new.txt", "3: file_delete dir", "4: file_move a.txt, moved/a.txt"} {
+		if !strings.Contains(list, want) {
+			t.Errorf("list missing %q:\n%s", want, list)
+		}
+	}
+
+	out, err := execTool(t, e, "revert_last", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("revert_last: %v", err)
+	}
+	if !strings.Contains(out, "op 4 (file_move") {
+		t.Errorf("revert_last = %q, want op 4", out)
+	}
+	if got := readFile(t, e, "a.txt"); got != "two\n" {
+		t.Errorf("a.txt after undoing move = %q", got)
+	}
+	if _, err := readFileErr(e, "moved/a.txt"); err == nil {
+		t.Error("moved/a.txt should be gone")
+	}
+
+	if _, err := execTool(t, e, "revert_to", map[string]int{"op_id": 1}); err != nil {
+		t.Fatalf("revert_to 1: %v", err)
+	}
+	if got := readFile(t, e, "a.txt"); got != "one\n" {
+		t.Errorf("a.txt = %q, want original", got)
+	}
+	if got := readFile(t, e, "dir/c/d.txt"); got != "dee\n" {
+		t.Errorf("dir/c/d.txt = %q, want restored", got)
+	}
+	if _, err := readFileErr(e, "new.txt"); err == nil {
+		t.Error("new.txt should have been removed")
+	}
+
+	if _, err := execTool(t, e, "revert_last", map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "nothing to revert") {
+		t.Errorf("revert_last on empty history: err = %v", err)
+	}
+}
+
+func TestSnapshotHistoryLimits(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"big.txt": strings.Repeat("x", 100)})
+	e.SetLimits(ExecutorLimits{SnapshotMaxOps: 2, SnapshotMaxBytes: 50})
+
+	for _, content := range []string{"1", "2", "3"} {
+		if _, err := execTool(t, e, "file_write", map[string]string{"path": "f.txt", "content": content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := execTool(t, e, "revert_to", map[string]int{"op_id": 1}); err == nil {
+		t.Error("op 1 should have been evicted")
+	}
+	if _, err := execTool(t, e, "revert_to", map[string]int{"op_id": 2}); err != nil {
+		t.Fatalf("revert_to 2: %v", err)
+	}
+	if got := readFile(t, e, "f.txt"); got != "1" {
+		t.Errorf("f.txt = %q, want 1", got)
+	}
+
+	// A change too large to snapshot clears the history rather than leaving
+	// older snapshots that would restore an inconsistent state.
+	if _, err := execTool(t, e, "file_write", map[string]string{"path": "f.txt", "content": "4"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execTool(t, e, "file_write", map[string]string{"path": "big.txt", "content": "small"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execTool(t, e, "revert_last", map[string]interface{}{}); err == nil {
+		t.Error("history should be empty after an unsnapshotted change")
+	}
+}
+
+func TestDryRunRecordsNoSnapshots(t *testing.T) {
+	e := newTestExecutor(t, map[string]string{"a.txt": "a"})
+	e.SetReadOnly(true)
+	if _, err := execTool(t, e, "file_write", map[string]string{"path": "a.txt", "content": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	e.SetReadOnly(false)
+	if _, err := execTool(t, e, "revert_last", map[string]interface{}{}); err == nil {
+		t.Error("dry-run call should not be revertible")
+	}
+}
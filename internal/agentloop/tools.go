@@ -130,9 +130,81 @@ func GTTools() []llm.ToolDef {
 				"required": ["message"]
 			}`),
 		},
+		{
+			Name:        "git_log",
+			Description: "Show commit history, newest first.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {
+						"type": "string",
+						"description": "Optional path to restrict history to"
+					},
+					"max_count": {
+						"type": "integer",
+						"description": "Maximum number of commits to show (default: 20)"
+					},
+					"oneline": {
+						"type": "boolean",
+						"description": "If true, show one line per commit"
+					}
+				},
+				"required": []
+			}`),
+		},
+		{
+			Name:        "git_show",
+			Description: "Show a commit's message and diff.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"ref": {
+						"type": "string",
+						"description": "Commit, branch, or tag to show (default: HEAD)"
+					}
+				},
+				"required": []
+			}`),
+		},
+		{
+			Name:        "git_branch",
+			Description: "Create a branch, or list branches when no name is given. Returns the branch list with the current branch marked.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"name": {
+						"type": "string",
+						"description": "Branch to create"
+					},
+					"base": {
+						"type": "string",
+						"description": "Optional commit or branch to start the new branch from (default: HEAD)"
+					}
+				},
+				"required": []
+			}`),
+		},
+		{
+			Name:        "git_checkout",
+			Description: "Switch to a branch, optionally creating it first. Returns the branch list with the current branch marked.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"branch": {
+						"type": "string",
+						"description": "Branch to switch to"
+					},
+					"create": {
+						"type": "boolean",
+						"description": "Create the branch from HEAD before switching"
+					}
+				},
+				"required": ["branch"]
+			}`),
+		},
 		{
 			Name:        "file_read",
-			Description: "Read file contents. Returns the file content with line numbers.",
+			Description: "Read file contents. Returns the file content with line numbers. With search, returns only the first matching line and the lines around it.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -147,6 +219,18 @@ func GTTools() []llm.ToolDef {
 					"end_line": {
 						"type": "integer",
 						"description": "Optional 1-based end line"
+					},
+					"search": {
+						"type": "string",
+						"description": "Optional text or regex to find; returns the first matching line with surrounding context instead of a line range"
+					},
+					"context_lines": {
+						"type": "integer",
+						"description": "Lines of context before and after the search match (default: 10)"
+					},
+					"force": {
+						"type": "boolean",
+						"description": "Read the file even if it looks binary (default: false)"
 					}
 				},
 				"required": ["path"]
@@ -172,7 +256,7 @@ func GTTools() []llm.ToolDef {
 		},
 		{
 			Name:        "file_edit",
-			Description: "Apply a search-and-replace edit to a file. Finds the first occurrence of search text and replaces it.",
+			Description: "Apply a search-and-replace edit to a file. Replaces the first occurrence of search text, or every occurrence with replace_all.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -187,11 +271,120 @@ func GTTools() []llm.ToolDef {
 					"replace": {
 						"type": "string",
 						"description": "Replacement text"
+					},
+					"replace_all": {
+						"type": "boolean",
+						"description": "If true, replace every occurrence instead of only the first"
+					},
+					"expected_count": {
+						"type": "integer",
+						"description": "Optional number of occurrences the search text must have; the edit fails without writing if the actual count differs. Values above 1 require replace_all"
 					}
 				},
 				"required": ["path", "search", "replace"]
 			}`),
 		},
+		{
+			Name:        "multi_edit",
+			Description: "Apply several search-and-replace edits to one file atomically. Edits are applied in order, each replacing the first occurrence of its search text; if any edit fails, the file is left untouched.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {
+						"type": "string",
+						"description": "File path relative to the working directory"
+					},
+					"edits": {
+						"type": "array",
+						"description": "Edits to apply in order; each sees the result of the previous ones",
+						"items": {
+							"type": "object",
+							"properties": {
+								"search": {
+									"type": "string",
+									"description": "Text to find (exact match)"
+								},
+								"replace": {
+									"type": "string",
+									"description": "Replacement text"
+								}
+							},
+							"required": ["search", "replace"]
+						}
+					}
+				},
+				"required": ["path", "edits"]
+			}`),
+		},
+		{
+			Name:        "apply_patch",
+			Description: "Apply a unified diff (as produced by 'git diff' or 'diff -u') to one or more files. Supports new and deleted files. The patch is applied atomically: if any hunk fails, no files are changed.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"patch": {
+						"type": "string",
+						"description": "Unified diff text with ---/+++ file headers and @@ hunks; paths are relative to the working directory"
+					}
+				},
+				"required": ["patch"]
+			}`),
+		},
+		{
+			Name:        "file_delete",
+			Description: "Delete a file. Directories are only deleted when recursive is true.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"path": {
+						"type": "string",
+						"description": "Path relative to the working directory"
+					},
+					"recursive": {
+						"type": "boolean",
+						"description": "If true, delete a directory and everything in it"
+					}
+				},
+				"required": ["path"]
+			}`),
+		},
+		{
+			Name:        "file_move",
+			Description: "Move or rename a file or directory. Creates parent directories of the destination as needed.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"from": {
+						"type": "string",
+						"description": "Existing path relative to the working directory"
+					},
+					"to": {
+						"type": "string",
+						"description": "New path relative to the working directory; must not already exist"
+					}
+				},
+				"required": ["from", "to"]
+			}`),
+		},
+		{
+			Name:        "revert_last",
+			Description: "Undo the most recent file_write, file_edit, multi_edit, apply_patch, file_delete or file_move, restoring the files it changed. Changes made by shell_exec or git tools are not undone.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{},"required":[]}`),
+		},
+		{
+			Name:        "revert_to",
+			Description: "Undo every recorded file operation from op_id onward, newest first. Call without op_id to list the recorded operations and their IDs.",
+			Parameters: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"op_id": {
+						"type": "integer",
+						"description": "ID of the oldest operation to undo, as listed by revert_to without op_id"
+					}
+				},
+				"required": []
+			}`),
+		},
 		{
 			Name:        "file_list",
 			Description: "List files and directories in a path. Like 'ls' or 'find'.",
@@ -209,6 +402,10 @@ func GTTools() []llm.ToolDef {
 					"pattern": {
 						"type": "string",
 						"description": "Optional glob pattern to filter results"
+					},
+					"respect_gitignore": {
+						"type": "boolean",
+						"description": "Skip paths ignored by .gitignore files (default: true)"
 					}
 				},
 				"required": []
@@ -229,8 +426,14 @@ func GTTools() []llm.ToolDef {
 						"description": "Optional path to restrict search to"
 					},
 					"include": {
-						"type": "string",
-						"description": "Optional file glob to include (e.g., '*.go')"
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Optional file globs to include (e.g., ['*.go', '*.mod']); a comma-separated string also works"
+					},
+					"exclude": {
+						"type": "array",
+						"items": {"type": "string"},
+						"description": "Optional file or directory globs to skip (e.g., ['testdata', 'vendor']); a comma-separated string also works. .git is always skipped"
 					}
 				},
 				"required": ["pattern"]
@@ -249,6 +452,15 @@ func GTTools() []llm.ToolDef {
 					"timeout_seconds": {
 						"type": "integer",
 						"description": "Maximum execution time in seconds (default: 120)"
+					},
+					"cwd": {
+						"type": "string",
+						"description": "Directory to run in, relative to the working directory (default: the working directory)"
+					},
+					"env": {
+						"type": "object",
+						"additionalProperties": {"type": "string"},
+						"description": "Extra environment variables (e.g., {\"CGO_ENABLED\": \"0\"}). GT_* identity variables cannot be overridden."
 					}
 				},
 				"required": ["command"]
@@ -271,6 +483,10 @@ func GTTools() []llm.ToolDef {
 					"body": {
 						"type": "string",
 						"description": "Message body"
+					},
+					"in_reply_to": {
+						"type": "string",
+						"description": "ID of the message being answered, as shown by gt_mail_read; threads the reply with it"
 					}
 				},
 				"required": ["to", "subject"]
@@ -278,7 +494,7 @@ func GTTools() []llm.ToolDef {
 		},
 		{
 			Name:        "gt_mail_read",
-			Description: "Read messages from the agent's mailbox.",
+			Description: "Read messages from the agent's mailbox. Each message is shown with its ID, for replying with gt_mail_send's in_reply_to.",
 			Parameters: json.RawMessage(`{
 				"type": "object",
 				"properties": {
@@ -327,3 +543,23 @@ func FilterTools(allowed []string) []llm.ToolDef {
 	}
 	return filtered
 }
+
+// readOnlyTools are tools that don't change the worktree or any external
+// state, so several of them can safely run at the same time.
+var readOnlyTools = map[string]bool{
+	"bd_show":     true,
+	"bd_list":     true,
+	"git_diff":    true,
+	"git_status":  true,
+	"git_log":     true,
+	"git_show":    true,
+	"file_read":   true,
+	"file_list":   true,
+	"file_search": true,
+}
+
+// isReadOnlyTool reports whether name is known not to have side effects.
+// Unknown tools are assumed to mutate.
+func isReadOnlyTool(name string) bool {
+	return readOnlyTools[name]
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -33,8 +34,25 @@ var (
 	alSystemPrompt  string
 	alMaxIterations int
 	alMaxTokens     int
+	alMaxCostUSD    float64
+	alMaxDuration   time.Duration
 	alIdleTimeout   time.Duration
+	alSleepOnIdle   bool
+	alDrainTimeout  time.Duration
 	alToolTimeout   time.Duration
+	alToolConc      int
+	alToolAttempts  int
+	alMaxFileRead   int64
+	alMaxOutput     int
+	alShellTimeout  time.Duration
+	alTools         []string
+	alCheckpoint    string
+	alAuditLog      string
+	alSummarize     bool
+	alDryRun        bool
+	alLogLLM        bool
+	alLogLLMContent bool
+	alResume        bool
 )
 
 var agentLoopCmd = &cobra.Command{
@@ -131,6 +149,9 @@ func runAgentLoopRun(cmd *cobra.Command, args []string) error {
 		}
 		client = llm.WithRetry(client, rc)
 	}
+	if alLogLLM || alLogLLMContent {
+		client = llm.WithLogging(client, slog.Default(), llm.LogOptions{LogContent: alLogLLMContent})
+	}
 
 	actor := makeActor(rigName, role, instance)
 
@@ -142,22 +163,46 @@ func runAgentLoopRun(cmd *cobra.Command, args []string) error {
 		actor,
 		role,
 	)
+	executor.SetAllowedTools(alTools)
+	if alAuditLog != "" {
+		audit, err := agentloop.OpenAuditLog(alAuditLog, agentloop.AuditOptions{})
+		if err != nil {
+			return err
+		}
+		defer func() { _ = audit.Close() }()
+		executor.SetAuditLog(audit)
+	}
 
 	cfg := &agentloop.AgentLoopConfig{
 		SystemPrompt:     alSystemPrompt,
 		MaxIterations:    alMaxIterations,
 		MaxTokensPerTask: alMaxTokens,
+		MaxCostUSD:       alMaxCostUSD,
+		MaxTaskDuration:  alMaxDuration,
 		IdleTimeout:      alIdleTimeout,
 		ToolTimeout:      alToolTimeout,
+		ToolConcurrency:  alToolConc,
 		Role:             role,
 		RigName:          rigName,
 		Actor:            actor,
+		CheckpointPath:   alCheckpoint,
+		SummarizeWithLLM: alSummarize,
+		ReadOnly:         alDryRun,
+		ToolRetry:        agentloop.ToolRetryConfig{MaxAttempts: alToolAttempts},
+		ExecutorLimits: agentloop.ExecutorLimits{
+			MaxFileReadSize: alMaxFileRead,
+			MaxOutputSize:   alMaxOutput,
+			ShellTimeout:    alShellTimeout,
+		},
 		OnHeartbeat: func(state agentloop.LoopState, iteration int, totalTokens int) {
 			// Publishing is best-effort and must not stall the agent loop.
 			go events.PublishAgentHeartbeat(actor, rigName, role, string(state))
 			_ = iteration
 			_ = totalTokens
 		},
+		OnIdle: func(time.Duration) bool {
+			return alSleepOnIdle
+		},
 		OnTaskComplete: func(task string, iterations int, totalTokens int, err error) {
 			_ = json.Marshal // keep import used even if logging is disabled below
 			if err != nil {
@@ -179,6 +224,16 @@ func runAgentLoopRun(cmd *cobra.Command, args []string) error {
 			return
 		}
 
+		if alResume {
+			if alCheckpoint == "" {
+				fmt.Fprintf(os.Stderr, "[agentloop] --resume requires --checkpoint\n")
+			} else if _, err := os.Stat(alCheckpoint); err == nil {
+				if err := loop.ResumeTask(ctx, alCheckpoint); err != nil {
+					fmt.Fprintf(os.Stderr, "[agentloop] resume failed: %v\n", err)
+				}
+			}
+		}
+
 		task := strings.TrimSpace(alTask)
 		if task != "" {
 			_ = loop.AssignWork(task)
@@ -189,7 +244,19 @@ func runAgentLoopRun(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	err = loop.Start(ctx)
+	if alDrainTimeout > 0 {
+		// On a signal, let the current iteration finish (and checkpoint)
+		// before stopping, instead of canceling mid-tool-call.
+		go func() {
+			<-ctx.Done()
+			if err := loop.StopGracefully(alDrainTimeout); err != nil {
+				fmt.Fprintf(os.Stderr, "[agentloop] graceful stop: %v\n", err)
+			}
+		}()
+		err = loop.Start(context.Background())
+	} else {
+		err = loop.Start(ctx)
+	}
 
 	// Treat signal cancellation as a clean shutdown.
 	if ctx.Err() != nil {
@@ -207,6 +274,12 @@ func runPrimeTicker(ctx context.Context, loop *agentloop.AgentLoop, executor *ag
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			// Only prime an idle or sleeping agent with nothing queued; otherwise each
+			// tick would queue another copy of the same prompt.
+			st := loop.Status()
+			if (st.State != agentloop.StateIdle && st.State != agentloop.StateSleeping) || st.QueuedTasks > 0 {
+				continue
+			}
 			primeCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
 			out, err := executor.Execute(primeCtx, llm.ToolCall{Name: "gt_prime"})
 			cancel()
@@ -217,7 +290,6 @@ func runPrimeTicker(ctx context.Context, loop *agentloop.AgentLoop, executor *ag
 			if task == "" {
 				continue
 			}
-			// AssignWork fails if busy; ignore and try again on next tick.
 			_ = loop.AssignWork(task)
 		}
 	}
@@ -302,8 +374,25 @@ func init() {
 	agentLoopRunCmd.Flags().StringVar(&alSystemPrompt, "system-prompt", "", "System prompt prepended to conversations")
 	agentLoopRunCmd.Flags().IntVar(&alMaxIterations, "max-iterations", 0, "Max think-act iterations per task (0 uses default)")
 	agentLoopRunCmd.Flags().IntVar(&alMaxTokens, "max-tokens", 0, "Max tokens per task (0 uses default)")
+	agentLoopRunCmd.Flags().Float64Var(&alMaxCostUSD, "max-cost", 0, "Max estimated spend per task in USD (0 disables)")
+	agentLoopRunCmd.Flags().DurationVar(&alMaxDuration, "max-duration", 0, "Max wall-clock time per task (0 disables)")
 	agentLoopRunCmd.Flags().DurationVar(&alIdleTimeout, "idle-timeout", 0, "Idle timeout (0 uses default)")
+	agentLoopRunCmd.Flags().DurationVar(&alDrainTimeout, "drain-timeout", 0, "On SIGINT/SIGTERM, let the current iteration finish for up to this long before stopping (0 stops immediately)")
+	agentLoopRunCmd.Flags().BoolVar(&alSleepOnIdle, "sleep-on-idle", false, "Sleep after the idle timeout, releasing LLM connections until new work arrives")
 	agentLoopRunCmd.Flags().DurationVar(&alToolTimeout, "tool-timeout", 0, "Tool timeout (0 uses default)")
+	agentLoopRunCmd.Flags().Int64Var(&alMaxFileRead, "max-file-read", 0, "Largest file tools will read, in bytes (0 uses default of 10MB)")
+	agentLoopRunCmd.Flags().IntVar(&alMaxOutput, "max-output", 0, "Most tool output returned to the model, in bytes (0 uses default of 100KB)")
+	agentLoopRunCmd.Flags().DurationVar(&alShellTimeout, "shell-timeout", 0, "Default timeout for shell and git/gt/bd commands (0 uses default of 2m)")
+	agentLoopRunCmd.Flags().IntVar(&alToolAttempts, "tool-attempts", 0, "Attempts for failed read-only tool calls before the model sees the error (0 disables retries)")
+	agentLoopRunCmd.Flags().IntVar(&alToolConc, "tool-concurrency", 0, "Max read-only tool calls run in parallel (0 uses default of 1)")
+	agentLoopRunCmd.Flags().BoolVar(&alDryRun, "dry-run", false, "Simulate tools that change files or state; the agent sees what they would have done")
+	agentLoopRunCmd.Flags().BoolVar(&alLogLLM, "log-llm", false, "Log each model call with token usage and latency")
+	agentLoopRunCmd.Flags().BoolVar(&alLogLLMContent, "log-llm-content", false, "Also log truncated prompts and responses (may include sensitive data; implies --log-llm)")
+	agentLoopRunCmd.Flags().BoolVar(&alSummarize, "summarize", false, "Summarize truncated context with the model instead of a statistical summary")
+	agentLoopRunCmd.Flags().StringVar(&alCheckpoint, "checkpoint", "", "Checkpoint the conversation to this file after each iteration")
+	agentLoopRunCmd.Flags().StringVar(&alAuditLog, "audit-log", "", "Append a JSONL record of every tool call that changes the worktree or outside state to this file")
+	agentLoopRunCmd.Flags().BoolVar(&alResume, "resume", false, "Resume the task saved at --checkpoint before accepting new work")
+	agentLoopRunCmd.Flags().StringSliceVar(&alTools, "tools", nil, "Comma-separated tool allowlist (default: all GT tools)")
 
 	_ = agentLoopRunCmd.MarkFlagRequired("role")
 	_ = agentLoopRunCmd.MarkFlagRequired("agent")
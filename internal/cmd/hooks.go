@@ -20,6 +20,7 @@ Subcommands:
   sync       Regenerate all .claude/settings.json files
   diff       Show what sync would change
   list       Show all managed settings.json locations
+  validate   Check base and override configs for errors
   scan       Scan workspace for existing hooks
   registry   List hooks from the registry
   install    Install a hook from the registry
@@ -35,7 +36,8 @@ Examples:
   gt hooks diff           # Preview what sync would change
   gt hooks base           # Edit the shared base config
   gt hooks override crew  # Edit overrides for all crew workers
-  gt hooks list           # Show managed locations and sync status`,
+  gt hooks list           # Show managed locations and sync status
+  gt hooks validate       # Check configs before syncing`,
 	RunE: requireSubcommand,
 }
 
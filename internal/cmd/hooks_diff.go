@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/cobra"
@@ -16,9 +18,11 @@ var hooksDiffCmd = &cobra.Command{
 	Short: "Show what sync would change",
 	Long: `Show what 'gt hooks sync' would change without applying.
 
-Compares the current .claude/settings.json files against what would
-be generated from base + overrides. Uses color to highlight additions
-and removals.
+Computes the .claude/settings.json that sync would write for each target
+from base + overrides (the same code path sync uses) and shows a colored
+unified diff against the current file.
+
+With --json, lists the hook commands each target would gain or lose instead.
 
 Exit codes:
   0 - No changes pending
@@ -26,12 +30,16 @@ Exit codes:
 
 Examples:
   gt hooks diff                    # Show all pending changes
-  gt hooks diff gastown/crew       # Show changes for specific target`,
+  gt hooks diff gastown/crew       # Show changes for specific target
+  gt hooks diff --json             # Machine-readable added/removed hooks`,
 	RunE: runHooksDiff,
 }
 
+var hooksDiffJSON bool
+
 func init() {
 	hooksCmd.AddCommand(hooksDiffCmd)
+	hooksDiffCmd.Flags().BoolVar(&hooksDiffJSON, "json", false, "Output added/removed hook entries per target as JSON")
 }
 
 // diffStyles for colored diff output.
@@ -40,6 +48,15 @@ var (
 	diffRemove = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#f07171", Dark: "#f07178"})
 )
 
+// hooksDiffTarget is one target's pending changes in --json output.
+type hooksDiffTarget struct {
+	Target  string             `json:"target"`
+	Path    string             `json:"path"`
+	Create  bool               `json:"create,omitempty"`
+	Added   []hooks.HookChange `json:"added"`
+	Removed []hooks.HookChange `json:"removed"`
+}
+
 func runHooksDiff(cmd *cobra.Command, args []string) error {
 	townRoot, err := workspace.FindFromCwdOrError()
 	if err != nil {
@@ -66,20 +83,14 @@ func runHooksDiff(cmd *cobra.Command, args []string) error {
 		targets = filtered
 	}
 
-	hasChanges := false
+	pending := []hooksDiffTarget{}
 
 	for _, target := range targets {
-		expected, err := hooks.ComputeExpected(target.Key)
-		if err != nil {
-			return fmt.Errorf("computing expected config for %s: %w", target.DisplayKey(), err)
-		}
-
-		current, err := hooks.LoadSettings(target.Path)
+		plan, err := hooks.PlanClaudeSettings(target)
 		if err != nil {
-			return fmt.Errorf("loading current settings for %s: %w", target.DisplayKey(), err)
+			return fmt.Errorf("computing changes for %s: %w", target.DisplayKey(), err)
 		}
-
-		if hooks.HooksEqual(expected, &current.Hooks) {
+		if !plan.Changed {
 			continue
 		}
 
@@ -89,28 +100,84 @@ func runHooksDiff(cmd *cobra.Command, args []string) error {
 			relPath = target.Path
 		}
 
-		changes := diffHooksConfigs(&current.Hooks, expected)
-		if len(changes) == 0 {
+		if hooksDiffJSON {
+			added, removed := hooks.DiffHooks(&plan.CurrentHooks, &plan.ExpectedHooks)
+			pending = append(pending, hooksDiffTarget{
+				Target:  target.DisplayKey(),
+				Path:    relPath,
+				Create:  !plan.Exists,
+				Added:   nonNilChanges(added),
+				Removed: nonNilChanges(removed),
+			})
 			continue
 		}
 
-		hasChanges = true
-		fmt.Printf("%s:\n", style.Bold.Render(relPath))
-		for _, change := range changes {
-			fmt.Print(change)
+		diff := planDiff(relPath, plan)
+		if diff == "" {
+			continue
 		}
+		pending = append(pending, hooksDiffTarget{Target: target.DisplayKey(), Path: relPath})
+		printColoredDiff(diff)
 		fmt.Println()
 	}
 
-	if !hasChanges {
+	if hooksDiffJSON {
+		data, err := json.MarshalIndent(map[string]interface{}{"targets": pending}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else if len(pending) == 0 {
 		fmt.Println(style.Dim.Render("No changes pending - all targets in sync"))
-		return nil
 	}
 
+	if len(pending) == 0 {
+		return nil
+	}
 	// Exit with code 1 to indicate changes pending (for scripting)
 	return NewSilentExit(1)
 }
 
+// planDiff renders the change plan would make to the file at relPath as a
+// unified diff.
+func planDiff(relPath string, plan *hooks.SettingsPlan) string {
+	fromName := "a/" + relPath
+	if !plan.Exists {
+		fromName = "/dev/null"
+	}
+	return hooks.UnifiedDiff(fromName, "b/"+relPath, plan.Current, plan.Proposed)
+}
+
+// printColoredDiff prints a unified diff with added and removed lines colored.
+func printColoredDiff(diff string) {
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		text := strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			fmt.Println(style.Bold.Render(text))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(style.Dim.Render(text))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(diffAdd.Render(text))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(diffRemove.Render(text))
+		default:
+			fmt.Println(text)
+		}
+	}
+}
+
+// nonNilChanges makes empty change lists encode as [] rather than null.
+func nonNilChanges(changes []hooks.HookChange) []hooks.HookChange {
+	if changes == nil {
+		return []hooks.HookChange{}
+	}
+	return changes
+}
+
 // diffHooksConfigs compares current and expected configs, returning formatted diff lines.
 func diffHooksConfigs(current, expected *hooks.HooksConfig) []string {
 	var lines []string
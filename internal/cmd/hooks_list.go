@@ -18,7 +18,9 @@ var hooksListCmd = &cobra.Command{
 	Long: `Show all managed .claude/settings.json locations and their sync status.
 
 Displays each target with its override chain and whether it is
-currently in sync with the base + overrides configuration.
+currently in sync with the base + overrides configuration. Targets edited
+by hand since the last sync are flagged as modified locally; sync leaves
+them alone unless run with --force.
 
 Examples:
   gt hooks list            # Show all managed locations
@@ -40,6 +42,11 @@ type listTargetInfo struct {
 	Status    string   `json:"status"`
 	Path      string   `json:"path"`
 	Exists    bool     `json:"exists"`
+	// Pending is true when sync would rewrite the file.
+	Pending bool `json:"pending"`
+	// LocallyModified is true when the file was edited since sync last
+	// wrote it; sync then needs --force to apply pending changes.
+	LocallyModified bool `json:"locally_modified"`
 }
 
 func runHooksListTargets(cmd *cobra.Command, args []string) error {
@@ -89,26 +96,25 @@ func buildTargetInfo(target hooks.Target) listTargetInfo {
 		}
 	}
 
-	// Check if settings.json exists
-	_, err := os.Stat(target.Path)
-	exists := err == nil
-
-	// Determine sync status
-	status := "missing"
-	if exists {
-		expected, err := hooks.ComputeExpected(target.Key)
-		if err != nil {
-			status = "error"
-		} else {
-			current, err := hooks.LoadSettings(target.Path)
-			if err != nil {
-				status = "error"
-			} else if hooks.HooksEqual(expected, &current.Hooks) {
-				status = "in sync"
-			} else {
-				status = "out of sync"
-			}
+	// Determine sync status from the same plan sync and diff use.
+	status := "error"
+	exists, pending, modified := false, false, false
+	if plan, err := hooks.PlanClaudeSettings(target); err == nil {
+		exists, pending = plan.Exists, plan.Changed
+		modified, _ = hooks.LocallyModified(target.Path)
+		switch {
+		case !exists:
+			status = "missing"
+		case modified:
+			status = "modified"
+		case pending:
+			status = "out of sync"
+		default:
+			status = "in sync"
 		}
+	} else {
+		_, statErr := os.Stat(target.Path)
+		exists = statErr == nil
 	}
 
 	return listTargetInfo{
@@ -117,6 +123,9 @@ func buildTargetInfo(target hooks.Target) listTargetInfo {
 		Status:    status,
 		Path:      target.Path,
 		Exists:    exists,
+
+		Pending:         pending,
+		LocallyModified: modified,
 	}
 }
 
@@ -241,6 +250,8 @@ func renderSyncStatus(status string) string {
 		return style.Success.Render("✓ in sync")
 	case "out of sync":
 		return style.Warning.Render("⚠ out of sync")
+	case "modified":
+		return style.Error.Render("✎ modified locally (sync needs --force)")
 	case "missing":
 		return style.Dim.Render("- missing")
 	case "error":
@@ -65,6 +65,7 @@ func TestRenderSyncStatus(t *testing.T) {
 	}{
 		{"in sync", false},
 		{"out of sync", false},
+		{"modified", false},
 		{"missing", false},
 		{"error", false},
 		{"unknown", false},
@@ -13,7 +13,10 @@ import (
 	"github.com/steveyegge/gastown/internal/workspace"
 )
 
-var hooksSyncDryRun bool
+var (
+	hooksSyncDryRun bool
+	hooksSyncForce  bool
+)
 
 var hooksSyncCmd = &cobra.Command{
 	Use:   "sync",
@@ -25,7 +28,10 @@ For Claude agents (settings.json merge):
 2. Apply role override (if exists)
 3. Apply rig+role override (if exists)
 4. Merge hooks section into existing settings.json (preserving all fields)
-5. Write updated settings.json
+5. Write updated settings.json and record its checksum
+
+A settings.json edited by hand since the last sync (its checksum no longer
+matches) is left alone and reported as an error; --force overwrites it.
 
 For template-based agents (OpenCode, Gemini, Copilot, etc.):
 1. Resolve the agent configured for each role
@@ -34,13 +40,15 @@ For template-based agents (OpenCode, Gemini, Copilot, etc.):
 
 Examples:
   gt hooks sync             # Regenerate all hook/settings files
-  gt hooks sync --dry-run   # Show what would change without writing`,
+  gt hooks sync --dry-run   # Show what would change without writing
+  gt hooks sync --force     # Overwrite settings edited since the last sync`,
 	RunE: runHooksSync,
 }
 
 func init() {
 	hooksCmd.AddCommand(hooksSyncCmd)
 	hooksSyncCmd.Flags().BoolVar(&hooksSyncDryRun, "dry-run", false, "Show what would change without writing")
+	hooksSyncCmd.Flags().BoolVar(&hooksSyncForce, "force", false, "Overwrite settings files modified since the last sync")
 }
 
 func runHooksSync(cmd *cobra.Command, args []string) error {
@@ -69,12 +77,14 @@ func runHooksSync(cmd *cobra.Command, args []string) error {
 	var failedTargets []string
 
 	for _, target := range targets {
-		result, err := syncTarget(target, hooksSyncDryRun)
+		result, err := syncTargetWithOptions(target, hooks.SyncOptions{DryRun: hooksSyncDryRun, Force: hooksSyncForce})
 		if err != nil {
 			label := "sync error"
 			if hooks.IsSettingsIntegrityError(err) {
 				label = "integrity violation"
 				integrityErrors++
+			} else if hooks.IsLocalModificationError(err) {
+				label = "local modifications"
 			}
 			fmt.Printf(
 				"  %s %s (%s): %v\n",
@@ -93,10 +103,22 @@ func runHooksSync(cmd *cobra.Command, args []string) error {
 			relPath = target.Path
 		}
 
+		var dryRunDiff string
+		if hooksSyncDryRun && result != syncUnchanged {
+			dryRunDiff, err = syncDryRunDiff(relPath, target)
+			if err != nil {
+				fmt.Printf("  %s %s (sync error): %v\n", style.Error.Render("✖"), target.DisplayKey(), err)
+				errors++
+				failedTargets = append(failedTargets, target.DisplayKey())
+				continue
+			}
+		}
+
 		switch result {
 		case syncCreated:
 			if hooksSyncDryRun {
 				fmt.Printf("  %s %s %s\n", style.Warning.Render("~"), relPath, style.Dim.Render("(would create)"))
+				printColoredDiff(dryRunDiff)
 			} else {
 				fmt.Printf("  %s %s %s\n", style.Success.Render("✓"), relPath, style.Dim.Render("(created)"))
 			}
@@ -104,6 +126,7 @@ func runHooksSync(cmd *cobra.Command, args []string) error {
 		case syncUpdated:
 			if hooksSyncDryRun {
 				fmt.Printf("  %s %s %s\n", style.Warning.Render("~"), relPath, style.Dim.Render("(would update)"))
+				printColoredDiff(dryRunDiff)
 			} else {
 				fmt.Printf("  %s %s %s\n", style.Success.Render("✓"), relPath, style.Dim.Render("(updated)"))
 			}
@@ -249,10 +272,16 @@ const (
 	syncCreated
 )
 
-// syncTarget syncs a single target's .claude/settings.json.
-// Uses MarshalSettings/UnmarshalSettings to preserve unknown fields.
+// syncTarget syncs a single target's .claude/settings.json, overwriting any
+// local edits. Uses MarshalSettings/UnmarshalSettings to preserve unknown fields.
 func syncTarget(target hooks.Target, dryRun bool) (syncResult, error) {
-	result, err := hooks.SyncManagedClaudeSettings(target, dryRun)
+	return syncTargetWithOptions(target, hooks.SyncOptions{DryRun: dryRun, Force: true})
+}
+
+// syncTargetWithOptions syncs a single target's .claude/settings.json,
+// refusing to overwrite local edits unless opts.Force is set.
+func syncTargetWithOptions(target hooks.Target, opts hooks.SyncOptions) (syncResult, error) {
+	result, err := hooks.SyncClaudeSettings(target, opts)
 	if err != nil {
 		return 0, err
 	}
@@ -268,3 +297,13 @@ func syncTarget(target hooks.Target, dryRun bool) (syncResult, error) {
 		return 0, fmt.Errorf("unknown sync result: %d", result)
 	}
 }
+
+// syncDryRunDiff returns the settings.json diff a dry-run sync would apply
+// to target, computed from the same plan as 'gt hooks diff'.
+func syncDryRunDiff(relPath string, target hooks.Target) (string, error) {
+	plan, err := hooks.PlanClaudeSettings(target)
+	if err != nil {
+		return "", fmt.Errorf("computing changes: %w", err)
+	}
+	return planDiff(relPath, plan), nil
+}
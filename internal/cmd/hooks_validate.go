@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/steveyegge/gastown/internal/hooks"
+	"github.com/steveyegge/gastown/internal/style"
+)
+
+var hooksValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the base and override hook configs for errors",
+	Long: `Check the base config and every override file for problems before
+they are synced to every agent.
+
+Reports, with the file and entry location of each problem:
+  - invalid JSON or misspelled hook event names
+  - duplicate matchers and unbalanced parentheses (e.g. "Bash(git push*")
+  - hooks with a missing or unknown type
+  - command hooks with an empty command
+  - override files whose name is not a valid role or rig/role target
+
+Exits non-zero if any config is invalid.
+
+Examples:
+  gt hooks validate`,
+	RunE: runHooksValidate,
+}
+
+func init() {
+	hooksCmd.AddCommand(hooksValidateCmd)
+}
+
+func runHooksValidate(cmd *cobra.Command, args []string) error {
+	results, err := hooks.ValidateAllConfigs()
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println(style.Dim.Render("No hook configs found (built-in defaults are in use)."))
+		return nil
+	}
+
+	invalid := 0
+	for _, r := range results {
+		label := "base"
+		if r.Target != "" {
+			label = r.Target
+		}
+		if len(r.Errors) == 0 {
+			fmt.Printf("  %s %s %s\n", style.Success.Render("✓"), label, style.Dim.Render(r.Path))
+			continue
+		}
+		invalid++
+		fmt.Printf("  %s %s %s\n", style.Error.Render("✖"), label, style.Dim.Render(r.Path))
+		for _, e := range r.Errors {
+			fmt.Printf("      %s\n", e)
+		}
+	}
+
+	fmt.Println()
+	if invalid > 0 {
+		fmt.Printf("%s %d of %d config(s) invalid\n", style.Error.Render("✖"), invalid, len(results))
+		return NewSilentExit(1)
+	}
+	fmt.Printf("%s %d config(s) valid\n", style.Success.Render("✓"), len(results))
+	return nil
+}
@@ -1,16 +1,22 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/steveyegge/gastown/internal/agentloop"
+	"github.com/steveyegge/gastown/internal/events"
+	"github.com/steveyegge/gastown/internal/llm"
 	"github.com/steveyegge/gastown/internal/mcp"
 	"github.com/steveyegge/gastown/internal/workspace"
 )
@@ -20,6 +26,22 @@ var (
 	mcpRig       string
 	mcpWorkdir   string
 	mcpAuthToken string
+	mcpTools     []string
+	mcpAdvertise bool
+
+	mcpMaxFileRead  int64
+	mcpMaxOutput    int
+	mcpShellTimeout time.Duration
+
+	mcpAuditLog          string
+	mcpAuditRedactWrites bool
+
+	mcpTLSCert  string
+	mcpTLSKey   string
+	mcpClientCA string
+
+	mcpMaxConcurrent int
+	mcpRPS           float64
 )
 
 var mcpCmd = &cobra.Command{
@@ -79,14 +101,59 @@ func runMCPServe(cmd *cobra.Command, args []string) error {
 		actor,
 		role,
 	)
+	executor.SetAllowedTools(mcpTools)
+	executor.SetLimits(agentloop.ExecutorLimits{
+		MaxFileReadSize: mcpMaxFileRead,
+		MaxOutputSize:   mcpMaxOutput,
+		ShellTimeout:    mcpShellTimeout,
+	})
 
 	addr := strings.TrimSpace(mcpAddr)
 	srv := mcp.NewServer(addr, executor, authToken)
 	srv.RegisterGTTools()
+	registerMCPResources(srv, executor, townRoot)
+
+	srv.SetLimits(mcp.Limits{
+		MaxConcurrent: mcpMaxConcurrent,
+		RPS:           mcpRPS,
+	})
+
+	useTLS := mcpTLSCert != "" || mcpTLSKey != ""
+	if useTLS {
+		if err := srv.SetTLS(mcp.TLSConfig{
+			CertFile:     mcpTLSCert,
+			KeyFile:      mcpTLSKey,
+			ClientCAFile: mcpClientCA,
+		}); err != nil {
+			return err
+		}
+	} else if mcpClientCA != "" {
+		return fmt.Errorf("--client-ca requires --tls-cert and --tls-key")
+	}
+
+	if path := strings.TrimSpace(mcpAuditLog); path != "" {
+		audit, err := mcp.OpenAuditLog(path, mcp.AuditOptions{RedactWrites: mcpAuditRedactWrites})
+		if err != nil {
+			return err
+		}
+		defer func() { _ = audit.Close() }()
+		srv.SetAuditLog(audit)
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	// Bring up the Nostr publisher now rather than on the first event so the
+	// spool drainer runs for the life of the server. This may dial a bunker,
+	// so don't hold up serving on it.
+	go events.EnsureNostrPublisher(role)
+
+	if mcpAdvertise {
+		if err := advertiseMCPServer(ctx, srv.Addr(), rigName, role, useTLS); err != nil {
+			fmt.Fprintf(os.Stderr, "[mcp] mDNS advertising disabled: %v\n", err)
+		}
+	}
+
 	if err := srv.Start(ctx); err != nil {
 		// When ctx is canceled, server.go returns ctx.Err() upstream only if ListenAndServe closes with non-ServerClosed.
 		// Treat context cancellation as a clean shutdown.
@@ -98,6 +165,66 @@ func runMCPServe(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// mcpEventFeedTail is how much of the end of the event log the feed resource returns.
+const mcpEventFeedTail = 64 * 1024
+
+// registerMCPResources exposes read-only rig context as MCP resources.
+func registerMCPResources(srv *mcp.Server, executor *agentloop.Executor, townRoot string) {
+	if executor.IsToolAllowed("bd_list") {
+		srv.RegisterResource("gastown://beads/issues", "Open beads issues", "text/plain", func(ctx context.Context) ([]byte, error) {
+			out, err := executor.Execute(ctx, llm.ToolCall{Name: "bd_list"})
+			return []byte(out), err
+		})
+	}
+
+	srv.RegisterResource("gastown://events/feed", "Recent town events", "application/jsonl", func(ctx context.Context) ([]byte, error) {
+		data, err := os.ReadFile(filepath.Join(townRoot, events.EventsFile))
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > mcpEventFeedTail {
+			// Start at a line boundary so every returned line is complete JSON.
+			data = data[len(data)-mcpEventFeedTail:]
+			if i := bytes.IndexByte(data, '\n'); i >= 0 {
+				data = data[i+1:]
+			}
+		}
+		return data, nil
+	})
+}
+
+// advertiseMCPServer announces the server on the LAN via mDNS until ctx ends.
+func advertiseMCPServer(ctx context.Context, addr, rigName, role string, useTLS bool) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("parsing listen address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return fmt.Errorf("server listens on loopback %s; use --addr 0.0.0.0:PORT to be reachable", host)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return fmt.Errorf("parsing listen port %q: %w", portStr, err)
+	}
+
+	metadata := map[string]string{
+		"rig":     rigName,
+		"role":    role,
+		"version": Version,
+	}
+	if useTLS {
+		metadata["tls"] = "1"
+	}
+	return mcp.NewDiscovery().Advertise(ctx, mcp.ServiceInfo{
+		Host:     host,
+		Port:     port,
+		Metadata: metadata,
+	})
+}
+
 func ensureRigScopedWorkdir(townRoot, workdir string) error {
 	tr := filepath.Clean(townRoot)
 	wd := filepath.Clean(workdir)
@@ -123,6 +250,18 @@ func init() {
 	mcpServeCmd.Flags().StringVar(&mcpRig, "rig", "", "Rig name (defaults to $GT_RIG or basename of GT_TOWN_ROOT)")
 	mcpServeCmd.Flags().StringVar(&mcpWorkdir, "workdir", "", "Rig workdir (must equal GT_TOWN_ROOT)")
 	mcpServeCmd.Flags().StringVar(&mcpAuthToken, "auth-token", "", "Bearer auth token (defaults to $GT_MCP_TOKEN)")
+	mcpServeCmd.Flags().StringSliceVar(&mcpTools, "tools", nil, "Comma-separated tool allowlist (default: all GT tools)")
+	mcpServeCmd.Flags().Int64Var(&mcpMaxFileRead, "max-file-read", 0, "Largest file tools will read, in bytes (0 uses default of 10MB)")
+	mcpServeCmd.Flags().IntVar(&mcpMaxOutput, "max-output", 0, "Most tool output returned, in bytes (0 uses default of 100KB)")
+	mcpServeCmd.Flags().DurationVar(&mcpShellTimeout, "shell-timeout", 0, "Default timeout for shell and git/gt/bd commands (0 uses default of 2m)")
+	mcpServeCmd.Flags().StringVar(&mcpAuditLog, "audit-log", "", "Append a JSONL record of every tool call (caller, tool, arguments, outcome) to this file")
+	mcpServeCmd.Flags().BoolVar(&mcpAuditRedactWrites, "audit-redact-writes", false, "Record only the size of file_write content in the audit log")
+	mcpServeCmd.Flags().IntVar(&mcpMaxConcurrent, "max-concurrent", 0, "Most tool calls run at once across all clients; more get 429 (0 = no cap)")
+	mcpServeCmd.Flags().Float64Var(&mcpRPS, "rps", 0, "Tool calls per second allowed per client (by certificate CN or IP), with bursts of twice that; more get 429 (0 = no limit)")
+	mcpServeCmd.Flags().StringVar(&mcpTLSCert, "tls-cert", "", "Serve HTTPS with this PEM certificate (requires --tls-key)")
+	mcpServeCmd.Flags().StringVar(&mcpTLSKey, "tls-key", "", "PEM private key for --tls-cert")
+	mcpServeCmd.Flags().StringVar(&mcpClientCA, "client-ca", "", "Require clients to present a certificate signed by a CA in this PEM bundle (mutual TLS)")
+	mcpServeCmd.Flags().BoolVar(&mcpAdvertise, "advertise", false, "Advertise the server on the LAN via mDNS (_gastown._tcp)")
 
 	mcpCmd.AddCommand(mcpServeCmd)
 	rootCmd.AddCommand(mcpCmd)
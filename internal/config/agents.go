@@ -206,9 +206,16 @@ type APIConfig struct {
 	// APIKey is the API key. Can reference env var: "$OPENAI_API_KEY".
 	APIKey string `json:"api_key,omitempty"`
 
-	// APIType selects the wire protocol: "openai" (default) or "anthropic".
+	// APIType selects the wire protocol: "openai" (default), "anthropic",
+	// "gemini", or "azure-openai".
 	APIType string `json:"api_type,omitempty"`
 
+	// Deployment is the Azure OpenAI deployment name. Azure only.
+	Deployment string `json:"deployment,omitempty"`
+
+	// APIVersion is the Azure OpenAI api-version (e.g., "2024-10-21"). Azure only.
+	APIVersion string `json:"api_version,omitempty"`
+
 	// MaxTokens is the maximum tokens per response. Default: 4096.
 	MaxTokens int `json:"max_tokens,omitempty"`
 
@@ -235,6 +242,20 @@ type APIConfig struct {
 
 	// Retry controls retry behavior on transient failures.
 	Retry *RetryConfig `json:"retry,omitempty"`
+
+	// CachePrompt enables Anthropic prompt caching: the system prompt and
+	// tool definitions are marked as cache breakpoints so repeated loop
+	// iterations reuse them at reduced input-token cost. Anthropic only.
+	CachePrompt bool `json:"cache_prompt,omitempty"`
+
+	// EmbeddingModel is the model used for embeddings (e.g.,
+	// "text-embedding-3-small"). Embeddings are unavailable when unset.
+	// OpenAI-compatible APIs only.
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+
+	// EmbeddingDimensions requests shortened embeddings from models that
+	// support it. Default: the model's native size.
+	EmbeddingDimensions int `json:"embedding_dimensions,omitempty"`
 }
 
 // RetryConfig controls retry behavior for API calls.
@@ -1725,6 +1725,7 @@ type NostrConfig struct {
 	Enabled        bool                      `json:"enabled"`                   // master switch for Nostr publishing
 	ReadRelays     []string                  `json:"read_relays,omitempty"`     // relays to subscribe for events
 	WriteRelays    []string                  `json:"write_relays,omitempty"`    // relays to publish events to
+	RequiresAuth   []string                  `json:"requires_auth,omitempty"`   // relays that need NIP-42 AUTH before reads/writes
 	BlossomServers []string                  `json:"blossom_servers,omitempty"` // Blossom blob storage servers
 	Identities     map[string]*NostrIdentity `json:"identities,omitempty"`      // role → identity mapping
 	Defaults       *NostrDefaults            `json:"defaults,omitempty"`        // timing and behavior defaults
@@ -1761,6 +1762,11 @@ type AgentProfile struct {
 type NostrDefaults struct {
 	HeartbeatIntervalSec  int `json:"heartbeat_interval_seconds,omitempty"`   // default: 60
 	SpoolDrainIntervalSec int `json:"spool_drain_interval_seconds,omitempty"` // default: 30
+	SpoolMaxMB            int `json:"spool_max_mb,omitempty"`                 // default: 256
+
+	// SpoolReconcile drops spooled events that write relays already hold
+	// (checked with NIP-77 negentropy) instead of republishing them.
+	SpoolReconcile bool `json:"spool_reconcile,omitempty"`
 }
 
 // DefaultNostrDefaults returns NostrDefaults with sensible defaults.
@@ -1768,6 +1774,7 @@ func DefaultNostrDefaults() *NostrDefaults {
 	return &NostrDefaults{
 		HeartbeatIntervalSec:  60,
 		SpoolDrainIntervalSec: 30,
+		SpoolMaxMB:            256,
 	}
 }
 
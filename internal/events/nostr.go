@@ -9,6 +9,8 @@ import (
 	"sync"
 	"time"
 
+	"fiatjaf.com/nostr"
+
 	"github.com/steveyegge/gastown/internal/config"
 	gtnostr "github.com/steveyegge/gastown/internal/nostr"
 )
@@ -192,32 +194,20 @@ func spoolDrainInterval(cfg *config.NostrConfig) time.Duration {
 
 func startPublisherMaintenance(publisher *gtnostr.Publisher, interval time.Duration) context.CancelFunc {
 	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				reconnectCtx, reconnectCancel := context.WithTimeout(ctx, gtnostr.DefaultConnectTimeout)
-				publisher.Pool().Reconnect(reconnectCtx)
-				reconnectCancel()
-
-				drainCtx, drainCancel := context.WithTimeout(ctx, gtnostr.DefaultPublishTimeout)
-				sent, failed, err := publisher.DrainSpool(drainCtx)
-				drainCancel()
-				if err != nil {
-					log.Printf("[events/nostr] Spool drain failed: %v", err)
-				} else if sent > 0 || failed > 0 {
-					log.Printf("[events/nostr] Spool drain: sent=%d failed=%d", sent, failed)
-				}
-			}
-		}
-	}()
+	publisher.Pool().StartReconnectLoop(ctx)
+	gtnostr.NewSpoolDrainer(publisher, interval).Start(ctx)
 	return cancel
 }
 
+// EnsureNostrPublisher initializes the Nostr publisher for role ahead of the
+// first event, which also starts relay reconnection and spool draining. Long
+// running processes call it at startup so events spooled by earlier runs are
+// delivered once relays are reachable. It reports whether publishing is
+// enabled and ready.
+func EnsureNostrPublisher(role string) bool {
+	return getPublisher(role) != nil
+}
+
 // publishToNostr converts an Event to a canonical NIP-38 status event and publishes it.
 // This is called asynchronously from write() and should never block.
 func publishToNostr(event Event) {
@@ -244,8 +234,8 @@ func publishToNostr(event Event) {
 	if correlations != nil {
 		gtnostr.WithCorrelation(nostrEvent, correlations.IssueID, correlations.ConvoyID, correlations.BeadID, correlations.SessionID)
 
-		// Add type-specific extra tags
-		addExtraTags(nostrEvent, event.Type, correlations)
+		// Add branch and merge-request tags
+		addExtraTags(nostrEvent, correlations)
 	}
 
 	// Publish (async - publisher handles spool fallback)
@@ -319,20 +309,11 @@ func extractCorrelations(eventType string, payload map[string]interface{}) *corr
 	return c
 }
 
-// addExtraTags adds event-type-specific tags to the Nostr event.
-func addExtraTags(event interface{}, eventType string, c *correlations) {
-	// The nostr.Event type uses Tags field - we need to work with the concrete type
-	// Since we're using fiatjaf.com/nostr, we add tags via the event construction
-	// The event.go helpers already handle base tags; extra tags are added via
-	// WithCorrelation which covers issue, convoy, bead, session
-	//
-	// Type-specific tags (branch, mr, target) are added here.
-	// Note: We rely on the tag format from nostr-protocol.md spec.
-	//
-	// TODO: Add branch and MR tags once the nostr event type allows tag mutation.
-	// For now, the correlation tags cover the critical cross-references.
-	_ = eventType
-	_ = c
+// addExtraTags adds the merge correlation tags (branch, mr) that
+// WithCorrelation doesn't cover, so the feed can be filtered by branch or
+// merge request.
+func addExtraTags(event *nostr.Event, c *correlations) {
+	gtnostr.WithMergeCorrelation(event, c.Branch, c.MergeReq)
 }
 
 // parseActor splits an actor address like "rig/polecats/Name" or "rig/witness"
@@ -251,3 +251,17 @@ func TestGetPublisherAppliesNostrEnvOverrides(t *testing.T) {
 		t.Fatalf("write relays = %v", captured.WriteRelays)
 	}
 }
+
+func TestMergeEventsCarryBranchAndMRTags(t *testing.T) {
+	c := extractCorrelations(TypeMergeFailed, map[string]interface{}{
+		"mr_id":  "mr-42",
+		"branch": "polecat/toast",
+	})
+	event := &nostr.Event{}
+	addExtraTags(event, c)
+
+	want := nostr.Tags{{"branch", "polecat/toast"}, {"mr", "mr-42"}}
+	if !reflect.DeepEqual(event.Tags, want) {
+		t.Errorf("tags = %v, want %v", event.Tags, want)
+	}
+}
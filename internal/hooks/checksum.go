@@ -0,0 +1,70 @@
+package hooks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/steveyegge/gastown/internal/atomicfile"
+)
+
+// checksumSuffix names the sidecar file that records what sync last wrote to
+// a settings file, e.g. settings.json.gt-sha256.
+const checksumSuffix = ".gt-sha256"
+
+// LocalModificationError indicates a settings file was edited by hand since
+// sync last wrote it. Sync refuses to overwrite it unless forced.
+type LocalModificationError struct {
+	Path string
+}
+
+func (e *LocalModificationError) Error() string {
+	return fmt.Sprintf("%s was modified since the last sync; review with 'gt hooks diff' and rerun with --force to overwrite", e.Path)
+}
+
+// IsLocalModificationError reports whether an error chain contains a
+// LocalModificationError.
+func IsLocalModificationError(err error) bool {
+	var modErr *LocalModificationError
+	return errors.As(err, &modErr)
+}
+
+// ChecksumPath returns the sidecar file holding the checksum of what sync
+// last wrote to settingsPath.
+func ChecksumPath(settingsPath string) string {
+	return settingsPath + checksumSuffix
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// LocallyModified reports whether the file at path no longer matches the
+// checksum recorded when sync last wrote it. Files sync has never written
+// (no recorded checksum) are not considered modified.
+func LocallyModified(path string) (bool, error) {
+	recorded, err := os.ReadFile(ChecksumPath(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return strings.TrimSpace(string(recorded)) != checksum(data), nil
+}
+
+// recordChecksum stores the checksum of data, just written to path.
+func recordChecksum(path string, data []byte) error {
+	return atomicfile.WriteFile(ChecksumPath(path), []byte(checksum(data)+"\n"), 0600)
+}
@@ -5,6 +5,7 @@
 package hooks
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -136,6 +137,30 @@ func MarshalSettings(s *SettingsJSON) ([]byte, error) {
 	return json.MarshalIndent(out, "", "  ")
 }
 
+// ReplaceSettingsHooks returns the settings.json data with only its "hooks"
+// key replaced by hooks. Every other top-level field is carried over as raw
+// JSON, so settings gt knows nothing about survive unchanged. Keys are
+// written in sorted order with MarshalConfig's indentation, making the output
+// stable: replacing the same hooks again yields identical bytes. Empty data
+// is treated as an empty object.
+func ReplaceSettingsHooks(data []byte, hooks *HooksConfig) ([]byte, error) {
+	fields := make(map[string]json.RawMessage)
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("parsing settings: %w", err)
+		}
+	}
+	if hooks == nil {
+		hooks = &HooksConfig{}
+	}
+	raw, err := json.Marshal(hooks)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling hooks: %w", err)
+	}
+	fields["hooks"] = raw
+	return json.MarshalIndent(fields, "", "  ")
+}
+
 // HasClaudePromptDefaults reports whether settings already contain the Claude
 // startup defaults Gas Town needs for non-interactive agent sessions.
 func HasClaudePromptDefaults(s *SettingsJSON) bool {
@@ -221,56 +246,144 @@ func LoadSettings(path string) (*SettingsJSON, error) {
 }
 
 // SyncManagedClaudeSettings merges computed managed hooks into a Claude
-// settings.json file while preserving non-hook settings fields.
+// settings.json file while preserving non-hook settings fields. Local edits
+// are overwritten; use SyncClaudeSettings to refuse them instead.
 func SyncManagedClaudeSettings(target Target, dryRun bool) (SyncResult, error) {
-	expected, err := ComputeExpected(target.Key)
-	if err != nil {
-		return 0, fmt.Errorf("computing expected config: %w", err)
-	}
+	return SyncClaudeSettings(target, SyncOptions{DryRun: dryRun, Force: true})
+}
 
-	current, err := LoadSettings(target.Path)
-	if err != nil {
-		return 0, fmt.Errorf("loading current settings: %w", err)
-	}
+// SyncOptions controls SyncClaudeSettings.
+type SyncOptions struct {
+	// DryRun reports what would change without writing anything.
+	DryRun bool
 
-	_, statErr := os.Stat(target.Path)
-	fileExists := statErr == nil
+	// Force overwrites a settings file even if it was edited since sync
+	// last wrote it. Without it such files fail with a
+	// LocalModificationError.
+	Force bool
+}
 
-	if fileExists && HooksEqual(expected, &current.Hooks) && HasClaudePromptDefaults(current) {
+// SyncClaudeSettings merges computed managed hooks into a Claude
+// settings.json file while preserving non-hook settings fields. Each write
+// records a checksum of the file so the next sync can tell whether it was
+// edited by hand in between.
+func SyncClaudeSettings(target Target, opts SyncOptions) (SyncResult, error) {
+	plan, err := PlanClaudeSettings(target)
+	if err != nil {
+		return 0, err
+	}
+	if !plan.Changed {
 		return SyncUnchanged, nil
 	}
 
-	if dryRun {
-		if fileExists {
-			return SyncUpdated, nil
+	if plan.Exists && !opts.Force {
+		modified, err := LocallyModified(target.Path)
+		if err != nil {
+			return 0, fmt.Errorf("checking for local modifications: %w", err)
+		}
+		if modified {
+			return 0, &LocalModificationError{Path: target.Path}
 		}
-		return SyncCreated, nil
 	}
 
-	current.Hooks = *expected
-	if current.EnabledPlugins == nil {
-		current.EnabledPlugins = make(map[string]bool)
+	if opts.DryRun {
+		if plan.Exists {
+			return SyncUpdated, nil
+		}
+		return SyncCreated, nil
 	}
-	current.EnabledPlugins["beads@beads-marketplace"] = false
 
 	if err := os.MkdirAll(filepath.Dir(target.Path), 0755); err != nil {
 		return 0, fmt.Errorf("creating .claude directory: %w", err)
 	}
+	if err := atomicfile.WriteFile(target.Path, plan.Proposed, 0600); err != nil {
+		return 0, fmt.Errorf("writing settings: %w", err)
+	}
+	if err := recordChecksum(target.Path, plan.Proposed); err != nil {
+		return 0, fmt.Errorf("recording settings checksum: %w", err)
+	}
+
+	if plan.Exists {
+		return SyncUpdated, nil
+	}
+	return SyncCreated, nil
+}
+
+// SettingsPlan describes what sync would do to one Claude settings.json.
+type SettingsPlan struct {
+	Target Target
+
+	// Exists reports whether the file is already on disk; Current holds its
+	// contents (nil when it does not exist).
+	Exists  bool
+	Current []byte
+
+	// Changed reports whether sync would write the file, and Proposed is
+	// what it would write.
+	Changed  bool
+	Proposed []byte
+
+	// CurrentHooks and ExpectedHooks are the hooks sections before and
+	// after sync.
+	CurrentHooks  HooksConfig
+	ExpectedHooks HooksConfig
+}
 
-	data, err := MarshalSettings(current)
+// PlanClaudeSettings computes the settings.json sync would write for target
+// from the base config and its overrides, without writing anything. Sync and
+// diff both go through here so they cannot disagree.
+func PlanClaudeSettings(target Target) (*SettingsPlan, error) {
+	expected, err := ComputeExpected(target.Key)
 	if err != nil {
-		return 0, fmt.Errorf("marshaling settings: %w", err)
+		return nil, fmt.Errorf("computing expected config: %w", err)
 	}
-	data = append(data, '\n')
 
-	if err := atomicfile.WriteFile(target.Path, data, 0600); err != nil {
-		return 0, fmt.Errorf("writing settings: %w", err)
+	current, err := LoadSettings(target.Path)
+	if err != nil {
+		return nil, fmt.Errorf("loading current settings: %w", err)
 	}
 
-	if fileExists {
-		return SyncUpdated, nil
+	plan := &SettingsPlan{
+		Target:        target,
+		CurrentHooks:  current.Hooks,
+		ExpectedHooks: *expected,
 	}
-	return SyncCreated, nil
+	plan.Current, err = os.ReadFile(target.Path)
+	if err == nil {
+		plan.Exists = true
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading current settings: %w", err)
+	}
+
+	plan.Changed = !plan.Exists || !HooksEqual(expected, &current.Hooks) || !HasClaudePromptDefaults(current)
+
+	// Only the hooks key is rewritten, unless the startup defaults Gas Town
+	// agents need are missing; then those are added too.
+	base := plan.Current
+	if !HasClaudePromptDefaults(current) || !beadsPluginDisabled(current) {
+		if current.EnabledPlugins == nil {
+			current.EnabledPlugins = make(map[string]bool)
+		}
+		current.EnabledPlugins[beadsPlugin] = false
+		if base, err = MarshalSettings(current); err != nil {
+			return nil, fmt.Errorf("marshaling settings: %w", err)
+		}
+	}
+
+	data, err := ReplaceSettingsHooks(base, expected)
+	if err != nil {
+		return nil, err
+	}
+	plan.Proposed = append(data, '\n')
+	return plan, nil
+}
+
+// beadsPlugin is the Claude plugin sync keeps disabled in agent settings.
+const beadsPlugin = "beads@beads-marketplace"
+
+func beadsPluginDisabled(s *SettingsJSON) bool {
+	enabled, ok := s.EnabledPlugins[beadsPlugin]
+	return ok && !enabled
 }
 
 // HooksEqual returns true if two HooksConfigs are structurally equal.
@@ -310,11 +423,7 @@ func (t Target) DisplayKey() string {
 //
 // Hook types not present in the override are preserved from the base.
 func Merge(base, override *HooksConfig) *HooksConfig {
-	if base == nil {
-		base = &HooksConfig{}
-	}
-	result := cloneConfig(base)
-	return applyOverride(result, override)
+	return MergeConfigs(base, override)
 }
 
 // DefaultOverrides returns built-in role-specific hook overrides.
@@ -0,0 +1,179 @@
+package hooks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HookChange is one hook command added or removed by a sync.
+type HookChange struct {
+	Event   string `json:"event"`
+	Matcher string `json:"matcher"`
+	Command string `json:"command"`
+}
+
+// DiffHooks lists the hook commands present in expected but not current
+// (added) and in current but not expected (removed), per event and matcher.
+func DiffHooks(current, expected *HooksConfig) (added, removed []HookChange) {
+	for _, event := range EventTypes {
+		have := hookCommands(event, current.GetEntries(event))
+		want := hookCommands(event, expected.GetEntries(event))
+		removed = append(removed, subtractChanges(have, want)...)
+		added = append(added, subtractChanges(want, have)...)
+	}
+	return added, removed
+}
+
+func hookCommands(event string, entries []HookEntry) []HookChange {
+	var changes []HookChange
+	for _, entry := range entries {
+		for _, h := range entry.Hooks {
+			changes = append(changes, HookChange{Event: event, Matcher: entry.Matcher, Command: h.Command})
+		}
+	}
+	return changes
+}
+
+// subtractChanges returns the entries of a not matched by an entry of b,
+// counting duplicates.
+func subtractChanges(a, b []HookChange) []HookChange {
+	remaining := make(map[HookChange]int, len(b))
+	for _, c := range b {
+		remaining[c]++
+	}
+	var out []HookChange
+	for _, c := range a {
+		if remaining[c] > 0 {
+			remaining[c]--
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// UnifiedDiff renders a unified diff from one file's contents to another's,
+// labelled with fromName and toName. It returns "" when they are equal.
+func UnifiedDiff(fromName, toName string, from, to []byte) string {
+	a := splitLines(string(from))
+	b := splitLines(string(to))
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// Extend the hunk until a run of unchanged lines long enough to
+		// separate it from the next change.
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				break
+			}
+			end = run
+		}
+
+		lo := max(start-diffContext, 0)
+		hi := min(end+diffContext, len(ops))
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&sb, ops[lo:hi])
+		start = hi
+	}
+	return sb.String()
+}
+
+type diffOp struct {
+	kind       byte // ' ', '-' or '+'
+	line       string
+	aIdx, bIdx int // line numbers (0-based) the op starts at in a and b
+}
+
+// diffLines computes a shortest edit script from a to b using the longest
+// common subsequence. Settings files are small, so O(len(a)*len(b)) is fine.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+func writeHunk(sb *strings.Builder, ops []diffOp) {
+	aStart, bStart := ops[0].aIdx, ops[0].bIdx
+	var aLen, bLen int
+	for _, op := range ops {
+		if op.kind != '+' {
+			aLen++
+		}
+		if op.kind != '-' {
+			bLen++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, op := range ops {
+		fmt.Fprintf(sb, "%c%s\n", op.kind, op.line)
+	}
+}
+
+// hunkRange formats a hunk header range, which is 1-based except that an
+// empty range names the line before it.
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
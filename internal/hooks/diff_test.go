@@ -0,0 +1,76 @@
+package hooks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	to := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn\n"
+
+	got := UnifiedDiff("old", "new", []byte(from), []byte(to))
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -11,3 +11,4 @@
+ k
+ l
+ m
++n
+`
+	if got != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := UnifiedDiff("old", "new", []byte(from), []byte(from)); got != "" {
+		t.Errorf("UnifiedDiff of equal input = %q, want empty", got)
+	}
+
+	created := UnifiedDiff("/dev/null", "new", nil, []byte("x\ny\n"))
+	if !strings.Contains(created, "@@ -0,0 +1,2 @@\n+x\n+y\n") {
+		t.Errorf("UnifiedDiff from empty =\n%s", created)
+	}
+}
+
+func TestDiffHooks(t *testing.T) {
+	current := &HooksConfig{
+		PreToolUse: []HookEntry{
+			{Matcher: "Bash", Hooks: []Hook{{Type: "command", Command: "guard"}, {Type: "command", Command: "old-audit"}}},
+		},
+		Stop: []HookEntry{
+			{Matcher: "", Hooks: []Hook{{Type: "command", Command: "stop"}}},
+		},
+	}
+	expected := &HooksConfig{
+		PreToolUse: []HookEntry{
+			{Matcher: "Bash", Hooks: []Hook{{Type: "command", Command: "guard"}, {Type: "command", Command: "new-audit"}}},
+		},
+		SessionStart: []HookEntry{
+			{Matcher: "", Hooks: []Hook{{Type: "command", Command: "prime"}}},
+		},
+	}
+
+	added, removed := DiffHooks(current, expected)
+	wantAdded := []HookChange{
+		{Event: "PreToolUse", Matcher: "Bash", Command: "new-audit"},
+		{Event: "SessionStart", Matcher: "", Command: "prime"},
+	}
+	wantRemoved := []HookChange{
+		{Event: "PreToolUse", Matcher: "Bash", Command: "old-audit"},
+		{Event: "Stop", Matcher: "", Command: "stop"},
+	}
+	if !reflect.DeepEqual(added, wantAdded) {
+		t.Errorf("added = %+v, want %+v", added, wantAdded)
+	}
+	if !reflect.DeepEqual(removed, wantRemoved) {
+		t.Errorf("removed = %+v, want %+v", removed, wantRemoved)
+	}
+}
@@ -22,21 +22,34 @@ import (
 //   - Hooks with different matcher: both are included
 //   - Override with empty hook list for a matcher: removes that hook (explicit disable)
 func MergeHooks(base *HooksConfig, overrides map[string]*HooksConfig, target string) *HooksConfig {
+	var applicable []*HooksConfig
+	for _, key := range GetApplicableOverrides(target) {
+		applicable = append(applicable, overrides[key])
+	}
+	return MergeConfigs(base, applicable...)
+}
+
+// MergeConfigs layers overrides onto base in order, later overrides taking
+// precedence over earlier ones. Neither base nor the overrides are modified;
+// a nil base is treated as empty and nil overrides are skipped.
+//
+// Each hook type is merged independently, keyed by matcher:
+//   - Same matcher: the later entry replaces the earlier one in place
+//   - Different matcher: entries accumulate, earlier ones first
+//   - Empty hooks list on a matcher: removes that entry (explicit disable)
+//
+// Hook types an override leaves empty are inherited unchanged.
+func MergeConfigs(base *HooksConfig, overrides ...*HooksConfig) *HooksConfig {
 	if base == nil {
 		base = &HooksConfig{}
 	}
-
 	result := cloneConfig(base)
-
-	// Apply overrides in order of specificity
-	for _, key := range GetApplicableOverrides(target) {
-		override, ok := overrides[key]
-		if !ok || override == nil {
+	for _, override := range overrides {
+		if override == nil {
 			continue
 		}
-		result = applyOverride(result, override)
+		result = applyOverride(result, cloneConfig(override))
 	}
-
 	return result
 }
 
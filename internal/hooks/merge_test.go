@@ -3,6 +3,7 @@ package hooks
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 )
@@ -224,6 +225,111 @@ func TestMergeHooksOverrideAddsNewType(t *testing.T) {
 	}
 }
 
+func TestMergeConfigs(t *testing.T) {
+	cmd := func(c string) []Hook { return []Hook{{Type: "command", Command: c}} }
+
+	tests := []struct {
+		name      string
+		base      *HooksConfig
+		overrides []*HooksConfig
+		want      *HooksConfig
+	}{
+		{
+			name: "nil base takes override",
+			overrides: []*HooksConfig{
+				{Stop: []HookEntry{{Matcher: "", Hooks: cmd("stop")}}},
+			},
+			want: &HooksConfig{Stop: []HookEntry{{Matcher: "", Hooks: cmd("stop")}}},
+		},
+		{
+			name: "empty base and no overrides",
+			base: &HooksConfig{},
+			want: &HooksConfig{},
+		},
+		{
+			name: "override-only hook type is added, others inherited",
+			base: &HooksConfig{SessionStart: []HookEntry{{Matcher: "", Hooks: cmd("prime")}}},
+			overrides: []*HooksConfig{
+				{PreCompact: []HookEntry{{Matcher: "", Hooks: cmd("cycle")}}},
+			},
+			want: &HooksConfig{
+				SessionStart: []HookEntry{{Matcher: "", Hooks: cmd("prime")}},
+				PreCompact:   []HookEntry{{Matcher: "", Hooks: cmd("cycle")}},
+			},
+		},
+		{
+			name: "matcher collision replaces in place",
+			base: &HooksConfig{PreToolUse: []HookEntry{
+				{Matcher: "Bash", Hooks: cmd("guard")},
+				{Matcher: "Edit", Hooks: cmd("lint")},
+			}},
+			overrides: []*HooksConfig{
+				{PreToolUse: []HookEntry{{Matcher: "Bash", Hooks: cmd("strict-guard")}}},
+			},
+			want: &HooksConfig{PreToolUse: []HookEntry{
+				{Matcher: "Bash", Hooks: cmd("strict-guard")},
+				{Matcher: "Edit", Hooks: cmd("lint")},
+			}},
+		},
+		{
+			name: "empty hooks list disables matcher",
+			base: &HooksConfig{PreToolUse: []HookEntry{
+				{Matcher: "Bash", Hooks: cmd("guard")},
+				{Matcher: "Edit", Hooks: cmd("lint")},
+			}},
+			overrides: []*HooksConfig{
+				{PreToolUse: []HookEntry{{Matcher: "Edit", Hooks: []Hook{}}}},
+			},
+			want: &HooksConfig{PreToolUse: []HookEntry{{Matcher: "Bash", Hooks: cmd("guard")}}},
+		},
+		{
+			name: "later overrides win and new matchers accumulate",
+			base: &HooksConfig{PreToolUse: []HookEntry{{Matcher: "Bash", Hooks: cmd("guard")}}},
+			overrides: []*HooksConfig{
+				{PreToolUse: []HookEntry{
+					{Matcher: "Bash", Hooks: cmd("role-guard")},
+					{Matcher: "Write", Hooks: cmd("role-write")},
+				}},
+				nil,
+				{PreToolUse: []HookEntry{
+					{Matcher: "Bash", Hooks: cmd("rig-guard")},
+					{Matcher: "Read", Hooks: cmd("rig-read")},
+				}},
+			},
+			want: &HooksConfig{PreToolUse: []HookEntry{
+				{Matcher: "Bash", Hooks: cmd("rig-guard")},
+				{Matcher: "Write", Hooks: cmd("role-write")},
+				{Matcher: "Read", Hooks: cmd("rig-read")},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeConfigs(tt.base, tt.overrides...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeConfigs = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeConfigsDoesNotAliasInputs(t *testing.T) {
+	base := &HooksConfig{Stop: []HookEntry{{Matcher: "", Hooks: []Hook{{Type: "command", Command: "stop"}}}}}
+	override := &HooksConfig{PreToolUse: []HookEntry{{Matcher: "Bash", Hooks: []Hook{{Type: "command", Command: "guard"}}}}}
+
+	result := MergeConfigs(base, override)
+	result.Stop[0].Hooks[0].Command = "changed"
+	result.PreToolUse[0].Hooks[0].Command = "changed"
+
+	if base.Stop[0].Hooks[0].Command != "stop" {
+		t.Errorf("base was mutated: %q", base.Stop[0].Hooks[0].Command)
+	}
+	if override.PreToolUse[0].Hooks[0].Command != "guard" {
+		t.Errorf("override was mutated: %q", override.PreToolUse[0].Hooks[0].Command)
+	}
+}
+
 func TestLoadAllOverrides(t *testing.T) {
 	tmpDir := t.TempDir()
 	setTestHome(t, tmpDir)
@@ -1,6 +1,7 @@
 package hooks
 
 import (
+	"bytes"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -190,6 +191,185 @@ func TestUnmarshalMarshalRoundtrip(t *testing.T) {
 	}
 }
 
+func TestReplaceSettingsHooksPreservesUnknownFields(t *testing.T) {
+	existing := `{
+  "model": "opus",
+  "permissions": {"allow": ["Bash(go test:*)"], "defaultMode": "acceptEdits"},
+  "env": {"FOO": "bar"},
+  "hooks": {
+    "Stop": [{"matcher": "", "hooks": [{"type": "command", "command": "old-stop"}]}]
+  },
+  "myCustomKey": [1, 2, {"deep": null}]
+}`
+	hooksCfg := &HooksConfig{
+		SessionStart: []HookEntry{
+			{Matcher: "", Hooks: []Hook{{Type: "command", Command: "gt prime"}}},
+		},
+	}
+
+	out, err := ReplaceSettingsHooks([]byte(existing), hooksCfg)
+	if err != nil {
+		t.Fatalf("ReplaceSettingsHooks: %v", err)
+	}
+
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(existing), &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out, &after); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+	if len(after) != len(before) {
+		t.Errorf("got keys %d, want %d:\n%s", len(after), len(before), out)
+	}
+	for key, raw := range before {
+		if key == "hooks" {
+			continue
+		}
+		var want, got bytes.Buffer
+		json.Compact(&want, raw)
+		json.Compact(&got, after[key])
+		if got.String() != want.String() {
+			t.Errorf("%s = %s, want %s", key, got.String(), want.String())
+		}
+	}
+
+	var gotHooks HooksConfig
+	if err := json.Unmarshal(after["hooks"], &gotHooks); err != nil {
+		t.Fatalf("parsing hooks: %v", err)
+	}
+	if !HooksEqual(&gotHooks, hooksCfg) {
+		t.Errorf("hooks = %s", after["hooks"])
+	}
+
+	// Output is stable: replacing the same hooks again changes nothing.
+	again, err := ReplaceSettingsHooks(out, hooksCfg)
+	if err != nil {
+		t.Fatalf("second ReplaceSettingsHooks: %v", err)
+	}
+	if !bytes.Equal(again, out) {
+		t.Errorf("output not stable:\n%s\nthen\n%s", out, again)
+	}
+}
+
+func TestReplaceSettingsHooksEmptyInput(t *testing.T) {
+	out, err := ReplaceSettingsHooks(nil, nil)
+	if err != nil {
+		t.Fatalf("ReplaceSettingsHooks: %v", err)
+	}
+	if string(out) != "{\n  \"hooks\": {}\n}" {
+		t.Errorf("got %s", out)
+	}
+	if _, err := ReplaceSettingsHooks([]byte("{not json"), nil); err == nil {
+		t.Error("expected an error for malformed settings")
+	}
+}
+
+func TestSyncClaudeSettingsKeepsUnknownFieldsStable(t *testing.T) {
+	tmpDir := t.TempDir()
+	setTestHome(t, tmpDir)
+
+	if err := SaveBase(&HooksConfig{
+		SessionStart: []HookEntry{
+			{Matcher: "", Hooks: []Hook{{Type: "command", Command: "gt prime"}}},
+		},
+	}); err != nil {
+		t.Fatalf("SaveBase: %v", err)
+	}
+
+	settingsPath := filepath.Join(tmpDir, "town", "rig1", "crew", "alice", ".claude", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	existing := `{"model": "opus", "env": {"FOO": "bar"}, "myCustomKey": {"nested": [1, 2]}}`
+	if err := os.WriteFile(settingsPath, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+	target := Target{Path: settingsPath, Key: "rig1/crew", Rig: "rig1", Role: "crew"}
+
+	if _, err := SyncClaudeSettings(target, SyncOptions{}); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	first, _ := os.ReadFile(settingsPath)
+	s, err := UnmarshalSettings(first)
+	if err != nil {
+		t.Fatalf("parsing synced settings: %v", err)
+	}
+	for key, want := range map[string]string{
+		"model":       `"opus"`,
+		"env":         `{"FOO":"bar"}`,
+		"myCustomKey": `{"nested":[1,2]}`,
+	} {
+		var got bytes.Buffer
+		json.Compact(&got, s.Extra[key])
+		if got.String() != want {
+			t.Errorf("%s = %s, want %s", key, got.String(), want)
+		}
+	}
+
+	result, err := SyncClaudeSettings(target, SyncOptions{})
+	if err != nil {
+		t.Fatalf("second sync: %v", err)
+	}
+	if result != SyncUnchanged {
+		t.Errorf("second sync result = %v, want unchanged", result)
+	}
+	second, _ := os.ReadFile(settingsPath)
+	if !bytes.Equal(first, second) {
+		t.Errorf("settings changed on resync:\n%s\nthen\n%s", first, second)
+	}
+}
+
+func TestSyncClaudeSettingsRewritesOnlyHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	setTestHome(t, tmpDir)
+
+	base := &HooksConfig{
+		SessionStart: []HookEntry{
+			{Matcher: "", Hooks: []Hook{{Type: "command", Command: "gt prime"}}},
+		},
+	}
+	if err := SaveBase(base); err != nil {
+		t.Fatalf("SaveBase: %v", err)
+	}
+	settingsPath := filepath.Join(tmpDir, "town", "mayor", ".claude", "settings.json")
+	target := Target{Path: settingsPath, Key: "mayor", Role: "mayor"}
+	if _, err := SyncClaudeSettings(target, SyncOptions{}); err != nil {
+		t.Fatalf("first sync: %v", err)
+	}
+	first, _ := os.ReadFile(settingsPath)
+
+	base.Stop = []HookEntry{{Matcher: "", Hooks: []Hook{{Type: "command", Command: "gt costs record"}}}}
+	if err := SaveBase(base); err != nil {
+		t.Fatalf("SaveBase: %v", err)
+	}
+	if result, err := SyncClaudeSettings(target, SyncOptions{}); err != nil || result != SyncUpdated {
+		t.Fatalf("second sync = %v, %v; want updated", result, err)
+	}
+	second, _ := os.ReadFile(settingsPath)
+
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(first, &before); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(second, &after); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(before["hooks"], after["hooks"]) {
+		t.Error("hooks were not updated")
+	}
+	delete(before, "hooks")
+	delete(after, "hooks")
+	if len(before) != len(after) {
+		t.Errorf("keys changed: %d before, %d after", len(before), len(after))
+	}
+	for key, raw := range before {
+		if !bytes.Equal(raw, after[key]) {
+			t.Errorf("%s changed:\n%s\nthen\n%s", key, raw, after[key])
+		}
+	}
+}
+
 func TestLoadSettingsMissingReturnsZeroValue(t *testing.T) {
 	s, err := LoadSettings("/nonexistent/path/settings.json")
 	if err != nil {
@@ -296,3 +476,78 @@ func TestMarshalSettingsPreservesFieldOrder(t *testing.T) {
 		t.Error("output missing customField")
 	}
 }
+
+func TestSyncClaudeSettingsRefusesLocalEdits(t *testing.T) {
+	tmpDir := t.TempDir()
+	setTestHome(t, tmpDir)
+
+	base := &HooksConfig{
+		SessionStart: []HookEntry{
+			{Matcher: "", Hooks: []Hook{{Type: "command", Command: "first-prime"}}},
+		},
+	}
+	if err := SaveBase(base); err != nil {
+		t.Fatalf("SaveBase: %v", err)
+	}
+	target := Target{
+		Path: filepath.Join(tmpDir, "town", "mayor", ".claude", "settings.json"),
+		Key:  "mayor",
+		Role: "mayor",
+	}
+
+	if result, err := SyncClaudeSettings(target, SyncOptions{}); err != nil || result != SyncCreated {
+		t.Fatalf("first sync = %v, %v; want created", result, err)
+	}
+	if _, err := os.Stat(ChecksumPath(target.Path)); err != nil {
+		t.Fatalf("checksum not recorded: %v", err)
+	}
+
+	// A base change with an untouched file syncs normally.
+	base.SessionStart[0].Hooks[0].Command = "second-prime"
+	if err := SaveBase(base); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := SyncClaudeSettings(target, SyncOptions{}); err != nil || result != SyncUpdated {
+		t.Fatalf("second sync = %v, %v; want updated", result, err)
+	}
+
+	// Hand-edit the file, then change the base again.
+	data, err := os.ReadFile(target.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := strings.Replace(string(data), "{", `{"localSetting": true,`, 1)
+	if err := os.WriteFile(target.Path, []byte(edited), 0600); err != nil {
+		t.Fatal(err)
+	}
+	base.SessionStart[0].Hooks[0].Command = "third-prime"
+	if err := SaveBase(base); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []SyncOptions{{}, {DryRun: true}} {
+		if _, err := SyncClaudeSettings(target, opts); !IsLocalModificationError(err) {
+			t.Fatalf("sync %+v of edited file: err = %v, want LocalModificationError", opts, err)
+		}
+	}
+	if got, _ := os.ReadFile(target.Path); string(got) != edited {
+		t.Fatal("refused sync modified the file")
+	}
+
+	if result, err := SyncClaudeSettings(target, SyncOptions{Force: true}); err != nil || result != SyncUpdated {
+		t.Fatalf("forced sync = %v, %v; want updated", result, err)
+	}
+	got, _ := os.ReadFile(target.Path)
+	if !strings.Contains(string(got), "third-prime") || !strings.Contains(string(got), "localSetting") {
+		t.Errorf("forced sync should apply hooks and keep other fields:\n%s", got)
+	}
+
+	// The forced write re-records the checksum, so the next sync is clean.
+	base.SessionStart[0].Hooks[0].Command = "fourth-prime"
+	if err := SaveBase(base); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SyncClaudeSettings(target, SyncOptions{}); err != nil {
+		t.Fatalf("sync after forced sync: %v", err)
+	}
+}
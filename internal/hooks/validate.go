@@ -0,0 +1,196 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HookTypes lists the hook types gt knows how to write to settings.json.
+var HookTypes = []string{"command"}
+
+// ValidationError locates a problem in a hooks config, e.g.
+// "PreToolUse[1] (matcher "Bash(git push*"): unbalanced parentheses in matcher".
+type ValidationError struct {
+	Event   string
+	Entry   int    // index into the event's entries, or -1 for the event itself
+	Hook    int    // index into the entry's hooks, or -1 for the entry itself
+	Matcher string // the entry's matcher, when Entry >= 0
+	Msg     string
+}
+
+func (e *ValidationError) Error() string {
+	var loc string
+	switch {
+	case e.Entry < 0:
+		loc = e.Event
+	case e.Hook < 0:
+		loc = fmt.Sprintf("%s[%d] (matcher %q)", e.Event, e.Entry, e.Matcher)
+	default:
+		loc = fmt.Sprintf("%s[%d].hooks[%d] (matcher %q)", e.Event, e.Entry, e.Hook, e.Matcher)
+	}
+	return loc + ": " + e.Msg
+}
+
+// Validate checks a hooks config for problems sync would otherwise push to
+// every agent: duplicate or malformed matchers, unknown hook types and
+// command hooks with no command. It returns every problem found, or nil.
+//
+// An entry with an empty hooks list is valid; overrides use it to disable a
+// matcher inherited from the base.
+func Validate(cfg *HooksConfig) []error {
+	var errs []error
+	for _, event := range EventTypes {
+		seen := make(map[string]bool)
+		for i, entry := range cfg.GetEntries(event) {
+			entryErr := func(hook int, format string, args ...any) {
+				errs = append(errs, &ValidationError{
+					Event:   event,
+					Entry:   i,
+					Hook:    hook,
+					Matcher: entry.Matcher,
+					Msg:     fmt.Sprintf(format, args...),
+				})
+			}
+
+			if seen[entry.Matcher] {
+				entryErr(-1, "duplicate matcher")
+			}
+			seen[entry.Matcher] = true
+			if err := checkMatcher(entry.Matcher); err != nil {
+				entryErr(-1, "%v", err)
+			}
+
+			for j, h := range entry.Hooks {
+				switch h.Type {
+				case "":
+					entryErr(j, "missing hook type")
+				case "command":
+					if strings.TrimSpace(h.Command) == "" {
+						entryErr(j, "command hook has an empty command")
+					}
+				default:
+					entryErr(j, "unknown hook type %q (want one of: %s)", h.Type, strings.Join(HookTypes, ", "))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// checkMatcher reports unbalanced parentheses in a matcher such as
+// "Bash(git push*)".
+func checkMatcher(matcher string) error {
+	depth := 0
+	for _, r := range matcher {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses in matcher: unexpected ')'")
+			}
+		}
+	}
+	if depth > 0 {
+		return fmt.Errorf("unbalanced parentheses in matcher: missing ')'")
+	}
+	return nil
+}
+
+// ValidateConfigFile parses and validates the hooks config at path. Unlike
+// loading, it reports every problem rather than stopping at the first, and
+// also flags top-level keys that are not known hook events (a misspelled
+// event would otherwise be silently ignored).
+func ValidateConfigFile(path string) ([]error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return []error{fmt.Errorf("invalid JSON: %w", err)}, nil
+	}
+	var cfg HooksConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return []error{fmt.Errorf("invalid hooks config: %w", err)}, nil
+	}
+
+	var errs []error
+	known := make(map[string]bool, len(EventTypes))
+	for _, event := range EventTypes {
+		known[event] = true
+	}
+	for _, key := range sortedKeys(raw) {
+		if !known[key] {
+			errs = append(errs, &ValidationError{Event: key, Entry: -1, Hook: -1,
+				Msg: fmt.Sprintf("unknown hook event (want one of: %s)", strings.Join(EventTypes, ", "))})
+		}
+	}
+	return append(errs, Validate(&cfg)...), nil
+}
+
+// ConfigValidation is the result of validating one config file.
+type ConfigValidation struct {
+	Path   string
+	Target string // override target, or "" for the base config
+	Errors []error
+}
+
+// ValidateAllConfigs validates the base config LoadBase would use (if any)
+// and every override file LoadAllOverrides would load, checking override
+// file names against ValidTarget.
+func ValidateAllConfigs() ([]ConfigValidation, error) {
+	var results []ConfigValidation
+
+	for _, dir := range gtConfigDirs() {
+		path := filepath.Join(dir, "hooks-base.json")
+		errs, err := ValidateConfigFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		results = append(results, ConfigValidation{Path: path, Errors: errs})
+		break
+	}
+
+	dir := OverridesDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading overrides directory %s: %w", dir, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		target := strings.ReplaceAll(strings.TrimSuffix(name, ".json"), "__", "/")
+
+		errs, err := ValidateConfigFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if !ValidTarget(target) {
+			errs = append([]error{fmt.Errorf("%q is not a valid override target (want a role or rig/role)", target)}, errs...)
+		}
+		results = append(results, ConfigValidation{Path: path, Target: target, Errors: errs})
+	}
+	return results, nil
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,89 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	cfg := &HooksConfig{
+		PreToolUse: []HookEntry{
+			{Matcher: "Bash(git push*)", Hooks: []Hook{{Type: "command", Command: "guard"}}},
+			{Matcher: "Bash(git push*", Hooks: []Hook{{Type: "command", Command: "guard"}}},
+			{Matcher: "Edit", Hooks: []Hook{{Type: "command", Command: "  "}, {Type: "shell", Command: "x"}}},
+			{Matcher: "Edit", Hooks: []Hook{{Command: "y"}}},
+		},
+		Stop: []HookEntry{
+			{Matcher: "", Hooks: []Hook{}},
+			{Matcher: "a)(", Hooks: []Hook{{Type: "command", Command: "z"}}},
+		},
+	}
+
+	var got []string
+	for _, err := range Validate(cfg) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`PreToolUse[1] (matcher "Bash(git push*"): unbalanced parentheses in matcher: missing ')'`,
+		`PreToolUse[2].hooks[0] (matcher "Edit"): command hook has an empty command`,
+		`PreToolUse[2].hooks[1] (matcher "Edit"): unknown hook type "shell" (want one of: command)`,
+		`PreToolUse[3] (matcher "Edit"): duplicate matcher`,
+		`PreToolUse[3].hooks[0] (matcher "Edit"): missing hook type`,
+		`Stop[1] (matcher "a)("): unbalanced parentheses in matcher: unexpected ')'`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Validate =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if errs := Validate(DefaultBase()); len(errs) != 0 {
+		t.Errorf("DefaultBase is invalid: %v", errs)
+	}
+}
+
+func TestValidateAllConfigs(t *testing.T) {
+	tmpDir := t.TempDir()
+	setTestHome(t, tmpDir)
+
+	if err := SaveBase(DefaultBase()); err != nil {
+		t.Fatalf("SaveBase: %v", err)
+	}
+	overridesDir := OverridesDir()
+	if err := os.MkdirAll(overridesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"gastown__crew.json": `{"Stop": [{"matcher": "", "hooks": [{"type": "command", "command": "done"}]}]}`,
+		"janitor.json":       `{}`,
+		"witness.json":       `{"PreToolUs": [], "Stop": [{"matcher": "", "hooks": [{"type": "command", "command": ""}]}]}`,
+		"deacon.json":        `{not json`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(overridesDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := ValidateAllConfigs()
+	if err != nil {
+		t.Fatalf("ValidateAllConfigs: %v", err)
+	}
+	errCount := make(map[string]int)
+	for _, r := range results {
+		key := r.Target
+		if key == "" {
+			key = "base"
+		}
+		errCount[key] = len(r.Errors)
+	}
+	want := map[string]int{"base": 0, "gastown/crew": 0, "janitor": 1, "witness": 2, "deacon": 1}
+	for key, n := range want {
+		if got, ok := errCount[key]; !ok || got != n {
+			t.Errorf("%s: %d errors (found=%v), want %d", key, got, ok, n)
+		}
+	}
+	if len(results) != len(want) {
+		t.Errorf("got %d results, want %d", len(results), len(want))
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -23,6 +22,10 @@ type AnthropicClient struct {
 	httpClient *http.Client
 	headers    map[string]string
 	modelInfo  *ModelInfo
+
+	// cachePrompt attaches cache_control breakpoints to the system prompt
+	// and the last tool definition.
+	cachePrompt bool
 }
 
 const (
@@ -56,7 +59,8 @@ func NewAnthropicClient(cfg *config.APIConfig, apiKey string) (*AnthropicClient,
 		httpClient: &http.Client{
 			Timeout: timeout,
 		},
-		headers: cfg.Headers,
+		headers:     cfg.Headers,
+		cachePrompt: cfg.CachePrompt,
 		modelInfo: &ModelInfo{
 			ID:             cfg.Model,
 			Provider:       "anthropic",
@@ -69,63 +73,15 @@ func NewAnthropicClient(cfg *config.APIConfig, apiKey string) (*AnthropicClient,
 
 // Chat sends a messages request and returns the response.
 func (c *AnthropicClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
-	// Build Anthropic messages request
-	anthReq := map[string]interface{}{
-		"model":      c.model,
-		"max_tokens": c.maxTokens,
-	}
-
-	if req.MaxTokens > 0 {
-		anthReq["max_tokens"] = req.MaxTokens
-	}
-	if req.Temperature != nil {
-		anthReq["temperature"] = *req.Temperature
-	}
-	if len(req.StopSeqs) > 0 {
-		anthReq["stop_sequences"] = req.StopSeqs
-	}
-
-	// Anthropic separates system messages from the messages array
-	system, messages := splitSystemMessages(req.Messages)
-	if system != "" {
-		anthReq["system"] = system
-	}
-	anthReq["messages"] = convertAnthropicMessages(messages)
-
-	if len(req.Tools) > 0 {
-		anthReq["tools"] = convertAnthropicTools(req.Tools)
-	}
-
-	body, err := json.Marshal(anthReq)
-	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
-	if c.apiKey != "" {
-		httpReq.Header.Set("x-api-key", c.apiKey)
-	}
-	for k, v := range c.headers {
-		httpReq.Header.Set(k, v)
+	if err := req.validateToolChoice(); err != nil {
+		return nil, err
 	}
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.post(ctx, c.buildRequest(req, false))
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		errBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(errBody))
-	}
-
 	var anthResp anthropicResponse
 	if err := json.NewDecoder(resp.Body).Decode(&anthResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
@@ -157,44 +113,229 @@ func (c *AnthropicClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResp
 	}
 
 	if anthResp.Usage != nil {
-		result.Usage = &Usage{
-			PromptTokens:     anthResp.Usage.InputTokens,
-			CompletionTokens: anthResp.Usage.OutputTokens,
-			TotalTokens:      anthResp.Usage.InputTokens + anthResp.Usage.OutputTokens,
+		result.Usage = anthResp.Usage.toUsage()
+	}
+
+	if req.wantsJSON() && len(result.ToolCalls) == 0 {
+		content, err := repairJSONContent(result.Content)
+		if err != nil {
+			return nil, err
 		}
+		result.Content = content
 	}
 
 	return result, nil
 }
 
 // Stream sends a streaming messages request.
+// Text deltas are emitted as they arrive. Tool-use input arrives as
+// input_json_delta fragments, which are reassembled per content block and
+// emitted as a complete ToolCallChunk on content_block_stop. The final Done
+// chunk carries the token usage reported by message_start/message_delta.
 func (c *AnthropicClient) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
-	// TODO: Implement SSE streaming with Anthropic's server-sent events
-	// For now, fall back to non-streaming and emit result as single chunk
-	ch := make(chan StreamChunk, 1)
+	if err := req.validateToolChoice(); err != nil {
+		return nil, err
+	}
+	resp, err := c.post(ctx, c.buildRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk, 16)
 
 	go func() {
 		defer close(ch)
+		defer func() { _ = resp.Body.Close() }()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
 
-		resp, err := c.Chat(ctx, req)
+		blocks := make(map[int]*anthropicStreamBlock)
+		var usage anthropicUsage
+
+		err := readSSE(resp.Body, func(event, data string) error {
+			var ev anthropicStreamEvent
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				return fmt.Errorf("decoding stream event: %w", err)
+			}
+			if event == "" {
+				event = ev.Type
+			}
+
+			switch event {
+			case "message_start":
+				if ev.Message != nil && ev.Message.Usage != nil {
+					usage = *ev.Message.Usage
+				}
+
+			case "content_block_start":
+				if ev.ContentBlock != nil && ev.ContentBlock.Type == "tool_use" {
+					blocks[ev.Index] = &anthropicStreamBlock{
+						id:   ev.ContentBlock.ID,
+						name: ev.ContentBlock.Name,
+					}
+				}
+
+			case "content_block_delta":
+				if ev.Delta == nil {
+					return nil
+				}
+				switch ev.Delta.Type {
+				case "text_delta":
+					if ev.Delta.Text != "" && !send(StreamChunk{Type: TextChunk, Text: ev.Delta.Text}) {
+						return ctx.Err()
+					}
+				case "input_json_delta":
+					if block, ok := blocks[ev.Index]; ok {
+						block.input.WriteString(ev.Delta.PartialJSON)
+					}
+				}
+
+			case "content_block_stop":
+				block, ok := blocks[ev.Index]
+				if !ok {
+					return nil
+				}
+				delete(blocks, ev.Index)
+				args := block.input.String()
+				if args == "" {
+					args = "{}"
+				}
+				tc := ToolCall{ID: block.id, Name: block.name, Args: json.RawMessage(args)}
+				if !send(StreamChunk{Type: ToolCallChunk, ToolCall: &tc}) {
+					return ctx.Err()
+				}
+
+			case "message_delta":
+				// output_tokens on message_delta is cumulative for the message.
+				if ev.Usage != nil && ev.Usage.OutputTokens > 0 {
+					usage.OutputTokens = ev.Usage.OutputTokens
+				}
+
+			case "message_stop":
+				return errStopSSE
+
+			case "error":
+				if ev.Error != nil {
+					return fmt.Errorf("API error in stream (%s): %s", ev.Error.Type, ev.Error.Message)
+				}
+				return fmt.Errorf("API error in stream")
+			}
+			return nil
+		})
 		if err != nil {
-			ch <- StreamChunk{Err: err, Done: true}
+			if ctx.Err() == nil {
+				send(StreamChunk{Err: err, Done: true})
+			}
 			return
 		}
 
-		if resp.Content != "" {
-			ch <- StreamChunk{Type: TextChunk, Text: resp.Content}
-		}
-		for _, tc := range resp.ToolCalls {
-			tcCopy := tc
-			ch <- StreamChunk{Type: ToolCallChunk, ToolCall: &tcCopy}
-		}
-		ch <- StreamChunk{Done: true}
+		send(StreamChunk{Done: true, Usage: usage.toUsage()})
 	}()
 
 	return ch, nil
 }
 
+// buildRequest converts a ChatRequest into the Anthropic Messages wire format.
+func (c *AnthropicClient) buildRequest(req *ChatRequest, stream bool) map[string]interface{} {
+	anthReq := map[string]interface{}{
+		"model":      c.model,
+		"max_tokens": c.maxTokens,
+	}
+
+	if stream {
+		anthReq["stream"] = true
+	}
+	if req.MaxTokens > 0 {
+		anthReq["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		anthReq["temperature"] = *req.Temperature
+	}
+	if len(req.StopSeqs) > 0 {
+		anthReq["stop_sequences"] = req.StopSeqs
+	}
+
+	// Anthropic separates system messages from the messages array
+	system, messages := splitSystemMessages(req.Messages)
+	if req.wantsJSON() {
+		// No native JSON mode; emulate it with a system instruction.
+		if system != "" {
+			system += "\n\n"
+		}
+		system += jsonInstruction(req)
+	}
+	if system != "" {
+		if c.cachePrompt {
+			// Caching requires the block form of the system prompt.
+			anthReq["system"] = []map[string]interface{}{
+				{
+					"type":          "text",
+					"text":          system,
+					"cache_control": anthropicEphemeralCache(),
+				},
+			}
+		} else {
+			anthReq["system"] = system
+		}
+	}
+	anthReq["messages"] = convertAnthropicMessages(messages)
+
+	if len(req.Tools) > 0 {
+		tools := convertAnthropicTools(req.Tools)
+		if c.cachePrompt {
+			// A breakpoint on the last tool caches the whole tool list.
+			tools[len(tools)-1]["cache_control"] = anthropicEphemeralCache()
+		}
+		anthReq["tools"] = tools
+		if tc := convertAnthropicToolChoice(req); tc != nil {
+			anthReq["tool_choice"] = tc
+		}
+	}
+
+	return anthReq
+}
+
+// post sends a messages request and returns the HTTP response.
+// Non-200 responses are converted to errors; the caller owns the body otherwise.
+func (c *AnthropicClient) post(ctx context.Context, anthReq map[string]interface{}) (*http.Response, error) {
+	body, err := json.Marshal(anthReq)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("anthropic-version", anthropicAPIVersion)
+	if c.apiKey != "" {
+		httpReq.Header.Set("x-api-key", c.apiKey)
+	}
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	return resp, nil
+}
+
 // ModelInfo returns information about the connected model.
 func (c *AnthropicClient) ModelInfo() *ModelInfo {
 	return c.modelInfo
@@ -237,8 +378,53 @@ type anthropicContent struct {
 }
 
 type anthropicUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// toUsage converts Anthropic usage to our Usage type. Anthropic reports
+// cached prompt tokens separately from input_tokens; they are folded into
+// PromptTokens so callers' token budgets see the full prompt size.
+func (u *anthropicUsage) toUsage() *Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return &Usage{
+		PromptTokens:        prompt,
+		CompletionTokens:    u.OutputTokens,
+		TotalTokens:         prompt + u.OutputTokens,
+		CacheCreationTokens: u.CacheCreationInputTokens,
+		CacheReadTokens:     u.CacheReadInputTokens,
+	}
+}
+
+type anthropicStreamEvent struct {
+	Type         string                `json:"type"`
+	Index        int                   `json:"index"`
+	Message      *anthropicResponse    `json:"message,omitempty"`
+	ContentBlock *anthropicContent     `json:"content_block,omitempty"`
+	Delta        *anthropicStreamDelta `json:"delta,omitempty"`
+	Usage        *anthropicUsage       `json:"usage,omitempty"`
+	Error        *anthropicStreamError `json:"error,omitempty"`
+}
+
+type anthropicStreamDelta struct {
+	Type        string `json:"type"`                   // "text_delta" or "input_json_delta"
+	Text        string `json:"text,omitempty"`         // for text_delta
+	PartialJSON string `json:"partial_json,omitempty"` // for input_json_delta
+	StopReason  string `json:"stop_reason,omitempty"`  // on message_delta
+}
+
+type anthropicStreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// anthropicStreamBlock accumulates a tool_use content block during streaming.
+type anthropicStreamBlock struct {
+	id    string
+	name  string
+	input strings.Builder
 }
 
 // --- Helpers ---
@@ -264,6 +450,11 @@ func splitSystemMessages(msgs []Message) (string, []Message) {
 	return system, rest
 }
 
+// anthropicEphemeralCache returns a cache_control marker for a content block.
+func anthropicEphemeralCache() map[string]interface{} {
+	return map[string]interface{}{"type": "ephemeral"}
+}
+
 // convertAnthropicMessages converts our Message type to Anthropic's format.
 func convertAnthropicMessages(msgs []Message) []map[string]interface{} {
 	var result []map[string]interface{}
@@ -331,6 +522,21 @@ func convertAnthropicTools(tools []ToolDef) []map[string]interface{} {
 	return result
 }
 
+// convertAnthropicToolChoice maps ToolChoice to Anthropic's tool_choice
+// object. It returns nil for auto, which is the API default.
+func convertAnthropicToolChoice(req *ChatRequest) map[string]interface{} {
+	switch req.ToolChoice {
+	case ToolChoiceNone:
+		return map[string]interface{}{"type": "none"}
+	case ToolChoiceRequired:
+		return map[string]interface{}{"type": "any"}
+	case ToolChoiceTool:
+		return map[string]interface{}{"type": "tool", "name": req.ToolChoiceName}
+	default:
+		return nil
+	}
+}
+
 // mapStopReason maps Anthropic stop reasons to OpenAI-compatible finish reasons.
 func mapStopReason(reason string) string {
 	switch reason {
@@ -0,0 +1,202 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func newTestAnthropicClient(t *testing.T, cfg *config.APIConfig, handler http.HandlerFunc) *AnthropicClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	if cfg == nil {
+		cfg = &config.APIConfig{}
+	}
+	cfg.BaseURL = srv.URL
+	if cfg.Model == "" {
+		cfg.Model = "claude-test"
+	}
+	client, err := NewAnthropicClient(cfg, "test-key")
+	if err != nil {
+		t.Fatalf("NewAnthropicClient: %v", err)
+	}
+	return client
+}
+
+func TestAnthropicStreamReassemblesToolUseAndReportsUsage(t *testing.T) {
+	events := []struct{ name, data string }{
+		{"message_start", `{"type":"message_start","message":{"usage":{"input_tokens":25,"output_tokens":1}}}`},
+		{"content_block_start", `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me "}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"check."}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":0}`},
+		{"ping", `{"type":"ping"}`},
+		{"content_block_start", `{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"bd_show","input":{}}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"issue_"}}`},
+		{"content_block_delta", `{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"id\":\"gt-1\"}"}}`},
+		{"content_block_stop", `{"type":"content_block_stop","index":1}`},
+		{"message_delta", `{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":17}}`},
+		{"message_stop", `{"type":"message_stop"}`},
+	}
+
+	client := newTestAnthropicClient(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range events {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.name, ev.data)
+		}
+	})
+
+	ch, err := client.Stream(context.Background(), &ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	var text string
+	var calls []ToolCall
+	var usage *Usage
+	for chunk := range ch {
+		if chunk.Err != nil {
+			t.Fatalf("stream error: %v", chunk.Err)
+		}
+		if chunk.Done {
+			usage = chunk.Usage
+			continue
+		}
+		switch chunk.Type {
+		case TextChunk:
+			text += chunk.Text
+		case ToolCallChunk:
+			calls = append(calls, *chunk.ToolCall)
+		}
+	}
+
+	if text != "Let me check." {
+		t.Errorf("text = %q", text)
+	}
+	if len(calls) != 1 || calls[0].ID != "toolu_1" || calls[0].Name != "bd_show" || string(calls[0].Args) != `{"issue_id":"gt-1"}` {
+		t.Fatalf("tool calls = %+v", calls)
+	}
+	if usage == nil || usage.PromptTokens != 25 || usage.CompletionTokens != 17 || usage.TotalTokens != 42 {
+		t.Errorf("usage = %+v, want 25/17/42", usage)
+	}
+}
+
+func TestAnthropicCachePromptMarksBreakpointsAndFoldsCacheUsage(t *testing.T) {
+	var got map[string]interface{}
+	client := newTestAnthropicClient(t, &config.APIConfig{CachePrompt: true}, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":10,"output_tokens":5,"cache_creation_input_tokens":100,"cache_read_input_tokens":900}}`))
+	})
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{
+		Messages: []Message{{Role: "system", Content: "sys"}, {Role: "user", Content: "hi"}},
+		Tools: []ToolDef{
+			{Name: "a", Parameters: json.RawMessage(`{"type":"object"}`)},
+			{Name: "b", Parameters: json.RawMessage(`{"type":"object"}`)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	system, ok := got["system"].([]interface{})
+	if !ok || len(system) != 1 || system[0].(map[string]interface{})["cache_control"] == nil {
+		t.Errorf("system = %#v, want one block with cache_control", got["system"])
+	}
+	tools := got["tools"].([]interface{})
+	if tools[0].(map[string]interface{})["cache_control"] != nil {
+		t.Error("first tool should not carry a cache breakpoint")
+	}
+	if tools[1].(map[string]interface{})["cache_control"] == nil {
+		t.Error("last tool should carry a cache breakpoint")
+	}
+
+	u := resp.Usage
+	if u.PromptTokens != 1010 || u.TotalTokens != 1015 || u.CacheReadTokens != 900 || u.CacheCreationTokens != 100 {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestAnthropicJSONModeEmulatedAndRepaired(t *testing.T) {
+	reply := "Here is the plan:\n```json\n{\"steps\": [\"a\", \"b\"]}\n```"
+	var got map[string]interface{}
+	client := newTestAnthropicClient(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		body, _ := json.Marshal(map[string]interface{}{
+			"content":     []map[string]string{{"type": "text", "text": reply}},
+			"stop_reason": "end_turn",
+		})
+		_, _ = w.Write(body)
+	})
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{
+		Messages:       []Message{{Role: "system", Content: "plan things"}, {Role: "user", Content: "go"}},
+		ResponseFormat: ResponseFormatJSONSchema,
+		ResponseSchema: json.RawMessage(`{"type":"object","required":["steps"]}`),
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != `{"steps": ["a", "b"]}` {
+		t.Errorf("content = %q", resp.Content)
+	}
+	system, _ := got["system"].(string)
+	if !strings.HasPrefix(system, "plan things") || !strings.Contains(system, `"required":["steps"]`) {
+		t.Errorf("system = %q, want original prompt plus schema instruction", system)
+	}
+
+	reply = "I can't do that."
+	_, err = client.Chat(context.Background(), &ChatRequest{
+		Messages:       []Message{{Role: "user", Content: "go"}},
+		ResponseFormat: ResponseFormatJSONObject,
+	})
+	if !errors.Is(err, ErrInvalidJSONResponse) {
+		t.Errorf("err = %v, want ErrInvalidJSONResponse", err)
+	}
+}
+
+func TestAnthropicToolChoiceMapping(t *testing.T) {
+	var got map[string]interface{}
+	client := newTestAnthropicClient(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+	})
+
+	_, err := client.Chat(context.Background(), &ChatRequest{
+		Messages:       []Message{{Role: "user", Content: "go"}},
+		Tools:          []ToolDef{{Name: "gt_prime", Parameters: json.RawMessage(`{"type":"object"}`)}},
+		ToolChoice:     ToolChoiceTool,
+		ToolChoiceName: "gt_prime",
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	tc, _ := got["tool_choice"].(map[string]interface{})
+	if tc["type"] != "tool" || tc["name"] != "gt_prime" {
+		t.Errorf("tool_choice = %#v", got["tool_choice"])
+	}
+
+	_, err = client.Chat(context.Background(), &ChatRequest{
+		Messages:   []Message{{Role: "user", Content: "go"}},
+		ToolChoice: ToolChoiceRequired,
+	})
+	if err == nil {
+		t.Error("required tool_choice without tools should fail")
+	}
+}
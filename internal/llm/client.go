@@ -9,6 +9,7 @@ package llm
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 )
 
 // Client is the interface for calling language models.
@@ -39,8 +40,67 @@ type ChatRequest struct {
 	MaxTokens   int        `json:"max_tokens,omitempty"`
 	Temperature *float64   `json:"temperature,omitempty"`
 	StopSeqs    []string   `json:"stop,omitempty"`
+
+	// ResponseFormat requests structured output. ResponseSchema is the
+	// JSON schema used with ResponseFormatJSONSchema.
+	ResponseFormat ResponseFormat  `json:"response_format,omitempty"`
+	ResponseSchema json.RawMessage `json:"response_schema,omitempty"`
+
+	// ToolChoice controls whether the model may or must call tools.
+	// ToolChoiceName names the tool to call with ToolChoiceTool.
+	ToolChoice     ToolChoice `json:"tool_choice,omitempty"`
+	ToolChoiceName string     `json:"tool_choice_name,omitempty"`
 }
 
+// ToolChoice selects how the model uses the request's tools.
+type ToolChoice string
+
+const (
+	// ToolChoiceAuto lets the model decide whether to call a tool (the default).
+	ToolChoiceAuto ToolChoice = ""
+	// ToolChoiceNone forbids tool calls.
+	ToolChoiceNone ToolChoice = "none"
+	// ToolChoiceRequired requires at least one tool call.
+	ToolChoiceRequired ToolChoice = "required"
+	// ToolChoiceTool requires a call to the tool named by ToolChoiceName.
+	ToolChoiceTool ToolChoice = "tool"
+)
+
+// validateToolChoice checks that ToolChoice is known and that a named tool
+// is one of req.Tools.
+func (req *ChatRequest) validateToolChoice() error {
+	switch req.ToolChoice {
+	case ToolChoiceAuto, ToolChoiceNone:
+		return nil
+	case ToolChoiceRequired:
+		if len(req.Tools) == 0 {
+			return fmt.Errorf("tool_choice %q requires tools", req.ToolChoice)
+		}
+		return nil
+	case ToolChoiceTool:
+		for _, t := range req.Tools {
+			if t.Name == req.ToolChoiceName {
+				return nil
+			}
+		}
+		return fmt.Errorf("tool_choice names unknown tool %q", req.ToolChoiceName)
+	default:
+		return fmt.Errorf("unknown tool_choice %q", req.ToolChoice)
+	}
+}
+
+// ResponseFormat selects the shape of the model's reply.
+type ResponseFormat string
+
+const (
+	// ResponseFormatText is free-form text (the default).
+	ResponseFormatText ResponseFormat = ""
+	// ResponseFormatJSONObject requires the reply to be a JSON object.
+	ResponseFormatJSONObject ResponseFormat = "json_object"
+	// ResponseFormatJSONSchema requires a JSON object matching ResponseSchema.
+	ResponseFormatJSONSchema ResponseFormat = "json_schema"
+)
+
 // Message represents a conversation message.
 type Message struct {
 	Role       string     `json:"role"`                  // "system", "user", "assistant", "tool"
@@ -79,6 +139,7 @@ type StreamChunk struct {
 	ToolCall *ToolCall // for ToolCallChunk (may be partial)
 	Done     bool      // true on final chunk
 	Err      error     // non-nil on stream error
+	Usage    *Usage    // token usage, set on the final chunk when the provider reports it
 }
 
 // ChunkType distinguishes text content from tool calls in streaming.
@@ -96,6 +157,11 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// Prompt-cache accounting, for providers that report it. Both are
+	// already included in PromptTokens.
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+	CacheReadTokens     int `json:"cache_read_tokens,omitempty"`
 }
 
 // ModelInfo describes the connected model.
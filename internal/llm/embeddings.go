@@ -0,0 +1,97 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Embedder is implemented by clients that can compute text embeddings.
+// Not every provider supports embeddings, so callers check for it:
+//
+//	if e, ok := client.(Embedder); ok { ... }
+type Embedder interface {
+	// Embed returns one vector per input, in input order.
+	Embed(ctx context.Context, inputs []string) ([][]float32, *Usage, error)
+
+	// EmbeddingInfo describes the embedding model.
+	EmbeddingInfo() *EmbeddingInfo
+}
+
+// EmbeddingInfo describes the embedding model behind an Embedder.
+type EmbeddingInfo struct {
+	Model      string `json:"model"`
+	Provider   string `json:"provider"`
+	Dimensions int    `json:"dimensions"` // 0 until known
+}
+
+// ErrEmbeddingsNotConfigured is returned by Embed when no embedding model
+// is configured.
+var ErrEmbeddingsNotConfigured = errors.New("embedding_model is not configured")
+
+// Embed computes embeddings for inputs via the /embeddings endpoint.
+func (c *OpenAIClient) Embed(ctx context.Context, inputs []string) ([][]float32, *Usage, error) {
+	if c.embeddingModel == "" {
+		return nil, nil, ErrEmbeddingsNotConfigured
+	}
+	if len(inputs) == 0 {
+		return nil, &Usage{}, nil
+	}
+
+	payload := map[string]interface{}{
+		"model": c.embeddingModel,
+		"input": inputs,
+	}
+	if dims := c.embeddingDims.Load(); dims > 0 {
+		payload["dimensions"] = dims
+	}
+
+	resp, err := c.postJSON(ctx, c.endpoint(c.embeddingModel, "/embeddings"), payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var embResp openAIEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if len(embResp.Data) != len(inputs) {
+		return nil, nil, fmt.Errorf("got %d embeddings for %d inputs", len(embResp.Data), len(inputs))
+	}
+
+	sort.Slice(embResp.Data, func(i, j int) bool { return embResp.Data[i].Index < embResp.Data[j].Index })
+	vectors := make([][]float32, len(embResp.Data))
+	for i, d := range embResp.Data {
+		vectors[i] = d.Embedding
+	}
+	c.embeddingDims.CompareAndSwap(0, int64(len(vectors[0])))
+
+	usage := &Usage{
+		PromptTokens: embResp.Usage.PromptTokens,
+		TotalTokens:  embResp.Usage.TotalTokens,
+	}
+	return vectors, usage, nil
+}
+
+// EmbeddingInfo returns information about the embedding model.
+func (c *OpenAIClient) EmbeddingInfo() *EmbeddingInfo {
+	return &EmbeddingInfo{
+		Model:      c.embeddingModel,
+		Provider:   c.modelInfo.Provider,
+		Dimensions: int(c.embeddingDims.Load()),
+	}
+}
+
+type openAIEmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func TestOpenAIEmbedOrdersVectorsAndLearnsDimensions(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{
+			"data": [
+				{"index": 1, "embedding": [0.3, 0.4, 0.5]},
+				{"index": 0, "embedding": [0.0, 0.1, 0.2]}
+			],
+			"usage": {"prompt_tokens": 7, "total_tokens": 7}
+		}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewOpenAIClient(&config.APIConfig{
+		BaseURL:        srv.URL,
+		Model:          "test-model",
+		EmbeddingModel: "test-embed",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e Embedder = client
+	if dims := e.EmbeddingInfo().Dimensions; dims != 0 {
+		t.Errorf("dimensions before first call = %d, want 0", dims)
+	}
+
+	vectors, usage, err := e.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if got["model"] != "test-embed" {
+		t.Errorf("model = %v", got["model"])
+	}
+	if _, ok := got["dimensions"]; ok {
+		t.Error("dimensions should be omitted when not configured")
+	}
+	if len(vectors) != 2 || vectors[0][1] != 0.1 || vectors[1][0] != 0.3 {
+		t.Errorf("vectors = %v, want input order", vectors)
+	}
+	if usage.PromptTokens != 7 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if info := e.EmbeddingInfo(); info.Dimensions != 3 || info.Model != "test-embed" {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestOpenAIEmbedRequiresModel(t *testing.T) {
+	client, err := NewOpenAIClient(&config.APIConfig{BaseURL: "http://unused", Model: "m"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.Embed(context.Background(), []string{"x"}); !errors.Is(err, ErrEmbeddingsNotConfigured) {
+		t.Errorf("err = %v, want ErrEmbeddingsNotConfigured", err)
+	}
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned by the provider clients when the API responds with a
+// non-200 status. It preserves the status code and any Retry-After guidance
+// so WithRetry can back off the way the server asked.
+type APIError struct {
+	StatusCode int
+	Body       string
+
+	// RetryAfter is the delay requested by the server via Retry-After
+	// (or retry-after-ms). Zero when the server gave no guidance.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if retried: rate limits,
+// request timeouts and server errors. Other 4xx responses are permanent.
+func (e *APIError) Retryable() bool {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests, e.StatusCode == http.StatusRequestTimeout:
+		return true
+	case e.StatusCode >= 400 && e.StatusCode < 500:
+		return false
+	default:
+		return true
+	}
+}
+
+// newAPIError builds an APIError from a non-200 response, consuming and
+// closing its body.
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+}
+
+// parseRetryAfter reads the server's requested retry delay. Retry-After may
+// be delay-seconds or an HTTP date; OpenAI additionally sends retry-after-ms.
+func parseRetryAfter(h http.Header, now time.Time) time.Duration {
+	if ms := strings.TrimSpace(h.Get("retry-after-ms")); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v > 0 {
+			return time.Duration(v * float64(time.Millisecond))
+		}
+	}
+
+	ra := strings.TrimSpace(h.Get("Retry-After"))
+	if ra == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(ra); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(ra); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
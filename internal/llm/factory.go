@@ -32,10 +32,20 @@ func NewClient(cfg *config.APIConfig) (Client, error) {
 		}
 		return NewOpenAIClient(cfg, apiKey)
 
+	case "azure-openai":
+		if strings.TrimSpace(cfg.BaseURL) == "" {
+			return nil, fmt.Errorf("base_url is required for api_type=%q", apiType)
+		}
+		return NewAzureOpenAIClient(cfg, apiKey)
+
 	case "anthropic":
 		// base_url is optional; NewAnthropicClient defaults to https://api.anthropic.com
 		return NewAnthropicClient(cfg, apiKey)
 
+	case "gemini":
+		// base_url is optional; NewGeminiClient defaults to https://generativelanguage.googleapis.com
+		return NewGeminiClient(cfg, apiKey)
+
 	default:
 		return nil, fmt.Errorf("unsupported api_type: %q", cfg.APIType)
 	}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// fallbackClient tries a primary client and fails over to the next
+// configured client on transient failures (connection errors, 5xx, 429).
+type fallbackClient struct {
+	clients []Client
+
+	mu     sync.Mutex
+	active int // index of the client that served the most recent request
+}
+
+// WithFallback returns a Client that sends each request to primary first and,
+// if it fails with a transient error, moves on to each fallback in order.
+// Permanent errors (4xx other than 429, cancellation) are returned as-is,
+// since another endpoint would reject the same request. Wrap each client
+// with WithRetry first if it should be retried before failing over.
+//
+// Stream fails over only before the first chunk is delivered; once output
+// has been emitted, errors are passed through to the caller.
+func WithFallback(primary Client, fallbacks ...Client) Client {
+	var clients []Client
+	for _, c := range append([]Client{primary}, fallbacks...) {
+		if c != nil {
+			clients = append(clients, c)
+		}
+	}
+	switch len(clients) {
+	case 0:
+		return nil
+	case 1:
+		return clients[0]
+	}
+	return &fallbackClient{clients: clients}
+}
+
+func (c *fallbackClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	var lastErr error
+	for i, client := range c.clients {
+		resp, err := client.Chat(ctx, req)
+		if err == nil {
+			c.setActive(i)
+			return resp, nil
+		}
+		lastErr = err
+		if !c.shouldFailOver(ctx, i, err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("all %d LLM endpoints failed: %w", len(c.clients), lastErr)
+}
+
+func (c *fallbackClient) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
+	var lastErr error
+	for i, client := range c.clients {
+		ch, err := client.Stream(ctx, req)
+		if err != nil {
+			lastErr = err
+			if !c.shouldFailOver(ctx, i, err) {
+				return nil, err
+			}
+			continue
+		}
+
+		// Peek at the first chunk: a stream that fails before producing any
+		// output can still be failed over without the caller noticing.
+		first, ok := <-ch
+		if !ok {
+			first = StreamChunk{Done: true}
+		}
+		if first.Err != nil {
+			lastErr = first.Err
+			if !c.shouldFailOver(ctx, i, first.Err) {
+				return replayStream(ctx, first, nil), nil
+			}
+			continue
+		}
+
+		c.setActive(i)
+		if !ok {
+			return replayStream(ctx, first, nil), nil
+		}
+		return replayStream(ctx, first, ch), nil
+	}
+	return nil, fmt.Errorf("all %d LLM endpoints failed: %w", len(c.clients), lastErr)
+}
+
+// ModelInfo reports the model of the client that served the most recent request.
+func (c *fallbackClient) ModelInfo() *ModelInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clients[c.active].ModelInfo()
+}
+
+// Ping returns nil if any configured client is reachable.
+func (c *fallbackClient) Ping(ctx context.Context) error {
+	var errs []error
+	for _, client := range c.clients {
+		err := client.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return fmt.Errorf("no LLM endpoint reachable: %w", errors.Join(errs...))
+}
+
+func (c *fallbackClient) Close() error {
+	var errs []error
+	for _, client := range c.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *fallbackClient) setActive(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.active != i {
+		if mi := c.clients[i].ModelInfo(); mi != nil {
+			log.Printf("[llm] switching to endpoint %d (%s)", i, mi.ID)
+		} else {
+			log.Printf("[llm] switching to endpoint %d", i)
+		}
+	}
+	c.active = i
+}
+
+// shouldFailOver reports whether err from client i warrants trying the next client.
+func (c *fallbackClient) shouldFailOver(ctx context.Context, i int, err error) bool {
+	if ctx.Err() != nil || !isRetryableLLMError(err) {
+		return false
+	}
+	if i < len(c.clients)-1 {
+		log.Printf("[llm] endpoint %d failed, failing over: %v", i, err)
+	}
+	return true
+}
+
+// replayStream returns a channel that yields first and then everything from
+// rest (if non-nil).
+func replayStream(ctx context.Context, first StreamChunk, rest <-chan StreamChunk) <-chan StreamChunk {
+	out := make(chan StreamChunk, 16)
+	go func() {
+		defer close(out)
+		out <- first
+		if rest == nil {
+			return
+		}
+		for chunk := range rest {
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
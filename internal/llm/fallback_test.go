@@ -0,0 +1,150 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// fakeClient is a Client whose Chat/Stream/Ping results are set per test.
+type fakeClient struct {
+	id        string
+	chatErr   error
+	streamErr error // returned from Stream itself
+	chunkErr  error // delivered as the first chunk of the stream
+	pingErr   error
+	chats     int
+	closed    bool
+}
+
+func (f *fakeClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	f.chats++
+	if f.chatErr != nil {
+		return nil, f.chatErr
+	}
+	return &ChatResponse{Content: f.id}, nil
+}
+
+func (f *fakeClient) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
+	if f.streamErr != nil {
+		return nil, f.streamErr
+	}
+	ch := make(chan StreamChunk, 2)
+	if f.chunkErr != nil {
+		ch <- StreamChunk{Err: f.chunkErr, Done: true}
+	} else {
+		ch <- StreamChunk{Type: TextChunk, Text: f.id}
+		ch <- StreamChunk{Done: true}
+	}
+	close(ch)
+	return ch, nil
+}
+
+func (f *fakeClient) ModelInfo() *ModelInfo          { return &ModelInfo{ID: f.id} }
+func (f *fakeClient) Ping(ctx context.Context) error { return f.pingErr }
+func (f *fakeClient) Close() error                   { f.closed = true; return nil }
+
+// bareClient is a fakeClient without model info.
+type bareClient struct{ fakeClient }
+
+func (b *bareClient) ModelInfo() *ModelInfo { return nil }
+
+var errConnRefused = errors.New("HTTP request failed: dial tcp 10.0.0.5:8000: connect: connection refused")
+
+func TestFallbackChatFailsOverOnTransientError(t *testing.T) {
+	primary := &fakeClient{id: "primary", chatErr: &APIError{StatusCode: http.StatusBadGateway}}
+	secondary := &fakeClient{id: "secondary", chatErr: errConnRefused}
+	tertiary := &fakeClient{id: "tertiary"}
+	client := WithFallback(primary, secondary, tertiary)
+
+	if got := client.ModelInfo().ID; got != "primary" {
+		t.Errorf("initial ModelInfo = %q, want primary", got)
+	}
+	resp, err := client.Chat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != "tertiary" {
+		t.Errorf("served by %q, want tertiary", resp.Content)
+	}
+	if got := client.ModelInfo().ID; got != "tertiary" {
+		t.Errorf("ModelInfo after failover = %q, want tertiary", got)
+	}
+
+	// Once the primary recovers it is tried first again.
+	primary.chatErr = nil
+	if resp, _ := client.Chat(context.Background(), &ChatRequest{}); resp.Content != "primary" {
+		t.Errorf("served by %q after recovery, want primary", resp.Content)
+	}
+
+	if err := client.Close(); err != nil || !primary.closed || !secondary.closed || !tertiary.closed {
+		t.Errorf("Close: err=%v, not all clients closed", err)
+	}
+}
+
+func TestFallbackChatDoesNotFailOverOnPermanentError(t *testing.T) {
+	primary := &fakeClient{id: "primary", chatErr: &APIError{StatusCode: http.StatusBadRequest}}
+	secondary := &fakeClient{id: "secondary"}
+	client := WithFallback(primary, secondary)
+
+	_, err := client.Chat(context.Background(), &ChatRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("err = %v, want the primary's 400", err)
+	}
+	if secondary.chats != 0 {
+		t.Error("secondary should not be tried on a 400")
+	}
+}
+
+func TestFallbackChatAllFail(t *testing.T) {
+	client := WithFallback(&fakeClient{chatErr: errConnRefused}, &fakeClient{chatErr: errConnRefused})
+	if _, err := client.Chat(context.Background(), &ChatRequest{}); !errors.Is(err, errConnRefused) {
+		t.Fatalf("err = %v, want wrapped last error", err)
+	}
+}
+
+func TestFallbackStreamFailsOverBeforeFirstChunk(t *testing.T) {
+	client := WithFallback(
+		&fakeClient{id: "a", streamErr: errConnRefused},
+		&fakeClient{id: "b", chunkErr: &APIError{StatusCode: http.StatusServiceUnavailable}},
+		&fakeClient{id: "c"},
+	)
+
+	ch, err := client.Stream(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	text, _, streamErr := collectStream(t, ch)
+	if streamErr != nil || text != "c" {
+		t.Errorf("stream text=%q err=%v, want text from c", text, streamErr)
+	}
+	if got := client.ModelInfo().ID; got != "c" {
+		t.Errorf("ModelInfo = %q, want c", got)
+	}
+}
+
+func TestFallbackPing(t *testing.T) {
+	down := &fakeClient{pingErr: errConnRefused}
+	if err := WithFallback(down, &fakeClient{}).Ping(context.Background()); err != nil {
+		t.Errorf("Ping with one reachable client: %v", err)
+	}
+	if err := WithFallback(down, &fakeClient{pingErr: errConnRefused}).Ping(context.Background()); err == nil {
+		t.Error("Ping should fail when no client is reachable")
+	}
+}
+
+func TestFallbackSwitchesToClientWithoutModelInfo(t *testing.T) {
+	primary := &fakeClient{id: "primary", chatErr: errConnRefused}
+	secondary := &bareClient{fakeClient{id: "secondary"}}
+	client := WithFallback(primary, secondary)
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != "secondary" {
+		t.Errorf("Content = %q, want secondary", resp.Content)
+	}
+}
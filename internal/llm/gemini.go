@@ -0,0 +1,494 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+// GeminiClient implements Client for Google's Gemini API
+// (generativelanguage.googleapis.com, v1beta generateContent).
+type GeminiClient struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	maxTokens  int
+	httpClient *http.Client
+	headers    map[string]string
+	modelInfo  *ModelInfo
+}
+
+const geminiDefaultBaseURL = "https://generativelanguage.googleapis.com"
+
+// NewGeminiClient creates a client for the Gemini generateContent API.
+func NewGeminiClient(cfg *config.APIConfig, apiKey string) (*GeminiClient, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = geminiDefaultBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	timeout := 300 * time.Second
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	return &GeminiClient{
+		baseURL:   baseURL,
+		apiKey:    apiKey,
+		model:     strings.TrimPrefix(cfg.Model, "models/"),
+		maxTokens: cfg.MaxTokens,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+		headers: cfg.Headers,
+		modelInfo: &ModelInfo{
+			ID:             cfg.Model,
+			Provider:       "gemini",
+			ContextWindow:  cfg.ContextWindow,
+			SupportsTools:  cfg.SupportsTools,
+			SupportsVision: cfg.SupportsVision,
+		},
+	}, nil
+}
+
+// Chat sends a generateContent request and returns the response.
+func (c *GeminiClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if err := req.validateToolChoice(); err != nil {
+		return nil, err
+	}
+	resp, err := c.post(ctx, ":generateContent", c.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var gemResp geminiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&gemResp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+
+	if len(gemResp.Candidates) == 0 {
+		if gemResp.PromptFeedback != nil && gemResp.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("prompt blocked: %s", gemResp.PromptFeedback.BlockReason)
+		}
+		return nil, fmt.Errorf("no candidates in response")
+	}
+
+	candidate := gemResp.Candidates[0]
+	result := &ChatResponse{
+		FinishReason: mapGeminiFinishReason(candidate.FinishReason),
+	}
+
+	for i, part := range candidate.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			result.ToolCalls = append(result.ToolCalls, geminiToolCall(part.FunctionCall, i))
+		case part.Text != "":
+			result.Content += part.Text
+		}
+	}
+	if len(result.ToolCalls) > 0 {
+		result.FinishReason = "tool_calls"
+	}
+
+	result.Usage = gemResp.UsageMetadata.toUsage()
+
+	if req.wantsJSON() && len(result.ToolCalls) == 0 {
+		content, err := repairJSONContent(result.Content)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = content
+	}
+
+	return result, nil
+}
+
+// Stream sends a streamGenerateContent request over SSE.
+// Each frame is a partial GenerateContentResponse: text parts are emitted as
+// they arrive, and function calls (which Gemini always sends whole) are
+// emitted as ToolCallChunks. The final Done chunk carries token usage.
+func (c *GeminiClient) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
+	if err := req.validateToolChoice(); err != nil {
+		return nil, err
+	}
+	resp, err := c.post(ctx, ":streamGenerateContent?alt=sse", c.buildRequest(req))
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk, 16)
+
+	go func() {
+		defer close(ch)
+		defer func() { _ = resp.Body.Close() }()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var usage *Usage
+		callIndex := 0
+
+		err := readSSE(resp.Body, func(_, data string) error {
+			var frame geminiResponse
+			if err := json.Unmarshal([]byte(data), &frame); err != nil {
+				return fmt.Errorf("decoding stream frame: %w", err)
+			}
+			if u := frame.UsageMetadata.toUsage(); u != nil {
+				usage = u
+			}
+			if len(frame.Candidates) == 0 {
+				return nil
+			}
+			for _, part := range frame.Candidates[0].Content.Parts {
+				switch {
+				case part.FunctionCall != nil:
+					tc := geminiToolCall(part.FunctionCall, callIndex)
+					callIndex++
+					if !send(StreamChunk{Type: ToolCallChunk, ToolCall: &tc}) {
+						return ctx.Err()
+					}
+				case part.Text != "":
+					if !send(StreamChunk{Type: TextChunk, Text: part.Text}) {
+						return ctx.Err()
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				send(StreamChunk{Err: err, Done: true})
+			}
+			return
+		}
+
+		send(StreamChunk{Done: true, Usage: usage})
+	}()
+
+	return ch, nil
+}
+
+// ModelInfo returns information about the connected model.
+func (c *GeminiClient) ModelInfo() *ModelInfo {
+	return c.modelInfo
+}
+
+// Ping checks if the API endpoint is reachable by fetching the model resource.
+func (c *GeminiClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/v1beta/models/"+c.model, nil)
+	if err != nil {
+		return err
+	}
+	c.setHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("endpoint unreachable: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close releases HTTP client resources.
+func (c *GeminiClient) Close() error {
+	c.httpClient.CloseIdleConnections()
+	return nil
+}
+
+// buildRequest converts a ChatRequest into the Gemini wire format.
+func (c *GeminiClient) buildRequest(req *ChatRequest) map[string]interface{} {
+	gemReq := map[string]interface{}{}
+
+	// Gemini takes system messages as a top-level systemInstruction
+	system, messages := splitSystemMessages(req.Messages)
+	if req.ResponseFormat == ResponseFormatJSONSchema && len(req.ResponseSchema) > 0 {
+		// responseMimeType enforces JSON; the schema is conveyed as an instruction.
+		if system != "" {
+			system += "\n\n"
+		}
+		system += jsonInstruction(req)
+	}
+	if system != "" {
+		gemReq["systemInstruction"] = map[string]interface{}{
+			"parts": []map[string]interface{}{{"text": system}},
+		}
+	}
+	gemReq["contents"] = convertGeminiContents(messages)
+
+	if len(req.Tools) > 0 {
+		gemReq["tools"] = []map[string]interface{}{
+			{"functionDeclarations": convertGeminiTools(req.Tools)},
+		}
+		if tc := convertGeminiToolConfig(req); tc != nil {
+			gemReq["toolConfig"] = tc
+		}
+	}
+
+	genCfg := map[string]interface{}{}
+	maxTokens := c.maxTokens
+	if req.MaxTokens > 0 {
+		maxTokens = req.MaxTokens
+	}
+	if maxTokens > 0 {
+		genCfg["maxOutputTokens"] = maxTokens
+	}
+	if req.Temperature != nil {
+		genCfg["temperature"] = *req.Temperature
+	}
+	if len(req.StopSeqs) > 0 {
+		genCfg["stopSequences"] = req.StopSeqs
+	}
+	if req.wantsJSON() {
+		genCfg["responseMimeType"] = "application/json"
+	}
+	if len(genCfg) > 0 {
+		gemReq["generationConfig"] = genCfg
+	}
+
+	return gemReq
+}
+
+// post sends a request to the model's method endpoint (e.g. ":generateContent").
+// Non-200 responses are converted to errors; the caller owns the body otherwise.
+func (c *GeminiClient) post(ctx context.Context, method string, gemReq map[string]interface{}) (*http.Response, error) {
+	body, err := json.Marshal(gemReq)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	url := c.baseURL + "/v1beta/models/" + c.model + method
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	c.setHeaders(httpReq)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	return resp, nil
+}
+
+func (c *GeminiClient) setHeaders(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("x-goog-api-key", c.apiKey)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+// --- Gemini wire format types ---
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate     `json:"candidates"`
+	UsageMetadata  *geminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text         string              `json:"text,omitempty"`
+	FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason"`
+}
+
+func (u *geminiUsageMetadata) toUsage() *Usage {
+	if u == nil {
+		return nil
+	}
+	total := u.TotalTokenCount
+	if total == 0 {
+		total = u.PromptTokenCount + u.CandidatesTokenCount
+	}
+	return &Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount,
+		TotalTokens:      total,
+	}
+}
+
+// --- Helpers ---
+
+// geminiToolCall converts a Gemini functionCall part into a ToolCall.
+// Older Gemini models don't assign call IDs, so one is synthesized from the
+// part index to keep tool results correlated within the turn.
+func geminiToolCall(fc *geminiFunctionCall, index int) ToolCall {
+	id := fc.ID
+	if id == "" {
+		id = fmt.Sprintf("gemini-call-%d", index)
+	}
+	args := fc.Args
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	return ToolCall{ID: id, Name: fc.Name, Args: args}
+}
+
+// convertGeminiContents converts our Message type to Gemini's contents format.
+// Assistant turns use role "model"; tool results become functionResponse parts
+// on a "user" turn, with consecutive results merged into a single turn.
+func convertGeminiContents(msgs []Message) []map[string]interface{} {
+	var result []map[string]interface{}
+	var pendingResponses []map[string]interface{}
+
+	flushResponses := func() {
+		if len(pendingResponses) == 0 {
+			return
+		}
+		result = append(result, map[string]interface{}{
+			"role":  "user",
+			"parts": pendingResponses,
+		})
+		pendingResponses = nil
+	}
+
+	for _, m := range msgs {
+		if m.Role == "tool" {
+			pendingResponses = append(pendingResponses, map[string]interface{}{
+				"functionResponse": map[string]interface{}{
+					"name": m.Name,
+					"response": map[string]interface{}{
+						"content": m.Content,
+					},
+				},
+			})
+			continue
+		}
+		flushResponses()
+
+		role := "user"
+		if m.Role == "assistant" {
+			role = "model"
+		}
+
+		var parts []map[string]interface{}
+		if m.Content != "" {
+			parts = append(parts, map[string]interface{}{"text": m.Content})
+		}
+		for _, tc := range m.ToolCalls {
+			var args interface{}
+			if err := json.Unmarshal(tc.Args, &args); err != nil {
+				args = map[string]interface{}{}
+			}
+			parts = append(parts, map[string]interface{}{
+				"functionCall": map[string]interface{}{
+					"name": tc.Name,
+					"args": args,
+				},
+			})
+		}
+		if len(parts) == 0 {
+			parts = append(parts, map[string]interface{}{"text": ""})
+		}
+
+		result = append(result, map[string]interface{}{
+			"role":  role,
+			"parts": parts,
+		})
+	}
+	flushResponses()
+
+	return result
+}
+
+// convertGeminiTools converts our ToolDef type to Gemini functionDeclarations.
+func convertGeminiTools(tools []ToolDef) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, t := range tools {
+		decl := map[string]interface{}{
+			"name":        t.Name,
+			"description": t.Description,
+		}
+		var schema map[string]interface{}
+		if err := json.Unmarshal(t.Parameters, &schema); err == nil {
+			// Gemini rejects an empty properties object; omit parameters instead.
+			if props, ok := schema["properties"].(map[string]interface{}); !ok || len(props) > 0 {
+				decl["parameters"] = schema
+			}
+		}
+		result = append(result, decl)
+	}
+	return result
+}
+
+// convertGeminiToolConfig maps ToolChoice to Gemini's functionCallingConfig.
+// It returns nil for auto, which is the API default.
+func convertGeminiToolConfig(req *ChatRequest) map[string]interface{} {
+	var cfg map[string]interface{}
+	switch req.ToolChoice {
+	case ToolChoiceNone:
+		cfg = map[string]interface{}{"mode": "NONE"}
+	case ToolChoiceRequired:
+		cfg = map[string]interface{}{"mode": "ANY"}
+	case ToolChoiceTool:
+		cfg = map[string]interface{}{"mode": "ANY", "allowedFunctionNames": []string{req.ToolChoiceName}}
+	default:
+		return nil
+	}
+	return map[string]interface{}{"functionCallingConfig": cfg}
+}
+
+// mapGeminiFinishReason maps Gemini finish reasons to OpenAI-compatible ones.
+func mapGeminiFinishReason(reason string) string {
+	switch reason {
+	case "STOP":
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "content_filter"
+	default:
+		return strings.ToLower(reason)
+	}
+}
@@ -0,0 +1,102 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func newTestGeminiClient(t *testing.T, handler http.HandlerFunc) *GeminiClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := NewGeminiClient(&config.APIConfig{
+		BaseURL:       srv.URL,
+		Model:         "gemini-test",
+		ContextWindow: 1000000,
+	}, "test-key")
+	if err != nil {
+		t.Fatalf("NewGeminiClient: %v", err)
+	}
+	return client
+}
+
+func TestGeminiChatMapsMessagesAndToolCalls(t *testing.T) {
+	var got map[string]interface{}
+	client := newTestGeminiClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-test:generateContent" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("missing api key header")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{
+			"candidates": [{"content": {"role": "model", "parts": [
+				{"text": "Checking."},
+				{"functionCall": {"name": "bd_show", "args": {"issue_id": "gt-1"}}}
+			]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 5, "totalTokenCount": 17}
+		}`))
+	})
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{
+		Messages: []Message{
+			{Role: "system", Content: "be terse"},
+			{Role: "user", Content: "show gt-1"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Name: "bd_list", Args: json.RawMessage(`{}`)}}},
+			{Role: "tool", ToolCallID: "c1", Name: "bd_list", Content: "gt-1"},
+		},
+		Tools: []ToolDef{{Name: "bd_show", Description: "show", Parameters: json.RawMessage(`{"type":"object","properties":{"issue_id":{"type":"string"}}}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	sys, _ := json.Marshal(got["systemInstruction"])
+	if !strings.Contains(string(sys), "be terse") {
+		t.Errorf("systemInstruction = %s", sys)
+	}
+	contents, _ := got["contents"].([]interface{})
+	if len(contents) != 3 {
+		t.Fatalf("contents len = %d, want 3", len(contents))
+	}
+	if role := contents[1].(map[string]interface{})["role"]; role != "model" {
+		t.Errorf("assistant role = %v, want model", role)
+	}
+	last, _ := json.Marshal(contents[2])
+	if !strings.Contains(string(last), `"functionResponse"`) || !strings.Contains(string(last), `"bd_list"`) {
+		t.Errorf("tool result = %s", last)
+	}
+	tools, _ := json.Marshal(got["tools"])
+	if !strings.Contains(string(tools), `"functionDeclarations"`) {
+		t.Errorf("tools = %s", tools)
+	}
+
+	if resp.Content != "Checking." {
+		t.Errorf("content = %q", resp.Content)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Name != "bd_show" || string(resp.ToolCalls[0].Args) != `{"issue_id": "gt-1"}` {
+		t.Errorf("tool calls = %+v", resp.ToolCalls)
+	}
+	if resp.ToolCalls[0].ID == "" {
+		t.Error("expected synthesized tool call ID")
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("finish reason = %q", resp.FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 17 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+	if info := client.ModelInfo(); info.Provider != "gemini" || info.ContextWindow != 1000000 {
+		t.Errorf("model info = %+v", info)
+	}
+}
@@ -0,0 +1,226 @@
+// Package llmtest provides a scripted llm.Client for tests.
+package llmtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+// ErrScriptExhausted is returned once every scripted turn has been used.
+var ErrScriptExhausted = errors.New("llmtest: script exhausted")
+
+// Turn is one scripted model reply: a response, or an error if Err is set.
+type Turn struct {
+	Response *llm.ChatResponse
+	Err      error
+}
+
+// Reply is a turn that answers with text and no tool calls, which ends an
+// agent loop task.
+func Reply(content string) Turn {
+	return Turn{Response: &llm.ChatResponse{Content: content, FinishReason: "stop"}}
+}
+
+// ToolCall is a turn that calls one tool. args is marshaled to JSON.
+func ToolCall(id, name string, args interface{}) Turn {
+	return ToolCalls(llm.ToolCall{ID: id, Name: name, Args: mustJSON(args)})
+}
+
+// ToolCalls is a turn that calls several tools at once.
+func ToolCalls(calls ...llm.ToolCall) Turn {
+	return Turn{Response: &llm.ChatResponse{ToolCalls: calls, FinishReason: "tool_calls"}}
+}
+
+// Fail is a turn whose Chat or Stream call returns err.
+func Fail(err error) Turn {
+	return Turn{Err: err}
+}
+
+// WithUsage returns t with its response reporting the given token usage.
+func (t Turn) WithUsage(prompt, completion int) Turn {
+	if t.Response != nil {
+		resp := *t.Response
+		resp.Usage = &llm.Usage{PromptTokens: prompt, CompletionTokens: completion, TotalTokens: prompt + completion}
+		t.Response = &resp
+	}
+	return t
+}
+
+var _ llm.Client = (*FakeClient)(nil)
+
+// FakeClient is an llm.Client that plays back scripted turns in order, one
+// per Chat or Stream call, and records every request it receives. It is safe
+// for concurrent use.
+type FakeClient struct {
+	mu       sync.Mutex
+	model    llm.ModelInfo
+	turns    []Turn
+	requests []*llm.ChatRequest
+	pingErr  error
+	closed   bool
+}
+
+// NewFakeClient returns a client that replies with turns in order. Its model
+// is "fake" with a 128k context window unless SetModel is called.
+func NewFakeClient(turns ...Turn) *FakeClient {
+	return &FakeClient{
+		model: llm.ModelInfo{ID: "fake", Provider: "fake", ContextWindow: 128000, SupportsTools: true},
+		turns: turns,
+	}
+}
+
+// Add appends turns to the script.
+func (f *FakeClient) Add(turns ...Turn) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.turns = append(f.turns, turns...)
+}
+
+// SetModel sets the ModelInfo the client reports.
+func (f *FakeClient) SetModel(info llm.ModelInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.model = info
+}
+
+// SetPingError makes Ping return err.
+func (f *FakeClient) SetPingError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pingErr = err
+}
+
+// Requests returns copies of the requests received so far, in order.
+func (f *FakeClient) Requests() []*llm.ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*llm.ChatRequest(nil), f.requests...)
+}
+
+// LastRequest returns the most recent request, or nil if there was none.
+func (f *FakeClient) LastRequest() *llm.ChatRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return nil
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+// Remaining reports how many scripted turns have not been used.
+func (f *FakeClient) Remaining() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.turns)
+}
+
+// Closed reports whether Close has been called.
+func (f *FakeClient) Closed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.closed
+}
+
+// next records req and pops the next turn.
+func (f *FakeClient) next(req *llm.ChatRequest) (Turn, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cp := *req
+	cp.Messages = append([]llm.Message(nil), req.Messages...)
+	cp.Tools = append([]llm.ToolDef(nil), req.Tools...)
+	f.requests = append(f.requests, &cp)
+
+	if len(f.turns) == 0 {
+		return Turn{}, ErrScriptExhausted
+	}
+	turn := f.turns[0]
+	f.turns = f.turns[1:]
+	if turn.Err == nil && turn.Response == nil {
+		return Turn{}, fmt.Errorf("llmtest: turn %d has neither a response nor an error", len(f.requests))
+	}
+	return turn, nil
+}
+
+// Chat returns the next scripted turn.
+func (f *FakeClient) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	turn, err := f.next(req)
+	if err != nil {
+		return nil, err
+	}
+	if turn.Err != nil {
+		return nil, turn.Err
+	}
+	resp := *turn.Response
+	return &resp, nil
+}
+
+// Stream delivers the next scripted turn as a text chunk, one chunk per
+// tool call, and a final Done chunk carrying the usage.
+func (f *FakeClient) Stream(ctx context.Context, req *llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	turn, err := f.next(req)
+	if err != nil {
+		return nil, err
+	}
+	if turn.Err != nil {
+		return nil, turn.Err
+	}
+
+	resp := turn.Response
+	ch := make(chan llm.StreamChunk, len(resp.ToolCalls)+2)
+	if resp.Content != "" {
+		ch <- llm.StreamChunk{Type: llm.TextChunk, Text: resp.Content}
+	}
+	for i := range resp.ToolCalls {
+		tc := resp.ToolCalls[i]
+		ch <- llm.StreamChunk{Type: llm.ToolCallChunk, ToolCall: &tc}
+	}
+	ch <- llm.StreamChunk{Done: true, Usage: resp.Usage}
+	close(ch)
+	return ch, nil
+}
+
+// ModelInfo returns the configured model.
+func (f *FakeClient) ModelInfo() *llm.ModelInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info := f.model
+	return &info
+}
+
+// Ping returns the error set with SetPingError.
+func (f *FakeClient) Ping(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pingErr
+}
+
+// Close marks the client closed.
+func (f *FakeClient) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	if raw, ok := v.(json.RawMessage); ok {
+		return raw
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("llmtest: marshaling tool args: %v", err))
+	}
+	return data
+}
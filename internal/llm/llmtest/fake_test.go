@@ -0,0 +1,70 @@
+package llmtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/llm"
+)
+
+func TestFakeClientPlaysBackScript(t *testing.T) {
+	boom := errors.New("boom")
+	f := NewFakeClient(
+		ToolCall("c1", "file_read", map[string]string{"path": "a.go"}).WithUsage(10, 2),
+		Fail(boom),
+		Reply("done"),
+	)
+	ctx := context.Background()
+	req := &llm.ChatRequest{Messages: []llm.Message{{Role: "user", Content: "go"}}}
+
+	resp, err := f.Chat(ctx, req)
+	if err != nil || len(resp.ToolCalls) != 1 || string(resp.ToolCalls[0].Args) != `{"path":"a.go"}` {
+		t.Fatalf("turn 1 = %+v, %v", resp, err)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 12 {
+		t.Errorf("turn 1 usage = %+v", resp.Usage)
+	}
+
+	// Requests are copied, so later changes by the caller don't show up.
+	req.Messages = append(req.Messages, llm.Message{Role: "assistant"})
+	req.Messages[0].Content = "changed"
+	if got := f.Requests()[0].Messages; len(got) != 1 || got[0].Content != "go" {
+		t.Errorf("recorded request = %+v", got)
+	}
+
+	if _, err := f.Chat(ctx, req); !errors.Is(err, boom) {
+		t.Errorf("turn 2 error = %v, want boom", err)
+	}
+	if resp, err := f.Chat(ctx, req); err != nil || resp.Content != "done" {
+		t.Errorf("turn 3 = %+v, %v", resp, err)
+	}
+	if _, err := f.Chat(ctx, req); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("after script error = %v", err)
+	}
+	if n := len(f.Requests()); n != 4 {
+		t.Errorf("recorded %d requests, want 4", n)
+	}
+}
+
+func TestFakeClientStream(t *testing.T) {
+	f := NewFakeClient(Turn{Response: &llm.ChatResponse{
+		Content:   "reading",
+		ToolCalls: []llm.ToolCall{{ID: "c1", Name: "file_read"}},
+	}}.WithUsage(5, 1))
+
+	ch, err := f.Stream(context.Background(), &llm.ChatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chunks []llm.StreamChunk
+	for c := range ch {
+		chunks = append(chunks, c)
+	}
+	if len(chunks) != 3 || chunks[0].Text != "reading" || chunks[1].ToolCall.Name != "file_read" {
+		t.Fatalf("chunks = %+v", chunks)
+	}
+	if last := chunks[2]; !last.Done || last.Usage == nil || last.Usage.TotalTokens != 6 {
+		t.Errorf("final chunk = %+v", last)
+	}
+}
@@ -0,0 +1,187 @@
+package llm
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// DefaultLogContentLen bounds each message logged when LogOptions.LogContent
+// is set.
+const DefaultLogContentLen = 500
+
+// LogOptions configures WithLogging.
+type LogOptions struct {
+	// Level is the level successful calls are logged at; failures are
+	// always logged at Warn. The zero value is slog.LevelInfo.
+	Level slog.Level
+
+	// LogContent adds the request messages and the response content to
+	// each record. Off by default: prompts and tool output can contain
+	// personal data and secrets. Likely credentials are redacted, but
+	// redaction is best-effort.
+	LogContent bool
+
+	// MaxContentLen truncates each logged message. Default:
+	// DefaultLogContentLen.
+	MaxContentLen int
+}
+
+// loggingClient logs every request and its outcome to a slog.Logger.
+type loggingClient struct {
+	inner  Client
+	logger *slog.Logger
+	opts   LogOptions
+}
+
+// WithLogging returns a Client that logs each Chat and Stream call with the
+// model, message and tool counts, token usage, latency, and finish reason.
+// It composes with the other wrappers; wrap outermost to log one record per
+// call as the caller sees it, or wrap each client passed to WithFallback to
+// log every endpoint attempt.
+func WithLogging(inner Client, logger *slog.Logger, opts LogOptions) Client {
+	if inner == nil || logger == nil {
+		return inner
+	}
+	if opts.MaxContentLen <= 0 {
+		opts.MaxContentLen = DefaultLogContentLen
+	}
+	return &loggingClient{inner: inner, logger: logger, opts: opts}
+}
+
+func (c *loggingClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	start := time.Now()
+	resp, err := c.inner.Chat(ctx, req)
+
+	attrs := c.requestAttrs(req, time.Since(start))
+	if err != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "llm chat failed", append(attrs, slog.Any("error", err))...)
+		return resp, err
+	}
+	attrs = append(attrs,
+		slog.String("finish_reason", resp.FinishReason),
+		slog.Int("tool_calls", len(resp.ToolCalls)),
+	)
+	attrs = append(attrs, usageAttrs(resp.Usage)...)
+	if c.opts.LogContent {
+		attrs = append(attrs, slog.String("response", c.content(resp.Content)))
+		for i, tc := range resp.ToolCalls {
+			attrs = append(attrs, slog.String("tool_call."+strconv.Itoa(i), tc.Name+" "+c.content(string(tc.Args))))
+		}
+	}
+	c.logger.LogAttrs(ctx, c.opts.Level, "llm chat", attrs...)
+	return resp, nil
+}
+
+func (c *loggingClient) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
+	start := time.Now()
+	ch, err := c.inner.Stream(ctx, req)
+	if err != nil {
+		attrs := c.requestAttrs(req, time.Since(start))
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "llm stream failed", append(attrs, slog.Any("error", err))...)
+		return ch, err
+	}
+
+	out := make(chan StreamChunk, 16)
+	go func() {
+		defer close(out)
+		var firstChunk time.Duration
+		var usage *Usage
+		var streamErr error
+		var textLen, toolCalls int
+		for chunk := range ch {
+			if firstChunk == 0 {
+				firstChunk = time.Since(start)
+			}
+			textLen += len(chunk.Text)
+			if chunk.Type == ToolCallChunk {
+				toolCalls++
+			}
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if chunk.Err != nil {
+				streamErr = chunk.Err
+			}
+			select {
+			case out <- chunk:
+				continue
+			case <-ctx.Done():
+				streamErr = ctx.Err()
+			}
+			break
+		}
+
+		attrs := c.requestAttrs(req, time.Since(start))
+		attrs = append(attrs,
+			slog.Duration("first_chunk", firstChunk),
+			slog.Int("text_bytes", textLen),
+			slog.Int("tool_call_chunks", toolCalls),
+		)
+		attrs = append(attrs, usageAttrs(usage)...)
+		if streamErr != nil {
+			c.logger.LogAttrs(ctx, slog.LevelWarn, "llm stream failed", append(attrs, slog.Any("error", streamErr))...)
+			return
+		}
+		c.logger.LogAttrs(ctx, c.opts.Level, "llm stream", attrs...)
+	}()
+	return out, nil
+}
+
+func (c *loggingClient) ModelInfo() *ModelInfo {
+	return c.inner.ModelInfo()
+}
+
+func (c *loggingClient) Ping(ctx context.Context) error {
+	return c.inner.Ping(ctx)
+}
+
+func (c *loggingClient) Close() error {
+	return c.inner.Close()
+}
+
+// requestAttrs describes req and how long the call took.
+func (c *loggingClient) requestAttrs(req *ChatRequest, latency time.Duration) []slog.Attr {
+	model := ""
+	if mi := c.inner.ModelInfo(); mi != nil {
+		model = mi.ID
+	}
+	attrs := []slog.Attr{
+		slog.String("model", model),
+		slog.Int("messages", len(req.Messages)),
+		slog.Int("tools", len(req.Tools)),
+		slog.Duration("latency", latency),
+	}
+	if c.opts.LogContent {
+		for i, m := range req.Messages {
+			attrs = append(attrs, slog.String("message."+strconv.Itoa(i), m.Role+": "+c.content(m.Content)))
+		}
+	}
+	return attrs
+}
+
+func usageAttrs(u *Usage) []slog.Attr {
+	if u == nil {
+		return nil
+	}
+	return []slog.Attr{
+		slog.Int("prompt_tokens", u.PromptTokens),
+		slog.Int("completion_tokens", u.CompletionTokens),
+		slog.Int("total_tokens", u.TotalTokens),
+	}
+}
+
+// secretPattern matches values of credential-like keys ("api_key": "...",
+// Authorization: Bearer ..., token=...) and bare provider keys (sk-...).
+var secretPattern = regexp.MustCompile(`(?i)((?:api[_-]?key|x-api-key|authorization|access[_-]?token|secret|password|token)["']?\s*[:=]\s*["']?(?:bearer\s+)?)[^"'\s,}&]+|\bsk-[A-Za-z0-9_-]{16,}`)
+
+// content redacts likely secrets from s and truncates it.
+func (c *loggingClient) content(s string) string {
+	s = secretPattern.ReplaceAllString(s, "${1}[REDACTED]")
+	if len(s) > c.opts.MaxContentLen {
+		s = s[:c.opts.MaxContentLen] + "...(truncated)"
+	}
+	return s
+}
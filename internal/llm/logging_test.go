@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWithLoggingChat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	req := &ChatRequest{Messages: []Message{{Role: "user", Content: `call it with {"api_key": "sk-abcdefghijklmnopqrstuvwx"}`}}}
+
+	client := WithLogging(&fakeClient{id: "gpt-test"}, logger, LogOptions{})
+	if _, err := client.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	line := buf.String()
+	for _, want := range []string{"msg=\"llm chat\"", "model=gpt-test", "messages=1", "latency="} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log missing %q: %s", want, line)
+		}
+	}
+	if strings.Contains(line, "call it") {
+		t.Errorf("content logged without LogContent: %s", line)
+	}
+
+	buf.Reset()
+	client = WithLogging(&fakeClient{id: "gpt-test"}, logger, LogOptions{LogContent: true})
+	if _, err := client.Chat(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	line = buf.String()
+	if !strings.Contains(line, "call it") || !strings.Contains(line, "[REDACTED]") || strings.Contains(line, "sk-abc") {
+		t.Errorf("content should be logged with the key redacted: %s", line)
+	}
+
+	buf.Reset()
+	client = WithLogging(&fakeClient{id: "gpt-test", chatErr: errors.New("boom")}, logger, LogOptions{})
+	if _, err := client.Chat(context.Background(), req); err == nil {
+		t.Fatal("want error")
+	}
+	if line = buf.String(); !strings.Contains(line, "level=WARN") || !strings.Contains(line, "error=boom") {
+		t.Errorf("failure log: %s", line)
+	}
+}
+
+func TestWithLoggingStream(t *testing.T) {
+	var buf bytes.Buffer
+	client := WithLogging(&fakeClient{id: "gpt-test"}, slog.New(slog.NewTextHandler(&buf, nil)), LogOptions{})
+
+	ch, err := client.Stream(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text string
+	for chunk := range ch {
+		text += chunk.Text
+	}
+	if text != "gpt-test" {
+		t.Errorf("streamed text = %q", text)
+	}
+	if line := buf.String(); !strings.Contains(line, "msg=\"llm stream\"") || !strings.Contains(line, "text_bytes=8") {
+		t.Errorf("stream log: %s", line)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	c := &loggingClient{opts: LogOptions{MaxContentLen: 1000}}
+	for in, want := range map[string]string{
+		"Authorization: Bearer abc.def":   "Authorization: Bearer [REDACTED]",
+		`{"password":"hunter2","user":1}`: `{"password":"[REDACTED]","user":1}`,
+		"token=xyz&next=1":                "token=[REDACTED]&next=1",
+		"key sk-proj-0123456789abcdefXYZ": "key [REDACTED]",
+		"nothing secret here":             "nothing secret here",
+	} {
+		if got := c.content(in); got != want {
+			t.Errorf("content(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/steveyegge/gastown/internal/config"
@@ -23,6 +25,15 @@ type OpenAIClient struct {
 	httpClient *http.Client
 	headers    map[string]string
 	modelInfo  *ModelInfo
+
+	embeddingModel string
+	embeddingDims  atomic.Int64 // configured, or learned from the first response
+
+	// Azure OpenAI routes requests through named deployments, versions the
+	// API with a query parameter, and authenticates with an api-key header.
+	azure      bool
+	deployment string
+	apiVersion string
 }
 
 // NewOpenAIClient creates a client for OpenAI-compatible endpoints.
@@ -32,7 +43,7 @@ func NewOpenAIClient(cfg *config.APIConfig, apiKey string) (*OpenAIClient, error
 		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
 	}
 
-	return &OpenAIClient{
+	c := &OpenAIClient{
 		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
 		apiKey:  apiKey,
 		model:   cfg.Model,
@@ -47,59 +58,48 @@ func NewOpenAIClient(cfg *config.APIConfig, apiKey string) (*OpenAIClient, error
 			SupportsTools:  cfg.SupportsTools,
 			SupportsVision: cfg.SupportsVision,
 		},
-	}, nil
-}
-
-// Chat sends a chat completion request and returns the response.
-func (c *OpenAIClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
-	// Build OpenAI request
-	oaiReq := map[string]interface{}{
-		"model":    c.model,
-		"messages": convertMessages(req.Messages),
+		embeddingModel: cfg.EmbeddingModel,
 	}
+	c.embeddingDims.Store(int64(cfg.EmbeddingDimensions))
+	return c, nil
+}
 
-	if req.MaxTokens > 0 {
-		oaiReq["max_tokens"] = req.MaxTokens
-	}
-	if req.Temperature != nil {
-		oaiReq["temperature"] = *req.Temperature
+// NewAzureOpenAIClient creates a client for an Azure OpenAI resource.
+// cfg.BaseURL is the resource endpoint (https://<name>.openai.azure.com),
+// cfg.Deployment the chat deployment and cfg.APIVersion the API version.
+// Embeddings use cfg.EmbeddingModel as the deployment name.
+func NewAzureOpenAIClient(cfg *config.APIConfig, apiKey string) (*OpenAIClient, error) {
+	if strings.TrimSpace(cfg.Deployment) == "" {
+		return nil, fmt.Errorf("deployment is required for Azure OpenAI")
 	}
-	if len(req.StopSeqs) > 0 {
-		oaiReq["stop"] = req.StopSeqs
-	}
-	if len(req.Tools) > 0 {
-		oaiReq["tools"] = convertTools(req.Tools)
+	if strings.TrimSpace(cfg.APIVersion) == "" {
+		return nil, fmt.Errorf("api_version is required for Azure OpenAI")
 	}
-
-	body, err := json.Marshal(oaiReq)
+	c, err := NewOpenAIClient(cfg, apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("marshaling request: %w", err)
+		return nil, err
 	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
+	c.azure = true
+	c.deployment = cfg.Deployment
+	c.apiVersion = cfg.APIVersion
+	c.modelInfo.Provider = "azure"
+	if c.modelInfo.ID == "" {
+		c.modelInfo.ID = cfg.Deployment
 	}
+	return c, nil
+}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.apiKey != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
-	for k, v := range c.headers {
-		httpReq.Header.Set(k, v)
+// Chat sends a chat completion request and returns the response.
+func (c *OpenAIClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	if err := req.validateToolChoice(); err != nil {
+		return nil, err
 	}
-
-	resp, err := c.httpClient.Do(httpReq)
+	resp, err := c.post(ctx, c.buildRequest(req, false))
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		errBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(errBody))
-	}
-
 	var oaiResp openAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&oaiResp); err != nil {
 		return nil, fmt.Errorf("decoding response: %w", err)
@@ -132,51 +132,207 @@ func (c *OpenAIClient) Chat(ctx context.Context, req *ChatRequest) (*ChatRespons
 		})
 	}
 
+	// OpenAI-compatible servers don't all honor response_format; validate.
+	if req.wantsJSON() && len(result.ToolCalls) == 0 {
+		content, err := repairJSONContent(result.Content)
+		if err != nil {
+			return nil, err
+		}
+		result.Content = content
+	}
+
 	return result, nil
 }
 
 // Stream sends a streaming chat completion request.
+// Text deltas are emitted as they arrive. Tool call deltas are accumulated by
+// index (arguments arrive fragmented across frames) and emitted as complete
+// ToolCallChunks when the choice finishes or the stream ends.
 func (c *OpenAIClient) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
-	// TODO: Implement SSE streaming for chat completions
-	// For now, fall back to non-streaming and emit result as single chunk
-	ch := make(chan StreamChunk, 1)
+	if err := req.validateToolChoice(); err != nil {
+		return nil, err
+	}
+	resp, err := c.post(ctx, c.buildRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan StreamChunk, 16)
 
 	go func() {
 		defer close(ch)
+		defer func() { _ = resp.Body.Close() }()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case ch <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		acc := newOpenAIToolCallAccumulator()
+		flush := func() bool {
+			for _, tc := range acc.drain() {
+				tcCopy := tc
+				if !send(StreamChunk{Type: ToolCallChunk, ToolCall: &tcCopy}) {
+					return false
+				}
+			}
+			return true
+		}
+
+		err := readSSE(resp.Body, func(_, data string) error {
+			if data == "[DONE]" {
+				return errStopSSE
+			}
+
+			var frame openAIStreamFrame
+			if err := json.Unmarshal([]byte(data), &frame); err != nil {
+				return fmt.Errorf("decoding stream frame: %w", err)
+			}
+			if frame.Error != nil {
+				return fmt.Errorf("API error in stream: %s", frame.Error.Message)
+			}
 
-		resp, err := c.Chat(ctx, req)
+			for _, choice := range frame.Choices {
+				if choice.Delta.Content != "" {
+					if !send(StreamChunk{Type: TextChunk, Text: choice.Delta.Content}) {
+						return ctx.Err()
+					}
+				}
+				for _, d := range choice.Delta.ToolCalls {
+					acc.add(d)
+				}
+				if choice.FinishReason != "" && !flush() {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
 		if err != nil {
-			ch <- StreamChunk{Err: err, Done: true}
+			if ctx.Err() == nil {
+				send(StreamChunk{Err: err, Done: true})
+			}
 			return
 		}
 
-		if resp.Content != "" {
-			ch <- StreamChunk{Type: TextChunk, Text: resp.Content}
-		}
-		for _, tc := range resp.ToolCalls {
-			tcCopy := tc
-			ch <- StreamChunk{Type: ToolCallChunk, ToolCall: &tcCopy}
+		if flush() {
+			send(StreamChunk{Done: true})
 		}
-		ch <- StreamChunk{Done: true}
 	}()
 
 	return ch, nil
 }
 
+// buildRequest converts a ChatRequest into the OpenAI wire format.
+func (c *OpenAIClient) buildRequest(req *ChatRequest, stream bool) map[string]interface{} {
+	oaiReq := map[string]interface{}{
+		"model":    c.model,
+		"messages": convertMessages(req.Messages),
+	}
+
+	if stream {
+		oaiReq["stream"] = true
+	}
+	if req.MaxTokens > 0 {
+		oaiReq["max_tokens"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		oaiReq["temperature"] = *req.Temperature
+	}
+	if len(req.StopSeqs) > 0 {
+		oaiReq["stop"] = req.StopSeqs
+	}
+	if len(req.Tools) > 0 {
+		oaiReq["tools"] = convertTools(req.Tools)
+		if tc := convertToolChoice(req); tc != nil {
+			oaiReq["tool_choice"] = tc
+		}
+	}
+	if rf := convertResponseFormat(req); rf != nil {
+		oaiReq["response_format"] = rf
+	}
+
+	return oaiReq
+}
+
+// post sends a chat completions request and returns the HTTP response.
+// Non-200 responses are converted to errors; the caller owns the body otherwise.
+func (c *OpenAIClient) post(ctx context.Context, oaiReq map[string]interface{}) (*http.Response, error) {
+	return c.postJSON(ctx, c.endpoint(c.deployment, "/chat/completions"), oaiReq)
+}
+
+// endpoint returns the URL of an API path. On Azure the path is routed
+// through deployment and the api-version is appended.
+func (c *OpenAIClient) endpoint(deployment, path string) string {
+	if !c.azure {
+		return c.baseURL + path
+	}
+	return c.baseURL + "/openai/deployments/" + url.PathEscape(deployment) + path +
+		"?api-version=" + url.QueryEscape(c.apiVersion)
+}
+
+// setAuth sets the API key header in the provider's expected form.
+func (c *OpenAIClient) setAuth(req *http.Request) {
+	switch {
+	case c.apiKey == "":
+	case c.azure:
+		req.Header.Set("api-key", c.apiKey)
+	default:
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+}
+
+// postJSON sends payload to endpointURL. Non-200 responses are converted to
+// errors; the caller owns the body otherwise.
+func (c *OpenAIClient) postJSON(ctx context.Context, endpointURL string, payload interface{}) (*http.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpointURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	c.setAuth(httpReq)
+	for k, v := range c.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp)
+	}
+
+	return resp, nil
+}
+
 // ModelInfo returns information about the connected model.
 func (c *OpenAIClient) ModelInfo() *ModelInfo {
 	return c.modelInfo
 }
 
 // Ping checks if the API endpoint is reachable.
+// Azure lists models at the resource level rather than per deployment.
 func (c *OpenAIClient) Ping(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/models", nil)
+	modelsURL := c.baseURL + "/models"
+	if c.azure {
+		modelsURL = c.baseURL + "/openai/models?api-version=" + url.QueryEscape(c.apiVersion)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", modelsURL, nil)
 	if err != nil {
 		return err
 	}
-	if c.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-	}
+	c.setAuth(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -225,12 +381,91 @@ type openAIFunctionCall struct {
 	Arguments string `json:"arguments"`
 }
 
+type openAIStreamFrame struct {
+	Choices []openAIStreamChoice `json:"choices"`
+	Error   *openAIStreamError   `json:"error,omitempty"`
+}
+
+type openAIStreamChoice struct {
+	Delta        openAIStreamDelta `json:"delta"`
+	FinishReason string            `json:"finish_reason"`
+}
+
+type openAIStreamDelta struct {
+	Content   string                `json:"content"`
+	ToolCalls []openAIToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+type openAIToolCallDelta struct {
+	Index    int                `json:"index"`
+	ID       string             `json:"id,omitempty"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+type openAIStreamError struct {
+	Message string `json:"message"`
+}
+
 type openAIUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// openAIToolCallAccumulator reassembles tool calls whose name and arguments
+// arrive split across multiple stream frames, keyed by the delta index.
+type openAIToolCallAccumulator struct {
+	calls map[int]*ToolCall
+	args  map[int]*strings.Builder
+	order []int
+}
+
+func newOpenAIToolCallAccumulator() *openAIToolCallAccumulator {
+	return &openAIToolCallAccumulator{
+		calls: make(map[int]*ToolCall),
+		args:  make(map[int]*strings.Builder),
+	}
+}
+
+func (a *openAIToolCallAccumulator) add(d openAIToolCallDelta) {
+	tc, ok := a.calls[d.Index]
+	if !ok {
+		tc = &ToolCall{}
+		a.calls[d.Index] = tc
+		a.args[d.Index] = &strings.Builder{}
+		a.order = append(a.order, d.Index)
+	}
+	if d.ID != "" {
+		tc.ID = d.ID
+	}
+	if d.Function.Name != "" {
+		tc.Name += d.Function.Name
+	}
+	a.args[d.Index].WriteString(d.Function.Arguments)
+}
+
+// drain returns all accumulated tool calls in index order and resets state.
+func (a *openAIToolCallAccumulator) drain() []ToolCall {
+	if len(a.order) == 0 {
+		return nil
+	}
+	sort.Ints(a.order)
+	calls := make([]ToolCall, 0, len(a.order))
+	for _, idx := range a.order {
+		tc := a.calls[idx]
+		args := a.args[idx].String()
+		if args == "" {
+			args = "{}"
+		}
+		tc.Args = json.RawMessage(args)
+		calls = append(calls, *tc)
+	}
+	a.calls = make(map[int]*ToolCall)
+	a.args = make(map[int]*strings.Builder)
+	a.order = nil
+	return calls
+}
+
 // --- Helpers ---
 
 func convertMessages(msgs []Message) []map[string]interface{} {
@@ -277,10 +512,47 @@ func convertTools(tools []ToolDef) []map[string]interface{} {
 	return result
 }
 
+// convertResponseFormat maps ResponseFormat to the response_format field.
+// json_schema without a schema degrades to json_object.
+func convertResponseFormat(req *ChatRequest) map[string]interface{} {
+	switch {
+	case req.ResponseFormat == ResponseFormatJSONSchema && len(req.ResponseSchema) > 0:
+		return map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "response",
+				"schema": req.ResponseSchema,
+			},
+		}
+	case req.wantsJSON():
+		return map[string]interface{}{"type": "json_object"}
+	default:
+		return nil
+	}
+}
+
+// convertToolChoice maps ToolChoice to OpenAI's tool_choice field. It returns
+// nil for auto, which is the API default.
+func convertToolChoice(req *ChatRequest) interface{} {
+	switch req.ToolChoice {
+	case ToolChoiceNone, ToolChoiceRequired:
+		return string(req.ToolChoice)
+	case ToolChoiceTool:
+		return map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": req.ToolChoiceName},
+		}
+	default:
+		return nil
+	}
+}
+
 func detectProvider(baseURL string) string {
 	switch {
 	case strings.Contains(baseURL, "ollama") || strings.Contains(baseURL, ":11434"):
 		return "ollama"
+	case strings.Contains(baseURL, "openai.azure.com"):
+		return "azure"
 	case strings.Contains(baseURL, "openai.com"):
 		return "openai"
 	case strings.Contains(baseURL, "anthropic.com"):
@@ -0,0 +1,247 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func newTestOpenAIClient(t *testing.T, handler http.HandlerFunc) *OpenAIClient {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	client, err := NewOpenAIClient(&config.APIConfig{BaseURL: srv.URL, Model: "test-model"}, "")
+	if err != nil {
+		t.Fatalf("NewOpenAIClient: %v", err)
+	}
+	return client
+}
+
+func collectStream(t *testing.T, ch <-chan StreamChunk) (string, []ToolCall, error) {
+	t.Helper()
+	var text strings.Builder
+	var calls []ToolCall
+	var streamErr error
+	sawDone := false
+	for chunk := range ch {
+		if chunk.Err != nil {
+			streamErr = chunk.Err
+		}
+		if chunk.Done {
+			sawDone = true
+			continue
+		}
+		switch chunk.Type {
+		case TextChunk:
+			text.WriteString(chunk.Text)
+		case ToolCallChunk:
+			calls = append(calls, *chunk.ToolCall)
+		}
+	}
+	if !sawDone {
+		t.Fatal("stream closed without a Done chunk")
+	}
+	return text.String(), calls, streamErr
+}
+
+func TestOpenAIStreamEmitsTextAndReassemblesToolCalls(t *testing.T) {
+	frames := []string{
+		`{"choices":[{"delta":{"content":"Hel"}}]}`,
+		`{"choices":[{"delta":{"content":"lo"}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"file_read","arguments":"{\"pa"}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","function":{"name":"git_status","arguments":""}}]}}]}`,
+		`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"a.go\"}"}}]}}]}`,
+		`{"choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+	}
+
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, f := range frames {
+			fmt.Fprintf(w, "data: %s\n\n", f)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	ch, err := client.Stream(context.Background(), &ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	text, calls, streamErr := collectStream(t, ch)
+	if streamErr != nil {
+		t.Fatalf("stream error: %v", streamErr)
+	}
+	if text != "Hello" {
+		t.Errorf("text = %q, want %q", text, "Hello")
+	}
+	if len(calls) != 2 {
+		t.Fatalf("got %d tool calls, want 2", len(calls))
+	}
+	if calls[0].ID != "call_1" || calls[0].Name != "file_read" || string(calls[0].Args) != `{"path":"a.go"}` {
+		t.Errorf("call[0] = %+v", calls[0])
+	}
+	if calls[1].Name != "git_status" || string(calls[1].Args) != "{}" {
+		t.Errorf("call[1] = %+v", calls[1])
+	}
+}
+
+func TestOpenAIStreamSurfacesMidStreamError(t *testing.T) {
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"partial\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"error\":{\"message\":\"overloaded\"}}\n\n")
+	})
+
+	ch, err := client.Stream(context.Background(), &ChatRequest{})
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+
+	_, _, streamErr := collectStream(t, ch)
+	if streamErr == nil || !strings.Contains(streamErr.Error(), "overloaded") {
+		t.Fatalf("stream error = %v, want overloaded", streamErr)
+	}
+}
+
+func TestOpenAIResponseFormatMapping(t *testing.T) {
+	var got map[string]interface{}
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" {\"ok\": true} "},"finish_reason":"stop"}]}`))
+	})
+
+	resp, err := client.Chat(context.Background(), &ChatRequest{
+		Messages:       []Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: ResponseFormatJSONSchema,
+		ResponseSchema: json.RawMessage(`{"type":"object"}`),
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Content != `{"ok": true}` {
+		t.Errorf("content = %q", resp.Content)
+	}
+	rf, _ := got["response_format"].(map[string]interface{})
+	if rf["type"] != "json_schema" || rf["json_schema"] == nil {
+		t.Errorf("response_format = %#v", got["response_format"])
+	}
+
+	if _, err := client.Chat(context.Background(), &ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if _, ok := got["response_format"]; ok {
+		t.Error("response_format should be omitted for plain text requests")
+	}
+}
+
+func TestOpenAIToolChoiceMapping(t *testing.T) {
+	var got map[string]interface{}
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	})
+	tools := []ToolDef{{Name: "gt_prime", Parameters: json.RawMessage(`{"type":"object"}`)}}
+
+	tests := []struct {
+		choice ToolChoice
+		name   string
+		want   string // JSON of tool_choice; empty means omitted
+	}{
+		{ToolChoiceAuto, "", ""},
+		{ToolChoiceNone, "", `"none"`},
+		{ToolChoiceRequired, "", `"required"`},
+		{ToolChoiceTool, "gt_prime", `{"function":{"name":"gt_prime"},"type":"function"}`},
+	}
+	for _, tt := range tests {
+		_, err := client.Chat(context.Background(), &ChatRequest{
+			Messages:       []Message{{Role: "user", Content: "hi"}},
+			Tools:          tools,
+			ToolChoice:     tt.choice,
+			ToolChoiceName: tt.name,
+		})
+		if err != nil {
+			t.Fatalf("Chat(%q): %v", tt.choice, err)
+		}
+		tc, ok := got["tool_choice"]
+		if tt.want == "" {
+			if ok {
+				t.Errorf("%q: tool_choice = %v, want omitted", tt.choice, tc)
+			}
+			continue
+		}
+		if b, _ := json.Marshal(tc); string(b) != tt.want {
+			t.Errorf("%q: tool_choice = %s, want %s", tt.choice, b, tt.want)
+		}
+	}
+
+	_, err := client.Chat(context.Background(), &ChatRequest{
+		Messages:       []Message{{Role: "user", Content: "hi"}},
+		Tools:          tools,
+		ToolChoice:     ToolChoiceTool,
+		ToolChoiceName: "gt_missing",
+	})
+	if err == nil || !strings.Contains(err.Error(), "gt_missing") {
+		t.Errorf("unknown tool err = %v", err)
+	}
+}
+
+func TestAzureOpenAIUsesDeploymentURLAndAPIKeyHeader(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+"?"+r.URL.RawQuery)
+		if r.Header.Get("api-key") != "azure-key" || r.Header.Get("Authorization") != "" {
+			t.Errorf("auth headers = %v", r.Header)
+		}
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(&config.APIConfig{
+		APIType:    "azure-openai",
+		BaseURL:    srv.URL,
+		APIKey:     "azure-key",
+		Deployment: "gpt-4o-prod",
+		APIVersion: "2024-10-21",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if p := client.ModelInfo().Provider; p != "azure" {
+		t.Errorf("provider = %q, want azure", p)
+	}
+	if _, err := client.Chat(context.Background(), &ChatRequest{Messages: []Message{{Role: "user", Content: "hi"}}}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	want := []string{
+		"/openai/deployments/gpt-4o-prod/chat/completions?api-version=2024-10-21",
+		"/openai/models?api-version=2024-10-21",
+	}
+	if fmt.Sprint(paths) != fmt.Sprint(want) {
+		t.Errorf("requests = %v, want %v", paths, want)
+	}
+
+	if _, err := NewClient(&config.APIConfig{APIType: "azure-openai", BaseURL: srv.URL, Deployment: "d"}); err == nil {
+		t.Error("missing api_version should fail")
+	}
+}
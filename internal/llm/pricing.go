@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"strings"
+)
+
+// Pricing holds per-model token prices in USD per million tokens.
+type Pricing struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+
+	// Prompt-cache rates. Zero means cached tokens are billed at InputPerMTok.
+	CacheReadPerMTok  float64 `json:"cache_read_per_mtok,omitempty"`
+	CacheWritePerMTok float64 `json:"cache_write_per_mtok,omitempty"`
+}
+
+// Cost returns the estimated USD cost of a single call's usage.
+func (p Pricing) Cost(u *Usage) float64 {
+	if u == nil {
+		return 0
+	}
+
+	cacheRead := p.CacheReadPerMTok
+	if cacheRead == 0 {
+		cacheRead = p.InputPerMTok
+	}
+	cacheWrite := p.CacheWritePerMTok
+	if cacheWrite == 0 {
+		cacheWrite = p.InputPerMTok
+	}
+
+	// PromptTokens includes cached tokens; bill those at the cache rates.
+	uncached := u.PromptTokens - u.CacheReadTokens - u.CacheCreationTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+
+	micro := float64(uncached)*p.InputPerMTok +
+		float64(u.CacheReadTokens)*cacheRead +
+		float64(u.CacheCreationTokens)*cacheWrite +
+		float64(u.CompletionTokens)*p.OutputPerMTok
+	return micro / 1_000_000
+}
+
+// defaultPricing is a best-effort table of list prices, keyed by model ID
+// prefix so dated snapshots (e.g. "claude-sonnet-4-20250514") match.
+// Self-hosted models are free and intentionally absent.
+var defaultPricing = map[string]Pricing{
+	// OpenAI
+	"gpt-4o":       {InputPerMTok: 2.50, OutputPerMTok: 10.00},
+	"gpt-4o-mini":  {InputPerMTok: 0.15, OutputPerMTok: 0.60},
+	"gpt-4.1":      {InputPerMTok: 2.00, OutputPerMTok: 8.00},
+	"gpt-4.1-mini": {InputPerMTok: 0.40, OutputPerMTok: 1.60},
+	"gpt-4.1-nano": {InputPerMTok: 0.10, OutputPerMTok: 0.40},
+	"o3":           {InputPerMTok: 2.00, OutputPerMTok: 8.00},
+	"o4-mini":      {InputPerMTok: 1.10, OutputPerMTok: 4.40},
+
+	// Anthropic (cache reads 0.1x input, cache writes 1.25x input)
+	"claude-opus-4":     {InputPerMTok: 15.00, OutputPerMTok: 75.00, CacheReadPerMTok: 1.50, CacheWritePerMTok: 18.75},
+	"claude-sonnet-4":   {InputPerMTok: 3.00, OutputPerMTok: 15.00, CacheReadPerMTok: 0.30, CacheWritePerMTok: 3.75},
+	"claude-3-7-sonnet": {InputPerMTok: 3.00, OutputPerMTok: 15.00, CacheReadPerMTok: 0.30, CacheWritePerMTok: 3.75},
+	"claude-3-5-sonnet": {InputPerMTok: 3.00, OutputPerMTok: 15.00, CacheReadPerMTok: 0.30, CacheWritePerMTok: 3.75},
+	"claude-haiku-4":    {InputPerMTok: 1.00, OutputPerMTok: 5.00, CacheReadPerMTok: 0.10, CacheWritePerMTok: 1.25},
+	"claude-3-5-haiku":  {InputPerMTok: 0.80, OutputPerMTok: 4.00, CacheReadPerMTok: 0.08, CacheWritePerMTok: 1.00},
+
+	// Google
+	"gemini-2.5-pro":   {InputPerMTok: 1.25, OutputPerMTok: 10.00},
+	"gemini-2.5-flash": {InputPerMTok: 0.30, OutputPerMTok: 2.50},
+	"gemini-2.0-flash": {InputPerMTok: 0.10, OutputPerMTok: 0.40},
+}
+
+// LookupPricing returns the default pricing for a model ID, matching the
+// longest known prefix. Router-style IDs ("anthropic/claude-sonnet-4") are
+// matched on the part after the last slash.
+func LookupPricing(model string) (Pricing, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+
+	var best string
+	for prefix := range defaultPricing {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return Pricing{}, false
+	}
+	return defaultPricing[best], true
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLookupPricingLongestPrefix(t *testing.T) {
+	tests := []struct {
+		model string
+		want  float64 // input price
+		ok    bool
+	}{
+		{"gpt-4o-2024-08-06", 2.50, true},
+		{"gpt-4o-mini", 0.15, true},
+		{"claude-sonnet-4-20250514", 3.00, true},
+		{"anthropic/claude-opus-4-1", 15.00, true},
+		{"llama3.1:70b", 0, false},
+	}
+	for _, tt := range tests {
+		p, ok := LookupPricing(tt.model)
+		if ok != tt.ok || p.InputPerMTok != tt.want {
+			t.Errorf("LookupPricing(%q) = %v, %v; want input %v, %v", tt.model, p.InputPerMTok, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPricingCostBillsCacheTokensSeparately(t *testing.T) {
+	p := Pricing{InputPerMTok: 3, OutputPerMTok: 15, CacheReadPerMTok: 0.3, CacheWritePerMTok: 3.75}
+	u := &Usage{
+		PromptTokens:        1_000_000 + 2_000_000 + 400_000,
+		CacheReadTokens:     2_000_000,
+		CacheCreationTokens: 400_000,
+		CompletionTokens:    100_000,
+	}
+	want := 3.0 + 0.6 + 1.5 + 1.5
+	if got := p.Cost(u); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Cost = %v, want %v", got, want)
+	}
+	if got := p.Cost(nil); got != 0 {
+		t.Errorf("Cost(nil) = %v", got)
+	}
+}
@@ -11,7 +11,10 @@ import (
 type RetryConfig struct {
 	MaxRetries     int
 	InitialBackoff time.Duration
-	MaxBackoff     time.Duration
+	// MaxBackoff caps the wait between attempts. It also bounds how long a
+	// server's Retry-After is honored: a longer one fails the call at once
+	// rather than stalling it.
+	MaxBackoff time.Duration
 }
 
 type retryingClient struct {
@@ -65,7 +68,15 @@ func (c *retryingClient) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 			break
 		}
 
+		// Prefer the server's own Retry-After guidance over computed backoff.
 		sleep := c.backoffForAttempt(attempt)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			if apiErr.RetryAfter > c.cfg.MaxBackoff {
+				return nil, err
+			}
+			sleep = apiErr.RetryAfter
+		}
 		timer := time.NewTimer(sleep)
 		select {
 		case <-ctx.Done():
@@ -133,9 +144,16 @@ func isRetryableLLMError(err error) bool {
 		return false
 	}
 
+	// Typed HTTP failures: don't retry 4xx (auth/validation) except 429/408.
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
+	}
+
 	msg := strings.ToLower(err.Error())
 
-	// Heuristic: clients return "API error <code>: ..." for HTTP failures.
+	// Heuristic for untyped errors that only report the HTTP status in
+	// their message (e.g. "endpoint returned status 404" from Ping).
 	// Don't retry 4xx (auth/validation).
 	if strings.Contains(msg, "api error 4") {
 		return false
@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// scriptedClient returns the queued errors from Chat in order, then succeeds.
+type scriptedClient struct {
+	errs  []error
+	calls int
+}
+
+func (c *scriptedClient) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	c.calls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return &ChatResponse{Content: "ok"}, nil
+}
+
+func (c *scriptedClient) Stream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+func (c *scriptedClient) ModelInfo() *ModelInfo          { return &ModelInfo{} }
+func (c *scriptedClient) Ping(ctx context.Context) error { return nil }
+func (c *scriptedClient) Close() error                   { return nil }
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"absent", http.Header{}, 0},
+		{"seconds", http.Header{"Retry-After": {"7"}}, 7 * time.Second},
+		{"http date", http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, 90 * time.Second},
+		{"past date", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, 0},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"250"}}, 250 * time.Millisecond},
+		{"garbage", http.Header{"Retry-After": {"soon"}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.header, now); got != tt.want {
+				t.Errorf("parseRetryAfter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryHonorsRetryAfterAndSkipsPermanent4xx(t *testing.T) {
+	inner := &scriptedClient{errs: []error{
+		&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 20 * time.Millisecond},
+	}}
+	// Computed backoff would be an hour; Retry-After must win.
+	client := WithRetry(inner, RetryConfig{MaxRetries: 2, InitialBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := client.Chat(ctx, &ChatRequest{}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("calls = %d, want 2", inner.calls)
+	}
+
+	inner = &scriptedClient{errs: []error{&APIError{StatusCode: http.StatusUnauthorized}}}
+	client = WithRetry(inner, RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond})
+	_, err := client.Chat(ctx, &ChatRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("err = %v, want 401 APIError", err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("calls = %d, want 1 (401 is not retryable)", inner.calls)
+	}
+}
+
+func TestRetryFailsFastOnRetryAfterBeyondMaxBackoff(t *testing.T) {
+	inner := &scriptedClient{errs: []error{
+		&APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour},
+	}}
+	client := WithRetry(inner, RetryConfig{MaxRetries: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Second})
+
+	start := time.Now()
+	_, err := client.Chat(context.Background(), &ChatRequest{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter != time.Hour {
+		t.Fatalf("err = %v, want the 429 with its Retry-After", err)
+	}
+	if inner.calls != 1 || time.Since(start) > time.Second {
+		t.Errorf("calls = %d after %v; want one call and no wait", inner.calls, time.Since(start))
+	}
+}
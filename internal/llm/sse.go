@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+// sseMaxLineSize bounds a single server-sent event line. Tool call argument
+// deltas are small, but some providers send whole content blocks in one frame.
+const sseMaxLineSize = 1024 * 1024
+
+// readSSE parses a text/event-stream body and calls fn once per event with the
+// event name (empty when the server did not send an "event:" field) and the
+// joined "data:" payload. Returning errStopSSE from fn ends the read cleanly.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), sseMaxLineSize)
+
+	var event string
+	var data []string
+
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, strings.Join(data, "\n"))
+		event = ""
+		data = data[:0]
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// A blank line terminates the current event.
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return stopOrErr(err)
+			}
+			continue
+		}
+
+		// Lines starting with ':' are comments (often used as keep-alives).
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Flush a trailing event that wasn't followed by a blank line.
+	return stopOrErr(dispatch())
+}
+
+// errStopSSE signals readSSE to stop reading without reporting an error.
+var errStopSSE = errors.New("stop reading event stream")
+
+func stopOrErr(err error) error {
+	if err == errStopSSE {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidJSONResponse is returned when structured output was requested
+// but the model's reply could not be parsed as JSON. The request can be
+// retried, ideally with the failure fed back to the model.
+var ErrInvalidJSONResponse = errors.New("model response is not valid JSON")
+
+// wantsJSON reports whether req asks for structured JSON output.
+func (req *ChatRequest) wantsJSON() bool {
+	return req.ResponseFormat == ResponseFormatJSONObject || req.ResponseFormat == ResponseFormatJSONSchema
+}
+
+// jsonInstruction is the system prompt addendum used to emulate structured
+// output on providers without native support.
+func jsonInstruction(req *ChatRequest) string {
+	var b strings.Builder
+	b.WriteString("Respond with a single JSON object and nothing else: no prose, no markdown code fences.")
+	if req.ResponseFormat == ResponseFormatJSONSchema && len(req.ResponseSchema) > 0 {
+		b.WriteString(" The object must conform to this JSON schema:\n")
+		b.Write(req.ResponseSchema)
+	}
+	return b.String()
+}
+
+// repairJSONContent extracts a JSON value from a model reply, tolerating the
+// common failure modes of emulated JSON mode: surrounding whitespace, a
+// markdown code fence, or a sentence of prose before/after the object.
+func repairJSONContent(content string) (string, error) {
+	s := strings.TrimSpace(content)
+
+	// Strip a ```json ... ``` fence.
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+			s = s[nl+1:]
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+	}
+	if json.Valid([]byte(s)) {
+		return s, nil
+	}
+
+	// Fall back to the outermost {...} or [...] span.
+	for _, delims := range [][2]string{{"{", "}"}, {"[", "]"}} {
+		start := strings.Index(s, delims[0])
+		end := strings.LastIndex(s, delims[1])
+		if start >= 0 && end > start {
+			if candidate := s[start : end+1]; json.Valid([]byte(candidate)) {
+				return candidate, nil
+			}
+		}
+	}
+
+	preview := s
+	if len(preview) > 200 {
+		preview = preview[:200] + "..."
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidJSONResponse, preview)
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultAuditMaxArgBytes caps the arguments recorded per audit entry.
+const DefaultAuditMaxArgBytes = 4096
+
+// AuditOptions configures an AuditLog.
+type AuditOptions struct {
+	// MaxArgBytes truncates recorded arguments. Default:
+	// DefaultAuditMaxArgBytes.
+	MaxArgBytes int
+
+	// RedactWrites records only the size of file_write content.
+	RedactWrites bool
+}
+
+// AuditLog appends one JSON line per tool call to a file, recording who
+// called which tool with what arguments and how it went. It is separate
+// from the server log and is appended to across restarts.
+type AuditLog struct {
+	opts AuditOptions
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Remote     string    `json:"remote,omitempty"`
+	Tool       string    `json:"tool"`
+	CallID     string    `json:"call_id,omitempty"`
+	Arguments  string    `json:"arguments"`
+	Truncated  bool      `json:"truncated,omitempty"`
+	OK         bool      `json:"ok"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// OpenAuditLog opens path for appending, creating it if needed.
+func OpenAuditLog(path string, opts AuditOptions) (*AuditLog, error) {
+	if opts.MaxArgBytes <= 0 {
+		opts.MaxArgBytes = DefaultAuditMaxArgBytes
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return &AuditLog{opts: opts, file: f}, nil
+}
+
+// Close closes the underlying file.
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+// record writes the entry for one finished call. Write failures are
+// returned so the caller can log them; they never fail the call.
+func (a *AuditLog) record(ctx context.Context, req toolCallRequest, start time.Time, callErr error) error {
+	entry := AuditEntry{
+		Time:       start.UTC(),
+		Remote:     remoteAddrFrom(ctx),
+		Tool:       req.Name,
+		CallID:     req.CallID,
+		OK:         callErr == nil,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if callErr != nil {
+		entry.Error = callErr.Error()
+	}
+	entry.Arguments, entry.Truncated = a.arguments(req)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.file.Write(line)
+	return err
+}
+
+// arguments renders req's arguments for the log, redacted and capped.
+func (a *AuditLog) arguments(req toolCallRequest) (string, bool) {
+	args := req.Arguments
+	if a.opts.RedactWrites && req.Name == "file_write" {
+		var fields map[string]interface{}
+		if json.Unmarshal(args, &fields) == nil {
+			if content, ok := fields["content"].(string); ok {
+				fields["content"] = fmt.Sprintf("[redacted %d bytes]", len(content))
+				if redacted, err := json.Marshal(fields); err == nil {
+					args = redacted
+				}
+			}
+		}
+	}
+	if len(args) > a.opts.MaxArgBytes {
+		return string(args[:a.opts.MaxArgBytes]), true
+	}
+	return string(args), false
+}
+
+type remoteAddrKey struct{}
+
+// withRemoteAddr records the client address of the request being served.
+func withRemoteAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, remoteAddrKey{}, addr)
+}
+
+func remoteAddrFrom(ctx context.Context) string {
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	return addr
+}
@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditLogRecordsToolCalls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	s, ts := newTestServer(t)
+
+	audit, err := OpenAuditLog(path, AuditOptions{RedactWrites: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetAuditLog(audit)
+
+	postRPC(t, ts, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"file_write","arguments":{"path":"x.txt","content":"top secret"}}}`)
+	postRPC(t, ts, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"file_read","arguments":{"path":"missing.txt"}}}`)
+	if err := audit.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening appends rather than truncating.
+	audit, err = OpenAuditLog(path, AuditOptions{MaxArgBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetAuditLog(audit)
+	postRPC(t, ts, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"file_read","arguments":{"path":"hello.txt"}}}`)
+	_ = audit.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("bad line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+
+	write := entries[0]
+	if write.Tool != "file_write" || !write.OK || write.Remote == "" || write.Time.IsZero() {
+		t.Errorf("write entry = %+v", write)
+	}
+	if strings.Contains(write.Arguments, "top secret") || !strings.Contains(write.Arguments, "[redacted 10 bytes]") {
+		t.Errorf("write arguments not redacted: %s", write.Arguments)
+	}
+	if failed := entries[1]; failed.OK || failed.Error == "" {
+		t.Errorf("failed entry = %+v", failed)
+	}
+	if capped := entries[2]; !capped.Truncated || len(capped.Arguments) != 10 {
+		t.Errorf("capped entry = %+v", capped)
+	}
+}
@@ -27,8 +27,9 @@ type ServiceInfo struct {
 }
 
 // Discovery handles finding GT MCP servers on the local network.
-// Currently uses a simple HTTP-based probe approach. Can be extended
-// to use mDNS/DNS-SD (github.com/hashicorp/mdns) for zero-config LAN discovery.
+// Browse and Advertise use mDNS/DNS-SD for zero-config LAN discovery;
+// HTTP probing (Probe, ScanSubnet) remains as a fallback for networks
+// that block multicast.
 type Discovery struct {
 	mu       sync.Mutex
 	services []ServiceInfo
@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// ProtocolVersion is the MCP protocol revision this server speaks.
+	ProtocolVersion = "2024-11-05"
+	// ServerName is reported to clients in the initialize handshake.
+	ServerName = "gastown"
+	// ServerVersion is reported to clients in the initialize handshake.
+	ServerVersion = "0.1.0"
+
+	jsonrpcVersion = "2.0"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+
+	// CodeResourceNotFound is the MCP-specific code for an unknown resource URI.
+	CodeResourceNotFound = -32002
+)
+
+// rpcRequest is a JSON-RPC 2.0 request or notification. A notification has
+// no ID and gets no response.
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+func (r *rpcRequest) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// rpcResponse is a JSON-RPC 2.0 response. Exactly one of Result and Error is set.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError is a JSON-RPC 2.0 error object.
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+func newRPCError(code int, format string, args ...interface{}) *RPCError {
+	return &RPCError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// initializeResult is the server's answer to the initialize handshake.
+type initializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      implementationInfo     `json:"serverInfo"`
+}
+
+type implementationInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// handleRPCBody dispatches a JSON-RPC payload, which may be a single request
+// or a batch. It returns the response to encode (an *rpcResponse or a slice
+// of them), or nil when there is nothing to send back (only notifications).
+func (s *Server) handleRPCBody(ctx context.Context, body []byte) interface{} {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return errorResponse(nil, newRPCError(CodeParseError, "parse error: %v", err))
+		}
+		if len(batch) == 0 {
+			return errorResponse(nil, newRPCError(CodeInvalidRequest, "empty batch"))
+		}
+		var responses []*rpcResponse
+		for _, raw := range batch {
+			if resp := s.handleRPCMessage(ctx, raw); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return responses
+	}
+
+	if resp := s.handleRPCMessage(ctx, body); resp != nil {
+		return resp
+	}
+	return nil
+}
+
+// handleRPCMessage decodes and dispatches one JSON-RPC message.
+func (s *Server) handleRPCMessage(ctx context.Context, raw json.RawMessage) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return errorResponse(nil, newRPCError(CodeParseError, "parse error: %v", err))
+	}
+	if req.JSONRPC != jsonrpcVersion || req.Method == "" {
+		return errorResponse(req.ID, newRPCError(CodeInvalidRequest, "invalid JSON-RPC 2.0 request"))
+	}
+
+	result, rpcErr := s.dispatch(ctx, &req)
+	if req.isNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, newRPCError(CodeInternalError, "encoding result: %v", err))
+	}
+	return &rpcResponse{JSONRPC: jsonrpcVersion, ID: req.ID, Result: data}
+}
+
+// dispatch runs a JSON-RPC method by name.
+func (s *Server) dispatch(ctx context.Context, req *rpcRequest) (interface{}, *RPCError) {
+	switch req.Method {
+	case "initialize":
+		return initializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities: map[string]interface{}{
+				"tools":     map[string]interface{}{"listChanged": false},
+				"prompts":   map[string]interface{}{"listChanged": false},
+				"resources": map[string]interface{}{"subscribe": false, "listChanged": false},
+			},
+			ServerInfo: implementationInfo{Name: ServerName, Version: ServerVersion},
+		}, nil
+
+	case "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+
+	case "ping":
+		return struct{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{"tools": s.listTools()}, nil
+
+	case "tools/call":
+		var params toolCallRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, newRPCError(CodeInvalidParams, "tools/call requires a tool name")
+		}
+		resp, ok := s.callTool(ctx, params)
+		if !ok {
+			return nil, newRPCError(CodeInvalidParams, "unknown tool: %s", params.Name)
+		}
+		return resp, nil
+
+	case "tools/cancel":
+		var params toolCancelRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.CallID == "" {
+			return nil, newRPCError(CodeInvalidParams, "tools/cancel requires a callId")
+		}
+		return toolCancelResponse{Cancelled: s.cancelCall(ctx, params.CallID)}, nil
+
+	case "prompts/list":
+		return map[string]interface{}{"prompts": s.listPrompts()}, nil
+
+	case "prompts/get":
+		var params promptGetRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, newRPCError(CodeInvalidParams, "prompts/get requires a prompt name")
+		}
+		return s.getPrompt(params)
+
+	case "resources/list":
+		return map[string]interface{}{"resources": s.listResources()}, nil
+
+	case "resources/read":
+		var params resourceReadRequest
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+			return nil, newRPCError(CodeInvalidParams, "resources/read requires a uri")
+		}
+		return s.readResource(ctx, params.URI)
+
+	default:
+		return nil, newRPCError(CodeMethodNotFound, "method not found: %s", req.Method)
+	}
+}
+
+func errorResponse(id json.RawMessage, rpcErr *RPCError) *rpcResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &rpcResponse{JSONRPC: jsonrpcVersion, ID: id, Error: rpcErr}
+}
@@ -0,0 +1,170 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Limits bounds how hard clients can drive the server. Each tool call may
+// spawn a subprocess, so an unbounded client can overwhelm the host.
+type Limits struct {
+	// MaxConcurrent caps tool calls running at once across all clients.
+	// 0 means no cap.
+	MaxConcurrent int
+
+	// RPS is the sustained tool-call rate allowed per client, in calls per
+	// second. 0 means no rate limit.
+	RPS float64
+
+	// Burst is how many calls a client may make at once before RPS applies.
+	// 0 uses twice RPS, rounded up.
+	Burst int
+}
+
+// callLimiter enforces Limits on incoming tool calls.
+type callLimiter struct {
+	sem chan struct{}
+
+	// limiters holds a *rate.Limiter per client identity. Entries are never
+	// evicted; there are only ever a handful of agents per server.
+	limiters sync.Map
+	rps      rate.Limit
+	burst    int
+}
+
+// SetLimits caps concurrent tool calls and the per-client call rate. Calls
+// over either limit are refused with 429 Too Many Requests and a
+// Retry-After header. Call before Start.
+func (s *Server) SetLimits(l Limits) {
+	if l.MaxConcurrent <= 0 && l.RPS <= 0 {
+		s.limiter = nil
+		return
+	}
+	cl := &callLimiter{}
+	if l.MaxConcurrent > 0 {
+		cl.sem = make(chan struct{}, l.MaxConcurrent)
+	}
+	if l.RPS > 0 {
+		cl.rps = rate.Limit(l.RPS)
+		cl.burst = l.Burst
+		if cl.burst <= 0 {
+			cl.burst = int(math.Ceil(2 * l.RPS))
+		}
+	}
+	s.limiter = cl
+}
+
+// admitToolCall applies the server's limits to a request carrying calls
+// tool calls (several for a JSON-RPC batch). Each call costs one rate-limit
+// token; the calls in a batch run one after another, so they share one
+// concurrency slot. If the request may run it returns a function that
+// releases its slot; otherwise it writes an error response and returns false.
+func (s *Server) admitToolCall(w http.ResponseWriter, r *http.Request, calls int) (release func(), ok bool) {
+	cl := s.limiter
+	if cl == nil {
+		return func() {}, true
+	}
+
+	if cl.rps > 0 {
+		client := clientIdentity(r)
+		res := cl.limiterFor(client).ReserveN(time.Now(), calls)
+		if !res.OK() {
+			// More calls than the burst: no amount of waiting admits it.
+			log.Printf("[mcp] Batch of %d tool calls from %s exceeds burst %d", calls, client, cl.burst)
+			http.Error(w, fmt.Sprintf("batch of %d tool calls exceeds the limit of %d", calls, cl.burst), http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		if delay := res.Delay(); delay > 0 {
+			res.Cancel()
+			log.Printf("[mcp] Rate limit exceeded for %s", client)
+			tooManyRequests(w, delay, "rate limit exceeded")
+			return nil, false
+		}
+	}
+
+	if cl.sem == nil {
+		return func() {}, true
+	}
+	select {
+	case cl.sem <- struct{}{}:
+		return func() { <-cl.sem }, true
+	default:
+		log.Printf("[mcp] Concurrency limit reached; refusing call from %s", clientIdentity(r))
+		tooManyRequests(w, time.Second, "too many concurrent tool calls")
+		return nil, false
+	}
+}
+
+func (cl *callLimiter) limiterFor(client string) *rate.Limiter {
+	if v, ok := cl.limiters.Load(client); ok {
+		return v.(*rate.Limiter)
+	}
+	v, _ := cl.limiters.LoadOrStore(client, rate.NewLimiter(cl.rps, cl.burst))
+	return v.(*rate.Limiter)
+}
+
+// clientIdentity names the client for rate limiting: the common name of its
+// TLS client certificate when it has one, else its IP address. The bearer
+// token is shared by every client, so it does not tell them apart.
+func clientIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		if cn := r.TLS.PeerCertificates[0].Subject.CommonName; cn != "" {
+			return "cn:" + cn
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tooManyRequests writes a 429 asking the client to retry after delay,
+// rounded up to whole seconds.
+func tooManyRequests(w http.ResponseWriter, delay time.Duration, msg string) {
+	secs := int(math.Ceil(delay.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
+
+// toolCallCount returns how many tools/call requests a JSON-RPC payload,
+// single or batch, contains. Only tool calls are subject to Limits, so a
+// client at its limit can still list tools and cancel the calls it has
+// running.
+func toolCallCount(body []byte) int {
+	type method struct {
+		Method string `json:"method"`
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []method
+		if json.Unmarshal(body, &batch) != nil {
+			return 0
+		}
+		n := 0
+		for _, m := range batch {
+			if m.Method == "tools/call" {
+				n++
+			}
+		}
+		return n
+	}
+	var m method
+	if json.Unmarshal(body, &m) == nil && m.Method == "tools/call" {
+		return 1
+	}
+	return 0
+}
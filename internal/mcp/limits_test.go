@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyCapHolds(t *testing.T) {
+	s, ts := newTestServer(t)
+	s.SetLimits(Limits{MaxConcurrent: 3})
+
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	s.RegisterTool("slow", "Blocks until released", json.RawMessage(`{"type":"object"}`), func(ctx context.Context, args json.RawMessage) (string, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		return "done", nil
+	})
+
+	const calls = 20
+	codes := make(chan int, calls)
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow","arguments":{}}}`))
+			req.Header.Set("Authorization", "Bearer secret")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusTooManyRequests && resp.Header.Get("Retry-After") == "" {
+				t.Error("429 without Retry-After")
+			}
+			codes <- resp.StatusCode
+		}()
+	}
+
+	// Every call is either running or has been refused before any finish.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(codes) < calls-3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	counts := map[int]int{}
+	for code := range codes {
+		counts[code]++
+	}
+	if got := peak.Load(); got > 3 {
+		t.Errorf("peak concurrency = %d, want <= 3", got)
+	}
+	if counts[http.StatusOK] != 3 || counts[http.StatusTooManyRequests] != calls-3 {
+		t.Errorf("status counts = %v, want 3 OK and %d 429", counts, calls-3)
+	}
+
+	// Non-call methods are not limited.
+	if code, _ := postRPC(t, ts, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`); code != http.StatusOK {
+		t.Errorf("tools/list status = %d", code)
+	}
+}
+
+func TestRateLimitIsRetryable(t *testing.T) {
+	s, ts := newTestServer(t)
+	s.SetLimits(Limits{RPS: 0.5, Burst: 2})
+
+	tr := NewSSETransport(ts.URL, "secret")
+	defer func() { _ = tr.Close() }()
+	ctx := context.Background()
+	args := json.RawMessage(`{"path":"hello.txt"}`)
+
+	for i := 0; i < 2; i++ {
+		if _, err := tr.CallTool(ctx, "file_read", args); err != nil {
+			t.Fatalf("call %d: %v", i+1, err)
+		}
+	}
+	_, err := tr.CallTool(ctx, "file_read", args)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("third call error = %v, want *HTTPError", err)
+	}
+	if httpErr.StatusCode != http.StatusTooManyRequests || !httpErr.Retryable() || httpErr.RetryAfter <= 0 {
+		t.Errorf("third call error = %+v, want retryable 429 with RetryAfter", httpErr)
+	}
+}
+
+func TestRateLimitChargesEachCallInABatch(t *testing.T) {
+	s, ts := newTestServer(t)
+	s.SetLimits(Limits{RPS: 0.5, Burst: 3})
+
+	call := `{"jsonrpc":"2.0","id":%d,"method":"tools/call","params":{"name":"file_read","arguments":{"path":"hello.txt"}}}`
+	batch := func(ids ...int) string {
+		parts := make([]string, len(ids))
+		for i, id := range ids {
+			parts[i] = fmt.Sprintf(call, id)
+		}
+		return "[" + strings.Join(parts, ",") + "]"
+	}
+
+	if code, body := postRPC(t, ts, batch(1, 2)); code != http.StatusOK {
+		t.Fatalf("first batch status = %d: %s", code, body)
+	}
+	// One token left: a batch of two must not slip through as one request.
+	if code, _ := postRPC(t, ts, batch(3, 4)); code != http.StatusTooManyRequests {
+		t.Errorf("second batch status = %d, want 429", code)
+	}
+	if code, _ := postRPC(t, ts, batch(5, 6, 7, 8)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("batch over burst status = %d, want 413", code)
+	}
+	if code, _ := postRPC(t, ts, fmt.Sprintf(call, 9)); code != http.StatusOK {
+		t.Errorf("single call with a token left status = %d", code)
+	}
+}
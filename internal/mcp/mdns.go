@@ -0,0 +1,546 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mDNS/DNS-SD (RFC 6762/6763) support for Discovery. Only the small subset
+// needed to advertise and find _gastown._tcp services is implemented: PTR,
+// SRV, TXT and A records over IPv4 multicast. Packing and parsing are left
+// to golang.org/x/net/dns/dnsmessage, the parser behind the standard
+// library's resolver.
+
+const (
+	// mdnsDomain is the link-local domain services are advertised in.
+	mdnsDomain = "local."
+	// mdnsTTL is the TTL of advertised records, in seconds.
+	mdnsTTL = 120
+)
+
+var mdnsGroup = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// mdnsCacheFlush is the cache-flush bit of a record's class (RFC 6762 §10.2).
+const mdnsCacheFlush dnsmessage.Class = 0x8000
+
+// serviceFQDN is the DNS-SD service type name, e.g. "_gastown._tcp.local.".
+func serviceFQDN() string {
+	return ServiceName + "." + mdnsDomain
+}
+
+// Advertise announces a local MCP server over mDNS and answers queries for
+// it until ctx is canceled, when a goodbye is sent. It returns once the
+// responder is listening. info.Port is required; an empty Host advertises
+// this machine's LAN address. info.Metadata (rig, role, version, ...) is
+// published as TXT records, and Metadata["instance"] names the service
+// instance (default: the hostname).
+func (d *Discovery) Advertise(ctx context.Context, info ServiceInfo) error {
+	responder, err := newMDNSResponder(info)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		return fmt.Errorf("joining mDNS group: %w", err)
+	}
+
+	announce := func(ttl uint32) {
+		msg, err := responder.announcement(ttl).pack()
+		if err == nil {
+			_, err = conn.WriteToUDP(msg, mdnsGroup)
+		}
+		if err != nil {
+			log.Printf("[mcp] mDNS announce failed: %v", err)
+		}
+	}
+	announce(mdnsTTL)
+	log.Printf("[mcp] Advertising %s on mDNS (%s:%d)", responder.instance, responder.ip, responder.port)
+
+	go func() {
+		<-ctx.Done()
+		announce(0) // goodbye
+		_ = conn.Close()
+	}()
+
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, src, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Printf("[mcp] mDNS read failed: %v", err)
+				}
+				return
+			}
+			query, err := parseDNSMessage(buf[:n])
+			if err != nil || query.response {
+				continue
+			}
+			resp := responder.answer(query)
+			if resp == nil {
+				continue
+			}
+			// Legacy unicast queries (RFC 6762 §6.7) come from a port other
+			// than 5353 and expect a direct reply echoing the query.
+			dst := mdnsGroup
+			if src.Port != mdnsGroup.Port {
+				dst = src
+				resp.id = query.id
+				resp.questions = query.questions
+			}
+			msg, err := resp.pack()
+			if err != nil {
+				log.Printf("[mcp] mDNS answer failed: %v", err)
+				continue
+			}
+			_, _ = conn.WriteToUDP(msg, dst)
+		}
+	}()
+
+	return nil
+}
+
+// Browse discovers GT MCP servers on the LAN by multicasting a DNS-SD query
+// for _gastown._tcp and collecting answers until timeout (DiscoveryTimeout
+// if zero). TXT records populate ServiceInfo.Metadata.
+func (d *Discovery) Browse(ctx context.Context, timeout time.Duration) ([]ServiceInfo, error) {
+	if timeout <= 0 {
+		timeout = DiscoveryTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4zero})
+	if err != nil {
+		return nil, fmt.Errorf("opening mDNS socket: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	go func() {
+		<-ctx.Done()
+		_ = conn.SetReadDeadline(time.Now())
+	}()
+
+	query, err := (&dnsMessage{
+		id:        uint16(time.Now().UnixNano()),
+		questions: []dnsQuestion{{name: serviceFQDN(), qtype: dnsmessage.TypePTR}},
+	}).pack()
+	if err != nil {
+		return nil, fmt.Errorf("building mDNS query: %w", err)
+	}
+	if _, err := conn.WriteToUDP(query, mdnsGroup); err != nil {
+		return nil, fmt.Errorf("sending mDNS query: %w", err)
+	}
+
+	collector := newMDNSCollector()
+	buf := make([]byte, 9000)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break // deadline reached (or socket closed)
+		}
+		if msg, err := parseDNSMessage(buf[:n]); err == nil && msg.response {
+			collector.add(msg, src.IP)
+		}
+	}
+
+	results := collector.services()
+	d.mu.Lock()
+	d.services = results
+	d.mu.Unlock()
+	return results, nil
+}
+
+// --- Responder ---
+
+// mdnsResponder holds the records advertised for one service instance.
+type mdnsResponder struct {
+	instance string // e.g. "gpu1._gastown._tcp.local."
+	host     string // e.g. "gpu1.local."
+	ip       net.IP
+	port     uint16
+	txt      []string
+}
+
+func newMDNSResponder(info ServiceInfo) (*mdnsResponder, error) {
+	if info.Port <= 0 || info.Port > 65535 {
+		return nil, fmt.Errorf("advertising requires a valid port, got %d", info.Port)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "gastown"
+	}
+	hostname = dnsLabel(strings.SplitN(hostname, ".", 2)[0])
+
+	ipStr := info.Host
+	if ip := net.ParseIP(ipStr); ip == nil || ip.IsUnspecified() {
+		if ipStr, err = LocalIP(); err != nil {
+			return nil, err
+		}
+	}
+	ip := net.ParseIP(ipStr).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("mDNS advertising needs an IPv4 address, got %q", ipStr)
+	}
+
+	instance := hostname
+	if name := info.Metadata["instance"]; name != "" {
+		instance = dnsLabel(name)
+	}
+
+	keys := make([]string, 0, len(info.Metadata))
+	for k := range info.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	txt := make([]string, 0, len(keys))
+	for _, k := range keys {
+		txt = append(txt, k+"="+info.Metadata[k])
+	}
+
+	return &mdnsResponder{
+		instance: instance + "." + serviceFQDN(),
+		host:     hostname + "." + mdnsDomain,
+		ip:       ip,
+		port:     uint16(info.Port),
+		txt:      txt,
+	}, nil
+}
+
+// records returns the full record set: PTR, SRV, TXT and A.
+func (r *mdnsResponder) records(ttl uint32) []dnsRecord {
+	return []dnsRecord{
+		{name: serviceFQDN(), rtype: dnsmessage.TypePTR, ttl: ttl, target: r.instance},
+		{name: r.instance, rtype: dnsmessage.TypeSRV, ttl: ttl, target: r.host, port: r.port, cacheFlush: true},
+		{name: r.instance, rtype: dnsmessage.TypeTXT, ttl: ttl, txt: r.txt, cacheFlush: true},
+		{name: r.host, rtype: dnsmessage.TypeA, ttl: ttl, ip: r.ip, cacheFlush: true},
+	}
+}
+
+func (r *mdnsResponder) announcement(ttl uint32) *dnsMessage {
+	return &dnsMessage{response: true, answers: r.records(ttl)}
+}
+
+// answer returns the response to a query, or nil if nothing in it is ours.
+func (r *mdnsResponder) answer(query *dnsMessage) *dnsMessage {
+	for _, q := range query.questions {
+		name := strings.ToLower(q.name)
+		asksService := name == strings.ToLower(serviceFQDN()) && (q.qtype == dnsmessage.TypePTR || q.qtype == dnsmessage.TypeALL)
+		asksInstance := name == strings.ToLower(r.instance)
+		asksHost := name == strings.ToLower(r.host) && (q.qtype == dnsmessage.TypeA || q.qtype == dnsmessage.TypeALL)
+		if asksService || asksInstance || asksHost {
+			return r.announcement(mdnsTTL)
+		}
+	}
+	return nil
+}
+
+// --- Browser ---
+
+// mdnsCollector assembles ServiceInfo from the records in mDNS responses.
+type mdnsCollector struct {
+	instances []string             // in order first seen
+	srv       map[string]dnsRecord // instance -> SRV
+	txt       map[string][]string  // instance -> TXT strings
+	addrs     map[string]net.IP    // host -> A
+	sources   map[string]net.IP    // instance -> responder address
+}
+
+func newMDNSCollector() *mdnsCollector {
+	return &mdnsCollector{
+		srv:     make(map[string]dnsRecord),
+		txt:     make(map[string][]string),
+		addrs:   make(map[string]net.IP),
+		sources: make(map[string]net.IP),
+	}
+}
+
+func (c *mdnsCollector) add(msg *dnsMessage, src net.IP) {
+	suffix := "." + strings.ToLower(serviceFQDN())
+	for _, rr := range msg.answers {
+		name := strings.ToLower(rr.name)
+		switch rr.rtype {
+		case dnsmessage.TypePTR:
+			if name == strings.ToLower(serviceFQDN()) && rr.ttl > 0 {
+				c.addInstance(strings.ToLower(rr.target), src)
+			}
+		case dnsmessage.TypeSRV:
+			if strings.HasSuffix(name, suffix) {
+				c.addInstance(name, src)
+				c.srv[name] = rr
+			}
+		case dnsmessage.TypeTXT:
+			if strings.HasSuffix(name, suffix) {
+				c.txt[name] = rr.txt
+			}
+		case dnsmessage.TypeA:
+			c.addrs[name] = rr.ip
+		}
+	}
+}
+
+func (c *mdnsCollector) addInstance(name string, src net.IP) {
+	if _, ok := c.sources[name]; !ok {
+		c.instances = append(c.instances, name)
+		c.sources[name] = src
+	}
+}
+
+// services returns every instance whose SRV record was seen.
+func (c *mdnsCollector) services() []ServiceInfo {
+	var results []ServiceInfo
+	for _, name := range c.instances {
+		srv, ok := c.srv[name]
+		if !ok {
+			continue
+		}
+		ip := c.addrs[strings.ToLower(srv.target)]
+		if ip == nil {
+			ip = c.sources[name]
+		}
+		host := ip.String()
+
+		metadata := map[string]string{
+			"instance": strings.TrimSuffix(name, "."+strings.ToLower(serviceFQDN())),
+		}
+		for _, kv := range c.txt[name] {
+			k, v, _ := strings.Cut(kv, "=")
+			if k != "" {
+				metadata[k] = v
+			}
+		}
+
+		// Servers started with TLS advertise tls=1.
+		scheme := "http"
+		if metadata["tls"] == "1" {
+			scheme = "https"
+		}
+
+		results = append(results, ServiceInfo{
+			Host:     host,
+			Port:     int(srv.port),
+			URL:      fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, fmt.Sprint(srv.port))),
+			Metadata: metadata,
+		})
+	}
+	return results
+}
+
+// dnsLabel makes s usable as a single DNS label.
+func dnsLabel(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ".", "-")
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	if s == "" {
+		s = "gastown"
+	}
+	return s
+}
+
+// --- DNS wire format ---
+
+type dnsQuestion struct {
+	name  string
+	qtype dnsmessage.Type
+}
+
+type dnsRecord struct {
+	name       string
+	rtype      dnsmessage.Type
+	ttl        uint32
+	cacheFlush bool
+
+	target string   // PTR, SRV
+	port   uint16   // SRV
+	txt    []string // TXT
+	ip     net.IP   // A
+}
+
+// dnsMessage is a DNS message. When parsing, records from the answer,
+// authority and additional sections are all collected in answers.
+type dnsMessage struct {
+	id        uint16
+	response  bool
+	questions []dnsQuestion
+	answers   []dnsRecord
+}
+
+func (m *dnsMessage) pack() ([]byte, error) {
+	b := dnsmessage.NewBuilder(make([]byte, 0, 512), dnsmessage.Header{
+		ID:            m.id,
+		Response:      m.response,
+		Authoritative: m.response,
+	})
+	b.EnableCompression()
+
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, q := range m.questions {
+		name, err := dnsmessage.NewName(q.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.Question(dnsmessage.Question{Name: name, Type: q.qtype, Class: dnsmessage.ClassINET}); err != nil {
+			return nil, fmt.Errorf("packing question %s: %w", q.name, err)
+		}
+	}
+
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	for _, rr := range m.answers {
+		if err := packRecord(&b, rr); err != nil {
+			return nil, fmt.Errorf("packing %s record %s: %w", rr.rtype, rr.name, err)
+		}
+	}
+	return b.Finish()
+}
+
+func packRecord(b *dnsmessage.Builder, rr dnsRecord) error {
+	name, err := dnsmessage.NewName(rr.name)
+	if err != nil {
+		return err
+	}
+	hdr := dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: rr.ttl}
+	if rr.cacheFlush {
+		hdr.Class |= mdnsCacheFlush
+	}
+
+	switch rr.rtype {
+	case dnsmessage.TypePTR:
+		target, err := dnsmessage.NewName(rr.target)
+		if err != nil {
+			return err
+		}
+		return b.PTRResource(hdr, dnsmessage.PTRResource{PTR: target})
+	case dnsmessage.TypeSRV:
+		target, err := dnsmessage.NewName(rr.target)
+		if err != nil {
+			return err
+		}
+		return b.SRVResource(hdr, dnsmessage.SRVResource{Port: rr.port, Target: target})
+	case dnsmessage.TypeTXT:
+		txt := make([]string, 0, len(rr.txt))
+		for _, s := range rr.txt {
+			if len(s) > 255 {
+				s = s[:255]
+			}
+			txt = append(txt, s)
+		}
+		if len(txt) == 0 {
+			txt = []string{""} // TXT must hold at least one string
+		}
+		return b.TXTResource(hdr, dnsmessage.TXTResource{TXT: txt})
+	case dnsmessage.TypeA:
+		var a dnsmessage.AResource
+		copy(a.A[:], rr.ip.To4())
+		return b.AResource(hdr, a)
+	}
+	return fmt.Errorf("unsupported record type %s", rr.rtype)
+}
+
+func parseDNSMessage(b []byte) (*dnsMessage, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(b)
+	if err != nil {
+		return nil, err
+	}
+	m := &dnsMessage{id: h.ID, response: h.Response}
+
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, err
+	}
+	for _, q := range questions {
+		m.questions = append(m.questions, dnsQuestion{name: q.Name.String(), qtype: q.Type})
+	}
+
+	sections := []struct {
+		header func() (dnsmessage.ResourceHeader, error)
+		skip   func() error
+	}{
+		{p.AnswerHeader, p.SkipAnswer},
+		{p.AuthorityHeader, p.SkipAuthority},
+		{p.AdditionalHeader, p.SkipAdditional},
+	}
+	for _, section := range sections {
+		for {
+			hdr, err := section.header()
+			if errors.Is(err, dnsmessage.ErrSectionDone) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			rr, ok, err := parseRecord(&p, hdr)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				if err := section.skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			m.answers = append(m.answers, rr)
+		}
+	}
+	return m, nil
+}
+
+// parseRecord reads the body of a PTR, SRV, TXT or A record whose header
+// was just read. For any other type it reports false and leaves the body
+// for the caller to skip.
+func parseRecord(p *dnsmessage.Parser, hdr dnsmessage.ResourceHeader) (dnsRecord, bool, error) {
+	rr := dnsRecord{
+		name:       hdr.Name.String(),
+		rtype:      hdr.Type,
+		ttl:        hdr.TTL,
+		cacheFlush: hdr.Class&mdnsCacheFlush != 0,
+	}
+	switch hdr.Type {
+	case dnsmessage.TypePTR:
+		r, err := p.PTRResource()
+		if err != nil {
+			return rr, false, err
+		}
+		rr.target = r.PTR.String()
+	case dnsmessage.TypeSRV:
+		r, err := p.SRVResource()
+		if err != nil {
+			return rr, false, err
+		}
+		rr.target, rr.port = r.Target.String(), r.Port
+	case dnsmessage.TypeTXT:
+		r, err := p.TXTResource()
+		if err != nil {
+			return rr, false, err
+		}
+		for _, s := range r.TXT {
+			if s != "" {
+				rr.txt = append(rr.txt, s)
+			}
+		}
+	case dnsmessage.TypeA:
+		r, err := p.AResource()
+		if err != nil {
+			return rr, false, err
+		}
+		rr.ip = net.IP(append([]byte(nil), r.A[:]...))
+	default:
+		return rr, false, nil
+	}
+	return rr, true, nil
+}
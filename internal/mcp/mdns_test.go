@@ -0,0 +1,147 @@
+package mcp
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mustPack packs m or fails the test.
+func mustPack(t testing.TB, m *dnsMessage) []byte {
+	t.Helper()
+	b, err := m.pack()
+	if err != nil {
+		t.Fatalf("packing %+v: %v", m, err)
+	}
+	return b
+}
+
+func TestMDNSResponderAnswersBrowseQuery(t *testing.T) {
+	responder, err := newMDNSResponder(ServiceInfo{
+		Host: "192.168.1.20",
+		Port: 9500,
+		Metadata: map[string]string{
+			"instance": "gpu1",
+			"rig":      "gastown",
+			"role":     "deacon",
+			"version":  "1.2.3",
+		},
+	})
+	if err != nil {
+		t.Fatalf("newMDNSResponder: %v", err)
+	}
+
+	query, err := parseDNSMessage(mustPack(t, &dnsMessage{
+		id:        42,
+		questions: []dnsQuestion{{name: "_GASTOWN._tcp.local.", qtype: dnsmessage.TypePTR}},
+	}))
+	if err != nil {
+		t.Fatalf("parsing query: %v", err)
+	}
+	resp := responder.answer(query)
+	if resp == nil {
+		t.Fatal("responder ignored a query for its service")
+	}
+	if responder.answer(&dnsMessage{questions: []dnsQuestion{{name: "_http._tcp.local.", qtype: dnsmessage.TypePTR}}}) != nil {
+		t.Error("responder answered a query for another service")
+	}
+
+	parsed, err := parseDNSMessage(mustPack(t, resp))
+	if err != nil {
+		t.Fatalf("parsing response: %v", err)
+	}
+	if !parsed.response || len(parsed.answers) != 4 {
+		t.Fatalf("response = %+v", parsed)
+	}
+
+	c := newMDNSCollector()
+	c.add(parsed, net.IPv4(10, 0, 0, 9))
+	services := c.services()
+	if len(services) != 1 {
+		t.Fatalf("got %d services, want 1", len(services))
+	}
+	got := services[0]
+	if got.Host != "192.168.1.20" || got.Port != 9500 || got.URL != "http://192.168.1.20:9500" {
+		t.Errorf("service = %+v", got)
+	}
+	for k, want := range map[string]string{"instance": "gpu1", "rig": "gastown", "role": "deacon", "version": "1.2.3"} {
+		if got.Metadata[k] != want {
+			t.Errorf("Metadata[%s] = %q, want %q", k, got.Metadata[k], want)
+		}
+	}
+}
+
+func TestMDNSCollectorFallsBackToSourceAddress(t *testing.T) {
+	msg := &dnsMessage{response: true, answers: []dnsRecord{
+		{name: serviceFQDN(), rtype: dnsmessage.TypePTR, ttl: 120, target: "a." + serviceFQDN()},
+		{name: "a." + serviceFQDN(), rtype: dnsmessage.TypeSRV, ttl: 120, target: "a.local.", port: 9600},
+		// A goodbye (TTL 0) must not resurrect a service.
+		{name: serviceFQDN(), rtype: dnsmessage.TypePTR, ttl: 0, target: "gone." + serviceFQDN()},
+	}}
+	parsed, err := parseDNSMessage(mustPack(t, msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := newMDNSCollector()
+	c.add(parsed, net.IPv4(10, 0, 0, 7))
+	services := c.services()
+	if len(services) != 1 || services[0].Host != "10.0.0.7" || services[0].Port != 9600 {
+		t.Errorf("services = %+v", services)
+	}
+}
+
+func TestParseDNSMessageFollowsCompression(t *testing.T) {
+	// One question: "local." at offset 12, then "_gastown._tcp" + a pointer
+	// to offset 12 as the question name.
+	msg := []byte{0, 1, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0}
+	msg = append(msg, 5, 'l', 'o', 'c', 'a', 'l', 0, 0, 1, 0, 1)
+	msg = append(msg, 8, '_', 'g', 'a', 's', 't', 'o', 'w', 'n', 4, '_', 't', 'c', 'p', 0xC0, 12, 0, 12, 0, 1)
+
+	parsed, err := parseDNSMessage(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.questions) != 2 || parsed.questions[1].name != "_gastown._tcp.local." {
+		t.Errorf("questions = %+v", parsed.questions)
+	}
+
+	loop := []byte{0, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0xC0, 12, 0, 12, 0, 1}
+	if _, err := parseDNSMessage(loop); err == nil {
+		t.Error("expected an error for a compression loop")
+	}
+}
+
+// FuzzParseDNSMessage feeds arbitrary packets through the parser and the
+// code that handles what it returns, as a packet from the LAN would be.
+func FuzzParseDNSMessage(f *testing.F) {
+	responder, err := newMDNSResponder(ServiceInfo{
+		Host:     "192.168.1.20",
+		Port:     9500,
+		Metadata: map[string]string{"instance": "gpu1", "rig": "gastown"},
+	})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(mustPack(f, &dnsMessage{questions: []dnsQuestion{{name: serviceFQDN(), qtype: dnsmessage.TypePTR}}}))
+	f.Add(mustPack(f, responder.announcement(mdnsTTL)))
+	f.Add(mustPack(f, responder.announcement(0)))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		msg, err := parseDNSMessage(b)
+		if err != nil {
+			return
+		}
+		if resp := responder.answer(msg); resp != nil {
+			// A legacy unicast reply echoes the query's questions.
+			resp.questions = msg.questions
+			if _, err := resp.pack(); err != nil {
+				t.Errorf("packing answer to %+v: %v", msg.questions, err)
+			}
+		}
+		c := newMDNSCollector()
+		c.add(msg, net.IPv4(10, 0, 0, 9))
+		c.services()
+	})
+}
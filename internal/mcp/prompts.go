@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode/utf8"
+)
+
+// PromptArgument describes a value a prompt template expects.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+}
+
+// PromptRegistration describes a registered prompt template.
+type PromptRegistration struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Arguments   []PromptArgument   `json:"arguments,omitempty"`
+	Template    *template.Template `json:"-"`
+}
+
+// ResourceReader returns the current contents of a resource.
+type ResourceReader func(ctx context.Context) ([]byte, error)
+
+// ResourceRegistration describes a registered read-only resource.
+type ResourceRegistration struct {
+	URI      string         `json:"uri"`
+	Name     string         `json:"name"`
+	MimeType string         `json:"mimeType,omitempty"`
+	Reader   ResourceReader `json:"-"`
+}
+
+// RegisterPrompt adds a prompt template to the MCP server. The template
+// uses text/template syntax; every field it references ({{.issue}}) becomes
+// a required prompt argument that clients supply in prompts/get.
+func (s *Server) RegisterPrompt(name, description, tmpl string) error {
+	t, err := template.New(name).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parsing prompt %q: %w", name, err)
+	}
+
+	var args []PromptArgument
+	for _, field := range templateFields(t) {
+		args = append(args, PromptArgument{Name: field, Required: true})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prompts[name] = &PromptRegistration{
+		Name:        name,
+		Description: description,
+		Arguments:   args,
+		Template:    t,
+	}
+	return nil
+}
+
+// RegisterResource adds a read-only resource to the MCP server. reader is
+// called on every resources/read, so it always returns fresh contents.
+func (s *Server) RegisterResource(uri, name, mimeType string, reader ResourceReader) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resources[uri] = &ResourceRegistration{
+		URI:      uri,
+		Name:     name,
+		MimeType: mimeType,
+		Reader:   reader,
+	}
+}
+
+type promptGetRequest struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+type promptMessage struct {
+	Role    string      `json:"role"`
+	Content toolContent `json:"content"`
+}
+
+type promptGetResponse struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []promptMessage `json:"messages"`
+}
+
+type resourceReadRequest struct {
+	URI string `json:"uri"`
+}
+
+type resourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"` // base64, for binary contents
+}
+
+type resourceReadResponse struct {
+	Contents []resourceContent `json:"contents"`
+}
+
+// listPrompts returns the registered prompts, sorted by name.
+func (s *Server) listPrompts() []*PromptRegistration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prompts := make([]*PromptRegistration, 0, len(s.prompts))
+	for _, p := range s.prompts {
+		prompts = append(prompts, p)
+	}
+	sort.Slice(prompts, func(i, j int) bool { return prompts[i].Name < prompts[j].Name })
+	return prompts
+}
+
+// getPrompt renders a prompt with the client's arguments as a single user message.
+func (s *Server) getPrompt(req promptGetRequest) (*promptGetResponse, *RPCError) {
+	s.mu.RLock()
+	p, ok := s.prompts[req.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, newRPCError(CodeInvalidParams, "unknown prompt: %s", req.Name)
+	}
+
+	for _, arg := range p.Arguments {
+		if _, ok := req.Arguments[arg.Name]; !ok && arg.Required {
+			return nil, newRPCError(CodeInvalidParams, "prompt %s requires argument %q", req.Name, arg.Name)
+		}
+	}
+
+	args := req.Arguments
+	if args == nil {
+		args = map[string]string{}
+	}
+	var sb strings.Builder
+	if err := p.Template.Execute(&sb, args); err != nil {
+		return nil, newRPCError(CodeInvalidParams, "rendering prompt %s: %v", req.Name, err)
+	}
+
+	return &promptGetResponse{
+		Description: p.Description,
+		Messages: []promptMessage{{
+			Role:    "user",
+			Content: toolContent{Type: "text", Text: sb.String()},
+		}},
+	}, nil
+}
+
+// listResources returns the registered resources, sorted by URI.
+func (s *Server) listResources() []*ResourceRegistration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resources := make([]*ResourceRegistration, 0, len(s.resources))
+	for _, r := range s.resources {
+		resources = append(resources, r)
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].URI < resources[j].URI })
+	return resources
+}
+
+// readResource reads a resource. Text is returned as-is; anything that
+// isn't valid UTF-8 is returned base64-encoded as a blob.
+func (s *Server) readResource(ctx context.Context, uri string) (*resourceReadResponse, *RPCError) {
+	s.mu.RLock()
+	r, ok := s.resources[uri]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, newRPCError(CodeResourceNotFound, "resource not found: %s", uri)
+	}
+
+	data, err := r.Reader(ctx)
+	if err != nil {
+		return nil, newRPCError(CodeInternalError, "reading %s: %v", uri, err)
+	}
+
+	content := resourceContent{URI: uri, MimeType: r.MimeType}
+	if utf8.Valid(data) {
+		content.Text = string(data)
+	} else {
+		content.Blob = base64.StdEncoding.EncodeToString(data)
+	}
+	return &resourceReadResponse{Contents: []resourceContent{content}}, nil
+}
+
+// templateFields returns the top-level field names a template references
+// (e.g. "issue" for {{.issue}}), in order of first use.
+func templateFields(t *template.Template) []string {
+	var fields []string
+	seen := make(map[string]bool)
+
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			// The body runs with dot set to each element, so only the
+			// pipeline and else branch refer to the arguments.
+			walk(n.Pipe)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.ElseList)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg)
+				}
+			}
+		case *parse.FieldNode:
+			if name := n.Ident[0]; !seen[name] {
+				seen[name] = true
+				fields = append(fields, name)
+			}
+		}
+	}
+
+	if t.Tree != nil {
+		walk(t.Tree.Root)
+	}
+	return fields
+}
@@ -6,10 +6,13 @@ package mcp
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -29,12 +32,18 @@ const (
 // ToolHandler is a function that handles an MCP tool call.
 type ToolHandler func(ctx context.Context, args json.RawMessage) (string, error)
 
-// ToolRegistration describes a registered tool.
+// ToolResultHandler handles an MCP tool call whose result may carry
+// structured content alongside its text.
+type ToolResultHandler func(ctx context.Context, args json.RawMessage) (agentloop.ToolResult, error)
+
+// ToolRegistration describes a registered tool. Exactly one of Handler and
+// ResultHandler is set.
 type ToolRegistration struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	InputSchema json.RawMessage `json:"inputSchema"`
-	Handler     ToolHandler     `json:"-"`
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	InputSchema   json.RawMessage   `json:"inputSchema"`
+	Handler       ToolHandler       `json:"-"`
+	ResultHandler ToolResultHandler `json:"-"`
 }
 
 // Server exposes Gastown tools via MCP protocol.
@@ -45,8 +54,23 @@ type Server struct {
 	authToken string
 	executor  *agentloop.Executor
 
-	mu    sync.RWMutex
-	tools map[string]*ToolRegistration
+	mu        sync.RWMutex
+	tools     map[string]*ToolRegistration
+	prompts   map[string]*PromptRegistration
+	resources map[string]*ResourceRegistration
+
+	// running maps client-supplied call IDs, scoped to the client that
+	// chose them, to the cancel functions of tool calls in flight.
+	runningMu sync.Mutex
+	running   map[callKey]context.CancelFunc
+
+	audit *AuditLog
+
+	// tlsConfig, when set by SetTLS, makes Start serve HTTPS.
+	tlsConfig *tls.Config
+
+	// limiter, when set by SetLimits, throttles tool calls.
+	limiter *callLimiter
 
 	httpServer *http.Server
 	started    bool
@@ -63,11 +87,19 @@ func NewServer(addr string, executor *agentloop.Executor, authToken string) *Ser
 		authToken: authToken,
 		executor:  executor,
 		tools:     make(map[string]*ToolRegistration),
+		prompts:   make(map[string]*PromptRegistration),
+		resources: make(map[string]*ResourceRegistration),
+		running:   make(map[callKey]context.CancelFunc),
 	}
 
 	return s
 }
 
+// SetAuditLog makes the server record every tool call to audit.
+func (s *Server) SetAuditLog(audit *AuditLog) {
+	s.audit = audit
+}
+
 // RegisterTool adds a tool to the MCP server.
 func (s *Server) RegisterTool(name, description string, schema json.RawMessage, handler ToolHandler) {
 	s.mu.Lock()
@@ -81,51 +113,88 @@ func (s *Server) RegisterTool(name, description string, schema json.RawMessage,
 	}
 }
 
-// RegisterGTTools registers all standard GT tools from the agentloop package.
+// RegisterResultTool adds a tool whose results may include structured
+// content, returned to clients as structuredContent.
+func (s *Server) RegisterResultTool(name, description string, schema json.RawMessage, handler ToolResultHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tools[name] = &ToolRegistration{
+		Name:          name,
+		Description:   description,
+		InputSchema:   schema,
+		ResultHandler: handler,
+	}
+}
+
+// RegisterGTTools registers the standard GT tools from the agentloop package
+// that the executor's allowlist permits for its role.
 func (s *Server) RegisterGTTools() {
-	gtTools := agentloop.GTTools()
+	gtTools := s.executor.Tools()
 	for _, tool := range gtTools {
 		toolName := tool.Name
-		s.RegisterTool(tool.Name, tool.Description, tool.Parameters, func(ctx context.Context, args json.RawMessage) (string, error) {
-			return s.executor.Execute(ctx, llmToolCall(toolName, args))
+		s.RegisterResultTool(tool.Name, tool.Description, tool.Parameters, func(ctx context.Context, args json.RawMessage) (agentloop.ToolResult, error) {
+			return s.executor.ExecuteResult(ctx, llmToolCall(toolName, args))
 		})
 	}
 }
 
 // Start begins listening for MCP connections.
 func (s *Server) Start(ctx context.Context) error {
-	mux := http.NewServeMux()
-
-	// MCP protocol endpoints
-	mux.HandleFunc("/mcp/tools/list", s.authMiddleware(s.handleToolsList))
-	mux.HandleFunc("/mcp/tools/call", s.authMiddleware(s.handleToolsCall))
-	mux.HandleFunc("/mcp/health", s.handleHealth)
-
-	// SSE endpoint for streaming
-	mux.HandleFunc("/mcp/sse", s.authMiddleware(s.handleSSE))
-
 	s.httpServer = &http.Server{
 		Addr:         s.addr,
-		Handler:      mux,
+		Handler:      s.handler(),
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 300 * time.Second, // Long timeout for tool execution
 		IdleTimeout:  120 * time.Second,
+		TLSConfig:    s.tlsConfig,
 	}
 
 	s.started = true
-	log.Printf("[mcp] Server listening on %s", s.addr)
+	scheme := "http"
+	if s.tlsConfig != nil {
+		scheme = "https"
+	}
+	log.Printf("[mcp] Server listening on %s://%s", scheme, s.addr)
 
 	go func() {
 		<-ctx.Done()
 		_ = s.Stop()
 	}()
 
-	if err := s.httpServer.ListenAndServe(); err != http.ErrServerClosed {
+	var err error
+	if s.tlsConfig != nil {
+		// The certificate is already loaded into TLSConfig.
+		err = s.httpServer.ListenAndServeTLS("", "")
+	} else {
+		err = s.httpServer.ListenAndServe()
+	}
+	if err != http.ErrServerClosed {
 		return fmt.Errorf("MCP server error: %w", err)
 	}
 	return nil
 }
 
+// handler builds the HTTP routes for the server.
+func (s *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// MCP protocol endpoint (JSON-RPC 2.0)
+	mux.HandleFunc("/mcp", s.authMiddleware(s.handleRPC))
+
+	// Deprecated REST endpoints, kept for one release so older clients
+	// keep working. New clients should use JSON-RPC on /mcp.
+	mux.HandleFunc("/mcp/tools/list", s.authMiddleware(s.handleToolsList))
+	mux.HandleFunc("/mcp/tools/call", s.authMiddleware(s.handleToolsCall))
+	mux.HandleFunc("/mcp/tools/cancel", s.authMiddleware(s.handleToolsCancel))
+	mux.HandleFunc("/mcp/health", s.handleHealth)
+
+	// SSE endpoint for streaming
+	mux.HandleFunc("/mcp/sse", s.authMiddleware(s.handleSSE))
+
+	return mux
+}
+
 // Stop gracefully shuts down the server.
 func (s *Server) Stop() error {
 	if !s.started || s.httpServer == nil {
@@ -145,18 +214,14 @@ func (s *Server) Addr() string {
 	return s.addr
 }
 
-// --- HTTP handlers ---
-
-func (s *Server) handleToolsList(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet && r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// --- Tool dispatch ---
 
+// listTools returns the registered tools, sorted by name.
+func (s *Server) listTools() []map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	var tools []map[string]interface{}
+	tools := make([]map[string]interface{}, 0, len(s.tools))
 	for _, t := range s.tools {
 		tools = append(tools, map[string]interface{}{
 			"name":        t.Name,
@@ -164,9 +229,156 @@ func (s *Server) handleToolsList(w http.ResponseWriter, r *http.Request) {
 			"inputSchema": t.InputSchema,
 		})
 	}
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i]["name"].(string) < tools[j]["name"].(string)
+	})
+	return tools
+}
+
+// callTool runs a registered tool. Tool failures are reported in the
+// response with IsError set; ok is false only if the tool is unknown.
+func (s *Server) callTool(ctx context.Context, req toolCallRequest) (resp toolCallResponse, ok bool) {
+	s.mu.RLock()
+	tool, ok := s.tools[req.Name]
+	s.mu.RUnlock()
+	if !ok {
+		return toolCallResponse{}, false
+	}
+
+	if req.CallID != "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		if !s.trackCall(ctx, req.CallID, cancel) {
+			return toolCallResponse{
+				Content: []toolContent{{Type: "text", Text: fmt.Sprintf("Error: call ID %q is already in use", req.CallID)}},
+				IsError: true,
+			}, true
+		}
+		defer s.untrackCall(ctx, req.CallID)
+	}
+
+	var result agentloop.ToolResult
+	var err error
+	start := time.Now()
+	if tool.ResultHandler != nil {
+		result, err = tool.ResultHandler(ctx, req.Arguments)
+	} else {
+		result.Text, err = tool.Handler(ctx, req.Arguments)
+	}
+	if s.audit != nil {
+		if auditErr := s.audit.record(ctx, req, start, err); auditErr != nil {
+			log.Printf("[mcp] Writing audit log: %v", auditErr)
+		}
+	}
+	if err != nil {
+		return toolCallResponse{
+			Content: []toolContent{{Type: "text", Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, true
+	}
+	return toolCallResponse{
+		Content:           []toolContent{{Type: "text", Text: result.Text}},
+		StructuredContent: result.Structured,
+	}, true
+}
+
+// callKey names a running call. Call IDs are chosen by clients, so they are
+// scoped to the client (as identified by clientIdentity): clients can't
+// collide with or cancel each other's calls.
+type callKey struct {
+	client string
+	id     string
+}
+
+type clientKey struct{}
+
+// withClient records the identity of the client making the request.
+func withClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+func callKeyFrom(ctx context.Context, id string) callKey {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return callKey{client: client, id: id}
+}
+
+// trackCall registers a running call under id for the client in ctx. It
+// returns false if that client already has a call with the same id running.
+func (s *Server) trackCall(ctx context.Context, id string, cancel context.CancelFunc) bool {
+	key := callKeyFrom(ctx, id)
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	if _, busy := s.running[key]; busy {
+		return false
+	}
+	s.running[key] = cancel
+	return true
+}
+
+func (s *Server) untrackCall(ctx context.Context, id string) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	delete(s.running, callKeyFrom(ctx, id))
+}
+
+// cancelCall cancels the client's running call with the given ID and
+// reports whether there was one.
+func (s *Server) cancelCall(ctx context.Context, id string) bool {
+	s.runningMu.Lock()
+	cancel, ok := s.running[callKeyFrom(ctx, id)]
+	s.runningMu.Unlock()
+	if ok {
+		log.Printf("[mcp] Cancelling tool call %s", id)
+		cancel()
+	}
+	return ok
+}
+
+// --- HTTP handlers ---
+
+// maxRPCBodySize bounds the size of a JSON-RPC request body.
+const maxRPCBodySize = 10 * 1024 * 1024
+
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRPCBodySize))
+	if err != nil {
+		http.Error(w, "Reading request body failed", http.StatusBadRequest)
+		return
+	}
+
+	if calls := toolCallCount(body); calls > 0 {
+		release, ok := s.admitToolCall(w, r, calls)
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
+	resp := s.handleRPCBody(r.Context(), body)
+	if resp == nil {
+		// Only notifications; nothing to answer.
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleToolsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
 	resp := map[string]interface{}{
-		"tools": tools,
+		"tools": s.listTools(),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -176,11 +388,23 @@ func (s *Server) handleToolsList(w http.ResponseWriter, r *http.Request) {
 type toolCallRequest struct {
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments"`
+	// CallID, if set, names the call so it can be cancelled while it runs.
+	CallID string `json:"callId,omitempty"`
+}
+
+type toolCancelRequest struct {
+	CallID string `json:"callId"`
+}
+
+type toolCancelResponse struct {
+	Cancelled bool `json:"cancelled"`
 }
 
 type toolCallResponse struct {
 	Content []toolContent `json:"content"`
-	IsError bool          `json:"isError,omitempty"`
+	// StructuredContent is the result as JSON, for tools that provide it.
+	StructuredContent json.RawMessage `json:"structuredContent,omitempty"`
+	IsError           bool            `json:"isError,omitempty"`
 }
 
 type toolContent struct {
@@ -200,38 +424,40 @@ func (s *Server) handleToolsCall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.mu.RLock()
-	tool, ok := s.tools[req.Name]
-	s.mu.RUnlock()
-
+	release, ok := s.admitToolCall(w, r, 1)
 	if !ok {
-		resp := toolCallResponse{
-			Content: []toolContent{{Type: "text", Text: fmt.Sprintf("Unknown tool: %s", req.Name)}},
-			IsError: true,
-		}
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(resp)
 		return
 	}
+	defer release()
 
-	result, err := tool.Handler(r.Context(), req.Arguments)
-
-	var resp toolCallResponse
-	if err != nil {
+	resp, ok := s.callTool(r.Context(), req)
+	if !ok {
 		resp = toolCallResponse{
-			Content: []toolContent{{Type: "text", Text: fmt.Sprintf("Error: %v", err)}},
+			Content: []toolContent{{Type: "text", Text: fmt.Sprintf("Unknown tool: %s", req.Name)}},
 			IsError: true,
 		}
-	} else {
-		resp = toolCallResponse{
-			Content: []toolContent{{Type: "text", Text: result}},
-		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+func (s *Server) handleToolsCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req toolCancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CallID == "" {
+		http.Error(w, "Request body must name a callId", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(toolCancelResponse{Cancelled: s.cancelCall(r.Context(), req.CallID)})
+}
+
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	s.mu.RLock()
 	toolCount := len(s.tools)
@@ -282,20 +508,19 @@ func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if s.authToken == "" {
-			// No auth configured — allow all (development mode)
-			next(w, r)
-			return
-		}
-
-		auth := r.Header.Get("Authorization")
-		expected := "Bearer " + s.authToken
-		if auth != expected {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+		// No auth configured — allow all (development mode)
+		if s.authToken != "" {
+			auth := r.Header.Get("Authorization")
+			expected := "Bearer " + s.authToken
+			if auth != expected {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
 		}
 
-		next(w, r)
+		ctx := withRemoteAddr(r.Context(), r.RemoteAddr)
+		ctx = withClient(ctx, clientIdentity(r))
+		next(w, r.WithContext(ctx))
 	}
 }
 
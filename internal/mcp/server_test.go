@@ -0,0 +1,310 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steveyegge/gastown/internal/agentloop"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	executor := agentloop.NewExecutor(dir, "rig", dir, dir, "rig/deacon/mcp", "deacon")
+	s := NewServer("", executor, "secret")
+	s.RegisterGTTools()
+
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func postRPC(t *testing.T, ts *httptest.Server, body string) (int, []byte) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(resp.Body)
+	return resp.StatusCode, buf.Bytes()
+}
+
+func TestTransportRoundTripOverJSONRPC(t *testing.T) {
+	_, ts := newTestServer(t)
+	ctx := context.Background()
+
+	tr := NewSSETransport(ts.URL, "secret")
+	defer func() { _ = tr.Close() }()
+	if err := tr.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	tools, err := tr.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	found := false
+	for _, tool := range tools {
+		if tool.Name == "file_read" {
+			found = len(tool.InputSchema) > 0
+		}
+	}
+	if !found {
+		t.Errorf("file_read missing from %d tools", len(tools))
+	}
+
+	out, err := tr.CallTool(ctx, "file_read", json.RawMessage(`{"path":"hello.txt"}`))
+	if err != nil || !strings.Contains(out, "hello") {
+		t.Errorf("CallTool = %q, %v", out, err)
+	}
+
+	_, err = tr.CallTool(ctx, "no_such_tool", nil)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("unknown tool err = %v, want InvalidParams", err)
+	}
+
+	if err := NewSSETransport(ts.URL, "wrong").Connect(ctx); err == nil {
+		t.Error("Connect with a bad token should fail")
+	}
+}
+
+func TestRPCEnvelopes(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	status, body := postRPC(t, ts, `{"jsonrpc":"2.0","id":7,"method":"initialize","params":{}}`)
+	var init struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      int             `json:"id"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &init); err != nil || status != http.StatusOK {
+		t.Fatalf("initialize: status %d, %s", status, body)
+	}
+	if init.JSONRPC != "2.0" || init.ID != 7 || !strings.Contains(string(init.Result), ProtocolVersion) {
+		t.Errorf("initialize response = %s", body)
+	}
+
+	// Notifications get no response body.
+	if status, body := postRPC(t, ts, `{"jsonrpc":"2.0","method":"notifications/initialized"}`); status != http.StatusAccepted || len(body) != 0 {
+		t.Errorf("notification: status %d, body %q", status, body)
+	}
+
+	for _, tc := range []struct {
+		body string
+		code int
+	}{
+		{`{not json`, CodeParseError},
+		{`{"id":1,"method":"ping"}`, CodeInvalidRequest},
+		{`{"jsonrpc":"2.0","id":1,"method":"completion/complete"}`, CodeMethodNotFound},
+		{`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{}}`, CodeInvalidParams},
+	} {
+		_, body := postRPC(t, ts, tc.body)
+		var resp rpcResponse
+		if err := json.Unmarshal(body, &resp); err != nil || resp.Error == nil || resp.Error.Code != tc.code {
+			t.Errorf("%s: response %s, want code %d", tc.body, body, tc.code)
+		}
+	}
+
+	// Batches answer every request but not notifications.
+	_, body = postRPC(t, ts, `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"tools/list"}]`)
+	var batch []rpcResponse
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) != 2 {
+		t.Fatalf("batch response = %s", body)
+	}
+	if string(batch[0].ID) != "1" || string(batch[1].ID) != "2" {
+		t.Errorf("batch ids = %s, %s", batch[0].ID, batch[1].ID)
+	}
+}
+
+func TestLegacyEndpointsStillWork(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	req, _ := http.NewRequest("POST", ts.URL+"/mcp/tools/call", strings.NewReader(`{"name":"file_read","arguments":{"path":"hello.txt"}}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result toolCallResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.IsError || len(result.Content) == 0 || !strings.Contains(result.Content[0].Text, "hello") {
+		t.Errorf("legacy tools/call = %+v", result)
+	}
+}
+
+func TestToolCallStructuredContent(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	_, body := postRPC(t, ts, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"file_list","arguments":{}}}`)
+	var resp struct {
+		Result toolCallResponse `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("response %s: %v", body, err)
+	}
+	if len(resp.Result.Content) == 0 || !strings.Contains(resp.Result.Content[0].Text, "hello.txt") {
+		t.Errorf("text content = %+v", resp.Result.Content)
+	}
+	if !strings.Contains(string(resp.Result.StructuredContent), `{"path":"hello.txt","dir":false}`) {
+		t.Errorf("structuredContent = %s", resp.Result.StructuredContent)
+	}
+
+	// Tools without a structured form return text only.
+	_, body = postRPC(t, ts, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"file_read","arguments":{"path":"hello.txt"}}}`)
+	if strings.Contains(string(body), "structuredContent") {
+		t.Errorf("file_read response has structuredContent: %s", body)
+	}
+}
+
+func TestPromptsAndResources(t *testing.T) {
+	s, ts := newTestServer(t)
+	ctx := context.Background()
+
+	if err := s.RegisterPrompt("review", "Review an issue", "Review {{.issue}}{{if .focus}} focusing on {{.focus}}{{end}}."); err != nil {
+		t.Fatalf("RegisterPrompt: %v", err)
+	}
+	if err := s.RegisterPrompt("broken", "", "{{.x"); err == nil {
+		t.Error("RegisterPrompt should reject an invalid template")
+	}
+	s.RegisterResource("gastown://beads/issues", "Issues", "application/json", func(context.Context) ([]byte, error) {
+		return []byte(`[{"id":"gt-1"}]`), nil
+	})
+	s.RegisterResource("gastown://bin", "Binary", "application/octet-stream", func(context.Context) ([]byte, error) {
+		return []byte{0xff, 0x00, 0xfe}, nil
+	})
+
+	tr := NewSSETransport(ts.URL, "secret")
+	if err := tr.Connect(ctx); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+
+	prompts, err := tr.ListPrompts(ctx)
+	if err != nil || len(prompts) != 1 {
+		t.Fatalf("ListPrompts = %+v, %v", prompts, err)
+	}
+	if args := prompts[0].Arguments; len(args) != 2 || args[0].Name != "issue" || args[1].Name != "focus" {
+		t.Errorf("prompt arguments = %+v", args)
+	}
+
+	text, err := tr.GetPrompt(ctx, "review", map[string]string{"issue": "gt-1", "focus": "tests"})
+	if err != nil || text != "Review gt-1 focusing on tests." {
+		t.Errorf("GetPrompt = %q, %v", text, err)
+	}
+	if _, err := tr.GetPrompt(ctx, "review", map[string]string{"focus": "tests"}); err == nil || !strings.Contains(err.Error(), "issue") {
+		t.Errorf("GetPrompt without a required argument: err = %v", err)
+	}
+
+	resources, err := tr.ListResources(ctx)
+	if err != nil || len(resources) != 2 || resources[0].URI != "gastown://beads/issues" {
+		t.Fatalf("ListResources = %+v, %v", resources, err)
+	}
+	data, err := tr.ReadResource(ctx, "gastown://beads/issues")
+	if err != nil || string(data) != `[{"id":"gt-1"}]` {
+		t.Errorf("ReadResource = %q, %v", data, err)
+	}
+	data, err = tr.ReadResource(ctx, "gastown://bin")
+	if err != nil || !bytes.Equal(data, []byte{0xff, 0x00, 0xfe}) {
+		t.Errorf("ReadResource(binary) = %v, %v", data, err)
+	}
+
+	_, err = tr.ReadResource(ctx, "gastown://missing")
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeResourceNotFound {
+		t.Errorf("missing resource err = %v", err)
+	}
+}
+
+func TestCancelRunningToolCall(t *testing.T) {
+	s, ts := newTestServer(t)
+	started := make(chan struct{})
+	s.RegisterTool("wait", "Blocks until cancelled", json.RawMessage(`{"type":"object"}`), func(ctx context.Context, args json.RawMessage) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	transport := NewSSETransport(ts.URL, "secret")
+	ctx := context.Background()
+	done := make(chan error, 1)
+	go func() {
+		_, err := transport.CallToolWithID(ctx, "call-1", "wait", json.RawMessage(`{}`))
+		done <- err
+	}()
+	<-started
+
+	cancelled, err := transport.CancelTool(ctx, "call-1")
+	if err != nil || !cancelled {
+		t.Fatalf("CancelTool = %v, %v", cancelled, err)
+	}
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "context canceled") {
+			t.Errorf("cancelled call error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("call did not return after cancellation")
+	}
+
+	// The finished call is no longer registered.
+	if cancelled, err := transport.CancelTool(ctx, "call-1"); err != nil || cancelled {
+		t.Errorf("second CancelTool = %v, %v; want false", cancelled, err)
+	}
+
+	// The legacy REST endpoint answers the same way.
+	req, _ := http.NewRequest("POST", ts.URL+"/mcp/tools/cancel", strings.NewReader(`{"callId":"nope"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var result toolCancelResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Cancelled {
+		t.Errorf("REST cancel = %+v, %v", result, err)
+	}
+}
+
+func TestCallIDsAreScopedToClient(t *testing.T) {
+	s, _ := newTestServer(t)
+	alice := withClient(context.Background(), "cn:alice")
+	bob := withClient(context.Background(), "cn:bob")
+
+	aliceCtx, cancelAlice := context.WithCancel(alice)
+	defer cancelAlice()
+	if !s.trackCall(alice, "1", cancelAlice) {
+		t.Fatal("trackCall(alice, 1) refused")
+	}
+	if s.cancelCall(bob, "1") {
+		t.Error("bob cancelled alice's call")
+	}
+	if aliceCtx.Err() != nil {
+		t.Fatal("alice's call was cancelled")
+	}
+	if !s.trackCall(bob, "1", func() {}) {
+		t.Error("bob's call ID collided with alice's")
+	}
+	if !s.cancelCall(alice, "1") || aliceCtx.Err() == nil {
+		t.Error("alice could not cancel their own call")
+	}
+}
@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSConfig enables HTTPS on the server.
+type TLSConfig struct {
+	// CertFile and KeyFile are the PEM server certificate and key.
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile, when set, turns on mutual TLS: clients must present a
+	// certificate signed by one of the CAs in this PEM bundle.
+	ClientCAFile string
+}
+
+// serverConfig builds the tls.Config the HTTP server listens with.
+func (c TLSConfig) serverConfig() (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return nil, fmt.Errorf("TLS requires both a certificate and a key")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if c.ClientCAFile != "" {
+		pool, err := loadCertPool(c.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client CA: %w", err)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// SetTLS makes Start serve HTTPS with cfg. The certificate and CA files are
+// loaded immediately so a bad path fails before the server starts.
+func (s *Server) SetTLS(cfg TLSConfig) error {
+	tlsConfig, err := cfg.serverConfig()
+	if err != nil {
+		return err
+	}
+	s.tlsConfig = tlsConfig
+	return nil
+}
+
+// ClientTLSConfig configures how a transport connects to an HTTPS server.
+type ClientTLSConfig struct {
+	// CAFile is a PEM bundle used to verify the server instead of the
+	// system roots, for servers with a private or self-signed certificate.
+	CAFile string
+
+	// CertFile and KeyFile are the client certificate presented to a
+	// server that requires mutual TLS.
+	CertFile string
+	KeyFile  string
+
+	// ServerName overrides the name checked against the server
+	// certificate, for connecting by IP address.
+	ServerName string
+}
+
+// clientConfig builds the tls.Config a transport dials with.
+func (c ClientTLSConfig) clientConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+	if c.CAFile != "" {
+		pool, err := loadCertPool(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading server CA: %w", err)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate requires both a certificate and a key")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// SetTLS configures the CA and client certificate used for https:// server
+// URLs.
+func (t *SSETransport) SetTLS(cfg ClientTLSConfig) error {
+	tlsConfig, err := cfg.clientConfig()
+	if err != nil {
+		return err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	t.httpClient.Transport = transport
+	return nil
+}
+
+// loadCertPool reads a PEM bundle of CA certificates.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
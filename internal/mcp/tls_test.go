@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	ca := &testCA{cert: cert, key: key, dir: t.TempDir()}
+	writePEM(t, ca.path("ca.pem"), "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) path(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+// issue writes name.pem and name-key.pem for a leaf certificate.
+func (ca *testCA) issue(t *testing.T, name string, serial int64, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = ca.path(name+".pem"), ca.path(name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "client", 3, x509.ExtKeyUsageClientAuth)
+
+	s, _ := newTestServer(t)
+	if err := s.SetTLS(TLSConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: ca.path("ca.pem")}); err != nil {
+		t.Fatalf("SetTLS: %v", err)
+	}
+	ts := httptest.NewUnstartedServer(s.handler())
+	ts.TLS = s.tlsConfig
+	ts.StartTLS()
+	defer ts.Close()
+
+	ctx := context.Background()
+
+	// Trusting the server is not enough without a client certificate.
+	anon := NewSSETransport(ts.URL, "secret")
+	if err := anon.SetTLS(ClientTLSConfig{CAFile: ca.path("ca.pem")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := anon.Connect(ctx); err == nil {
+		t.Fatal("Connect without a client certificate succeeded")
+	}
+
+	tr := NewSSETransport(ts.URL, "secret")
+	defer func() { _ = tr.Close() }()
+	if err := tr.SetTLS(ClientTLSConfig{CAFile: ca.path("ca.pem"), CertFile: clientCert, KeyFile: clientKey}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Connect(ctx); err != nil {
+		t.Fatalf("Connect with a client certificate: %v", err)
+	}
+	if _, err := tr.ListTools(ctx); err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+}
+
+func TestSetTLSValidatesFiles(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", 2, x509.ExtKeyUsageServerAuth)
+	s, _ := newTestServer(t)
+
+	if err := s.SetTLS(TLSConfig{CertFile: serverCert}); err == nil {
+		t.Error("SetTLS without a key succeeded")
+	}
+	if err := s.SetTLS(TLSConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: serverKey}); err == nil {
+		t.Error("SetTLS with a CA file holding no certificates succeeded")
+	}
+	if err := NewSSETransport("https://127.0.0.1", "").SetTLS(ClientTLSConfig{CertFile: serverCert}); err == nil {
+		t.Error("client SetTLS with a certificate but no key succeeded")
+	}
+}
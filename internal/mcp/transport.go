@@ -3,11 +3,14 @@ package mcp
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -38,15 +41,29 @@ type Transport interface {
 	// CallTool invokes a tool on the remote server and returns the result.
 	CallTool(ctx context.Context, name string, args json.RawMessage) (string, error)
 
+	// ListPrompts returns the prompt templates the remote server offers.
+	ListPrompts(ctx context.Context) ([]PromptRegistration, error)
+
+	// GetPrompt renders a prompt template with the given arguments.
+	GetPrompt(ctx context.Context, name string, args map[string]string) (string, error)
+
+	// ListResources returns the read-only resources the remote server offers.
+	ListResources(ctx context.Context) ([]ResourceRegistration, error)
+
+	// ReadResource returns the contents of a resource.
+	ReadResource(ctx context.Context, uri string) ([]byte, error)
+
 	// Close tears down the transport connection.
 	Close() error
 }
 
 // SSETransport implements Transport over HTTP with SSE for streaming.
+// Requests are JSON-RPC 2.0 messages POSTed to the server's /mcp endpoint.
 type SSETransport struct {
 	baseURL    string
 	authToken  string
 	httpClient *http.Client
+	nextID     atomic.Int64
 }
 
 // NewSSETransport creates an SSE transport client.
@@ -63,88 +80,56 @@ func NewSSETransport(baseURL, authToken string) *SSETransport {
 	}
 }
 
-// Connect checks that the MCP server is reachable.
+// Connect performs the MCP initialize handshake with the server.
 func (t *SSETransport) Connect(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", t.baseURL+"/mcp/health", nil)
-	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
-	}
-
-	resp, err := t.httpClient.Do(req)
+	var result initializeResult
+	err := t.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      implementationInfo{Name: ServerName, Version: ServerVersion},
+	}, &result)
 	if err != nil {
 		return fmt.Errorf("connecting to MCP server: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("MCP server returned %d: %s", resp.StatusCode, string(body))
+	if result.ProtocolVersion == "" {
+		return fmt.Errorf("connecting to MCP server: initialize returned no protocol version")
 	}
 
+	if err := t.notify(ctx, "notifications/initialized"); err != nil {
+		return fmt.Errorf("connecting to MCP server: %w", err)
+	}
 	return nil
 }
 
 // ListTools retrieves available tools from the MCP server.
 func (t *SSETransport) ListTools(ctx context.Context) ([]ToolRegistration, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", t.baseURL+"/mcp/tools/list", nil)
-	if err != nil {
-		return nil, fmt.Errorf("creating request: %w", err)
-	}
-	t.setAuth(req)
-
-	resp, err := t.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("listing tools: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("list tools failed %d: %s", resp.StatusCode, string(body))
-	}
-
 	var result struct {
 		Tools []ToolRegistration `json:"tools"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decoding tools: %w", err)
+	if err := t.call(ctx, "tools/list", nil, &result); err != nil {
+		return nil, fmt.Errorf("listing tools: %w", err)
 	}
-
 	return result.Tools, nil
 }
 
 // CallTool invokes a tool on the MCP server.
 func (t *SSETransport) CallTool(ctx context.Context, name string, args json.RawMessage) (string, error) {
-	body, err := json.Marshal(toolCallRequest{
+	return t.CallToolWithID(ctx, "", name, args)
+}
+
+// CallToolWithID invokes a tool like CallTool, naming the call callID so it
+// can be stopped with CancelTool while it runs. IDs must be unique among the
+// server's running calls.
+func (t *SSETransport) CallToolWithID(ctx context.Context, callID, name string, args json.RawMessage) (string, error) {
+	var result toolCallResponse
+	err := t.call(ctx, "tools/call", toolCallRequest{
 		Name:      name,
 		Arguments: args,
-	})
-	if err != nil {
-		return "", fmt.Errorf("marshaling request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL+"/mcp/tools/call", bytes.NewReader(body))
-	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-	t.setAuth(req)
-
-	resp, err := t.httpClient.Do(req)
+		CallID:    callID,
+	}, &result)
 	if err != nil {
 		return "", fmt.Errorf("calling tool: %w", err)
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("tool call failed %d: %s", resp.StatusCode, string(respBody))
-	}
-
-	var result toolCallResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("decoding response: %w", err)
-	}
 
 	if result.IsError {
 		if len(result.Content) > 0 {
@@ -159,12 +144,151 @@ func (t *SSETransport) CallTool(ctx context.Context, name string, args json.RawM
 	return "", nil
 }
 
+// CancelTool cancels the running call started with CallToolWithID(callID).
+// It reports whether the server found such a call.
+func (t *SSETransport) CancelTool(ctx context.Context, callID string) (bool, error) {
+	var result toolCancelResponse
+	if err := t.call(ctx, "tools/cancel", toolCancelRequest{CallID: callID}, &result); err != nil {
+		return false, fmt.Errorf("cancelling tool call: %w", err)
+	}
+	return result.Cancelled, nil
+}
+
+// ListPrompts retrieves available prompt templates from the MCP server.
+func (t *SSETransport) ListPrompts(ctx context.Context) ([]PromptRegistration, error) {
+	var result struct {
+		Prompts []PromptRegistration `json:"prompts"`
+	}
+	if err := t.call(ctx, "prompts/list", nil, &result); err != nil {
+		return nil, fmt.Errorf("listing prompts: %w", err)
+	}
+	return result.Prompts, nil
+}
+
+// GetPrompt renders a prompt on the MCP server and returns its text.
+func (t *SSETransport) GetPrompt(ctx context.Context, name string, args map[string]string) (string, error) {
+	var result promptGetResponse
+	err := t.call(ctx, "prompts/get", promptGetRequest{Name: name, Arguments: args}, &result)
+	if err != nil {
+		return "", fmt.Errorf("getting prompt: %w", err)
+	}
+
+	var parts []string
+	for _, m := range result.Messages {
+		parts = append(parts, m.Content.Text)
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// ListResources retrieves available resources from the MCP server.
+func (t *SSETransport) ListResources(ctx context.Context) ([]ResourceRegistration, error) {
+	var result struct {
+		Resources []ResourceRegistration `json:"resources"`
+	}
+	if err := t.call(ctx, "resources/list", nil, &result); err != nil {
+		return nil, fmt.Errorf("listing resources: %w", err)
+	}
+	return result.Resources, nil
+}
+
+// ReadResource reads a resource from the MCP server.
+func (t *SSETransport) ReadResource(ctx context.Context, uri string) ([]byte, error) {
+	var result resourceReadResponse
+	if err := t.call(ctx, "resources/read", resourceReadRequest{URI: uri}, &result); err != nil {
+		return nil, fmt.Errorf("reading resource: %w", err)
+	}
+	if len(result.Contents) == 0 {
+		return nil, nil
+	}
+	c := result.Contents[0]
+	if c.Blob != "" {
+		data, err := base64.StdEncoding.DecodeString(c.Blob)
+		if err != nil {
+			return nil, fmt.Errorf("decoding resource blob: %w", err)
+		}
+		return data, nil
+	}
+	return []byte(c.Text), nil
+}
+
 // Close releases HTTP client resources.
 func (t *SSETransport) Close() error {
 	t.httpClient.CloseIdleConnections()
 	return nil
 }
 
+// call sends a JSON-RPC request and decodes its result into result.
+// A JSON-RPC error from the server is returned as an *RPCError.
+func (t *SSETransport) call(ctx context.Context, method string, params, result interface{}) error {
+	id, _ := json.Marshal(t.nextID.Add(1))
+	body, err := t.post(ctx, method, id, params)
+	if err != nil {
+		return err
+	}
+
+	var resp rpcResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("decoding %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// notify sends a JSON-RPC notification, which has no response.
+func (t *SSETransport) notify(ctx context.Context, method string) error {
+	_, err := t.post(ctx, method, nil, nil)
+	return err
+}
+
+func (t *SSETransport) post(ctx context.Context, method string, id json.RawMessage, params interface{}) ([]byte, error) {
+	msg := rpcRequest{JSONRPC: jsonrpcVersion, ID: id, Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling request: %w", err)
+		}
+		msg.Params = raw
+	}
+	reqBody, err := json.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.baseURL+"/mcp", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	t.setAuth(req)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		return nil, &HTTPError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RetryAfter: parseRetryAfter(resp.Header),
+		}
+	}
+	return body, nil
+}
+
 func (t *SSETransport) setAuth(req *http.Request) {
 	if t.authToken != "" {
 		req.Header.Set("Authorization", "Bearer "+t.authToken)
@@ -184,3 +308,38 @@ func NewTransport(transportType TransportType, serverURL, authToken string) (Tra
 		return nil, fmt.Errorf("unknown transport type: %s", transportType)
 	}
 }
+
+// HTTPError is returned by SSETransport when the server answers with an
+// unexpected HTTP status. It keeps the status and any Retry-After guidance so
+// callers can back off when the server is rate limiting them.
+type HTTPError struct {
+	StatusCode int
+	Body       string
+
+	// RetryAfter is the delay the server asked for via Retry-After. Zero
+	// when the server gave no guidance.
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("MCP server returned %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the request may succeed if retried: the server
+// was rate limiting or briefly unavailable.
+func (e *HTTPError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds.
+func parseRetryAfter(h http.Header) time.Duration {
+	secs, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -4,17 +4,45 @@ import (
 	"bytes"
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"strings"
 	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+// KindBlossomAuth is the BUD-01 authorization event kind.
+const KindBlossomAuth = 24242
+
+// blossomAuthTTL is how long a BUD-01 authorization event stays valid.
+const blossomAuthTTL = 5 * time.Minute
+
+// Attachment types for blobs attached to Gas Town events.
+const (
+	BlobTypePatch      = "patch"
+	BlobTypeDiff       = "diff"
+	BlobTypeScreenshot = "screenshot"
+	BlobTypeLog        = "log"
 )
 
+// ValidBlobType reports whether t is one of the BlobType* attachment types.
+func ValidBlobType(t string) bool {
+	switch t {
+	case BlobTypePatch, BlobTypeDiff, BlobTypeScreenshot, BlobTypeLog:
+		return true
+	}
+	return false
+}
+
 // BlobReference identifies content stored on a Blossom server.
 type BlobReference struct {
-	Type   string `json:"type"`
+	Type   string `json:"type"` // MIME type from Upload, or a BlobType* for attachments
 	URL    string `json:"url"`
 	SHA256 string `json:"sha256"`
 	Size   int    `json:"size"`
@@ -26,6 +54,7 @@ type BlobReference struct {
 type BlobUploader struct {
 	servers    []string
 	httpClient *http.Client
+	signer     Signer // optional; signs BUD-01 authorization events
 }
 
 // BlobUploadResult is the response from a successful Blossom upload.
@@ -46,6 +75,41 @@ func NewBlobUploader(servers []string) *BlobUploader {
 	}
 }
 
+// SetSigner sets the signer used to authorize requests with BUD-01 events.
+// Without one, requests are sent unauthenticated, which public servers accept.
+func (u *BlobUploader) SetSigner(signer Signer) {
+	u.signer = signer
+}
+
+// authorize signs a BUD-01 authorization event for verb ("upload", "get",
+// "list", ...) on the blob hashHex and sets it as the request's
+// Authorization header. It does nothing when no signer is configured.
+func (u *BlobUploader) authorize(ctx context.Context, req *http.Request, verb, hashHex string) error {
+	if u.signer == nil {
+		return nil
+	}
+	now := time.Now()
+	event := &nostr.Event{
+		CreatedAt: nostr.Timestamp(now.Unix()),
+		Kind:      nostr.Kind(KindBlossomAuth),
+		Tags: nostr.Tags{
+			{"t", verb},
+			{"x", hashHex},
+			ExpirationTag(now.Add(blossomAuthTTL)),
+		},
+		Content: verb + " blob " + hashHex,
+	}
+	if err := u.signer.Sign(ctx, event); err != nil {
+		return fmt.Errorf("signing blossom %s authorization: %w", verb, err)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encoding blossom authorization: %w", err)
+	}
+	req.Header.Set("Authorization", "Nostr "+base64.StdEncoding.EncodeToString(data))
+	return nil
+}
+
 // Upload uploads data to configured Blossom servers.
 // Returns the first successful upload result. Data is content-addressed by SHA-256.
 func (u *BlobUploader) Upload(ctx context.Context, data []byte, contentType string) (*BlobReference, error) {
@@ -70,6 +134,22 @@ func (u *BlobUploader) Upload(ctx context.Context, data []byte, contentType stri
 	return nil, fmt.Errorf("all blossom servers failed, last error: %w", lastErr)
 }
 
+// UploadAttachment uploads an artifact (a patch, diff, screenshot or log)
+// and returns a reference typed with blobType, ready to be appended to the
+// blob list of an event's content before it is published.
+func (u *BlobUploader) UploadAttachment(ctx context.Context, data []byte, blobType, contentType string) (*BlobReference, error) {
+	if !ValidBlobType(blobType) {
+		return nil, fmt.Errorf("invalid blob type %q (want %s, %s, %s or %s)",
+			blobType, BlobTypePatch, BlobTypeDiff, BlobTypeScreenshot, BlobTypeLog)
+	}
+	ref, err := u.Upload(ctx, data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	ref.Type = blobType
+	return ref, nil
+}
+
 // uploadToServer uploads to a single Blossom server.
 func (u *BlobUploader) uploadToServer(ctx context.Context, server string, data []byte, contentType, hashHex string) (*BlobReference, error) {
 	// Blossom PUT /upload with SHA-256 header
@@ -82,6 +162,9 @@ func (u *BlobUploader) uploadToServer(ctx context.Context, server string, data [
 
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("X-SHA-256", hashHex)
+	if err := u.authorize(ctx, req, "upload", hashHex); err != nil {
+		return nil, err
+	}
 
 	resp, err := u.httpClient.Do(req)
 	if err != nil {
@@ -121,6 +204,9 @@ func (u *BlobUploader) Check(ctx context.Context, hashHex string) (string, error
 		if err != nil {
 			continue
 		}
+		if err := u.authorize(ctx, req, "list", hashHex); err != nil {
+			return "", err
+		}
 
 		resp, err := u.httpClient.Do(req)
 		if err != nil {
@@ -134,3 +220,122 @@ func (u *BlobUploader) Check(ctx context.Context, hashHex string) (string, error
 	}
 	return "", fmt.Errorf("blob %s not found on any server", hashHex)
 }
+
+// Download fetches the blob with the given SHA-256 from the configured
+// servers. The content is hashed and a server returning bytes that don't
+// match hashHex is skipped, so the first valid copy wins.
+func (u *BlobUploader) Download(ctx context.Context, hashHex string) ([]byte, error) {
+	hashHex, err := normalizeBlobHash(hashHex)
+	if err != nil {
+		return nil, err
+	}
+	if len(u.servers) == 0 {
+		return nil, fmt.Errorf("no blossom servers configured")
+	}
+
+	var lastErr error
+	for _, server := range u.servers {
+		var buf bytes.Buffer
+		if err := u.fetchVerified(ctx, server+"/"+hashHex, hashHex, &buf); err != nil {
+			lastErr = err
+			continue
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("blob %s not available from any server, last error: %w", hashHex, lastErr)
+}
+
+// DownloadTo streams the blob described by ref into w without holding it in
+// memory. The reference URL is tried first, then the configured servers.
+// Each attempt is staged in a temporary file and only copied to w once its
+// hash matches, so w never receives content from a lying server.
+func (u *BlobUploader) DownloadTo(ctx context.Context, ref BlobReference, w io.Writer) error {
+	hashHex, err := normalizeBlobHash(ref.SHA256)
+	if err != nil {
+		return err
+	}
+
+	var urls []string
+	if ref.URL != "" {
+		urls = append(urls, ref.URL)
+	}
+	for _, server := range u.servers {
+		if url := server + "/" + hashHex; url != ref.URL {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("no blossom servers configured")
+	}
+
+	tmp, err := os.CreateTemp("", "gt-blob-*")
+	if err != nil {
+		return fmt.Errorf("creating staging file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	var lastErr error
+	for _, url := range urls {
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := tmp.Truncate(0); err != nil {
+			return err
+		}
+		if err := u.fetchVerified(ctx, url, hashHex, tmp); err != nil {
+			lastErr = err
+			continue
+		}
+		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, tmp); err != nil {
+			return fmt.Errorf("writing blob %s: %w", hashHex, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("blob %s not available from any server, last error: %w", hashHex, lastErr)
+}
+
+// fetchVerified GETs url into dst and checks the content hashes to hashHex.
+// On error dst may hold partial or mismatched content.
+func (u *BlobUploader) fetchVerified(ctx context.Context, url, hashHex string, dst io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+	if err := u.authorize(ctx, req, "get", hashHex); err != nil {
+		return err
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch of %s failed %d", url, resp.StatusCode)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dst, h), resp.Body); err != nil {
+		return fmt.Errorf("reading %s: %w", url, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != hashHex {
+		return fmt.Errorf("%s returned content with sha256 %s, want %s", url, got, hashHex)
+	}
+	return nil
+}
+
+// normalizeBlobHash validates a hex SHA-256 and returns it in lower case.
+func normalizeBlobHash(hashHex string) (string, error) {
+	hashHex = strings.ToLower(hashHex)
+	if b, err := hex.DecodeString(hashHex); err != nil || len(b) != sha256.Size {
+		return "", fmt.Errorf("invalid blob sha256 %q", hashHex)
+	}
+	return hashHex, nil
+}
@@ -0,0 +1,124 @@
+package nostr
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestBlobDownloadSkipsServerWithWrongContent(t *testing.T) {
+	blob := []byte("diff --git a/main.go b/main.go")
+	sum := sha256.Sum256(blob)
+	hashHex := hex.EncodeToString(sum[:])
+
+	liar := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("not the blob"))
+	}))
+	defer liar.Close()
+	honest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+hashHex {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(blob)
+	}))
+	defer honest.Close()
+
+	u := NewBlobUploader([]string{liar.URL, honest.URL})
+
+	got, err := u.Download(context.Background(), hashHex)
+	if err != nil || !bytes.Equal(got, blob) {
+		t.Fatalf("Download = %q, %v", got, err)
+	}
+
+	var buf bytes.Buffer
+	ref := BlobReference{URL: liar.URL + "/" + hashHex, SHA256: hashHex}
+	if err := u.DownloadTo(context.Background(), ref, &buf); err != nil || !bytes.Equal(buf.Bytes(), blob) {
+		t.Fatalf("DownloadTo = %q, %v", buf.Bytes(), err)
+	}
+
+	if _, err := NewBlobUploader([]string{liar.URL}).Download(context.Background(), hashHex); err == nil {
+		t.Error("Download should fail when no server returns matching content")
+	}
+	if _, err := u.Download(context.Background(), "nothex"); err == nil {
+		t.Error("Download should reject an invalid hash")
+	}
+}
+
+func TestBlobUploadSendsBUD01Authorization(t *testing.T) {
+	signer, err := NewLocalSigner("0000000000000000000000000000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := []byte("screenshot")
+	sum := sha256.Sum256(blob)
+	hashHex := hex.EncodeToString(sum[:])
+
+	var auth nostr.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(r.Header.Get("Authorization"), "Nostr "))
+		if err != nil || json.Unmarshal(data, &auth) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"url":"https://blossom.example/` + hashHex + `"}`))
+	}))
+	defer server.Close()
+
+	u := NewBlobUploader([]string{server.URL})
+	u.SetSigner(signer)
+	if _, err := u.Upload(context.Background(), blob, "image/png"); err != nil {
+		t.Fatal(err)
+	}
+
+	if auth.Kind != nostr.Kind(KindBlossomAuth) {
+		t.Errorf("auth kind = %d, want %d", auth.Kind, KindBlossomAuth)
+	}
+	if err := VerifyEvent(&auth); err != nil {
+		t.Errorf("auth event: %v", err)
+	}
+	if verb, _ := tagValue(auth.Tags, "t"); verb != "upload" {
+		t.Errorf("t = %q, want upload", verb)
+	}
+	if x, _ := tagValue(auth.Tags, "x"); x != hashHex {
+		t.Errorf("x = %q, want %s", x, hashHex)
+	}
+	if _, ok := expiresAt(auth.Tags); !ok {
+		t.Error("auth event has no expiration")
+	}
+}
+
+func TestUploadAttachmentTypesReference(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "text/x-diff" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"url":"https://blobs.example/abc"}`))
+	}))
+	defer srv.Close()
+	u := NewBlobUploader([]string{srv.URL})
+
+	data := []byte("--- a/x\n+++ b/x\n")
+	ref, err := u.UploadAttachment(context.Background(), data, BlobTypePatch, "text/x-diff")
+	if err != nil {
+		t.Fatalf("UploadAttachment: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if ref.Type != BlobTypePatch || ref.SHA256 != hex.EncodeToString(sum[:]) || ref.Size != len(data) {
+		t.Errorf("ref = %+v", ref)
+	}
+
+	if _, err := u.UploadAttachment(context.Background(), data, "video", "video/mp4"); err == nil || !strings.Contains(err.Error(), "invalid blob type") {
+		t.Errorf("UploadAttachment with bad type: err = %v", err)
+	}
+}
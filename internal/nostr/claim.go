@@ -0,0 +1,91 @@
+package nostr
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+// ClaimSettleWindow is how long a worker waits after publishing a claim
+// before calling ResolveClaim. Relays are only eventually consistent: two
+// workers can both see an item as available and both publish a claim, and
+// neither sees the other's claim until it has propagated. Waiting out the
+// window lets every competing claim arrive so all workers resolve the same
+// winner; a claim that arrives later than this can still be lost.
+const ClaimSettleWindow = 3 * time.Second
+
+const (
+	claimantTag  = "claimant"
+	claimedAtTag = "claimed_at"
+)
+
+var (
+	claimClockMu sync.Mutex
+	lastClaimAt  int64
+)
+
+// ClaimTags returns the arbitration tags for a work-item claim by claimant
+// (hex pubkey): the claimant and a claim timestamp in Unix milliseconds that
+// strictly increases within this process.
+func ClaimTags(claimant string) nostr.Tags {
+	claimClockMu.Lock()
+	at := time.Now().UnixMilli()
+	if at <= lastClaimAt {
+		at = lastClaimAt + 1
+	}
+	lastClaimAt = at
+	claimClockMu.Unlock()
+
+	return nostr.Tags{
+		{claimantTag, claimant},
+		{claimedAtTag, strconv.FormatInt(at, 10)},
+	}
+}
+
+// ResolveClaim picks the winning claim among competing claim events for the
+// same work item and returns the winner's pubkey, or "" if none is valid.
+// The earliest claim timestamp wins, with ties going to the lowest pubkey, so
+// every worker that sees the same set of claims agrees on the winner. Claims
+// that fail VerifyEvent, or whose claimant tag doesn't match the event's
+// author, are ignored, so a relay can't win arbitration with a forged claim.
+func ResolveClaim(events []*nostr.Event) (winner string) {
+	var winnerAt int64
+	for _, event := range events {
+		claimant, at, ok := parseClaim(event)
+		if !ok {
+			continue
+		}
+		if winner == "" || at < winnerAt || (at == winnerAt && claimant < winner) {
+			winner, winnerAt = claimant, at
+		}
+	}
+	return winner
+}
+
+func parseClaim(event *nostr.Event) (claimant string, at int64, ok bool) {
+	if VerifyEvent(event) != nil {
+		return "", 0, false
+	}
+	var atStr string
+	for _, tag := range event.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case claimantTag:
+			claimant = tag[1]
+		case claimedAtTag:
+			atStr = tag[1]
+		}
+	}
+	if claimant == "" || claimant != PubKeyToString(event.PubKey) {
+		return "", 0, false
+	}
+	at, err := strconv.ParseInt(atStr, 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return claimant, at, true
+}
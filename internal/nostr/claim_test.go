@@ -0,0 +1,108 @@
+package nostr
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestResolveClaimConcurrentClaimants(t *testing.T) {
+	ctx := context.Background()
+	keys := []string{
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+	}
+
+	// Both workers see the item as available and claim it at the same time.
+	claims := make([]*nostr.Event, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			signer, err := NewLocalSigner(key)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			event := &nostr.Event{Kind: 1, Tags: ClaimTags(signer.GetPublicKey())}
+			if err := signer.Sign(ctx, event); err != nil {
+				t.Error(err)
+			}
+			claims[i] = event
+		}(i, key)
+	}
+	wg.Wait()
+
+	// After settling, each worker sees the claims in its own order but
+	// both must agree on one winner: the earliest claim.
+	first := ResolveClaim(claims)
+	second := ResolveClaim([]*nostr.Event{claims[1], claims[0]})
+	if first == "" || first != second {
+		t.Fatalf("winners disagree: %q vs %q", first, second)
+	}
+	_, at0, _ := parseClaim(claims[0])
+	_, at1, _ := parseClaim(claims[1])
+	if at0 == at1 {
+		t.Fatal("claim timestamps within a process must be distinct")
+	}
+	want := PubKeyToString(claims[0].PubKey)
+	if at1 < at0 {
+		want = PubKeyToString(claims[1].PubKey)
+	}
+	if first != want {
+		t.Errorf("winner = %s, want earliest claimant %s", first, want)
+	}
+}
+
+func TestResolveClaimTieBreakAndValidation(t *testing.T) {
+	signers := map[string]*LocalSigner{}
+	for _, key := range []string{
+		"0000000000000000000000000000000000000000000000000000000000000001",
+		"0000000000000000000000000000000000000000000000000000000000000002",
+	} {
+		signer, err := NewLocalSigner(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers[signer.GetPublicKey()] = signer
+	}
+	a := "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	b := "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
+	claim := func(author, claimant, at string) *nostr.Event {
+		event := &nostr.Event{
+			Kind: 1,
+			Tags: nostr.Tags{{claimantTag, claimant}, {claimedAtTag, at}},
+		}
+		if err := signers[author].Sign(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	if got := ResolveClaim([]*nostr.Event{claim(b, b, "100"), claim(a, a, "100")}); got != a {
+		t.Errorf("tie winner = %s, want lowest pubkey %s", got, a)
+	}
+	// b's claim names a as the claimant, so it's forged and ignored.
+	if got := ResolveClaim([]*nostr.Event{claim(b, a, "50"), claim(b, b, "100")}); got != b {
+		t.Errorf("winner = %s, want %s", got, b)
+	}
+	if got := ResolveClaim([]*nostr.Event{claim(a, a, "soon"), nil}); got != "" {
+		t.Errorf("winner of invalid claims = %q, want none", got)
+	}
+
+	// An earlier claim in a's name that a never signed loses to b's.
+	forged := &nostr.Event{
+		PubKey: PubKeyFromHexGT(a),
+		Kind:   1,
+		Tags:   nostr.Tags{{claimantTag, a}, {claimedAtTag, "1"}},
+	}
+	forged.ID = forged.GetID()
+	tampered := claim(a, a, "2")
+	tampered.Tags[1][1] = "1"
+	if got := ResolveClaim([]*nostr.Event{forged, tampered, claim(b, b, "100")}); got != b {
+		t.Errorf("winner = %s, want %s over unsigned and tampered claims", got, b)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/rand/v2"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,14 +25,65 @@ type RelayPool struct {
 	readRelays  []*nostr.Relay
 	writeRelays []*nostr.Relay
 	closed      bool
+
+	// NIP-42 authentication. authURLs are relays configured as requiring
+	// AUTH; authed tracks connections that have already authenticated.
+	authURLs   map[string]bool
+	authSigner Signer
+	authMu     sync.Mutex
+	authed     map[*nostr.Relay]bool
+
+	// reconnected is closed (and replaced) whenever Reconnect replaces a
+	// relay connection, so managed subscriptions know to resubscribe.
+	reconnected chan struct{}
+
+	// backoff tracks reconnect attempts per relay URL.
+	backoff map[string]*relayBackoff
+
+	// reconnectMu lets one reconnect dial at a time. Dials happen without
+	// holding mu, so a slow relay doesn't stall the rest of the pool.
+	reconnectMu sync.Mutex
+
+	// dmRelays caches recipients' DM relays for FetchDMRelays.
+	dmRelays dmRelayCache
+
+	// negSkipUntil holds write relays that recently failed a NIP-77
+	// reconcile; see HeldEvents.
+	negMu        sync.Mutex
+	negSkipUntil map[string]time.Time
+}
+
+// relayBackoff is the reconnect state of one relay URL.
+type relayBackoff struct {
+	failures    int       // consecutive failed attempts
+	next        time.Time // earliest time the reconnect loop retries
+	lastAttempt time.Time
+	lastErr     error
 }
 
+// Reconnect loop timing. Failed relays are retried after
+// reconnectBaseDelay, doubling per consecutive failure up to
+// reconnectMaxDelay, with jitter so several processes don't retry in step.
+const (
+	DefaultReconnectCheckInterval = 10 * time.Second
+	reconnectBaseDelay            = 5 * time.Second
+	reconnectMaxDelay             = 5 * time.Minute
+)
+
 // NewRelayPool creates a relay pool from the Nostr configuration.
 // It connects to all configured read and write relays.
 func NewRelayPool(ctx context.Context, cfg *config.NostrConfig) (*RelayPool, error) {
 	p := &RelayPool{
 		readURLs:  append([]string(nil), cfg.ReadRelays...),
 		writeURLs: append([]string(nil), cfg.WriteRelays...),
+		authURLs:  make(map[string]bool, len(cfg.RequiresAuth)),
+		authed:    make(map[*nostr.Relay]bool),
+
+		reconnected: make(chan struct{}),
+		backoff:     make(map[string]*relayBackoff),
+	}
+	for _, url := range cfg.RequiresAuth {
+		p.authURLs[url] = true
 	}
 
 	// Connect to write relays (required)
@@ -59,44 +112,141 @@ func NewRelayPool(ctx context.Context, cfg *config.NostrConfig) (*RelayPool, err
 // Publish sends an event to all write relays.
 // Returns an error only if ALL relays fail.
 func (p *RelayPool) Publish(ctx context.Context, event nostr.Event) error {
+	results, err := p.PublishDetailed(ctx, event)
+	if err != nil {
+		return err
+	}
+	return acceptedOrError(results)
+}
+
+// PublishDetailed sends an event to all write relays concurrently and
+// reports the outcome per relay URL. A nil entry means the relay answered
+// with OK=true; a relay that answered OK=false, timed out waiting for OK, or
+// failed to send gets the corresponding error. The returned error is only
+// set when nothing could be attempted (pool closed, no relays connected).
+//
+// Each relay gets DefaultPublishTimeout per attempt (plus one AUTH and
+// retry when the relay asks for it). A relay that does not return within
+// that budget is reported as timed out rather than holding up the rest.
+func (p *RelayPool) PublishDetailed(ctx context.Context, event nostr.Event) (map[string]error, error) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
 	if p.closed {
-		return fmt.Errorf("relay pool is closed")
+		return nil, fmt.Errorf("relay pool is closed")
 	}
 
 	if len(p.writeRelays) == 0 {
-		return fmt.Errorf("no write relays connected")
+		return nil, fmt.Errorf("no write relays connected")
 	}
 
-	var lastErr error
-	successes := 0
-
+	type publishResult struct {
+		url string
+		err error
+	}
+	// Buffered so a relay that finishes after the deadline doesn't leak
+	// its goroutine.
+	done := make(chan publishResult, len(p.writeRelays))
+	pending := make(map[string]bool, len(p.writeRelays))
 	for _, relay := range p.writeRelays {
-		if err := relay.Publish(ctx, event); err != nil {
-			lastErr = err
-			log.Printf("[nostr] publish to %s failed: %v", relay.URL, err)
-		} else {
-			successes++
+		pending[relay.URL] = true
+		// The event is passed by value; each relay gets its own copy.
+		go func(relay *nostr.Relay, event nostr.Event) {
+			done <- publishResult{relay.URL, p.publishToRelay(ctx, relay, event)}
+		}(relay, event)
+	}
+
+	budget := 3 * relayPublishTimeout // publish, AUTH, publish again
+	deadline := time.NewTimer(budget)
+	defer deadline.Stop()
+
+	results := make(map[string]error, len(p.writeRelays))
+	for len(pending) > 0 {
+		select {
+		case r := <-done:
+			if r.err != nil {
+				log.Printf("[nostr] publish to %s rejected: %v", r.url, r.err)
+			}
+			results[r.url] = r.err
+			delete(pending, r.url)
+		case <-deadline.C:
+			for url := range pending {
+				log.Printf("[nostr] publish to %s timed out after %s", url, budget)
+				results[url] = fmt.Errorf("publish to %s timed out after %s", url, budget)
+			}
+			pending = nil
 		}
 	}
 
-	if successes == 0 {
-		return fmt.Errorf("all write relays failed, last error: %w", lastErr)
+	return results, nil
+}
+
+// publishToRelay publishes event to one relay, authenticating and retrying
+// once if the relay requires it.
+func (p *RelayPool) publishToRelay(ctx context.Context, relay *nostr.Relay, event nostr.Event) error {
+	// relay.Publish blocks until the relay's OK message arrives and returns
+	// its rejection reason when OK=false, so a nil error here means the
+	// relay really accepted the event.
+	err := publishWithTimeout(ctx, relay, event)
+	if err != nil && p.shouldAuth(relay, err) {
+		if authErr := p.authenticate(ctx, relay); authErr == nil {
+			err = publishWithTimeout(ctx, relay, event)
+		}
 	}
+	return err
+}
 
-	return nil
+// relayPublish and relayPublishTimeout are variables so tests can stand in
+// for real relays.
+var (
+	relayPublish        = (*nostr.Relay).Publish
+	relayPublishTimeout = DefaultPublishTimeout
+)
+
+func publishWithTimeout(ctx context.Context, relay *nostr.Relay, event nostr.Event) error {
+	publishCtx, cancel := context.WithTimeout(ctx, relayPublishTimeout)
+	defer cancel()
+	return relayPublish(relay, publishCtx, event)
+}
+
+// acceptedOrError returns nil if at least one relay accepted, otherwise an
+// error wrapping one of the rejections.
+func acceptedOrError(results map[string]error) error {
+	var lastErr error
+	for _, err := range results {
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("all write relays failed, last error: %w", lastErr)
 }
 
 // Subscribe creates a subscription across all read relays.
 // The caller is responsible for reading from the returned channel.
 func (p *RelayPool) Subscribe(ctx context.Context, filters []nostr.Filter) []*nostr.Subscription {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
+	relays := append([]*nostr.Relay(nil), p.readRelays...)
+	p.mu.RUnlock()
+
+	// Auth-required relays close unauthenticated subscriptions (and DMs are
+	// only served to their recipient), so AUTH first. Relays authenticate
+	// concurrently and without the pool lock, so slow ones neither add up
+	// nor hold off reconnects.
+	var wg sync.WaitGroup
+	for _, relay := range relays {
+		if p.authURLs[relay.URL] {
+			wg.Add(1)
+			go func(relay *nostr.Relay) {
+				defer wg.Done()
+				_ = p.authenticate(ctx, relay)
+			}(relay)
+		}
+	}
+	wg.Wait()
 
 	var subs []*nostr.Subscription
-	for _, relay := range p.readRelays {
+	for _, relay := range relays {
 		// Subscribe to each filter individually since fiatjaf.com/nostr
 		// takes a single Filter per subscription call.
 		for _, f := range filters {
@@ -112,54 +262,321 @@ func (p *RelayPool) Subscribe(ctx context.Context, filters []nostr.Filter) []*no
 	return subs
 }
 
-// Reconnect attempts to reconnect disconnected relays.
-// Call this periodically from a health monitor goroutine.
+// SetAuthSigner sets the signer used to answer NIP-42 AUTH challenges.
+// Without one, auth-required relays are used anonymously and will reject
+// writes and subscriptions.
+func (p *RelayPool) SetAuthSigner(signer Signer) {
+	p.authMu.Lock()
+	defer p.authMu.Unlock()
+	p.authSigner = signer
+}
+
+// shouldAuth reports whether a failed publish to relay is worth retrying
+// after authenticating: the relay is configured as requiring AUTH or
+// rejected the event with the NIP-42 "auth-required:" prefix.
+func (p *RelayPool) shouldAuth(relay *nostr.Relay, err error) bool {
+	p.authMu.Lock()
+	hasSigner := p.authSigner != nil
+	p.authMu.Unlock()
+	return hasSigner && (p.authURLs[relay.URL] || isAuthRequired(err))
+}
+
+func isAuthRequired(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "auth-required:")
+}
+
+// authenticate answers relay's AUTH challenge with a kind-22242 event signed
+// by the pool's auth signer. Each connection authenticates at most once.
+func (p *RelayPool) authenticate(ctx context.Context, relay *nostr.Relay) error {
+	p.authMu.Lock()
+	signer := p.authSigner
+	done := p.authed[relay]
+	p.authMu.Unlock()
+	if signer == nil {
+		return fmt.Errorf("no signer configured for NIP-42 auth")
+	}
+	if done {
+		return nil
+	}
+
+	log.Printf("[nostr] authenticating to %s as %s", relay.URL, FormatPubKey(signer.GetPublicKey()))
+	authCtx, cancel := context.WithTimeout(ctx, DefaultPublishTimeout)
+	defer cancel()
+	if err := relay.Auth(authCtx, signer.Sign); err != nil {
+		log.Printf("[nostr] auth to %s failed: %v", relay.URL, err)
+		return fmt.Errorf("auth to %s: %w", relay.URL, err)
+	}
+	log.Printf("[nostr] auth to %s succeeded", relay.URL)
+
+	p.authMu.Lock()
+	p.authed[relay] = true
+	p.authMu.Unlock()
+	return nil
+}
+
+// Reconnect attempts to reconnect disconnected relays immediately,
+// regardless of backoff. StartReconnectLoop is the usual way to keep relays
+// connected; Reconnect is for callers that need a connection now.
 func (p *RelayPool) Reconnect(ctx context.Context) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	p.reconnect(ctx, true)
+}
+
+// StartReconnectLoop checks relay connections every
+// DefaultReconnectCheckInterval until ctx is done or the pool is closed, and
+// reconnects dead relays with per-relay exponential backoff.
+func (p *RelayPool) StartReconnectLoop(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(DefaultReconnectCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if p.isClosed() {
+					return
+				}
+				reconnectCtx, cancel := context.WithTimeout(ctx, DefaultConnectTimeout)
+				p.reconnect(reconnectCtx, false)
+				cancel()
+			}
+		}
+	}()
+}
+
+func (p *RelayPool) isClosed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.closed
+}
+
+func (p *RelayPool) reconnect(ctx context.Context, force bool) {
+	p.reconnectMu.Lock()
+	defer p.reconnectMu.Unlock()
 
+	p.mu.Lock()
 	if p.closed {
+		p.mu.Unlock()
 		return
 	}
-
 	// Iterate configured URLs rather than only the successfully connected relay
 	// slices. This also retries URLs that failed during NewRelayPool.
-	p.writeRelays = reconnectConfiguredRelays(ctx, "write", p.writeURLs, p.writeRelays)
-	p.readRelays = reconnectConfiguredRelays(ctx, "read", p.readURLs, p.readRelays)
+	now := time.Now()
+	dials := p.dueRelays("write", p.writeURLs, p.writeRelays, now, force)
+	dials = append(dials, p.dueRelays("read", p.readURLs, p.readRelays, now, force)...)
+	p.mu.Unlock()
+
+	if len(dials) == 0 {
+		return
+	}
+
+	// Dial without holding p.mu: each attempt can take up to
+	// DefaultConnectTimeout, and publishes and subscriptions on the relays
+	// that are up shouldn't wait for it.
+	failed := make(map[string]bool)
+	for i := range dials {
+		d := &dials[i]
+		if failed[d.url] {
+			// Configured for both read and write, and already failed once
+			// this round.
+			d.skipped = true
+			continue
+		}
+		log.Printf("[nostr] reconnecting %s relay %s", d.relayType, d.url)
+		d.relay, d.err = relayConnect(ctx, d.url, nostr.RelayOptions{})
+		if d.err != nil {
+			failed[d.url] = true
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		for _, d := range dials {
+			if d.relay != nil {
+				_ = d.relay.Close()
+			}
+		}
+		return
+	}
+
+	changed := false
+	for _, d := range dials {
+		if d.skipped {
+			continue
+		}
+		state := p.backoffFor(d.url)
+		if d.err != nil {
+			state.failures++
+			state.lastErr = d.err
+			state.next = now.Add(reconnectDelay(state.failures))
+			log.Printf("[nostr] reconnect failed for %s (attempt %d, next in %s): %v",
+				d.url, state.failures, state.next.Sub(now).Round(time.Second), d.err)
+			continue
+		}
+		state.failures = 0
+		state.lastErr = nil
+		state.next = time.Time{}
+
+		if d.relayType == "write" {
+			p.writeRelays = replaceRelay(p.writeRelays, d.relay)
+		} else {
+			p.readRelays = replaceRelay(p.readRelays, d.relay)
+		}
+		changed = true
+	}
+
+	if changed && p.reconnected != nil {
+		close(p.reconnected)
+		p.reconnected = make(chan struct{})
+	}
+}
+
+// reconnectSignal returns a channel that is closed the next time Reconnect
+// replaces a relay connection.
+func (p *RelayPool) reconnectSignal() <-chan struct{} {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.reconnected
+}
+
+// relayDial is one reconnect attempt planned by dueRelays.
+type relayDial struct {
+	relayType string
+	url       string
+	relay     *nostr.Relay
+	err       error
+	skipped   bool
 }
 
-func reconnectConfiguredRelays(ctx context.Context, relayType string, urls []string, relays []*nostr.Relay) []*nostr.Relay {
-	indices := make(map[string]int, len(relays))
-	for i, relay := range relays {
-		if relay != nil {
-			indices[relay.URL] = i
+// dueRelays returns a dial for every configured URL that has no live
+// connection and, unless force is set, whose backoff has expired, and
+// records the attempt in its backoff state. The caller holds p.mu.
+func (p *RelayPool) dueRelays(relayType string, urls []string, relays []*nostr.Relay, now time.Time, force bool) []relayDial {
+	connected := make(map[string]bool, len(relays))
+	for _, relay := range relays {
+		if relay != nil && relay.IsConnected() {
+			connected[relay.URL] = true
 		}
 	}
 
+	var dials []relayDial
 	for _, url := range urls {
-		if i, ok := indices[url]; ok && relays[i] != nil && relays[i].IsConnected() {
+		if connected[url] {
 			continue
 		}
-
-		log.Printf("[nostr] reconnecting %s relay %s", relayType, url)
-		newRelay, err := relayConnect(ctx, url, nostr.RelayOptions{})
-		if err != nil {
-			log.Printf("[nostr] reconnect failed for %s: %v", url, err)
+		state := p.backoffFor(url)
+		if !force && now.Before(state.next) {
 			continue
 		}
+		state.lastAttempt = now
+		dials = append(dials, relayDial{relayType: relayType, url: url})
+	}
+	return dials
+}
 
-		if i, ok := indices[url]; ok {
-			if relays[i] != nil {
-				_ = relays[i].Close()
+// replaceRelay swaps relay in for the connection to the same URL in relays,
+// closing the old one, or appends it if there is none.
+func replaceRelay(relays []*nostr.Relay, relay *nostr.Relay) []*nostr.Relay {
+	for i, old := range relays {
+		if old != nil && old.URL == relay.URL {
+			_ = old.Close()
+			relays[i] = relay
+			return relays
+		}
+	}
+	return append(relays, relay)
+}
+
+func (p *RelayPool) backoffFor(url string) *relayBackoff {
+	if p.backoff == nil {
+		p.backoff = make(map[string]*relayBackoff)
+	}
+	state, ok := p.backoff[url]
+	if !ok {
+		state = &relayBackoff{}
+		p.backoff[url] = state
+	}
+	return state
+}
+
+// reconnectDelay returns the wait before the next attempt after the given
+// number of consecutive failures: exponential, capped, with "equal jitter"
+// (half fixed, half random).
+func reconnectDelay(failures int) time.Duration {
+	delay := reconnectMaxDelay
+	if shift := failures - 1; shift < 16 {
+		delay = min(reconnectBaseDelay<<shift, reconnectMaxDelay)
+	}
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// RelayHealth reports every configured relay's connection state and its
+// most recent reconnect attempt, write relays first. A relay counts as
+// connected only if it has a live connection, as in ConnectedWriteRelayURLs
+// and ConnectedReadRelayURLs.
+func (p *RelayPool) RelayHealth() []RelayStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var statuses []RelayStatus
+	add := func(relayType string, urls []string, relays []*nostr.Relay) {
+		connected := make(map[string]bool, len(relays))
+		for _, url := range connectedURLs(relays) {
+			connected[url] = true
+		}
+		for _, url := range urls {
+			rs := RelayStatus{URL: url, Type: relayType, Connected: connected[url]}
+			if state, ok := p.backoff[url]; ok {
+				rs.LastReconnectAttempt = state.lastAttempt
+				if state.lastErr != nil && !rs.Connected {
+					rs.Error = state.lastErr.Error()
+				}
 			}
-			relays[i] = newRelay
-		} else {
-			indices[url] = len(relays)
-			relays = append(relays, newRelay)
+			statuses = append(statuses, rs)
 		}
 	}
+	add("write", p.writeURLs, p.writeRelays)
+	add("read", p.readURLs, p.readRelays)
+	return statuses
+}
 
-	return relays
+// Probe checks that the relay at url is actually answering by opening a
+// short-lived subscription for a single event and waiting for it to reply
+// with an event or EOSE, up to DefaultProbeTimeout.
+func (p *RelayPool) Probe(ctx context.Context, url string) error {
+	p.mu.RLock()
+	var relay *nostr.Relay
+	for _, r := range append(append([]*nostr.Relay(nil), p.writeRelays...), p.readRelays...) {
+		if r != nil && r.URL == url {
+			relay = r
+			break
+		}
+	}
+	p.mu.RUnlock()
+	if relay == nil {
+		return fmt.Errorf("relay %s is not connected", url)
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+	defer cancel()
+	sub, err := relay.Subscribe(probeCtx, nostr.Filter{Limit: 1}, nostr.SubscriptionOptions{})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsub()
+
+	select {
+	case <-sub.Events:
+		return nil
+	case <-sub.EndOfStoredEvents:
+		return nil
+	case reason := <-sub.ClosedReason:
+		return fmt.Errorf("subscription closed: %s", reason)
+	case <-probeCtx.Done():
+		return fmt.Errorf("no response within %s", DefaultProbeTimeout)
+	}
 }
 
 // ConnectedWriteRelays returns the number of currently connected write relays.
@@ -176,13 +593,45 @@ func (p *RelayPool) ConnectedWriteRelays() int {
 	return count
 }
 
-// WriteRelayURLs returns the configured write relay URLs.
+// WriteRelayURLs returns the configured write relay URLs, connected or
+// not. Use ConnectedWriteRelayURLs for the relays that are reachable now.
 func (p *RelayPool) WriteRelayURLs() []string {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 	return append([]string(nil), p.writeURLs...)
 }
 
+// ReadRelayURLs returns the configured read relay URLs, connected or not.
+func (p *RelayPool) ReadRelayURLs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return append([]string(nil), p.readURLs...)
+}
+
+// ConnectedWriteRelayURLs returns the write relays with a live connection.
+func (p *RelayPool) ConnectedWriteRelayURLs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return connectedURLs(p.writeRelays)
+}
+
+// ConnectedReadRelayURLs returns the read relays with a live connection.
+func (p *RelayPool) ConnectedReadRelayURLs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return connectedURLs(p.readRelays)
+}
+
+func connectedURLs(relays []*nostr.Relay) []string {
+	var urls []string
+	for _, relay := range relays {
+		if relay != nil && relay.IsConnected() {
+			urls = append(urls, relay.URL)
+		}
+	}
+	return urls
+}
+
 // HealthCheck logs the current connection status of all relays.
 func (p *RelayPool) HealthCheck() {
 	p.mu.RLock()
@@ -227,3 +676,6 @@ const DefaultPublishTimeout = 10 * time.Second
 
 // DefaultConnectTimeout is the default timeout for connecting to a relay.
 const DefaultConnectTimeout = 15 * time.Second
+
+// DefaultProbeTimeout is how long Probe waits for a relay to answer.
+const DefaultProbeTimeout = 5 * time.Second
@@ -3,7 +3,9 @@ package nostr
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"fiatjaf.com/nostr"
 
@@ -35,3 +37,242 @@ func TestRelayPoolReconnectRetriesInitiallyFailedURL(t *testing.T) {
 		t.Fatalf("connect calls after reconnect = %d, want 2", calls)
 	}
 }
+
+func TestAcceptedOrErrorNeedsOneAcceptingRelay(t *testing.T) {
+	rejected := errors.New("blocked: not on whitelist")
+
+	if err := acceptedOrError(map[string]error{"wss://a": rejected, "wss://b": nil}); err != nil {
+		t.Errorf("one accepting relay: err = %v", err)
+	}
+	err := acceptedOrError(map[string]error{"wss://a": rejected})
+	if !errors.Is(err, rejected) {
+		t.Errorf("all rejected: err = %v, want wrapped rejection", err)
+	}
+}
+
+func TestPublishDetailedWithoutRelays(t *testing.T) {
+	pool := &RelayPool{}
+	if _, err := pool.PublishDetailed(context.Background(), nostr.Event{}); err == nil {
+		t.Error("PublishDetailed with no write relays should fail")
+	}
+}
+
+func TestShouldAuthOnAuthRequiredRejection(t *testing.T) {
+	signer, err := NewLocalSigner("0000000000000000000000000000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, err := NewRelayPool(context.Background(), &config.NostrConfig{
+		RequiresAuth: []string{"wss://private.example"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := &nostr.Relay{URL: "wss://private.example"}
+	public := &nostr.Relay{URL: "wss://public.example"}
+	authErr := errors.New("msg: auth-required: we only accept events from registered users")
+	otherErr := errors.New("msg: rate-limited: slow down")
+
+	if pool.shouldAuth(public, authErr) {
+		t.Error("should not try AUTH without a signer")
+	}
+	pool.SetAuthSigner(signer)
+	if !pool.shouldAuth(public, authErr) {
+		t.Error("auth-required rejection should trigger AUTH")
+	}
+	if pool.shouldAuth(public, otherErr) {
+		t.Error("rate-limited rejection should not trigger AUTH")
+	}
+	if !pool.shouldAuth(private, otherErr) {
+		t.Error("relay configured with requires_auth should trigger AUTH")
+	}
+}
+
+func TestReconnectLoopBacksOffFailedRelays(t *testing.T) {
+	originalConnect := relayConnect
+	t.Cleanup(func() { relayConnect = originalConnect })
+
+	calls := 0
+	relayConnect = func(context.Context, string, nostr.RelayOptions) (*nostr.Relay, error) {
+		calls++
+		return nil, errors.New("relay unavailable")
+	}
+
+	pool, err := NewRelayPool(context.Background(), &config.NostrConfig{
+		ReadRelays: []string{"wss://offline.example"},
+	})
+	if err != nil {
+		t.Fatalf("NewRelayPool: %v", err)
+	}
+
+	pool.reconnect(context.Background(), false)
+	pool.reconnect(context.Background(), false)
+	if calls != 2 {
+		t.Fatalf("connect calls = %d, want 2 (initial + first retry, then backoff)", calls)
+	}
+
+	health := pool.RelayHealth()
+	if len(health) != 1 || health[0].Type != "read" || health[0].Connected {
+		t.Fatalf("RelayHealth = %+v", health)
+	}
+	if health[0].LastReconnectAttempt.IsZero() || health[0].Error == "" {
+		t.Errorf("RelayHealth should report the failed attempt: %+v", health[0])
+	}
+
+	// A manual Reconnect ignores backoff.
+	pool.Reconnect(context.Background())
+	if calls != 3 {
+		t.Errorf("connect calls after Reconnect = %d, want 3", calls)
+	}
+}
+
+func TestReconnectDialsWithoutHoldingPoolLock(t *testing.T) {
+	originalConnect := relayConnect
+	t.Cleanup(func() { relayConnect = originalConnect })
+	relayConnect = func(context.Context, string, nostr.RelayOptions) (*nostr.Relay, error) {
+		return nil, errors.New("relay unavailable")
+	}
+
+	pool, err := NewRelayPool(context.Background(), &config.NostrConfig{
+		WriteRelays: []string{"wss://slow.example"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dialing := make(chan struct{})
+	release := make(chan struct{})
+	relayConnect = func(context.Context, string, nostr.RelayOptions) (*nostr.Relay, error) {
+		close(dialing)
+		<-release
+		return nil, errors.New("relay unavailable")
+	}
+	done := make(chan struct{})
+	go func() {
+		pool.Reconnect(context.Background())
+		close(done)
+	}()
+	<-dialing
+
+	healthy := make(chan []RelayStatus)
+	go func() { healthy <- pool.RelayHealth() }()
+	select {
+	case <-healthy:
+	case <-time.After(time.Second):
+		t.Fatal("RelayHealth blocked while a relay was being dialed")
+	}
+	close(release)
+	<-done
+
+	if health := pool.RelayHealth(); len(health) != 1 || health[0].Error == "" {
+		t.Errorf("RelayHealth = %+v, want the failed dial recorded", health)
+	}
+}
+
+func TestReconnectDelayGrowsAndCaps(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:   reconnectBaseDelay,
+		2:   2 * reconnectBaseDelay,
+		3:   4 * reconnectBaseDelay,
+		100: reconnectMaxDelay,
+	} {
+		got := reconnectDelay(failures)
+		if got < want/2 || got > want {
+			t.Errorf("reconnectDelay(%d) = %s, want within [%s, %s]", failures, got, want/2, want)
+		}
+	}
+}
+
+func TestPublishDetailedFansOutAndBoundsHungRelays(t *testing.T) {
+	originalPublish, originalTimeout := relayPublish, relayPublishTimeout
+	hung := make(chan struct{})
+	t.Cleanup(func() {
+		close(hung)
+		relayPublish, relayPublishTimeout = originalPublish, originalTimeout
+	})
+	relayPublishTimeout = 50 * time.Millisecond
+
+	rejected := errors.New("blocked: rate limited")
+	relayPublish = func(r *nostr.Relay, ctx context.Context, _ nostr.Event) error {
+		switch r.URL {
+		case "wss://hung.example":
+			<-hung // ignores its context entirely
+			return nil
+		case "wss://reject.example":
+			time.Sleep(40 * time.Millisecond)
+			return rejected
+		default:
+			time.Sleep(40 * time.Millisecond)
+			return nil
+		}
+	}
+
+	pool := &RelayPool{writeRelays: []*nostr.Relay{
+		{URL: "wss://ok.example"},
+		{URL: "wss://reject.example"},
+		{URL: "wss://hung.example"},
+	}}
+
+	start := time.Now()
+	results, err := pool.PublishDetailed(context.Background(), nostr.Event{Content: "hi"})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("PublishDetailed: %v", err)
+	}
+	if elapsed > time.Second {
+		t.Errorf("publish took %s; a hung relay should only cost its timeout budget", elapsed)
+	}
+	if results["wss://ok.example"] != nil {
+		t.Errorf("ok relay: %v", results["wss://ok.example"])
+	}
+	if !errors.Is(results["wss://reject.example"], rejected) {
+		t.Errorf("rejecting relay: %v", results["wss://reject.example"])
+	}
+	if err := results["wss://hung.example"]; err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("hung relay: %v", err)
+	}
+	if err := acceptedOrError(results); err != nil {
+		t.Errorf("one relay accepted, but got %v", err)
+	}
+}
+
+func TestRelayURLAccessorsDistinguishConfiguredFromConnected(t *testing.T) {
+	originalConnect := relayConnect
+	t.Cleanup(func() { relayConnect = originalConnect })
+	relayConnect = func(context.Context, string, nostr.RelayOptions) (*nostr.Relay, error) {
+		return nil, errors.New("relay unavailable")
+	}
+
+	cfg := &config.NostrConfig{
+		Enabled:     true,
+		WriteRelays: []string{"wss://w1.example", "wss://w2.example"},
+		ReadRelays:  []string{"wss://r.example"},
+	}
+	pool, err := NewRelayPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := pool.WriteRelayURLs(); len(got) != 2 {
+		t.Errorf("WriteRelayURLs = %v, want both configured", got)
+	}
+	if got := pool.ReadRelayURLs(); len(got) != 1 || got[0] != "wss://r.example" {
+		t.Errorf("ReadRelayURLs = %v", got)
+	}
+	if got := pool.ConnectedWriteRelayURLs(); len(got) != 0 {
+		t.Errorf("ConnectedWriteRelayURLs = %v, want none", got)
+	}
+	if got := pool.ConnectedReadRelayURLs(); len(got) != 0 {
+		t.Errorf("ConnectedReadRelayURLs = %v, want none", got)
+	}
+
+	status := CheckHealth(context.Background(), pool, nil, nil, cfg, false)
+	if len(status.WriteRelays) != 2 || len(status.ReadRelays) != 1 {
+		t.Fatalf("relays = %+v / %+v", status.WriteRelays, status.ReadRelays)
+	}
+	for _, rs := range append(status.WriteRelays, status.ReadRelays...) {
+		if rs.Connected {
+			t.Errorf("%s reported connected", rs.URL)
+		}
+	}
+}
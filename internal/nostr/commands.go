@@ -0,0 +1,173 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CommandHandler runs a DM command for sender (a hex pubkey) and returns the
+// reply text.
+type CommandHandler func(ctx context.Context, sender string, args []string) (string, error)
+
+// CommandSpec describes the arguments a command accepts. Dispatch checks the
+// argument count against it and replies with Usage instead of calling the
+// handler when it is wrong.
+type CommandSpec struct {
+	MinArgs int
+	MaxArgs int // -1 for no upper bound
+
+	// Usage is the reply sent on an argument mismatch, e.g.
+	// "assign <polecat> <issue>".
+	Usage string
+}
+
+type command struct {
+	spec    CommandSpec
+	handler CommandHandler
+}
+
+// CommandRouter dispatches DM commands to registered handlers. Command names
+// are matched case-insensitively.
+type CommandRouter struct {
+	mu       sync.RWMutex
+	commands map[string]*command
+}
+
+// NewCommandRouter creates an empty router.
+func NewCommandRouter() *CommandRouter {
+	return &CommandRouter{commands: make(map[string]*command)}
+}
+
+// Register adds a command that accepts any number of arguments; the handler
+// checks them itself.
+func (r *CommandRouter) Register(name string, handler CommandHandler) {
+	r.RegisterWithSpec(name, CommandSpec{MaxArgs: -1, Usage: strings.ToLower(name)}, handler)
+}
+
+// RegisterWithSpec adds a command whose handler only runs when the argument
+// count satisfies spec.
+func (r *CommandRouter) RegisterWithSpec(name string, spec CommandSpec, handler CommandHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands[strings.ToLower(name)] = &command{spec: spec, handler: handler}
+}
+
+// Dispatch parses content as a command and returns the reply to send back.
+// Parse errors, unknown commands, argument mismatches and handler errors are
+// all answered with a message rather than returned, since the sender only
+// ever sees the reply. "help" lists the registered commands unless a help
+// command has been registered.
+func (r *CommandRouter) Dispatch(ctx context.Context, sender, content string) string {
+	name, args, err := ParseCommand(content)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if name == "" {
+		return ""
+	}
+
+	r.mu.RLock()
+	cmd, ok := r.commands[name]
+	r.mu.RUnlock()
+	if !ok {
+		if name == "help" {
+			return r.help()
+		}
+		return fmt.Sprintf("Unknown command: %s. Try 'help' for available commands.", name)
+	}
+
+	if len(args) < cmd.spec.MinArgs || (cmd.spec.MaxArgs >= 0 && len(args) > cmd.spec.MaxArgs) {
+		return "Usage: " + cmd.spec.Usage
+	}
+	reply, err := cmd.handler(ctx, sender, args)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	return reply
+}
+
+// help lists the usage of every registered command.
+func (r *CommandRouter) help() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	usages := make([]string, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		usages = append(usages, cmd.spec.Usage)
+	}
+	sort.Strings(usages)
+	return "Available commands:\n  " + strings.Join(usages, "\n  ")
+}
+
+// ParseCommand splits a DM command such as `assign Toast "fix the login bug"`
+// into a lower-cased command name and its arguments. Arguments are split like
+// a POSIX shell would: single quotes keep everything literally, double quotes
+// keep whitespace but honor backslash escapes of `"` and `\`, and outside
+// quotes a backslash escapes the next character. An empty or blank message
+// returns an empty command.
+func ParseCommand(content string) (cmd string, args []string, err error) {
+	words, err := splitCommandLine(content)
+	if err != nil || len(words) == 0 {
+		return "", nil, err
+	}
+	return strings.ToLower(words[0]), words[1:], nil
+}
+
+// splitCommandLine tokenizes s into shell-style words.
+func splitCommandLine(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune // the open quote character, or 0
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch {
+			case r == '"':
+				quote = 0
+			case r == '\\' && i+1 < len(runes) && (runes[i+1] == '"' || runes[i+1] == '\\'):
+				i++
+				word.WriteRune(runes[i])
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, fmt.Errorf("trailing backslash at end of command")
+			}
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in command; close it or escape it with a backslash", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
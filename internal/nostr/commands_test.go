@@ -0,0 +1,107 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		in   string
+		cmd  string
+		args []string
+	}{
+		{`assign Toast "fix the login bug"`, "assign", []string{"Toast", "fix the login bug"}},
+		{`ASSIGN Toast gt-123`, "assign", []string{"Toast", "gt-123"}},
+		{`  status  `, "status", []string{}},
+		{`nudge 'it''s done'`, "nudge", []string{"its done"}},
+		{`nudge 'say "hi"'`, "nudge", []string{`say "hi"`}},
+		{`nudge "say \"hi\" \\ now"`, "nudge", []string{`say "hi" \ now`}},
+		{`nudge "keep \n as is"`, "nudge", []string{`keep \n as is`}},
+		{`retry feature\ branch`, "retry", []string{"feature branch"}},
+		{`retry ""`, "retry", []string{""}},
+		{`retry pre"fix me"post`, "retry", []string{"prefix mepost"}},
+		{"", "", nil},
+	}
+	for _, tt := range tests {
+		cmd, args, err := ParseCommand(tt.in)
+		if err != nil {
+			t.Errorf("ParseCommand(%q): %v", tt.in, err)
+			continue
+		}
+		if len(args) == 0 && len(tt.args) == 0 {
+			args, tt.args = nil, nil
+		}
+		if cmd != tt.cmd || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("ParseCommand(%q) = %q %q, want %q %q", tt.in, cmd, args, tt.cmd, tt.args)
+		}
+	}
+}
+
+func TestParseCommandErrors(t *testing.T) {
+	for _, in := range []string{`assign Toast "fix the bug`, `assign 'Toast`, `assign Toast\`} {
+		_, _, err := ParseCommand(in)
+		if err == nil {
+			t.Errorf("ParseCommand(%q) succeeded, want an error", in)
+			continue
+		}
+		if strings.Contains(in, `\`) {
+			continue
+		}
+		if !strings.Contains(err.Error(), "unterminated") {
+			t.Errorf("ParseCommand(%q) error = %v, want an unterminated quote error", in, err)
+		}
+	}
+}
+
+func TestCommandRouterValidatesArgs(t *testing.T) {
+	r := NewCommandRouter()
+	var got []string
+	r.RegisterWithSpec("assign", CommandSpec{MinArgs: 2, MaxArgs: 2, Usage: "assign <polecat> <issue>"},
+		func(ctx context.Context, sender string, args []string) (string, error) {
+			got = args
+			return "assigned " + args[1] + " to " + args[0], nil
+		})
+	r.RegisterWithSpec("status", CommandSpec{Usage: "status"},
+		func(ctx context.Context, sender string, args []string) (string, error) {
+			return "all good", nil
+		})
+	r.Register("echo", func(ctx context.Context, sender string, args []string) (string, error) {
+		if len(args) == 0 {
+			return "", errors.New("nothing to echo")
+		}
+		return strings.Join(args, " "), nil
+	})
+
+	ctx := context.Background()
+	tests := []struct {
+		in, want string
+	}{
+		{`Assign Toast "fix the login bug"`, "assigned fix the login bug to Toast"},
+		{`assign Toast`, "Usage: assign <polecat> <issue>"},
+		{`assign Toast gt-1 extra`, "Usage: assign <polecat> <issue>"},
+		{`status`, "all good"},
+		{`status now`, "Usage: status"},
+		{`echo a b c`, "a b c"},
+		{`echo`, "Error: nothing to echo"},
+		{`frobnicate`, "Unknown command: frobnicate. Try 'help' for available commands."},
+		{`assign "Toast`, `Error: unterminated " quote in command; close it or escape it with a backslash`},
+		{`help`, "Available commands:\n  assign <polecat> <issue>\n  echo\n  status"},
+		{`   `, ""},
+	}
+	for _, tt := range tests {
+		got = nil
+		if reply := r.Dispatch(ctx, "npub", tt.in); reply != tt.want {
+			t.Errorf("Dispatch(%q) = %q, want %q", tt.in, reply, tt.want)
+		}
+	}
+
+	got = nil
+	r.Dispatch(ctx, "npub", "assign Toast")
+	if got != nil {
+		t.Errorf("handler ran with invalid args %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+package nostr
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+const (
+	// dmRelayCacheTTL is how long a recipient's DM relays are reused before
+	// they are fetched again.
+	dmRelayCacheTTL = 10 * time.Minute
+
+	// dmRelayQueryTimeout bounds the relay lookup for one recipient.
+	dmRelayQueryTimeout = 5 * time.Second
+)
+
+type dmRelayCache struct {
+	mu      sync.Mutex
+	entries map[string]dmRelayEntry
+}
+
+type dmRelayEntry struct {
+	relays  []string
+	fetched time.Time
+}
+
+// FetchDMRelays returns the relays a NIP-17 gift wrap for recipientPubKey
+// (hex) should be published to: the relay tags of the recipient's latest
+// kind-10050 list, else the write relays of their kind-10002 list, else our
+// own write relays. Results are cached for dmRelayCacheTTL.
+func (p *RelayPool) FetchDMRelays(ctx context.Context, recipientPubKey string) ([]string, error) {
+	recipient := PubKeyFromHexGT(recipientPubKey)
+	if recipient == (nostr.PubKey{}) {
+		return nil, fmt.Errorf("invalid recipient pubkey %q", recipientPubKey)
+	}
+
+	p.dmRelays.mu.Lock()
+	entry, ok := p.dmRelays.entries[recipientPubKey]
+	p.dmRelays.mu.Unlock()
+	if ok && time.Since(entry.fetched) < dmRelayCacheTTL {
+		return entry.relays, nil
+	}
+
+	relays := p.lookupDMRelays(ctx, recipient)
+	if len(relays) == 0 {
+		p.mu.RLock()
+		relays = append([]string(nil), p.writeURLs...)
+		p.mu.RUnlock()
+		if len(relays) == 0 {
+			return nil, fmt.Errorf("no DM relays known for %s", recipientPubKey)
+		}
+	}
+
+	p.dmRelays.mu.Lock()
+	if p.dmRelays.entries == nil {
+		p.dmRelays.entries = make(map[string]dmRelayEntry)
+	}
+	p.dmRelays.entries[recipientPubKey] = dmRelayEntry{relays: relays, fetched: time.Now()}
+	p.dmRelays.mu.Unlock()
+	return relays, nil
+}
+
+// lookupDMRelays reads the recipient's relay lists from the read relays.
+func (p *RelayPool) lookupDMRelays(ctx context.Context, recipient nostr.PubKey) []string {
+	queryCtx, cancel := context.WithTimeout(ctx, dmRelayQueryTimeout)
+	defer cancel()
+
+	latest := p.queryLatest(queryCtx, nostr.Filter{
+		Kinds:   []nostr.Kind{nostr.Kind(KindDMRelayList), nostr.Kind(KindRelayList)},
+		Authors: []nostr.PubKey{recipient},
+	})
+
+	if event := latest[KindDMRelayList]; event != nil {
+		if relays := dmRelayTags(event.Tags); len(relays) > 0 {
+			return relays
+		}
+	}
+	if event := latest[KindRelayList]; event != nil {
+		return writeRelayTags(event.Tags)
+	}
+	return nil
+}
+
+// queryLatest returns the newest event of each kind matching filter that the
+// read relays hold.
+func (p *RelayPool) queryLatest(ctx context.Context, filter nostr.Filter) map[int]*nostr.Event {
+	latest := make(map[int]*nostr.Event)
+	p.queryStored(ctx, []nostr.Filter{filter}, func(event *nostr.Event) {
+		kind := int(event.Kind)
+		if cur := latest[kind]; cur == nil || event.CreatedAt > cur.CreatedAt {
+			latest[kind] = event
+		}
+	})
+	return latest
+}
+
+// dmRelayTags returns the ["relay", url] tags of a kind-10050 list.
+func dmRelayTags(tags nostr.Tags) []string {
+	var relays []string
+	for _, tag := range tags {
+		if len(tag) >= 2 && tag[0] == "relay" && tag[1] != "" {
+			relays = append(relays, tag[1])
+		}
+	}
+	return relays
+}
+
+// writeRelayTags returns the write relays of a NIP-65 kind-10002 list:
+// ["r", url] tags with no marker or a "write" marker.
+func writeRelayTags(tags nostr.Tags) []string {
+	var relays []string
+	for _, tag := range tags {
+		if len(tag) < 2 || tag[0] != "r" || tag[1] == "" {
+			continue
+		}
+		if len(tag) >= 3 && tag[2] != "write" {
+			continue
+		}
+		relays = append(relays, tag[1])
+	}
+	return relays
+}
@@ -0,0 +1,43 @@
+package nostr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestDMRelayTagParsing(t *testing.T) {
+	dm := nostr.Tags{{"relay", "wss://dm.example"}, {"r", "wss://other.example"}, {"relay", ""}}
+	if got := dmRelayTags(dm); !reflect.DeepEqual(got, []string{"wss://dm.example"}) {
+		t.Errorf("dmRelayTags = %v", got)
+	}
+
+	list := nostr.Tags{
+		{"r", "wss://both.example"},
+		{"r", "wss://write.example", "write"},
+		{"r", "wss://read.example", "read"},
+	}
+	if got := writeRelayTags(list); !reflect.DeepEqual(got, []string{"wss://both.example", "wss://write.example"}) {
+		t.Errorf("writeRelayTags = %v", got)
+	}
+}
+
+func TestFetchDMRelaysFallsBackToOwnWriteRelays(t *testing.T) {
+	pool := &RelayPool{writeURLs: []string{"wss://ours.example"}}
+	recipient := "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+	relays, err := pool.FetchDMRelays(context.Background(), recipient)
+	if err != nil || !reflect.DeepEqual(relays, []string{"wss://ours.example"}) {
+		t.Fatalf("FetchDMRelays = %v, %v", relays, err)
+	}
+
+	pool.writeURLs = nil
+	if cached, err := pool.FetchDMRelays(context.Background(), recipient); err != nil || len(cached) != 1 {
+		t.Errorf("second lookup = %v, %v; want cached result", cached, err)
+	}
+	if _, err := pool.FetchDMRelays(context.Background(), "not-a-key"); err == nil {
+		t.Error("invalid pubkey should fail")
+	}
+}
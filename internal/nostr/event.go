@@ -14,6 +14,10 @@ const (
 	agentHeartbeatSchema = "cascadia.agent.heartbeat.v1"
 )
 
+// DefaultHeartbeatTTL is the NIP-40 lifetime of a heartbeat: three of the
+// default heartbeat intervals, after which a newer one should have replaced it.
+const DefaultHeartbeatTTL = 3 * 60 * time.Second
+
 // NewLogStatusEvent creates a canonical NIP-38 agent status event carrying a
 // Gas Town activity update.
 func NewLogStatusEvent(rig, role, actor, eventType, visibility string, payload interface{}) (*nostr.Event, error) {
@@ -76,15 +80,45 @@ func NewAgentHeartbeatEvent(agentID, rig, role, status string) (*nostr.Event, er
 		return nil, err
 	}
 
-	return &nostr.Event{
+	event := &nostr.Event{
 		CreatedAt: nostr.Timestamp(time.Now().Unix()),
 		Kind:      nostr.Kind(cascadia.CAS_AGENT_HEARTBEAT),
 		Tags:      tags,
 		Content:   string(content),
-	}, nil
+	}
+	WithExpiration(event, DefaultHeartbeatTTL)
+	return event, nil
+}
+
+// WithExpiration sets a NIP-40 expiration tag ttl after the event's creation
+// time, replacing any existing one. Relays that honor NIP-40 drop the event
+// after that, and the spool discards it instead of publishing it late.
+// A ttl <= 0 leaves the event unchanged.
+func WithExpiration(event *nostr.Event, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	created := time.Unix(int64(event.CreatedAt), 0)
+	if event.CreatedAt == 0 {
+		created = time.Now()
+	}
+
+	tags := event.Tags[:0:0]
+	for _, tag := range event.Tags {
+		if len(tag) >= 1 && tag[0] == "expiration" {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	event.Tags = append(tags, ExpirationTag(created.Add(ttl)))
 }
 
 // WithCorrelation appends correlation tags to an event.
 func WithCorrelation(event *nostr.Event, issueID, convoyID, beadID, sessionID string) {
 	event.Tags = append(event.Tags, CorrelationTags(issueID, convoyID, beadID, sessionID)...)
 }
+
+// WithMergeCorrelation appends branch and merge-request tags to an event.
+func WithMergeCorrelation(event *nostr.Event, branch, mergeReq string) {
+	event.Tags = append(event.Tags, MergeTags(branch, mergeReq)...)
+}
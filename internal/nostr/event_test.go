@@ -3,6 +3,7 @@ package nostr
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"fiatjaf.com/nostr"
 	cascadia "git.sharegap.net/cascadia/cascadia-go"
@@ -64,6 +65,23 @@ func TestNewAgentHeartbeatEventUsesCanonicalShape(t *testing.T) {
 	if payload.ActiveTasks != 1 {
 		t.Errorf("active_tasks = %d, want 1", payload.ActiveTasks)
 	}
+
+	exp, ok := expiresAt(event.Tags)
+	if want := time.Unix(int64(event.CreatedAt), 0).Add(DefaultHeartbeatTTL); !ok || !exp.Equal(want) {
+		t.Errorf("expiration = %v, %v; want %v", exp, ok, want)
+	}
+}
+
+func TestWithExpirationReplacesExistingTag(t *testing.T) {
+	event := &nostr.Event{CreatedAt: 1000, Tags: nostr.Tags{{"expiration", "1"}, {"t", "gt-1"}}}
+	WithExpiration(event, time.Minute)
+
+	if len(event.Tags) != 2 {
+		t.Fatalf("tags = %v", event.Tags)
+	}
+	if got, _ := tagValue(event.Tags, "expiration"); got != "1060" {
+		t.Errorf("expiration = %s, want 1060", got)
+	}
 }
 
 func tagValue(tags nostr.Tags, key string) (string, bool) {
@@ -44,9 +44,12 @@ type HealthStatus struct {
 
 // RelayStatus represents a relay's connection status.
 type RelayStatus struct {
-	URL       string `json:"url"`
-	Connected bool   `json:"connected"`
-	Error     string `json:"error,omitempty"`
+	URL                  string    `json:"url"`
+	Type                 string    `json:"type,omitempty"` // "read" or "write"
+	Connected            bool      `json:"connected"`
+	LastReconnectAttempt time.Time `json:"last_reconnect_attempt,omitzero"`
+	Probed               bool      `json:"probed,omitempty"` // liveness was actively checked
+	Error                string    `json:"error,omitempty"`
 }
 
 // AgentHealthInfo represents an agent's health from lifecycle events.
@@ -58,7 +61,12 @@ type AgentHealthInfo struct {
 }
 
 // CheckHealth performs a comprehensive Nostr health check.
-func CheckHealth(ctx context.Context, pool *RelayPool, spool *Spool, cfg *config.NostrConfig) *HealthStatus {
+// Relay state comes from the pool's cached connection state; with probe set,
+// every connected relay is also sent a tiny subscription (see
+// RelayPool.Probe) so a half-open connection is reported as down. Agent
+// state comes from monitor when one is running; otherwise it is read from the
+// pool's relays with QueryAgents, which is bounded in time.
+func CheckHealth(ctx context.Context, pool *RelayPool, spool *Spool, monitor *StaleMonitor, cfg *config.NostrConfig, probe bool) *HealthStatus {
 	status := &HealthStatus{
 		Enabled: cfg != nil && cfg.Enabled,
 		Sunset:  LoadSunsetFlags(),
@@ -70,55 +78,43 @@ func CheckHealth(ctx context.Context, pool *RelayPool, spool *Spool, cfg *config
 
 	// Check relay connection status
 	if pool != nil {
-		pool.mu.RLock()
-
-		// Check write relays
-		for _, url := range cfg.WriteRelays {
-			rs := RelayStatus{URL: url, Connected: false}
-			for _, relay := range pool.writeRelays {
-				if relay.URL == url && relay.IsConnected() {
-					rs.Connected = true
-					break
+		for _, rs := range pool.RelayHealth() {
+			if probe && rs.Connected {
+				rs.Probed = true
+				if err := pool.Probe(ctx, rs.URL); err != nil {
+					rs.Connected = false
+					rs.Error = "probe failed: " + err.Error()
 				}
 			}
-			status.WriteRelays = append(status.WriteRelays, rs)
-		}
-
-		// Check read relays
-		for _, url := range cfg.ReadRelays {
-			rs := RelayStatus{URL: url, Connected: false}
-			for _, relay := range pool.readRelays {
-				if relay.URL == url && relay.IsConnected() {
-					rs.Connected = true
-					break
-				}
+			if rs.Type == "write" {
+				status.WriteRelays = append(status.WriteRelays, rs)
+			} else {
+				status.ReadRelays = append(status.ReadRelays, rs)
 			}
-			status.ReadRelays = append(status.ReadRelays, rs)
 		}
-
-		pool.mu.RUnlock()
 	} else {
 		// No pool - all relays disconnected
 		for _, url := range cfg.WriteRelays {
-			status.WriteRelays = append(status.WriteRelays, RelayStatus{URL: url, Connected: false})
+			status.WriteRelays = append(status.WriteRelays, RelayStatus{URL: url, Type: "write", Connected: false})
 		}
 		for _, url := range cfg.ReadRelays {
-			status.ReadRelays = append(status.ReadRelays, RelayStatus{URL: url, Connected: false})
+			status.ReadRelays = append(status.ReadRelays, RelayStatus{URL: url, Type: "read", Connected: false})
 		}
 	}
 
-	// Signer status
-	if len(cfg.Identities) > 0 {
-		status.SignerStatus = "configured"
-	} else {
-		status.SignerStatus = "not configured"
-	}
+	status.SignerStatus = signerStatus(ctx, pool, cfg, probe)
 
 	// Spool count
 	if spool != nil {
 		status.SpoolCount = spool.Count()
 	}
 
+	if monitor != nil {
+		status.Agents = monitor.Agents()
+	} else if pool != nil {
+		status.Agents = QueryAgents(ctx, pool, 0)
+	}
+
 	return status
 }
 
@@ -136,20 +132,12 @@ func FormatHealthStatus(h *HealthStatus) string {
 
 	// Write relays
 	for _, r := range h.WriteRelays {
-		icon := "connected"
-		if !r.Connected {
-			icon = "disconnected"
-		}
-		sb.WriteString(fmt.Sprintf("  Write Relay: %s (%s)\n", r.URL, icon))
+		sb.WriteString(fmt.Sprintf("  Write Relay: %s (%s)\n", r.URL, relayStatusLabel(r)))
 	}
 
 	// Read relays
 	for _, r := range h.ReadRelays {
-		icon := "connected"
-		if !r.Connected {
-			icon = "disconnected"
-		}
-		sb.WriteString(fmt.Sprintf("  Read Relay: %s (%s)\n", r.URL, icon))
+		sb.WriteString(fmt.Sprintf("  Read Relay: %s (%s)\n", r.URL, relayStatusLabel(r)))
 	}
 
 	sb.WriteString(fmt.Sprintf("  Signer: %s\n", h.SignerStatus))
@@ -184,6 +172,40 @@ func FormatHealthStatus(h *HealthStatus) string {
 	return sb.String()
 }
 
+// signerStatus describes the publishing signer. Signers with a remote
+// backend (a NIP-46 bunker) report whether it is reachable; with probe set
+// the bunker is pinged, otherwise the outcome of its last request is used.
+func signerStatus(ctx context.Context, pool *RelayPool, cfg *config.NostrConfig, probe bool) string {
+	var signer Signer
+	if pool != nil {
+		pool.authMu.Lock()
+		signer = pool.authSigner
+		pool.authMu.Unlock()
+	}
+
+	sh, ok := signer.(SignerHealth)
+	if !ok {
+		if len(cfg.Identities) > 0 {
+			return "configured"
+		}
+		return "not configured"
+	}
+
+	if probe {
+		if err := sh.Ping(ctx); err != nil {
+			return "unreachable: " + err.Error()
+		}
+		return "connected (probed)"
+	}
+	if sh.Healthy() {
+		return "connected"
+	}
+	if err := sh.LastError(); err != nil {
+		return "unreachable: " + err.Error()
+	}
+	return "unreachable"
+}
+
 // --- Sunset convenience functions ---
 
 // IsEventsLocalEnabled returns true if local event file writing is enabled.
@@ -213,6 +235,20 @@ func IsNudgeLocalEnabled() bool {
 
 // --- Helpers ---
 
+func relayStatusLabel(r RelayStatus) string {
+	if r.Connected {
+		return "connected"
+	}
+	label := "disconnected"
+	if !r.LastReconnectAttempt.IsZero() {
+		label += fmt.Sprintf(", last retry %s ago", time.Since(r.LastReconnectAttempt).Truncate(time.Second))
+	}
+	if r.Error != "" {
+		label += ": " + r.Error
+	}
+	return label
+}
+
 func sunsetLabel(localEnabled bool) string {
 	if localEnabled {
 		return "ON  (dual-write)"
@@ -0,0 +1,43 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"fiatjaf.com/nostr"
+
+	"github.com/steveyegge/gastown/internal/config"
+)
+
+func TestCheckHealthReportsReadRelaysFromPool(t *testing.T) {
+	originalConnect := relayConnect
+	t.Cleanup(func() { relayConnect = originalConnect })
+	relayConnect = func(context.Context, string, nostr.RelayOptions) (*nostr.Relay, error) {
+		return nil, errors.New("relay unavailable")
+	}
+
+	cfg := &config.NostrConfig{
+		Enabled:     true,
+		ReadRelays:  []string{"wss://read.example"},
+		WriteRelays: []string{"wss://write.example"},
+	}
+	pool, err := NewRelayPool(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.Reconnect(context.Background())
+
+	status := CheckHealth(context.Background(), pool, nil, nil, cfg, true)
+	if len(status.ReadRelays) != 1 || len(status.WriteRelays) != 1 {
+		t.Fatalf("relays = %+v / %+v", status.ReadRelays, status.WriteRelays)
+	}
+	read := status.ReadRelays[0]
+	if read.URL != "wss://read.example" || read.Connected || read.Error == "" || read.Probed {
+		t.Errorf("read relay = %+v", read)
+	}
+
+	if err := pool.Probe(context.Background(), "wss://read.example"); err == nil {
+		t.Error("Probe of a disconnected relay should fail")
+	}
+}
@@ -58,11 +58,17 @@ func (im *IdentityManager) ProvisionAgent(ctx context.Context, actor, role, rig
 		}
 	}
 
+	// Configs may give the key as an npub; everything downstream compares hex.
+	pk, err := ParsePubKey(roleIdentity.Pubkey)
+	if err != nil {
+		return nil, fmt.Errorf("identity for role %q: invalid pubkey: %w", role, err)
+	}
+
 	agent := &AgentIdentity{
 		Actor:     actor,
 		Role:      role,
 		Rig:       rig,
-		Pubkey:    roleIdentity.Pubkey,
+		Pubkey:    PubKeyToString(pk),
 		BunkerURI: roleIdentity.Signer.Bunker,
 		Profile:   roleIdentity.Profile,
 		CreatedAt: time.Now(),
@@ -0,0 +1,149 @@
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+// NIP-17 private direct message event kinds.
+const (
+	KindDMRumor     = 14    // unsigned chat message
+	KindSeal        = 13    // rumor encrypted to the recipient, signed by the sender
+	KindGiftWrap    = 1059  // seal encrypted by a throwaway key
+	KindDMRelayList = 10050 // relays a user wants to receive DMs on
+)
+
+// giftWrapTimeJitter is how far back seal and wrap timestamps are randomized,
+// so relays can't correlate them with the rumor's real send time.
+const giftWrapTimeJitter = 2 * 24 * time.Hour
+
+// WrapDM builds a NIP-17 private direct message from signer to
+// recipientPubKey (hex) and returns the kind-1059 gift wrap ready to publish.
+//
+// The kind-14 rumor is never signed; it is NIP-44 encrypted into a kind-13
+// seal signed by the sender, and the seal is encrypted again into a gift wrap
+// signed by a freshly generated key. Relays therefore only ever see the
+// recipient and a one-time pubkey. The signer must implement Encrypter.
+func WrapDM(ctx context.Context, signer Signer, recipientPubKey, content string) (*nostr.Event, error) {
+	enc, ok := signer.(Encrypter)
+	if !ok {
+		return nil, fmt.Errorf("signer %T cannot NIP-44 encrypt", signer)
+	}
+	recipient := PubKeyFromHexGT(recipientPubKey)
+	if recipient == (nostr.PubKey{}) {
+		return nil, fmt.Errorf("invalid recipient pubkey %q", recipientPubKey)
+	}
+
+	rumor := nostr.Event{
+		PubKey:    PubKeyFromHexGT(signer.GetPublicKey()),
+		CreatedAt: nostr.Timestamp(time.Now().Unix()),
+		Kind:      nostr.Kind(KindDMRumor),
+		Tags:      nostr.Tags{{"p", recipientPubKey}},
+		Content:   content,
+	}
+	rumor.ID = rumor.GetID()
+	rumorJSON, err := json.Marshal(rumor)
+	if err != nil {
+		return nil, fmt.Errorf("encoding rumor: %w", err)
+	}
+
+	sealed, err := enc.NIP44Encrypt(ctx, recipientPubKey, string(rumorJSON))
+	if err != nil {
+		return nil, fmt.Errorf("encrypting rumor: %w", err)
+	}
+	seal := nostr.Event{
+		CreatedAt: jitteredTimestamp(),
+		Kind:      nostr.Kind(KindSeal),
+		Tags:      nostr.Tags{},
+		Content:   sealed,
+	}
+	if err := signer.Sign(ctx, &seal); err != nil {
+		return nil, fmt.Errorf("signing seal: %w", err)
+	}
+	sealJSON, err := json.Marshal(seal)
+	if err != nil {
+		return nil, fmt.Errorf("encoding seal: %w", err)
+	}
+
+	ephemeral := nostr.Generate()
+	key, err := ConversationKey(ephemeral, recipient)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := Encrypt(string(sealJSON), key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypting seal: %w", err)
+	}
+	wrap := &nostr.Event{
+		CreatedAt: jitteredTimestamp(),
+		Kind:      nostr.Kind(KindGiftWrap),
+		Tags:      nostr.Tags{{"p", recipientPubKey}},
+		Content:   wrapped,
+	}
+	if err := wrap.Sign(ephemeral); err != nil {
+		return nil, fmt.Errorf("signing gift wrap: %w", err)
+	}
+	return wrap, nil
+}
+
+// jitteredTimestamp returns a time up to giftWrapTimeJitter in the past.
+func jitteredTimestamp() nostr.Timestamp {
+	jitter := rand.Int64N(int64(giftWrapTimeJitter / time.Second))
+	return nostr.Timestamp(time.Now().Unix() - jitter)
+}
+
+// UnwrapDM opens a NIP-17 gift wrap addressed to signer and returns the real
+// sender's pubkey (hex) and the message content.
+//
+// The wrap and seal must carry valid signatures and the seal's author must
+// match the rumor's: the rumor is unsigned, so without that check anyone
+// could seal a rumor claiming to be from someone else.
+func UnwrapDM(ctx context.Context, signer Signer, wrap *nostr.Event) (sender, content string, err error) {
+	if wrap.Kind != nostr.Kind(KindGiftWrap) {
+		return "", "", fmt.Errorf("not a gift wrap: kind %d", wrap.Kind)
+	}
+	if err := VerifyEvent(wrap); err != nil {
+		return "", "", fmt.Errorf("gift wrap: %w", err)
+	}
+	enc, ok := signer.(Encrypter)
+	if !ok {
+		return "", "", fmt.Errorf("signer %T cannot NIP-44 decrypt", signer)
+	}
+
+	sealJSON, err := enc.NIP44Decrypt(ctx, PubKeyToString(wrap.PubKey), wrap.Content)
+	if err != nil {
+		return "", "", fmt.Errorf("decrypting gift wrap: %w", err)
+	}
+	var seal nostr.Event
+	if err := json.Unmarshal([]byte(sealJSON), &seal); err != nil {
+		return "", "", fmt.Errorf("decoding seal: %w", err)
+	}
+	if seal.Kind != nostr.Kind(KindSeal) {
+		return "", "", fmt.Errorf("gift wrap contains kind %d, want seal", seal.Kind)
+	}
+	if err := VerifyEvent(&seal); err != nil {
+		return "", "", fmt.Errorf("seal: %w", err)
+	}
+
+	rumorJSON, err := enc.NIP44Decrypt(ctx, PubKeyToString(seal.PubKey), seal.Content)
+	if err != nil {
+		return "", "", fmt.Errorf("decrypting seal: %w", err)
+	}
+	var rumor nostr.Event
+	if err := json.Unmarshal([]byte(rumorJSON), &rumor); err != nil {
+		return "", "", fmt.Errorf("decoding rumor: %w", err)
+	}
+	if rumor.Kind != nostr.Kind(KindDMRumor) {
+		return "", "", fmt.Errorf("seal contains kind %d, want DM rumor", rumor.Kind)
+	}
+	if rumor.PubKey != seal.PubKey {
+		return "", "", fmt.Errorf("rumor author %s does not match seal author %s",
+			PubKeyToString(rumor.PubKey), PubKeyToString(seal.PubKey))
+	}
+	return PubKeyToString(seal.PubKey), rumor.Content, nil
+}
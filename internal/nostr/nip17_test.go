@@ -0,0 +1,101 @@
+package nostr
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+const (
+	dmSenderSec    = "0000000000000000000000000000000000000000000000000000000000000001"
+	dmRecipientSec = "0000000000000000000000000000000000000000000000000000000000000002"
+	dmRecipientPub = "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5"
+)
+
+func TestWrapDMRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	sender, err := NewLocalSigner(dmSenderSec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := NewLocalSigner(dmRecipientSec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrap, err := WrapDM(ctx, sender, dmRecipientPub, "hello deacon")
+	if err != nil {
+		t.Fatalf("WrapDM: %v", err)
+	}
+	if wrap.Kind != nostr.Kind(KindGiftWrap) {
+		t.Fatalf("kind = %d, want %d", wrap.Kind, KindGiftWrap)
+	}
+	if PubKeyToString(wrap.PubKey) == sender.GetPublicKey() {
+		t.Fatal("gift wrap must not be signed by the sender")
+	}
+
+	from, content, err := UnwrapDM(ctx, recipient, wrap)
+	if err != nil {
+		t.Fatalf("UnwrapDM: %v", err)
+	}
+	if from != sender.GetPublicKey() || content != "hello deacon" {
+		t.Errorf("UnwrapDM = %s, %q", from, content)
+	}
+
+	// Only the recipient can open it.
+	if _, _, err := UnwrapDM(ctx, sender, wrap); err == nil {
+		t.Error("UnwrapDM by a non-recipient should fail")
+	}
+}
+
+func TestUnwrapDMRejectsSpoofedAuthor(t *testing.T) {
+	ctx := context.Background()
+	sender, err := NewLocalSigner(dmSenderSec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient, err := NewLocalSigner(dmRecipientSec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sender seals a rumor that claims to come from the recipient.
+	rumor := nostr.Event{
+		PubKey:  PubKeyFromHexGT(dmRecipientPub),
+		Kind:    nostr.Kind(KindDMRumor),
+		Tags:    nostr.Tags{{"p", dmRecipientPub}},
+		Content: "trust me",
+	}
+	rumor.ID = rumor.GetID()
+	rumorJSON, _ := json.Marshal(rumor)
+	sealed, err := sender.NIP44Encrypt(ctx, dmRecipientPub, string(rumorJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seal := nostr.Event{Kind: nostr.Kind(KindSeal), Tags: nostr.Tags{}, Content: sealed}
+	if err := sender.Sign(ctx, &seal); err != nil {
+		t.Fatal(err)
+	}
+	sealJSON, _ := json.Marshal(seal)
+
+	ephemeral := nostr.Generate()
+	key, err := ConversationKey(ephemeral, PubKeyFromHexGT(dmRecipientPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := Encrypt(string(sealJSON), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrap := &nostr.Event{Kind: nostr.Kind(KindGiftWrap), Tags: nostr.Tags{{"p", dmRecipientPub}}, Content: wrapped}
+	if err := wrap.Sign(ephemeral); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := UnwrapDM(ctx, recipient, wrap); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("UnwrapDM err = %v, want author mismatch", err)
+	}
+}
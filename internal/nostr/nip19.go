@@ -0,0 +1,248 @@
+package nostr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"fiatjaf.com/nostr"
+)
+
+// NIP-19 bech32 entity encoding.
+//
+// Gas Town passes pubkeys and event IDs around as hex internally, but people
+// and standard Nostr clients expect the bech32 forms: npub for keys, note and
+// nevent for events, nprofile for a key with relay hints. These helpers
+// convert between the two so identities can be logged and shared in a form
+// that pastes straight into a client.
+
+// NIP-19 TLV types used in nprofile and nevent.
+const (
+	tlvSpecial = 0 // pubkey (nprofile) or event ID (nevent)
+	tlvRelay   = 1
+	tlvAuthor  = 2
+)
+
+// EncodeNpub returns the npub form of pk.
+func EncodeNpub(pk nostr.PubKey) string {
+	return bech32Encode("npub", pk[:])
+}
+
+// DecodeNpub parses an npub into a pubkey.
+func DecodeNpub(s string) (nostr.PubKey, error) {
+	data, err := decodeEntity("npub", s)
+	if err != nil {
+		return nostr.PubKey{}, err
+	}
+	if len(data) != 32 {
+		return nostr.PubKey{}, fmt.Errorf("npub should be 32 bytes, got %d", len(data))
+	}
+	return nostr.PubKey(data), nil
+}
+
+// EncodeNprofile returns the nprofile form of pk, with optional relay hints.
+func EncodeNprofile(pk nostr.PubKey, relays []string) string {
+	var buf bytes.Buffer
+	writeTLV(&buf, tlvSpecial, pk[:])
+	for _, url := range relays {
+		writeTLV(&buf, tlvRelay, []byte(url))
+	}
+	return bech32Encode("nprofile", buf.Bytes())
+}
+
+// EncodeNote returns the note form of an event ID.
+func EncodeNote(id nostr.ID) string {
+	return bech32Encode("note", id[:])
+}
+
+// EncodeNevent returns the nevent form of an event ID, with optional relay
+// hints and author. A zero author is left out.
+func EncodeNevent(id nostr.ID, relays []string, author nostr.PubKey) string {
+	var buf bytes.Buffer
+	writeTLV(&buf, tlvSpecial, id[:])
+	for _, url := range relays {
+		writeTLV(&buf, tlvRelay, []byte(url))
+	}
+	if author != nostr.ZeroPK {
+		writeTLV(&buf, tlvAuthor, author[:])
+	}
+	return bech32Encode("nevent", buf.Bytes())
+}
+
+// ParsePubKey accepts a pubkey as hex, npub or nprofile, the forms users
+// and configs may hold.
+func ParsePubKey(s string) (nostr.PubKey, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "npub1"):
+		return DecodeNpub(s)
+	case strings.HasPrefix(s, "nprofile1"):
+		data, err := decodeEntity("nprofile", s)
+		if err != nil {
+			return nostr.PubKey{}, err
+		}
+		for len(data) > 0 {
+			typ, value, rest, err := readTLV(data)
+			if err != nil {
+				return nostr.PubKey{}, fmt.Errorf("nprofile: %w", err)
+			}
+			if typ == tlvSpecial {
+				if len(value) != 32 {
+					return nostr.PubKey{}, fmt.Errorf("nprofile pubkey should be 32 bytes, got %d", len(value))
+				}
+				return nostr.PubKey(value), nil
+			}
+			data = rest
+		}
+		return nostr.PubKey{}, fmt.Errorf("nprofile has no pubkey")
+	}
+	return nostr.PubKeyFromHex(s)
+}
+
+// FormatPubKey renders a hex pubkey as an npub for logs and messages,
+// falling back to the input if it is not a valid key.
+func FormatPubKey(hexPubKey string) string {
+	pk, err := nostr.PubKeyFromHex(hexPubKey)
+	if err != nil {
+		return hexPubKey
+	}
+	return EncodeNpub(pk)
+}
+
+func decodeEntity(want, s string) ([]byte, error) {
+	hrp, data, err := bech32Decode(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", want, err)
+	}
+	if hrp != want {
+		return nil, fmt.Errorf("expected %s, got %s", want, hrp)
+	}
+	return data, nil
+}
+
+func writeTLV(buf *bytes.Buffer, typ byte, value []byte) {
+	buf.WriteByte(typ)
+	buf.WriteByte(byte(len(value)))
+	buf.Write(value)
+}
+
+func readTLV(data []byte) (typ byte, value, rest []byte, err error) {
+	if len(data) < 2 {
+		return 0, nil, nil, fmt.Errorf("truncated TLV entry")
+	}
+	n := int(data[1])
+	if len(data) < 2+n {
+		return 0, nil, nil, fmt.Errorf("truncated TLV entry")
+	}
+	return data[0], data[2 : 2+n], data[2+n:], nil
+}
+
+// --- bech32 (BIP-173), without the 90-character limit NIP-19 lifts ---
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// bech32Encode encodes 8-bit data under hrp.
+func bech32Encode(hrp string, data []byte) string {
+	values := convertBits(data, 8, 5, true)
+	checksumInput := append(bech32HRPExpand(hrp), values...)
+	checksumInput = append(checksumInput, 0, 0, 0, 0, 0, 0)
+	mod := bech32Polymod(checksumInput) ^ 1
+
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(values) + 6)
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, v := range values {
+		sb.WriteByte(bech32Charset[v])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(mod>>uint(5*(5-i)))&31])
+	}
+	return sb.String()
+}
+
+// bech32Decode returns the hrp and 8-bit data of a bech32 string.
+func bech32Decode(s string) (string, []byte, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, fmt.Errorf("mixed case")
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, fmt.Errorf("invalid separator position")
+	}
+	hrp := s[:sep]
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return "", nil, fmt.Errorf("invalid character in prefix")
+		}
+	}
+
+	values := make([]byte, 0, len(s)-sep-1)
+	for i := sep + 1; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("invalid character %q", s[i])
+		}
+		values = append(values, byte(v))
+	}
+	if bech32Polymod(append(bech32HRPExpand(hrp), values...)) != 1 {
+		return "", nil, fmt.Errorf("invalid checksum")
+	}
+
+	data := values[:len(values)-6]
+	if len(data)*5%8 >= 5 {
+		return "", nil, fmt.Errorf("invalid padding")
+	}
+	out := convertBits(data, 5, 8, false)
+	return hrp, out, nil
+}
+
+// convertBits regroups data from fromBits-bit to toBits-bit groups. With
+// pad, leftover bits are zero-padded into a final group; without, they are
+// dropped.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) []byte {
+	var acc uint32
+	var bits uint
+	maxv := uint32(1)<<toBits - 1
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, v := range data {
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad && bits > 0 {
+		out = append(out, byte(acc<<(toBits-bits)&maxv))
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package nostr
+
+import (
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+// Test vectors from NIP-19.
+func TestNpubRoundTrip(t *testing.T) {
+	tests := []struct {
+		hex, npub string
+	}{
+		{"7e7e9c42a91bfef19fa929e5fda1b72e0ebc1a4c1141673e2794234d86addf4e", "npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg"},
+		{"3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d", "npub180cvv07tjdrrgpa0j7j7tmnyl2yr6yr7l8j4s3evf6u64th6gkwsyjh6w6"},
+	}
+	for _, tt := range tests {
+		pk := nostr.MustPubKeyFromHex(tt.hex)
+		if got := EncodeNpub(pk); got != tt.npub {
+			t.Errorf("EncodeNpub(%s) = %s, want %s", tt.hex, got, tt.npub)
+		}
+		got, err := DecodeNpub(tt.npub)
+		if err != nil {
+			t.Fatalf("DecodeNpub(%s): %v", tt.npub, err)
+		}
+		if got != pk {
+			t.Errorf("DecodeNpub(%s) = %s, want %s", tt.npub, got.Hex(), tt.hex)
+		}
+		if got := FormatPubKey(tt.hex); got != tt.npub {
+			t.Errorf("FormatPubKey(%s) = %s", tt.hex, got)
+		}
+	}
+}
+
+func TestDecodeNpubRejectsBadInput(t *testing.T) {
+	for _, s := range []string{
+		"npub180cvv07tjdrrgpa0j7j7tmnyl2yr6yr7l8j4s3evf6u64th6gkwsyjh6w4", // bad checksum
+		"nPub180cvv07tjdrrgpa0j7j7tmnyl2yr6yr7l8j4s3evf6u64th6gkwsyjh6w6", // mixed case
+		"note1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqn2l0z3", // wrong prefix
+		"npub1",
+		"",
+	} {
+		if _, err := DecodeNpub(s); err == nil {
+			t.Errorf("DecodeNpub(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestEncodeNprofile(t *testing.T) {
+	pk := nostr.MustPubKeyFromHex("3bf0c63fcb93463407af97a5e5ee64fa883d107ef9e558472c4eb9aaaefa459d")
+	want := "nprofile1qqsrhuxx8l9ex335q7he0f09aej04zpazpl0ne2cgukyawd24mayt8gpp4mhxue69uhhytnc9e3k7mgpz4mhxue69uhkg6nzv9ejuumpv34kytnrdaksjlyr9p"
+	if got := EncodeNprofile(pk, []string{"wss://r.x.com", "wss://djbas.sadkb.com"}); got != want {
+		t.Errorf("EncodeNprofile = %s\nwant %s", got, want)
+	}
+	got, err := ParsePubKey(want)
+	if err != nil || got != pk {
+		t.Errorf("ParsePubKey(nprofile) = %s, %v", got.Hex(), err)
+	}
+}
+
+func TestEncodeNoteAndNevent(t *testing.T) {
+	id := nostr.MustIDFromHex("45326f5d6962ab1e3cd424e758c3002b8665f7b0d8dcee9fe9e288d7751ac194")
+	author := nostr.MustPubKeyFromHex("7fa56f5d6962ab1e3cd424e758c3002b8665f7b0d8dcee9fe9e288d7751abb88")
+
+	want := "nevent1qqsy2vn0t45k92c78n2zfe6ccvqzhpn977cd3h8wnl579zxhw5dvr9qpzpmhxue69uhkyctwv9hxztnrdaksygrl54h466tz4v0re4pyuavvxqptsejl0vxcmnhfl60z3rth2x4m3q04ndyp"
+	if got := EncodeNevent(id, []string{"wss://banana.com"}, author); got != want {
+		t.Errorf("EncodeNevent = %s\nwant %s", got, want)
+	}
+	if a, b := EncodeNevent(id, nil, nostr.ZeroPK), EncodeNevent(id, nil, author); a == b {
+		t.Error("zero author should be omitted from nevent")
+	}
+
+	note := EncodeNote(id)
+	if !strings.HasPrefix(note, "note1") {
+		t.Fatalf("EncodeNote = %s", note)
+	}
+	hrp, data, err := bech32Decode(note)
+	if err != nil || hrp != "note" || nostr.ID(data) != id {
+		t.Errorf("note round trip: hrp=%q data=%x err=%v", hrp, data, err)
+	}
+}
+
+func TestParsePubKeyAcceptsHexAndNpub(t *testing.T) {
+	const hexKey = "7e7e9c42a91bfef19fa929e5fda1b72e0ebc1a4c1141673e2794234d86addf4e"
+	want := nostr.MustPubKeyFromHex(hexKey)
+	for _, s := range []string{hexKey, "npub10elfcs4fr0l0r8af98jlmgdh9c8tcxjvz9qkw038js35mp4dma8qzvjptg"} {
+		got, err := ParsePubKey(s)
+		if err != nil || got != want {
+			t.Errorf("ParsePubKey(%s) = %s, %v", s, got.Hex(), err)
+		}
+	}
+	if _, err := ParsePubKey("not-a-key"); err == nil {
+		t.Error("ParsePubKey accepted garbage")
+	}
+}
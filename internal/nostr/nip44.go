@@ -0,0 +1,197 @@
+package nostr
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/chacha20"
+)
+
+// NIP-44 v2 payload limits.
+const (
+	nip44Version      = 2
+	nip44MinPlaintext = 1
+	nip44MaxPlaintext = 65535
+
+	nip44MinPayload = 132   // base64 length of the smallest payload
+	nip44MaxPayload = 87472 // base64 length of the largest payload
+	nip44MinDecoded = 99    // version + nonce + 32-byte padded block + mac
+	nip44MaxDecoded = 65603 // version + nonce + 65536-byte padded block + mac
+)
+
+const nip44Salt = "nip44-v2"
+
+var (
+	errNIP44Version     = errors.New("nip44: unknown version")
+	errNIP44PayloadSize = errors.New("nip44: invalid payload size")
+	errNIP44MAC         = errors.New("nip44: invalid MAC")
+	errNIP44Padding     = errors.New("nip44: invalid padding")
+)
+
+// ConversationKey derives the NIP-44 v2 conversation key shared by the
+// holder of priv and the owner of the x-only public key pub. The key is
+// symmetric: ConversationKey(a, B) == ConversationKey(b, A).
+func ConversationKey(priv, pub [32]byte) ([32]byte, error) {
+	var key [32]byte
+
+	var scalar secp256k1.ModNScalar
+	if overflow := scalar.SetByteSlice(priv[:]); overflow || scalar.IsZero() {
+		return key, errors.New("nip44: invalid private key")
+	}
+	pk, err := secp256k1.ParsePubKey(append([]byte{0x02}, pub[:]...))
+	if err != nil {
+		return key, fmt.Errorf("nip44: invalid public key: %w", err)
+	}
+
+	shared := secp256k1.GenerateSharedSecret(secp256k1.NewPrivateKey(&scalar), pk)
+	prk, err := hkdf.Extract(sha256.New, shared, []byte(nip44Salt))
+	if err != nil {
+		return key, err
+	}
+	copy(key[:], prk)
+	return key, nil
+}
+
+// Encrypt encrypts plaintext for a conversation and returns the base64
+// NIP-44 v2 payload. A fresh random nonce is used for every call.
+func Encrypt(plaintext string, conversationKey [32]byte) (string, error) {
+	var nonce [32]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return "", fmt.Errorf("nip44: generating nonce: %w", err)
+	}
+	return encryptWithNonce(plaintext, conversationKey, nonce)
+}
+
+// Decrypt verifies and decrypts a base64 NIP-44 v2 payload.
+func Decrypt(payload string, conversationKey [32]byte) (string, error) {
+	if payload == "" || payload[0] == '#' {
+		return "", errNIP44Version
+	}
+	if len(payload) < nip44MinPayload || len(payload) > nip44MaxPayload {
+		return "", errNIP44PayloadSize
+	}
+
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("nip44: decoding payload: %w", err)
+	}
+	if len(data) < nip44MinDecoded || len(data) > nip44MaxDecoded {
+		return "", errNIP44PayloadSize
+	}
+	if data[0] != nip44Version {
+		return "", errNIP44Version
+	}
+
+	var nonce [32]byte
+	copy(nonce[:], data[1:33])
+	ciphertext := data[33 : len(data)-32]
+	mac := data[len(data)-32:]
+
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal(mac, nip44MAC(hmacKey, nonce, ciphertext)) {
+		return "", errNIP44MAC
+	}
+
+	padded := make([]byte, len(ciphertext))
+	if err := nip44XOR(chachaKey, chachaNonce, padded, ciphertext); err != nil {
+		return "", err
+	}
+	return nip44Unpad(padded)
+}
+
+func encryptWithNonce(plaintext string, conversationKey, nonce [32]byte) (string, error) {
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(conversationKey, nonce)
+	if err != nil {
+		return "", err
+	}
+	padded, err := nip44Pad(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext := make([]byte, len(padded))
+	if err := nip44XOR(chachaKey, chachaNonce, ciphertext, padded); err != nil {
+		return "", err
+	}
+
+	out := make([]byte, 0, 1+len(nonce)+len(ciphertext)+sha256.Size)
+	out = append(out, nip44Version)
+	out = append(out, nonce[:]...)
+	out = append(out, ciphertext...)
+	out = append(out, nip44MAC(hmacKey, nonce, ciphertext)...)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// nip44MessageKeys expands the per-message ChaCha20 key, ChaCha20 nonce and
+// HMAC key from the conversation key and the message nonce.
+func nip44MessageKeys(conversationKey, nonce [32]byte) (chachaKey []byte, chachaNonce []byte, hmacKey []byte, err error) {
+	keys, err := hkdf.Expand(sha256.New, conversationKey[:], string(nonce[:]), 76)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return keys[0:32], keys[32:44], keys[44:76], nil
+}
+
+func nip44XOR(key, nonce, dst, src []byte) error {
+	cipher, err := chacha20.NewUnauthenticatedCipher(key, nonce)
+	if err != nil {
+		return err
+	}
+	cipher.XORKeyStream(dst, src)
+	return nil
+}
+
+// nip44MAC authenticates the ciphertext with the nonce as associated data.
+func nip44MAC(hmacKey []byte, nonce [32]byte, ciphertext []byte) []byte {
+	h := hmac.New(sha256.New, hmacKey)
+	h.Write(nonce[:])
+	h.Write(ciphertext)
+	return h.Sum(nil)
+}
+
+// nip44PaddedLen rounds a plaintext length up to the padded block size, which
+// hides the exact message length.
+func nip44PaddedLen(n int) int {
+	if n <= 32 {
+		return 32
+	}
+	nextPower := 1 << bits.Len(uint(n-1))
+	chunk := 32
+	if nextPower > 256 {
+		chunk = nextPower / 8
+	}
+	return chunk * ((n-1)/chunk + 1)
+}
+
+func nip44Pad(plaintext string) ([]byte, error) {
+	n := len(plaintext)
+	if n < nip44MinPlaintext || n > nip44MaxPlaintext {
+		return nil, fmt.Errorf("nip44: plaintext length %d out of range", n)
+	}
+	padded := make([]byte, 2+nip44PaddedLen(n))
+	binary.BigEndian.PutUint16(padded, uint16(n))
+	copy(padded[2:], plaintext)
+	return padded, nil
+}
+
+func nip44Unpad(padded []byte) (string, error) {
+	if len(padded) < 2 {
+		return "", errNIP44Padding
+	}
+	n := int(binary.BigEndian.Uint16(padded))
+	if n < nip44MinPlaintext || len(padded) != 2+nip44PaddedLen(n) {
+		return "", errNIP44Padding
+	}
+	return string(padded[2 : 2+n]), nil
+}
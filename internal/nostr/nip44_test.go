@@ -0,0 +1,163 @@
+package nostr
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func hex32(t *testing.T, s string) [32]byte {
+	t.Helper()
+	var out [32]byte
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		t.Fatalf("bad 32-byte hex %q", s)
+	}
+	copy(out[:], b)
+	return out
+}
+
+// Vectors below are from the official NIP-44 v2 test vector file
+// (nip44.vectors.json).
+
+func TestNIP44ConversationKey(t *testing.T) {
+	tests := []struct {
+		sec1, pub2, want string
+	}{
+		{
+			sec1: "315e59ff51cb9209768cf7da80791ddcaae56ac9775eb25b6dee1234bc5d2268",
+			pub2: "c2f9d9948dc8c7c38321e4b85c8558872eafa0641cd269db76848a6073e69133",
+			want: "3dfef0ce2a4d80a25e7a328accf73448ef67096f65f79588e358d9a0eb9013f1",
+		},
+		{
+			sec1: "0000000000000000000000000000000000000000000000000000000000000001",
+			pub2: "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+			want: "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d",
+		},
+	}
+	for _, tt := range tests {
+		got, err := ConversationKey(hex32(t, tt.sec1), hex32(t, tt.pub2))
+		if err != nil {
+			t.Fatalf("ConversationKey(%s): %v", tt.sec1, err)
+		}
+		if hex.EncodeToString(got[:]) != tt.want {
+			t.Errorf("ConversationKey(%s) = %x, want %s", tt.sec1, got, tt.want)
+		}
+	}
+
+	var zero [32]byte
+	if _, err := ConversationKey(zero, hex32(t, tests[0].pub2)); err == nil {
+		t.Error("ConversationKey should reject a zero private key")
+	}
+}
+
+func TestNIP44MessageKeys(t *testing.T) {
+	key := hex32(t, "a1a3d60f3470a8612633924e91febf96dc5366ce130f658b1f0fc652c20b3b54")
+	nonce := hex32(t, "e1e6f880560d6d149ed83dcc7e5861ee62a5ee051f7fde9975fe5d25d2a02d72")
+
+	chachaKey, chachaNonce, hmacKey, err := nip44MessageKeys(key, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(chachaKey); got != "f145f3bed47cb70dbeaac07f3a3fe683e822b3715edb7c4fe310829014ce7d76" {
+		t.Errorf("chacha key = %s", got)
+	}
+	if got := hex.EncodeToString(chachaNonce); got != "c4ad129bb01180c0933a160c" {
+		t.Errorf("chacha nonce = %s", got)
+	}
+	if got := hex.EncodeToString(hmacKey); got != "027c1db445f05e2eee864a0975b0ddef5b7110583c8c192de3732571ca5838c4" {
+		t.Errorf("hmac key = %s", got)
+	}
+}
+
+func TestNIP44PaddedLen(t *testing.T) {
+	tests := [][2]int{
+		{16, 32}, {32, 32}, {33, 64}, {37, 64}, {45, 64}, {49, 64}, {64, 64},
+		{65, 96}, {100, 128}, {111, 128}, {200, 224}, {250, 256}, {320, 320},
+		{383, 384}, {384, 384}, {400, 448}, {500, 512}, {512, 512}, {515, 640},
+		{700, 768}, {800, 896}, {900, 1024}, {1020, 1024}, {65536, 65536},
+	}
+	for _, tt := range tests {
+		if got := nip44PaddedLen(tt[0]); got != tt[1] {
+			t.Errorf("nip44PaddedLen(%d) = %d, want %d", tt[0], got, tt[1])
+		}
+	}
+}
+
+func TestNIP44EncryptDecrypt(t *testing.T) {
+	key := hex32(t, "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d")
+	nonce := hex32(t, "0000000000000000000000000000000000000000000000000000000000000001")
+	const want = "AgAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABee0G5VSK0/9YypIObAtDKfYEAjD35uVkHyB0F4DwrcNaCXlCWZKaArsGrY6M9wnuTMxWfp1RTN9Xga8no+kF5Vsb"
+
+	payload, err := encryptWithNonce("a", key, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload != want {
+		t.Errorf("encrypt = %s\nwant      %s", payload, want)
+	}
+	if got, err := Decrypt(want, key); err != nil || got != "a" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+
+	// Both sides of a conversation derive the same key.
+	alice := hex32(t, "0000000000000000000000000000000000000000000000000000000000000001")
+	bob := hex32(t, "0000000000000000000000000000000000000000000000000000000000000002")
+	alicePub := hex32(t, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	bobPub := hex32(t, "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	ab, err := ConversationKey(alice, bobPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ba, err := ConversationKey(bob, alicePub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ab != ba {
+		t.Fatal("conversation keys differ between sides")
+	}
+
+	for _, msg := range []string{"hi", "🍕🫃", strings.Repeat("x", 300), strings.Repeat("y", nip44MaxPlaintext)} {
+		payload, err := Encrypt(msg, ab)
+		if err != nil {
+			t.Fatalf("Encrypt(%d bytes): %v", len(msg), err)
+		}
+		if got, err := Decrypt(payload, ba); err != nil || got != msg {
+			t.Errorf("round trip of %d bytes failed: %v", len(msg), err)
+		}
+	}
+}
+
+func TestNIP44RejectsInvalid(t *testing.T) {
+	key := hex32(t, "c41c775356fd92eadc63ff5a0dc1da211b268cbea22316767095b2871ea1412d")
+	valid := "AgAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABee0G5VSK0/9YypIObAtDKfYEAjD35uVkHyB0F4DwrcNaCXlCWZKaArsGrY6M9wnuTMxWfp1RTN9Xga8no+kF5Vsb"
+
+	if _, err := Encrypt("", key); err == nil {
+		t.Error("Encrypt should reject an empty message")
+	}
+	if _, err := Encrypt(strings.Repeat("x", nip44MaxPlaintext+1), key); err == nil {
+		t.Error("Encrypt should reject an oversized message")
+	}
+
+	otherKey := key
+	otherKey[0] ^= 1
+	tampered := []byte(valid)
+	tampered[len(tampered)-6] ^= 1
+
+	tests := []struct {
+		name    string
+		payload string
+		key     [32]byte
+	}{
+		{"unsupported version", "#" + valid[1:], key},
+		{"too short", valid[:100], key},
+		{"bad base64", valid[:len(valid)-4] + "!!!!", key},
+		{"tampered mac", string(tampered), key},
+		{"wrong key", valid, otherKey},
+	}
+	for _, tt := range tests {
+		if _, err := Decrypt(tt.payload, tt.key); err == nil {
+			t.Errorf("%s: Decrypt should fail", tt.name)
+		}
+	}
+}
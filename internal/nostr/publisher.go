@@ -28,7 +28,15 @@ func NewPublisher(ctx context.Context, cfg *config.NostrConfig, signer Signer, r
 		return nil, fmt.Errorf("creating relay pool: %w", err)
 	}
 
+	pool.SetAuthSigner(signer)
+
 	spool := NewSpool(runtimeDir)
+	if cfg.Defaults != nil && cfg.Defaults.SpoolMaxMB > 0 {
+		spool.SetMaxBytes(int64(cfg.Defaults.SpoolMaxMB) << 20)
+	}
+	if cfg.Defaults != nil && cfg.Defaults.SpoolReconcile {
+		spool.SetReconcile(true)
+	}
 
 	return &Publisher{
 		signer: signer,
@@ -50,7 +58,7 @@ func (p *Publisher) WithSigner(signer Signer) *Publisher {
 }
 
 // Publish signs and broadcasts a regular (non-replaceable) event.
-// If all relays fail, the event is spooled locally for later drain.
+// If no relay accepts it, the event is spooled locally for later drain.
 // Returns an error only if both publishing and spooling fail.
 func (p *Publisher) Publish(ctx context.Context, event *nostr.Event) error {
 	// Sign the event
@@ -58,8 +66,12 @@ func (p *Publisher) Publish(ctx context.Context, event *nostr.Event) error {
 		return fmt.Errorf("signing event: %w", err)
 	}
 
-	// Attempt to broadcast
-	if err := p.pool.Publish(ctx, *event); err != nil {
+	// Attempt to broadcast; only relays that answered OK=true count.
+	results, err := p.pool.PublishDetailed(ctx, *event)
+	if err == nil {
+		err = acceptedOrError(results)
+	}
+	if err != nil {
 		log.Printf("[nostr] publish failed, spooling event %s: %v", IDToString(event.ID), err)
 		// Spool for later retry
 		if spoolErr := p.spool.Enqueue(event, p.pool.WriteRelayURLs()); spoolErr != nil {
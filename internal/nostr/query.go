@@ -0,0 +1,92 @@
+package nostr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+// DefaultQueryTimeout bounds a one-off query, so a relay that never sends
+// EOSE cannot stall the caller.
+const DefaultQueryTimeout = 5 * time.Second
+
+// ErrNotFound is returned by QueryOne and FetchLatestReplaceable when no
+// relay holds a matching event.
+var ErrNotFound = errors.New("no matching event found")
+
+// QueryAll reads the stored events matching filter from every read relay and
+// returns them de-duplicated and newest first. It returns once every relay
+// has sent EOSE, or after DefaultQueryTimeout with whatever arrived by then.
+// Events that don't actually match filter are dropped, since relays are not
+// trusted to filter correctly.
+func QueryAll(ctx context.Context, pool *RelayPool, filter nostr.Filter) ([]*nostr.Event, error) {
+	if len(pool.ConnectedReadRelayURLs()) == 0 {
+		return nil, fmt.Errorf("no read relays connected")
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, DefaultQueryTimeout)
+	defer cancel()
+
+	var events []*nostr.Event
+	pool.queryStored(queryCtx, []nostr.Filter{filter}, func(event *nostr.Event) {
+		if filter.Matches(*event) {
+			events = append(events, event)
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sortNewestFirst(events)
+	return events, nil
+}
+
+// QueryOne returns the newest stored event matching filter, or ErrNotFound.
+// Each relay is asked for a single event.
+func QueryOne(ctx context.Context, pool *RelayPool, filter nostr.Filter) (*nostr.Event, error) {
+	filter = filter.Clone()
+	filter.Limit = 1
+	events, err := QueryAll(ctx, pool, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrNotFound
+	}
+	return events[0], nil
+}
+
+// FetchLatestReplaceable returns the current version of a replaceable event:
+// the newest event of kind by pubkey (hex, npub or nprofile). For
+// addressable kinds (30000-39999) it is the version with d tag dTag; for
+// other kinds dTag is ignored.
+func FetchLatestReplaceable(ctx context.Context, pool *RelayPool, kind int, pubkey, dTag string) (*nostr.Event, error) {
+	author, err := ParsePubKey(pubkey)
+	if err != nil {
+		return nil, fmt.Errorf("parsing pubkey: %w", err)
+	}
+	filter := nostr.Filter{
+		Kinds:   []nostr.Kind{nostr.Kind(kind)},
+		Authors: []nostr.PubKey{author},
+	}
+	if nostr.Kind(kind).IsAddressable() {
+		filter.Tags = nostr.TagMap{"d": {dTag}}
+	}
+	return QueryOne(ctx, pool, filter)
+}
+
+// sortNewestFirst orders events by created_at, newest first. Ties go to the
+// lowest ID, matching how relays pick between two versions of a replaceable
+// event with the same timestamp (NIP-01).
+func sortNewestFirst(events []*nostr.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].CreatedAt != events[j].CreatedAt {
+			return events[i].CreatedAt > events[j].CreatedAt
+		}
+		return bytes.Compare(events[i].ID[:], events[j].ID[:]) < 0
+	})
+}
@@ -0,0 +1,40 @@
+package nostr
+
+import (
+	"context"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestSortNewestFirstBreaksTiesByLowestID(t *testing.T) {
+	events := []*nostr.Event{
+		{ID: nostr.ID{3}, CreatedAt: 100},
+		{ID: nostr.ID{9}, CreatedAt: 200},
+		{ID: nostr.ID{2}, CreatedAt: 200},
+		{ID: nostr.ID{1}, CreatedAt: 50},
+	}
+	sortNewestFirst(events)
+
+	want := []nostr.ID{{2}, {9}, {3}, {1}}
+	for i, id := range want {
+		if events[i].ID != id {
+			t.Errorf("events[%d] = %x, want %x", i, events[i].ID[:1], id[:1])
+		}
+	}
+}
+
+func TestQueryHelpersRequireReadRelays(t *testing.T) {
+	pool := &RelayPool{}
+	ctx := context.Background()
+
+	if _, err := QueryAll(ctx, pool, nostr.Filter{}); err == nil {
+		t.Error("QueryAll without relays should fail")
+	}
+	if _, err := QueryOne(ctx, pool, nostr.Filter{}); err == nil {
+		t.Error("QueryOne without relays should fail")
+	}
+	if _, err := FetchLatestReplaceable(ctx, pool, 30078, "not-a-key", "x"); err == nil {
+		t.Error("FetchLatestReplaceable should reject an invalid pubkey")
+	}
+}
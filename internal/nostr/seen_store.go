@@ -0,0 +1,203 @@
+package nostr
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// SeenStore records the IDs of events a consumer has already acted on, so a
+// restarted process that replays relay history can skip them instead of
+// handling them twice.
+type SeenStore interface {
+	// Seen reports whether id was marked and has not yet expired.
+	Seen(id string) bool
+	// MarkSeen records id as handled.
+	MarkSeen(id string) error
+	// Compact drops expired IDs from the store and returns how many it
+	// dropped.
+	Compact() (dropped int, err error)
+}
+
+const (
+	SeenStoreFileName = "nostr-seen.jsonl"
+
+	// DefaultSeenTTL outlasts the heartbeat history window and the spool's
+	// max age, so any event a relay can still replay is remembered.
+	DefaultSeenTTL = 48 * time.Hour
+)
+
+// seenEntry is one line of the seen store file.
+type seenEntry struct {
+	ID     string    `json:"id"`
+	SeenAt time.Time `json:"seen_at"`
+}
+
+// FileSeenStore is the default SeenStore: an in-memory set backed by an
+// append-only JSONL file. IDs older than the TTL are forgotten, and the file
+// is rewritten without them on open and whenever expired or duplicate lines
+// make up more than half of it.
+type FileSeenStore struct {
+	mu    sync.Mutex
+	path  string
+	ttl   time.Duration
+	seen  map[string]time.Time
+	lines int // lines in the file, live or not
+	now   func() time.Time
+}
+
+// NewFileSeenStore opens (or creates) the seen store in runtimeDir. A ttl
+// <= 0 uses DefaultSeenTTL.
+func NewFileSeenStore(runtimeDir string, ttl time.Duration) (*FileSeenStore, error) {
+	if ttl <= 0 {
+		ttl = DefaultSeenTTL
+	}
+	s := &FileSeenStore{
+		path: filepath.Join(runtimeDir, SeenStoreFileName),
+		ttl:  ttl,
+		seen: make(map[string]time.Time),
+		now:  time.Now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	if _, err := s.compactLocked(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Seen reports whether id was marked within the TTL.
+func (s *FileSeenStore) Seen(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok := s.seen[id]
+	return ok && s.now().Sub(at) < s.ttl
+}
+
+// MarkSeen records id as handled, appending it to the file.
+func (s *FileSeenStore) MarkSeen(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := seenEntry{ID: id, SeenAt: s.now()}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling seen entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("creating seen store directory: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening seen store: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("writing seen store: %w", err)
+	}
+
+	s.seen[id] = entry.SeenAt
+	s.lines++
+	if s.lines > 2*len(s.seen) {
+		if _, err := s.compactLocked(); err != nil {
+			log.Printf("[nostr] compacting seen store: %v", err)
+		}
+	}
+	return nil
+}
+
+// Compact forgets IDs older than the TTL and rewrites the file with the rest.
+func (s *FileSeenStore) Compact() (dropped int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compactLocked()
+}
+
+// Len returns the number of IDs currently remembered.
+func (s *FileSeenStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.seen)
+}
+
+func (s *FileSeenStore) compactLocked() (int, error) {
+	cutoff := s.now().Add(-s.ttl)
+	dropped := 0
+	for id, at := range s.seen {
+		if !at.After(cutoff) {
+			delete(s.seen, id)
+			dropped++
+		}
+	}
+	if s.lines == len(s.seen) {
+		return dropped, nil
+	}
+
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("rewriting seen store: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	for id, at := range s.seen {
+		data, err := json.Marshal(seenEntry{ID: id, SeenAt: at})
+		if err != nil {
+			continue
+		}
+		_, _ = w.Write(append(data, '\n'))
+	}
+	err = w.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, fmt.Errorf("rewriting seen store: %w", err)
+	}
+	s.lines = len(s.seen)
+	return dropped, nil
+}
+
+func (s *FileSeenStore) loadLocked() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("opening seen store: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		s.lines++
+		var entry seenEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			log.Printf("[nostr] skipping malformed seen store entry: %v", err)
+			continue
+		}
+		if entry.SeenAt.After(s.seen[entry.ID]) {
+			s.seen[entry.ID] = entry.SeenAt
+		}
+	}
+	return scanner.Err()
+}
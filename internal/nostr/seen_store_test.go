@@ -0,0 +1,103 @@
+package nostr
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		n++
+	}
+	return n
+}
+
+func TestFileSeenStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSeenStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "a"} {
+		if err := s.MarkSeen(id); err != nil {
+			t.Fatalf("MarkSeen(%s): %v", id, err)
+		}
+	}
+
+	reopened, err := NewFileSeenStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reopened.Seen("a") || !reopened.Seen("b") || reopened.Seen("c") {
+		t.Errorf("after restart: a=%v b=%v c=%v", reopened.Seen("a"), reopened.Seen("b"), reopened.Seen("c"))
+	}
+	// Opening rewrites away the duplicate line.
+	if n := countLines(t, filepath.Join(dir, SeenStoreFileName)); n != 2 {
+		t.Errorf("file has %d lines after reopen, want 2", n)
+	}
+}
+
+func TestFileSeenStoreExpiresAndCompacts(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSeenStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	for _, id := range []string{"old1", "old2"} {
+		if err := s.MarkSeen(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now = now.Add(30 * time.Minute)
+	if err := s.MarkSeen("fresh"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(45 * time.Minute)
+	if s.Seen("old1") {
+		t.Error("old1 should have expired")
+	}
+	if !s.Seen("fresh") {
+		t.Error("fresh should still be seen")
+	}
+
+	dropped, err := s.Compact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 2 || s.Len() != 1 {
+		t.Errorf("Compact dropped %d, Len = %d; want 2 and 1", dropped, s.Len())
+	}
+	if n := countLines(t, filepath.Join(dir, SeenStoreFileName)); n != 1 {
+		t.Errorf("file has %d lines after compaction, want 1", n)
+	}
+}
+
+func TestFileSeenStoreCompactsAsItGrows(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSeenStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := s.MarkSeen("same"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countLines(t, filepath.Join(dir, SeenStoreFileName)); n > 2 {
+		t.Errorf("file has %d lines for one ID, want it compacted", n)
+	}
+}
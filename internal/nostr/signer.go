@@ -2,10 +2,12 @@ package nostr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
+	"time"
 
 	"fiatjaf.com/nostr"
 	"fiatjaf.com/nostr/nip46"
@@ -24,15 +26,81 @@ type Signer interface {
 	Close() error
 }
 
+// Encrypter is implemented by signers that can NIP-44 encrypt and decrypt
+// messages exchanged with a peer. It is kept separate from Signer so
+// signing-only backends and test fakes don't have to implement it; the DM
+// code type-asserts for it.
+type Encrypter interface {
+	// NIP44Encrypt encrypts plaintext for the holder of recipientPubKey (hex).
+	NIP44Encrypt(ctx context.Context, recipientPubKey, plaintext string) (string, error)
+
+	// NIP44Decrypt decrypts a payload sent to us by the holder of senderPubKey (hex).
+	NIP44Decrypt(ctx context.Context, senderPubKey, payload string) (string, error)
+}
+
 // --- NIP-46 Signer (production) ---
 
+// NIP-46 request and reconnect timing. Each bunker request gets its own
+// timeout so a dead connection fails fast enough to leave time for a
+// reconnect within the caller's deadline.
+const (
+	nip46RequestTimeout    = 15 * time.Second
+	nip46ReconnectAttempts = 3
+)
+
+// nip46ReconnectBaseDelay is the wait before the first reconnect, doubling
+// per attempt. A variable so tests can shorten it.
+var nip46ReconnectBaseDelay = time.Second
+
+// SignerHealth is implemented by signers whose backend can become
+// unreachable, such as NIP46Signer. CheckHealth uses it to report the
+// signer's state.
+type SignerHealth interface {
+	// Ping checks that the backend is answering, reconnecting if needed.
+	Ping(ctx context.Context) error
+
+	// Healthy reports whether the most recent request succeeded.
+	Healthy() bool
+
+	// LastError is the most recent request's error, or nil.
+	LastError() error
+}
+
+// bunkerConn is the subset of nip46.BunkerClient the signer uses.
+type bunkerConn interface {
+	SignEvent(ctx context.Context, event *nostr.Event) error
+	NIP44Encrypt(ctx context.Context, target nostr.PubKey, plaintext string) (string, error)
+	NIP44Decrypt(ctx context.Context, target nostr.PubKey, ciphertext string) (string, error)
+	GetPublicKey(ctx context.Context) (nostr.PubKey, error)
+	Ping(ctx context.Context) error
+}
+
+// connectBunker opens a NIP-46 session; tests replace it.
+var connectBunker = func(ctx context.Context, clientKey nostr.SecretKey, bunkerURI string) (bunkerConn, error) {
+	bunker, err := nip46.ConnectBunker(ctx, clientKey, bunkerURI, nil, func(status string) {
+		log.Printf("[nostr/signer] bunker status: %s", status)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bunker, nil
+}
+
 // NIP46Signer signs events via an external NIP-46 bunker.
 // This is the production signing path — no secret keys are stored on disk.
+//
+// If a request fails because the bunker or its relay went away, the signer
+// reconnects (a bounded number of times, with backoff) and retries before
+// returning the error.
 type NIP46Signer struct {
 	mu        sync.Mutex
 	bunkerURI string
+	clientKey nostr.SecretKey
 	pubkey    string
-	bunker    *nip46.BunkerClient
+	bunker    bunkerConn
+
+	healthy bool
+	lastErr error
 }
 
 // NewNIP46Signer creates a signer that connects to a NIP-46 bunker.
@@ -42,12 +110,10 @@ func NewNIP46Signer(ctx context.Context, bunkerURI string) (*NIP46Signer, error)
 		return nil, fmt.Errorf("invalid bunker URI: must start with bunker://")
 	}
 
-	// ConnectBunker requires: ctx, clientSecretKey, bunkerURI, pool, statusCallback
 	// Generate an ephemeral client secret key for the NIP-46 connection.
+	// It is kept so reconnects resume the same session with the bunker.
 	clientKey := nostr.Generate()
-	bunker, err := nip46.ConnectBunker(ctx, clientKey, bunkerURI, nil, func(status string) {
-		log.Printf("[nostr/signer] bunker status: %s", status)
-	})
+	bunker, err := connectBunker(ctx, clientKey, bunkerURI)
 	if err != nil {
 		return nil, fmt.Errorf("connecting to bunker: %w", err)
 	}
@@ -59,8 +125,10 @@ func NewNIP46Signer(ctx context.Context, bunkerURI string) (*NIP46Signer, error)
 
 	return &NIP46Signer{
 		bunkerURI: bunkerURI,
+		clientKey: clientKey,
 		pubkey:    PubKeyToString(pubkeyBytes),
 		bunker:    bunker,
+		healthy:   true,
 	}, nil
 }
 
@@ -71,7 +139,144 @@ func (s *NIP46Signer) Sign(ctx context.Context, event *nostr.Event) error {
 
 	event.PubKey = PubKeyFromHexGT(s.pubkey)
 
-	return s.bunker.SignEvent(ctx, event)
+	return s.do(ctx, func(ctx context.Context, b bunkerConn) error {
+		return b.SignEvent(ctx, event)
+	})
+}
+
+// NIP44Encrypt encrypts plaintext via the bunker's nip44_encrypt RPC, so the
+// secret key never leaves the bunker.
+func (s *NIP46Signer) NIP44Encrypt(ctx context.Context, recipientPubKey, plaintext string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out string
+	err := s.do(ctx, func(ctx context.Context, b bunkerConn) error {
+		var err error
+		out, err = b.NIP44Encrypt(ctx, PubKeyFromHexGT(recipientPubKey), plaintext)
+		return err
+	})
+	return out, err
+}
+
+// NIP44Decrypt decrypts payload via the bunker's nip44_decrypt RPC.
+func (s *NIP46Signer) NIP44Decrypt(ctx context.Context, senderPubKey, payload string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out string
+	err := s.do(ctx, func(ctx context.Context, b bunkerConn) error {
+		var err error
+		out, err = b.NIP44Decrypt(ctx, PubKeyFromHexGT(senderPubKey), payload)
+		return err
+	})
+	return out, err
+}
+
+// Ping checks that the bunker is answering, reconnecting if it is not.
+func (s *NIP46Signer) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.do(ctx, func(ctx context.Context, b bunkerConn) error {
+		return b.Ping(ctx)
+	})
+}
+
+// Healthy reports whether the most recent bunker request succeeded.
+func (s *NIP46Signer) Healthy() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.healthy
+}
+
+// LastError returns the error from the most recent failed bunker request,
+// or nil if the last request succeeded.
+func (s *NIP46Signer) LastError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastErr
+}
+
+// do runs one bunker request, reconnecting and retrying when it fails with
+// what looks like a dropped connection. The caller holds s.mu.
+func (s *NIP46Signer) do(ctx context.Context, req func(context.Context, bunkerConn) error) error {
+	if s.bunker == nil {
+		return fmt.Errorf("signer is closed")
+	}
+
+	err := s.attempt(ctx, req)
+	retry := err != nil && isBunkerConnError(err)
+	for attempt := 1; retry && attempt <= nip46ReconnectAttempts; attempt++ {
+		delay := nip46ReconnectBaseDelay << (attempt - 1)
+		log.Printf("[nostr/signer] bunker request failed (%v); reconnecting in %s (attempt %d/%d)",
+			err, delay, attempt, nip46ReconnectAttempts)
+		select {
+		case <-ctx.Done():
+			s.setHealth(err)
+			return err
+		case <-time.After(delay):
+		}
+
+		bunker, cerr := s.reconnect(ctx)
+		if cerr != nil {
+			err = fmt.Errorf("reconnecting to bunker: %w", cerr)
+			continue
+		}
+		s.bunker = bunker
+		err = s.attempt(ctx, req)
+		retry = err != nil && isBunkerConnError(err)
+	}
+
+	s.setHealth(err)
+	return err
+}
+
+// attempt runs req with the per-request timeout.
+func (s *NIP46Signer) attempt(ctx context.Context, req func(context.Context, bunkerConn) error) error {
+	reqCtx, cancel := context.WithTimeout(ctx, nip46RequestTimeout)
+	defer cancel()
+	return req(reqCtx, s.bunker)
+}
+
+// reconnect opens a new bunker session with the same client key.
+func (s *NIP46Signer) reconnect(ctx context.Context) (bunkerConn, error) {
+	connCtx, cancel := context.WithTimeout(ctx, nip46RequestTimeout)
+	defer cancel()
+	return connectBunker(connCtx, s.clientKey, s.bunkerURI)
+}
+
+func (s *NIP46Signer) setHealth(err error) {
+	s.healthy = err == nil
+	s.lastErr = err
+}
+
+// isBunkerConnError reports whether err from a bunker request means the
+// bunker or its relays could not be reached, as opposed to the bunker
+// answering with an error (such as refusing to sign).
+func isBunkerConnError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.HasPrefix(msg, "response error:") {
+		return false
+	}
+	for _, s := range []string{
+		"couldn't reach the bunker",
+		"couldn't connect to any relay",
+		"context canceled",
+		"context deadline exceeded",
+		"connection",
+		"not connected",
+		"eof",
+		"broken pipe",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetPublicKey returns the signer's public key.
@@ -122,6 +327,24 @@ func (s *LocalSigner) Sign(_ context.Context, event *nostr.Event) error {
 	return event.Sign(s.secretKey)
 }
 
+// NIP44Encrypt encrypts plaintext with the local private key.
+func (s *LocalSigner) NIP44Encrypt(_ context.Context, recipientPubKey, plaintext string) (string, error) {
+	key, err := ConversationKey(s.secretKey, PubKeyFromHexGT(recipientPubKey))
+	if err != nil {
+		return "", err
+	}
+	return Encrypt(plaintext, key)
+}
+
+// NIP44Decrypt decrypts payload with the local private key.
+func (s *LocalSigner) NIP44Decrypt(_ context.Context, senderPubKey, payload string) (string, error) {
+	key, err := ConversationKey(s.secretKey, PubKeyFromHexGT(senderPubKey))
+	if err != nil {
+		return "", err
+	}
+	return Decrypt(payload, key)
+}
+
 // GetPublicKey returns the signer's public key.
 func (s *LocalSigner) GetPublicKey() string {
 	return s.pubkey
@@ -0,0 +1,115 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+// fakeBunker fails requests with err until it is replaced by a reconnect.
+type fakeBunker struct {
+	err   error
+	signs int
+}
+
+func (b *fakeBunker) SignEvent(context.Context, *nostr.Event) error {
+	b.signs++
+	return b.err
+}
+
+func (b *fakeBunker) NIP44Encrypt(context.Context, nostr.PubKey, string) (string, error) {
+	return "", b.err
+}
+
+func (b *fakeBunker) NIP44Decrypt(context.Context, nostr.PubKey, string) (string, error) {
+	return "", b.err
+}
+
+func (b *fakeBunker) GetPublicKey(context.Context) (nostr.PubKey, error) {
+	return nostr.PubKey{}, b.err
+}
+
+func (b *fakeBunker) Ping(context.Context) error {
+	return b.err
+}
+
+func newFakeNIP46Signer(t *testing.T, bunker *fakeBunker, reconnects func() (bunkerConn, error)) (*NIP46Signer, *int) {
+	t.Helper()
+	originalConnect, originalDelay := connectBunker, nip46ReconnectBaseDelay
+	t.Cleanup(func() { connectBunker, nip46ReconnectBaseDelay = originalConnect, originalDelay })
+	nip46ReconnectBaseDelay = time.Millisecond
+
+	calls := 0
+	connectBunker = func(context.Context, nostr.SecretKey, string) (bunkerConn, error) {
+		calls++
+		if calls == 1 {
+			return bunker, nil
+		}
+		return reconnects()
+	}
+	s, err := NewNIP46Signer(context.Background(), "bunker://npub1test?relay=wss://relay.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reconnectCount := new(int)
+	connect := connectBunker
+	connectBunker = func(ctx context.Context, key nostr.SecretKey, uri string) (bunkerConn, error) {
+		*reconnectCount++
+		return connect(ctx, key, uri)
+	}
+	return s, reconnectCount
+}
+
+func TestNIP46SignerReconnectsAfterDroppedConnection(t *testing.T) {
+	dead := &fakeBunker{}
+	fresh := &fakeBunker{}
+	s, reconnects := newFakeNIP46Signer(t, dead, func() (bunkerConn, error) { return fresh, nil })
+	dead.err = errors.New("couldn't reach the bunker, it is probably offline")
+
+	if err := s.Sign(context.Background(), &nostr.Event{}); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if *reconnects != 1 || fresh.signs != 1 {
+		t.Errorf("reconnects = %d, fresh signs = %d; want 1 and 1", *reconnects, fresh.signs)
+	}
+	if !s.Healthy() || s.LastError() != nil {
+		t.Errorf("Healthy = %v, LastError = %v after recovery", s.Healthy(), s.LastError())
+	}
+}
+
+func TestNIP46SignerGivesUpAfterBoundedRetries(t *testing.T) {
+	dead := &fakeBunker{}
+	s, reconnects := newFakeNIP46Signer(t, dead, func() (bunkerConn, error) {
+		return nil, errors.New("couldn't connect to any relay")
+	})
+	dead.err = errors.New("couldn't connect to any relay")
+
+	if err := s.Ping(context.Background()); err == nil {
+		t.Fatal("Ping succeeded with the bunker unreachable")
+	}
+	if *reconnects != nip46ReconnectAttempts {
+		t.Errorf("reconnects = %d, want %d", *reconnects, nip46ReconnectAttempts)
+	}
+	if s.Healthy() || s.LastError() == nil {
+		t.Errorf("Healthy = %v, LastError = %v; want unhealthy", s.Healthy(), s.LastError())
+	}
+}
+
+func TestNIP46SignerDoesNotReconnectOnBunkerRefusal(t *testing.T) {
+	bunker := &fakeBunker{}
+	s, reconnects := newFakeNIP46Signer(t, bunker, func() (bunkerConn, error) {
+		t.Fatal("unexpected reconnect")
+		return nil, nil
+	})
+	bunker.err = errors.New("response error: permission denied")
+
+	if err := s.Sign(context.Background(), &nostr.Event{}); err == nil {
+		t.Fatal("Sign succeeded despite refusal")
+	}
+	if *reconnects != 0 || bunker.signs != 1 {
+		t.Errorf("reconnects = %d, signs = %d; want 0 and 1", *reconnects, bunker.signs)
+	}
+}
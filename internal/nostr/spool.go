@@ -9,10 +9,12 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"fiatjaf.com/nostr"
+	cascadia "git.sharegap.net/cascadia/cascadia-go"
 )
 
 // Spool is a local event store for offline resilience.
@@ -26,6 +28,8 @@ type Spool struct {
 	archivePath string // archive file for old events
 	softLimit   int    // warning threshold (default: 10,000)
 	hardLimit   int    // stop threshold (default: 100,000)
+	maxBytes    int64  // stop threshold for the spool file size (default: 256 MiB)
+	reconcile   bool   // ask relays which events they already hold (NIP-77) before draining
 }
 
 // SpoolEntry is a single spooled event with retry metadata.
@@ -45,11 +49,29 @@ type SpoolEntry struct {
 
 // SpoolMeta contains retry tracking information.
 type SpoolMeta struct {
-	SpooledAt    time.Time `json:"spooled_at"`
-	TargetRelays []string  `json:"target_relays"`
-	Attempts     int       `json:"attempts"`
+	SpooledAt    time.Time  `json:"spooled_at"`
+	TargetRelays []string   `json:"target_relays"`
+	Attempts     int        `json:"attempts"`
 	LastAttempt  *time.Time `json:"last_attempt"`
-	LastError    *string   `json:"last_error"`
+	LastError    *string    `json:"last_error"`
+	Priority     int        `json:"priority,omitempty"` // higher drains first; see spoolPriority
+}
+
+// Spool drain priorities.
+const (
+	SpoolPriorityNormal = 0
+	SpoolPriorityHigh   = 10
+)
+
+// urgentSpoolTypes are event types (the Cascadia type tag) that should reach
+// relays ahead of routine traffic once connectivity returns.
+var urgentSpoolTypes = map[string]bool{
+	"alert":           true,
+	"escalation_sent": true,
+	"merge_failed":    true,
+	"MERGE_FAILED":    true,
+	"session_death":   true,
+	"mass_death":      true,
 }
 
 // Default spool limits.
@@ -59,6 +81,8 @@ const (
 	SpoolFileName         = "nostr-spool.jsonl"
 	SpoolArchiveFileName  = "nostr-spool-archive.jsonl"
 	SpoolMaxAge           = 24 * time.Hour
+
+	DefaultSpoolMaxBytes int64 = 256 << 20
 )
 
 // NewSpool creates a new spool in the given runtime directory.
@@ -68,28 +92,38 @@ func NewSpool(runtimeDir string) *Spool {
 		archivePath: filepath.Join(runtimeDir, SpoolArchiveFileName),
 		softLimit:   DefaultSpoolSoftLimit,
 		hardLimit:   DefaultSpoolHardLimit,
+		maxBytes:    DefaultSpoolMaxBytes,
 	}
 }
 
-// Enqueue adds an event to the spool.
-// Returns an error if the hard limit is exceeded.
-func (s *Spool) Enqueue(event *nostr.Event, targetRelays []string) error {
+// SetMaxBytes sets the hard limit on the spool file's size. Values <= 0
+// restore the default.
+func (s *Spool) SetMaxBytes(n int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	// Check hard limit
-	count := s.countLocked()
-	if count >= s.hardLimit {
-		return fmt.Errorf("spool hard limit exceeded (%d events); require operator intervention", count)
-	}
-	if count >= s.softLimit {
-		log.Printf("[nostr] spool soft limit reached (%d events)", count)
+	if n <= 0 {
+		n = DefaultSpoolMaxBytes
 	}
+	s.maxBytes = n
+}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
-		return fmt.Errorf("creating spool directory: %w", err)
-	}
+// SetReconcile makes Drain first ask write relays that support NIP-77
+// negentropy which spooled events they already hold, and drop those without
+// republishing. Relays without support are published to as usual.
+func (s *Spool) SetReconcile(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconcile = on
+}
+
+// Enqueue adds an event to the spool.
+// Replaceable events (see replaceableKey) replace any spooled version with
+// the same pubkey, kind, and d tag instead of appending, keeping only the
+// newest by CreatedAt. Returns an error if the hard event-count or byte-size
+// limit is exceeded.
+func (s *Spool) Enqueue(event *nostr.Event, targetRelays []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	// Create entry
 	entry := SpoolEntry{
@@ -104,21 +138,48 @@ func (s *Spool) Enqueue(event *nostr.Event, targetRelays []string) error {
 			SpooledAt:    time.Now(),
 			TargetRelays: targetRelays,
 			Attempts:     0,
+			Priority:     spoolPriority(event.Tags),
 		},
 	}
 
-	// Append to spool file
-	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
-	if err != nil {
-		return fmt.Errorf("opening spool file: %w", err)
+	if key, ok := replaceableKey(entry.PubKey, entry.Kind, entry.Tags); ok {
+		replaced, err := s.replaceLocked(key, entry)
+		if err != nil || replaced {
+			return err
+		}
+	}
+
+	// Check hard limit
+	count := s.countLocked()
+	if count >= s.hardLimit {
+		return fmt.Errorf("spool hard limit exceeded (%d events); require operator intervention", count)
+	}
+	if count >= s.softLimit {
+		log.Printf("[nostr] spool soft limit reached (%d events)", count)
+	}
+
+	// Ensure directory exists
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("creating spool directory: %w", err)
 	}
-	defer f.Close()
 
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return fmt.Errorf("marshaling spool entry: %w", err)
 	}
 
+	// Check byte-size limit
+	if size := s.sizeLocked(); size+int64(len(data))+1 > s.maxBytes {
+		return fmt.Errorf("spool size limit exceeded (%d of %d bytes); require operator intervention", size, s.maxBytes)
+	}
+
+	// Append to spool file
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening spool file: %w", err)
+	}
+	defer f.Close()
+
 	if _, err := f.Write(append(data, '\n')); err != nil {
 		return fmt.Errorf("writing spool entry: %w", err)
 	}
@@ -126,13 +187,61 @@ func (s *Spool) Enqueue(event *nostr.Event, targetRelays []string) error {
 	return nil
 }
 
+// replaceLocked swaps entry in for a spooled entry with the same replaceable
+// key. It reports false when there is no such entry, so the caller appends.
+// An incoming entry older than the spooled one is dropped.
+func (s *Spool) replaceLocked(key string, entry SpoolEntry) (bool, error) {
+	entries, err := s.readAllLocked()
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	kept := entries[:0:0]
+	for _, existing := range entries {
+		if k, ok := replaceableKey(existing.PubKey, existing.Kind, existing.Tags); !ok || k != key {
+			kept = append(kept, existing)
+			continue
+		}
+		if found {
+			continue // collapse duplicates left by older spools
+		}
+		found = true
+		if existing.CreatedAt > entry.CreatedAt {
+			kept = append(kept, existing)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	if err := s.writeAllLocked(kept); err != nil {
+		return false, fmt.Errorf("rewriting spool: %w", err)
+	}
+	return true, nil
+}
+
 // Drain attempts to send all spooled events to relays.
 // Successfully sent events are removed from the spool.
 // Failed events remain with updated attempt counts.
 //
-// Implements exponential backoff: events that have failed recently
-// are skipped based on their attempt count.
+// Higher-priority entries are attempted first; within a priority, entries
+// go in spool order. Events past their NIP-40 expiration are deleted without
+// being published, as are events a write relay already holds when
+// reconciliation is on (see SetReconcile). Implements exponential backoff:
+// events that have failed recently are skipped based on their attempt count.
+// Draining stops when ctx is done; entries not yet tried, and a publish cut
+// short by ctx, are left as they were rather than charged a failed attempt.
 func (s *Spool) Drain(ctx context.Context, pool *RelayPool) (sent int, failed int, err error) {
+	return s.DrainWithLimit(ctx, pool, 0)
+}
+
+// DrainWithLimit is Drain, but attempts at most maxEvents publishes (0 means
+// no limit) so a caller can drain in bounded batches instead of blocking on
+// a slow relay for the whole backlog.
+func (s *Spool) DrainWithLimit(ctx context.Context, pool *RelayPool, maxEvents int) (sent int, failed int, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -145,50 +254,93 @@ func (s *Spool) Drain(ctx context.Context, pool *RelayPool) (sent int, failed in
 		return 0, 0, nil
 	}
 
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return entries[order[a]].SpoolMeta.Priority > entries[order[b]].SpoolMeta.Priority
+	})
+
 	now := time.Now()
-	var remaining []SpoolEntry
+	done := make([]bool, len(entries))
+	attempted := 0
+
+	var held map[nostr.ID]bool
+	if s.reconcile {
+		events := make([]nostr.Event, len(entries))
+		for i := range entries {
+			events[i] = entries[i].event()
+		}
+		held = pool.HeldEvents(ctx, events)
+	}
+	reconciled := 0
+
+	for _, i := range order {
+		if maxEvents > 0 && attempted >= maxEvents {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		entry := &entries[i]
+
+		// Drop events whose NIP-40 expiration has passed
+		if exp, ok := expiresAt(entry.Tags); ok && !now.Before(exp) {
+			log.Printf("[nostr] dropping expired spooled event %s (kind %d)", entry.ID, entry.Kind)
+			done[i] = true
+			continue
+		}
+
+		// Reconstruct event from spool entry
+		event := entry.event()
+
+		// Drop events a relay already holds
+		if held[event.ID] {
+			done[i] = true
+			reconciled++
+			continue
+		}
 
-	for _, entry := range entries {
 		// Check exponential backoff
 		if entry.SpoolMeta.LastAttempt != nil {
 			backoff := backoffDuration(entry.SpoolMeta.Attempts)
 			if now.Sub(*entry.SpoolMeta.LastAttempt) < backoff {
-				remaining = append(remaining, entry)
 				continue
 			}
 		}
 
-		// Reconstruct nostr event
-		// Reconstruct event from spool entry
-		var id nostr.ID
-		if b, err := hex.DecodeString(entry.ID); err == nil && len(b) == len(id) {
-			copy(id[:], b)
-		}
-		event := nostr.Event{
-			ID:        id,
-			CreatedAt: nostr.Timestamp(entry.CreatedAt),
-			Kind:      nostr.Kind(entry.Kind),
-			Tags:      entry.Tags,
-			Content:   entry.Content,
-			PubKey:    PubKeyFromHexGT(entry.PubKey),
-			Sig:       SigFromHex(entry.Sig),
-		}
-
 		// Try to publish
+		attempted++
 		if pubErr := pool.Publish(ctx, event); pubErr != nil {
+			if ctx.Err() != nil {
+				// Cut short by the caller, not the relays.
+				break
+			}
 			// Update attempt metadata
 			entry.SpoolMeta.Attempts++
 			nowCopy := now
 			entry.SpoolMeta.LastAttempt = &nowCopy
 			errStr := pubErr.Error()
 			entry.SpoolMeta.LastError = &errStr
-			remaining = append(remaining, entry)
 			failed++
 		} else {
+			done[i] = true
 			sent++
 		}
 	}
 
+	if reconciled > 0 {
+		log.Printf("[nostr] spool reconcile: dropped %d event(s) relays already hold", reconciled)
+	}
+
+	var remaining []SpoolEntry
+	for i, entry := range entries {
+		if !done[i] {
+			remaining = append(remaining, entry)
+		}
+	}
+
 	// Rewrite spool file with remaining entries
 	if err := s.writeAllLocked(remaining); err != nil {
 		return sent, failed, fmt.Errorf("rewriting spool: %w", err)
@@ -197,6 +349,23 @@ func (s *Spool) Drain(ctx context.Context, pool *RelayPool) (sent int, failed in
 	return sent, failed, nil
 }
 
+// event reconstructs the spooled nostr event.
+func (e *SpoolEntry) event() nostr.Event {
+	var id nostr.ID
+	if b, err := hex.DecodeString(e.ID); err == nil && len(b) == len(id) {
+		copy(id[:], b)
+	}
+	return nostr.Event{
+		ID:        id,
+		CreatedAt: nostr.Timestamp(e.CreatedAt),
+		Kind:      nostr.Kind(e.Kind),
+		Tags:      e.Tags,
+		Content:   e.Content,
+		PubKey:    PubKeyFromHexGT(e.PubKey),
+		Sig:       SigFromHex(e.Sig),
+	}
+}
+
 // Count returns the number of events in the spool.
 func (s *Spool) Count() int {
 	s.mu.Lock()
@@ -204,6 +373,50 @@ func (s *Spool) Count() int {
 	return s.countLocked()
 }
 
+// Compact rewrites the spool dropping replaceable events that are superseded
+// by a newer version already in the spool (same pubkey, kind, and d tag), so
+// a long outage doesn't leave dozens of stale heartbeats to drain. It returns
+// the number of entries dropped.
+func (s *Spool) Compact() (dropped int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.readAllLocked()
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the newest entry for each replaceable key; on equal CreatedAt the
+	// later entry in the file wins.
+	newest := make(map[string]int)
+	for i, entry := range entries {
+		key, ok := replaceableKey(entry.PubKey, entry.Kind, entry.Tags)
+		if !ok {
+			continue
+		}
+		if j, seen := newest[key]; !seen || entry.CreatedAt >= entries[j].CreatedAt {
+			newest[key] = i
+		}
+	}
+
+	kept := entries[:0:0]
+	for i, entry := range entries {
+		if key, ok := replaceableKey(entry.PubKey, entry.Kind, entry.Tags); ok && newest[key] != i {
+			dropped++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+
+	if dropped == 0 {
+		return 0, nil
+	}
+	if err := s.writeAllLocked(kept); err != nil {
+		return 0, fmt.Errorf("rewriting spool: %w", err)
+	}
+	return dropped, nil
+}
+
 // ArchiveOld moves events older than maxAge to the archive file.
 func (s *Spool) ArchiveOld(maxAge time.Duration) (archived int, err error) {
 	s.mu.Lock()
@@ -268,6 +481,41 @@ func (s *Spool) countLocked() int {
 	return count
 }
 
+// spoolPriority classifies an event for draining from its type tag.
+func spoolPriority(tags nostr.Tags) int {
+	for _, tag := range tags {
+		if len(tag) >= 2 && tag[0] == cascadia.TagType && urgentSpoolTypes[tag[1]] {
+			return SpoolPriorityHigh
+		}
+	}
+	return SpoolPriorityNormal
+}
+
+func (s *Spool) sizeLocked() int64 {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// replaceableKey returns the identity under which relays keep only the
+// newest version of an event: pubkey and kind for replaceable kinds (0, 3,
+// 10000-19999), plus the d tag for addressable kinds (30000-39999).
+func replaceableKey(pubkey string, kind int, tags nostr.Tags) (string, bool) {
+	switch {
+	case kind == 0 || kind == 3 || (kind >= 10000 && kind < 20000):
+		return fmt.Sprintf("%s:%d:", pubkey, kind), true
+	case kind >= 30000 && kind < 40000:
+		for _, tag := range tags {
+			if len(tag) >= 2 && tag[0] == "d" {
+				return fmt.Sprintf("%s:%d:%s", pubkey, kind, tag[1]), true
+			}
+		}
+	}
+	return "", false
+}
+
 func (s *Spool) readAllLocked() ([]SpoolEntry, error) {
 	f, err := os.Open(s.path)
 	if err != nil {
@@ -333,4 +581,4 @@ func backoffDuration(attempts int) time.Duration {
 	default:
 		return 300 * time.Second // cap at 5 minutes
 	}
-}
\ No newline at end of file
+}
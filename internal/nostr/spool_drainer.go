@@ -0,0 +1,100 @@
+package nostr
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// spoolDrainBatch bounds the publishes in one drain pass, so a large backlog
+// is worked through over several ticks instead of holding the spool lock for
+// the whole of it.
+const spoolDrainBatch = 100
+
+// SpoolDrainer periodically drains a publisher's spool and archives entries
+// older than SpoolMaxAge, so long-running processes deliver spooled events
+// once relays come back without anyone calling DrainSpool.
+type SpoolDrainer struct {
+	publisher *Publisher
+	interval  time.Duration
+
+	mu          sync.Mutex
+	stats       SpoolDrainStats
+	overSoftCap bool
+}
+
+// SpoolDrainStats are cumulative counters for a SpoolDrainer.
+type SpoolDrainStats struct {
+	Sent      int       `json:"sent"`
+	Failed    int       `json:"failed"`
+	Archived  int       `json:"archived"`
+	Depth     int       `json:"depth"` // spool size after the last pass
+	LastDrain time.Time `json:"last_drain,omitzero"`
+}
+
+// NewSpoolDrainer creates a drainer for publisher's spool that runs every
+// interval once started.
+func NewSpoolDrainer(publisher *Publisher, interval time.Duration) *SpoolDrainer {
+	return &SpoolDrainer{publisher: publisher, interval: interval}
+}
+
+// Start runs the drain loop in the background until ctx is done.
+func (d *SpoolDrainer) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.drainOnce(ctx)
+			}
+		}
+	}()
+}
+
+// Stats returns a snapshot of the drainer's counters.
+func (d *SpoolDrainer) Stats() SpoolDrainStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+func (d *SpoolDrainer) drainOnce(ctx context.Context) {
+	spool := d.publisher.spool
+
+	// Each publish is bounded per relay (see PublishDetailed), so the pass
+	// needs no overall deadline beyond ctx.
+	sent, failed, err := spool.DrainWithLimit(ctx, d.publisher.pool, spoolDrainBatch)
+	if err != nil {
+		log.Printf("[nostr] spool drain failed: %v", err)
+	} else if sent > 0 || failed > 0 {
+		log.Printf("[nostr] spool drain: sent=%d failed=%d", sent, failed)
+	}
+
+	archived, err := spool.ArchiveOld(SpoolMaxAge)
+	if err != nil {
+		log.Printf("[nostr] spool archive failed: %v", err)
+	} else if archived > 0 {
+		log.Printf("[nostr] archived %d spooled event(s) older than %s", archived, SpoolMaxAge)
+	}
+
+	depth := spool.Count()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stats.Sent += sent
+	d.stats.Failed += failed
+	d.stats.Archived += archived
+	d.stats.Depth = depth
+	d.stats.LastDrain = time.Now()
+
+	// Warn once per crossing rather than on every pass.
+	over := depth >= spool.softLimit
+	if over && !d.overSoftCap {
+		log.Printf("[nostr] warning: spool depth %d has reached the soft limit (%d)", depth, spool.softLimit)
+	}
+	d.overSoftCap = over
+}
@@ -0,0 +1,34 @@
+package nostr
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSpoolDrainerDrainsArchivesAndCounts(t *testing.T) {
+	spool := NewSpool(t.TempDir())
+	publisher := &Publisher{pool: &RelayPool{}, spool: spool}
+
+	stale := SpoolEntry{Kind: 1, Content: "stale", PubKey: spoolTestPubKey}
+	stale.SpoolMeta.SpooledAt = time.Now().Add(-2 * SpoolMaxAge)
+	if err := spool.writeAllLocked([]SpoolEntry{stale}); err != nil {
+		t.Fatal(err)
+	}
+	if err := spool.Enqueue(spoolTestEvent(1, 1, "", "fresh"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewSpoolDrainer(publisher, time.Hour)
+	d.drainOnce(context.Background())
+
+	// No relays are connected, so both publishes fail; the stale entry is
+	// then archived and only the fresh one is left.
+	stats := d.Stats()
+	if stats.Sent != 0 || stats.Failed != 2 || stats.Archived != 1 || stats.Depth != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.LastDrain.IsZero() {
+		t.Error("LastDrain not set")
+	}
+}
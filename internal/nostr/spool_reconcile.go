@@ -0,0 +1,119 @@
+package nostr
+
+import (
+	"context"
+	"iter"
+	"log"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip77"
+)
+
+// NIP-77 reconciliation timing. Relays that don't speak negentropy usually
+// ignore NEG-OPEN rather than reject it, so a relay that fails to reconcile
+// is skipped for negentropyRetryAfter instead of costing the full timeout on
+// every drain.
+const (
+	DefaultReconcileTimeout = 10 * time.Second
+	negentropyRetryAfter    = time.Hour
+)
+
+// negentropySync runs a NIP-77 reconciliation; tests replace it.
+var negentropySync = nip77.NegentropySync
+
+// spooledEvents serves the events being reconciled as the local side of a
+// negentropy sync.
+type spooledEvents []nostr.Event
+
+func (s spooledEvents) QueryEvents(nostr.Filter) iter.Seq[nostr.Event] {
+	return func(yield func(nostr.Event) bool) {
+		for _, evt := range s {
+			if !yield(evt) {
+				return
+			}
+		}
+	}
+}
+
+// HeldEvents asks each write relay, via NIP-77 negentropy, which of events it
+// already stores, and returns the IDs held by at least one of them. Relays
+// that fail to reconcile (most often because they don't support NIP-77)
+// contribute nothing and are skipped for a while.
+func (p *RelayPool) HeldEvents(ctx context.Context, events []nostr.Event) map[nostr.ID]bool {
+	held := make(map[nostr.ID]bool)
+	if len(events) == 0 {
+		return held
+	}
+
+	p.mu.RLock()
+	urls := append([]string(nil), p.writeURLs...)
+	p.mu.RUnlock()
+
+	ids := make([]nostr.ID, len(events))
+	for i, evt := range events {
+		ids[i] = evt.ID
+	}
+
+	for _, url := range urls {
+		if p.skipNegentropy(url) {
+			continue
+		}
+		missing, err := p.reconcileWith(ctx, url, ids, events)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			log.Printf("[nostr] negentropy reconcile with %s failed, publishing normally: %v", url, err)
+			p.markNegentropyFailed(url)
+			continue
+		}
+		for _, id := range ids {
+			if !missing[id] {
+				held[id] = true
+			}
+		}
+	}
+	return held
+}
+
+// reconcileWith returns the IDs among ids that the relay at url lacks.
+func (p *RelayPool) reconcileWith(ctx context.Context, url string, ids []nostr.ID, events []nostr.Event) (map[nostr.ID]bool, error) {
+	syncCtx, cancel := context.WithTimeout(ctx, DefaultReconcileTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	missing := make(map[nostr.ID]bool)
+	err := negentropySync(syncCtx, url, nostr.Filter{IDs: ids}, spooledEvents(events), nil,
+		func(_ context.Context, dir nip77.Direction) {
+			// Items are the IDs we have and the relay doesn't.
+			for id := range dir.Items {
+				mu.Lock()
+				missing[id] = true
+				mu.Unlock()
+			}
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return missing, nil
+}
+
+func (p *RelayPool) skipNegentropy(url string) bool {
+	p.negMu.Lock()
+	defer p.negMu.Unlock()
+	return time.Now().Before(p.negSkipUntil[url])
+}
+
+func (p *RelayPool) markNegentropyFailed(url string) {
+	p.negMu.Lock()
+	defer p.negMu.Unlock()
+	if p.negSkipUntil == nil {
+		p.negSkipUntil = make(map[string]time.Time)
+	}
+	p.negSkipUntil[url] = time.Now().Add(negentropyRetryAfter)
+}
@@ -0,0 +1,262 @@
+package nostr
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip77"
+)
+
+const spoolTestPubKey = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+
+func spoolTestEvent(kind int, createdAt int64, d, content string) *nostr.Event {
+	event := &nostr.Event{
+		PubKey:    PubKeyFromHexGT(spoolTestPubKey),
+		CreatedAt: nostr.Timestamp(createdAt),
+		Kind:      nostr.Kind(kind),
+		Content:   content,
+	}
+	if d != "" {
+		event.Tags = nostr.Tags{{"d", d}}
+	}
+	return event
+}
+
+func TestSpoolEnqueueEnforcesByteLimit(t *testing.T) {
+	s := NewSpool(t.TempDir())
+	s.SetMaxBytes(2048)
+
+	if err := s.Enqueue(spoolTestEvent(1, 1, "", "small"), nil); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	err := s.Enqueue(spoolTestEvent(1, 2, "", strings.Repeat("x", 4096)), nil)
+	if err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Errorf("oversized Enqueue err = %v", err)
+	}
+	if got := s.Count(); got != 1 {
+		t.Errorf("Count = %d, want 1", got)
+	}
+}
+
+func TestSpoolCompactDropsSupersededReplaceables(t *testing.T) {
+	s := NewSpool(t.TempDir())
+	// Enqueue already dedupes replaceables, so write the stale backlog
+	// directly, as an older spool would have left it.
+	var backlog []SpoolEntry
+	for _, event := range []*nostr.Event{
+		spoolTestEvent(30316, 100, "toast", "old"),
+		spoolTestEvent(1, 100, "", "note"),
+		spoolTestEvent(30316, 300, "toast", "newest"),
+		spoolTestEvent(30316, 200, "toast", "middle"),
+		spoolTestEvent(30316, 100, "nux", "other agent"),
+	} {
+		backlog = append(backlog, SpoolEntry{
+			CreatedAt: int64(event.CreatedAt),
+			Kind:      int(event.Kind),
+			Tags:      event.Tags,
+			Content:   event.Content,
+			PubKey:    spoolTestPubKey,
+		})
+	}
+	if err := s.writeAllLocked(backlog); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := s.Compact()
+	if err != nil || dropped != 2 {
+		t.Fatalf("Compact = %d, %v; want 2 dropped", dropped, err)
+	}
+
+	entries, err := s.readAllLocked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, entry := range entries {
+		contents = append(contents, entry.Content)
+	}
+	if got := strings.Join(contents, ","); got != "note,newest,other agent" {
+		t.Errorf("remaining = %s", got)
+	}
+}
+
+func TestSpoolEnqueueReplacesHeartbeats(t *testing.T) {
+	s := NewSpool(t.TempDir())
+	for _, event := range []*nostr.Event{
+		spoolTestEvent(30316, 100, "toast", "first"),
+		spoolTestEvent(30316, 300, "toast", "third"),
+		spoolTestEvent(30316, 200, "toast", "late arrival"),
+	} {
+		if err := s.Enqueue(event, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := s.readAllLocked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Content != "third" {
+		t.Fatalf("spool = %+v, want only the newest heartbeat", entries)
+	}
+
+	// Non-replaceable events still append.
+	for i := 0; i < 2; i++ {
+		if err := s.Enqueue(spoolTestEvent(1, 100, "", "note"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := s.Count(); got != 3 {
+		t.Errorf("Count = %d, want 3", got)
+	}
+}
+
+func TestSpoolDrainWithLimitTriesUrgentFirst(t *testing.T) {
+	s := NewSpool(t.TempDir())
+	for i := 0; i < 3; i++ {
+		if err := s.Enqueue(spoolTestEvent(1, int64(i), "", "routine"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	urgent := spoolTestEvent(1, 10, "", "urgent")
+	urgent.Tags = nostr.Tags{TypeTag("merge_failed")}
+	if err := s.Enqueue(urgent, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// No relays are connected, so the one attempted publish fails and is
+	// recorded on the entry it tried.
+	sent, failed, err := s.DrainWithLimit(context.Background(), &RelayPool{}, 1)
+	if err != nil || sent != 0 || failed != 1 {
+		t.Fatalf("DrainWithLimit = %d, %d, %v", sent, failed, err)
+	}
+
+	entries, err := s.readAllLocked()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("spool has %d entries, want 4", len(entries))
+	}
+	for _, entry := range entries {
+		wantAttempts := 0
+		if entry.Content == "urgent" {
+			wantAttempts = 1
+			if entry.SpoolMeta.Priority != SpoolPriorityHigh {
+				t.Errorf("urgent priority = %d", entry.SpoolMeta.Priority)
+			}
+		}
+		if entry.SpoolMeta.Attempts != wantAttempts {
+			t.Errorf("%s attempts = %d, want %d", entry.Content, entry.SpoolMeta.Attempts, wantAttempts)
+		}
+	}
+}
+
+func TestSpoolDrainDropsExpiredEvents(t *testing.T) {
+	s := NewSpool(t.TempDir())
+	expired := spoolTestEvent(1, time.Now().Add(-time.Hour).Unix(), "", "stale heartbeat")
+	WithExpiration(expired, time.Minute)
+	if err := s.Enqueue(expired, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Enqueue(spoolTestEvent(1, time.Now().Unix(), "", "live"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	sent, failed, err := s.Drain(context.Background(), &RelayPool{})
+	if err != nil || sent != 0 || failed != 1 {
+		t.Fatalf("Drain = %d, %d, %v; want only the live event attempted", sent, failed, err)
+	}
+	entries, err := s.readAllLocked()
+	if err != nil || len(entries) != 1 || entries[0].Content != "live" {
+		t.Errorf("spool after drain = %+v, %v", entries, err)
+	}
+}
+
+func TestSpoolDrainReconcileDropsEventsRelaysHold(t *testing.T) {
+	original := negentropySync
+	t.Cleanup(func() { negentropySync = original })
+
+	var plainCalls, negCalls int
+	negentropySync = func(ctx context.Context, url string, filter nostr.Filter, source nostr.Querier, _ nostr.Publisher,
+		handle func(context.Context, nip77.Direction)) error {
+		if url == "wss://plain.example" {
+			plainCalls++
+			return errors.New("relay returned a NEG-ERROR: unsupported")
+		}
+		// The first drain reconciles all three events; the second only the
+		// one left in the spool.
+		negCalls++
+		want := 3
+		if negCalls > 1 {
+			want = 1
+		}
+		if len(filter.IDs) != want {
+			t.Errorf("reconcile %d: filter IDs = %d, want %d", negCalls, len(filter.IDs), want)
+		}
+		// The relay is missing only the third event.
+		items := make(chan nostr.ID, 1)
+		items <- nostr.ID{3}
+		close(items)
+		handle(ctx, nip77.Direction{From: source, Items: items})
+		return nil
+	}
+
+	s := NewSpool(t.TempDir())
+	s.SetReconcile(true)
+	for i := byte(1); i <= 3; i++ {
+		event := spoolTestEvent(1, int64(i), "", "note")
+		event.ID = nostr.ID{i}
+		if err := s.Enqueue(event, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No live relays, so the one event that still needs publishing fails.
+	pool := &RelayPool{writeURLs: []string{"wss://neg.example", "wss://plain.example"}}
+	sent, failed, err := s.Drain(context.Background(), pool)
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if sent != 0 || failed != 1 || s.Count() != 1 {
+		t.Errorf("sent=%d failed=%d count=%d, want 0, 1, 1", sent, failed, s.Count())
+	}
+
+	// A relay that failed to reconcile isn't asked again right away.
+	if _, _, err := s.Drain(context.Background(), pool); err != nil {
+		t.Fatal(err)
+	}
+	if plainCalls != 1 {
+		t.Errorf("unsupported relay asked %d times, want 1", plainCalls)
+	}
+}
+
+func TestSpoolDrainStopsWithoutChargingUntriedEntries(t *testing.T) {
+	s := NewSpool(t.TempDir())
+	for i := 0; i < 3; i++ {
+		if err := s.Enqueue(spoolTestEvent(1, int64(i), "", "pending"), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sent, failed, err := s.Drain(ctx, &RelayPool{})
+	if err != nil || sent != 0 || failed != 0 {
+		t.Fatalf("Drain with done ctx = %d, %d, %v; want nothing attempted", sent, failed, err)
+	}
+
+	entries, err := s.readAllLocked()
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("spool after drain = %d entries, %v", len(entries), err)
+	}
+	for _, entry := range entries {
+		if entry.SpoolMeta.Attempts != 0 || entry.SpoolMeta.LastAttempt != nil {
+			t.Errorf("untried entry was charged: %+v", entry.SpoolMeta)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package nostr
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+	cascadia "git.sharegap.net/cascadia/cascadia-go"
+)
+
+// AgentStatusStale is reported for an agent whose last heartbeat is older
+// than the monitor's threshold.
+const AgentStatusStale = "stale"
+
+// StaleMonitor watches agent heartbeats on the read relays and tracks the
+// latest one per agent, flagging agents that stop heartbeating before they
+// report a terminal status.
+//
+// Declaring a stale agent dead on the relays needs a lifecycle event that the
+// monitor itself may author; until that exists the monitor only logs, once
+// per stale agent, and reports the agent as stale in Agents.
+type StaleMonitor struct {
+	pool      *RelayPool
+	threshold time.Duration
+
+	// historyWindow and historyLimit bound the stored heartbeats read at
+	// start; see SetHistory.
+	historyWindow time.Duration
+	historyLimit  int
+
+	mu     sync.Mutex
+	agents map[string]*agentHeartbeat
+}
+
+type agentHeartbeat struct {
+	actor    string
+	role     string
+	status   string
+	issue    string
+	last     time.Time
+	reported bool // stale warning already logged
+}
+
+// DefaultAgentQueryTimeout bounds how long QueryAgents waits for relays.
+const DefaultAgentQueryTimeout = 5 * time.Second
+
+// Stored heartbeat history a monitor reads when it starts. Older heartbeats
+// only describe agents that went stale long ago.
+const (
+	// DefaultHeartbeatHistoryIntervals is how many stale thresholds back
+	// the monitor looks.
+	DefaultHeartbeatHistoryIntervals = 3
+	// DefaultHeartbeatHistoryLimit caps the stored heartbeats per relay.
+	DefaultHeartbeatHistoryLimit = 1000
+)
+
+// NewStaleMonitor creates a monitor reading heartbeats from pool. An agent is
+// stale once threshold passes without a heartbeat; threshold <= 0 uses
+// DefaultHeartbeatTTL, after which the last heartbeat has expired anyway.
+func NewStaleMonitor(pool *RelayPool, threshold time.Duration) *StaleMonitor {
+	if threshold <= 0 {
+		threshold = DefaultHeartbeatTTL
+	}
+	return &StaleMonitor{
+		pool:          pool,
+		threshold:     threshold,
+		historyWindow: DefaultHeartbeatHistoryIntervals * threshold,
+		historyLimit:  DefaultHeartbeatHistoryLimit,
+		agents:        make(map[string]*agentHeartbeat),
+	}
+}
+
+// SetHistory sets how far back, and how many stored heartbeats per relay,
+// the monitor reads when it starts. A zero window or limit removes that
+// bound. Call before Start.
+func (m *StaleMonitor) SetHistory(window time.Duration, limit int) {
+	m.historyWindow = window
+	m.historyLimit = limit
+}
+
+// historySince is the oldest heartbeat the monitor reads, or zero for no
+// bound.
+func (m *StaleMonitor) historySince(now time.Time) time.Time {
+	if m.historyWindow <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-m.historyWindow)
+}
+
+// Start subscribes to agent heartbeats, starting with the stored ones inside
+// the monitor's history window, and checks for stale agents every check
+// interval until ctx is done.
+func (m *StaleMonitor) Start(ctx context.Context, check time.Duration) {
+	events, _ := SubscribeSince(ctx, m.pool, heartbeatFilters(), m.historySince(time.Now()), m.historyLimit)
+
+	go func() {
+		ticker := time.NewTicker(check)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				m.observe(event)
+			case <-ticker.C:
+				m.checkStale(time.Now())
+			}
+		}
+	}()
+}
+
+// QueryAgents does a one-off read of agent heartbeats from pool and returns
+// the resulting view as StaleMonitor.Agents would. It returns once every
+// relay has sent its stored events, or after DefaultAgentQueryTimeout, so a
+// slow relay cannot stall a health check.
+func QueryAgents(ctx context.Context, pool *RelayPool, threshold time.Duration) []AgentHealthInfo {
+	m := NewStaleMonitor(pool, threshold)
+
+	queryCtx, cancel := context.WithTimeout(ctx, DefaultAgentQueryTimeout)
+	defer cancel()
+	filters := boundFilters(heartbeatFilters(), m.historySince(time.Now()), m.historyLimit)
+	pool.queryStored(queryCtx, filters, m.observe)
+	return m.Agents()
+}
+
+func heartbeatFilters() []nostr.Filter {
+	return []nostr.Filter{{Kinds: []nostr.Kind{nostr.Kind(cascadia.CAS_AGENT_HEARTBEAT)}}}
+}
+
+// observe records a heartbeat, keeping only the newest per agent.
+func (m *StaleMonitor) observe(event *nostr.Event) {
+	var actor, role, status, issue string
+	for _, tag := range event.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "d":
+			actor = tag[1]
+		case cascadia.TagAgent:
+			role = tag[1]
+		case cascadia.TagStatus:
+			status = tag[1]
+		case "t":
+			issue = tag[1]
+		}
+	}
+	if actor == "" {
+		return
+	}
+	at := time.Unix(int64(event.CreatedAt), 0)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hb, ok := m.agents[actor]; ok && !at.After(hb.last) {
+		return
+	}
+	m.agents[actor] = &agentHeartbeat{actor: actor, role: role, status: status, issue: issue, last: at}
+}
+
+// checkStale logs each agent that has newly gone stale as of now.
+func (m *StaleMonitor) checkStale(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, hb := range m.agents {
+		if hb.reported || !m.isStale(hb, now) {
+			continue
+		}
+		hb.reported = true
+		missed := int(now.Sub(hb.last) / m.threshold)
+		log.Printf("[nostr] agent %s (%s) is stale: missed %d heartbeat window(s) since %s",
+			hb.actor, hb.role, missed, hb.last.Format(time.RFC3339))
+	}
+}
+
+func (m *StaleMonitor) isStale(hb *agentHeartbeat, now time.Time) bool {
+	switch hb.status {
+	case "retiring", "retired", "dead":
+		return false
+	}
+	return now.Sub(hb.last) >= m.threshold
+}
+
+// Agents returns the latest known state of every agent, sorted by actor.
+// Stale agents are reported with status AgentStatusStale.
+func (m *StaleMonitor) Agents() []AgentHealthInfo {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	agents := make([]AgentHealthInfo, 0, len(m.agents))
+	for _, hb := range m.agents {
+		status := hb.status
+		if m.isStale(hb, now) {
+			status = AgentStatusStale
+		}
+		agents = append(agents, AgentHealthInfo{
+			Actor:         hb.actor,
+			Status:        status,
+			LastHeartbeat: hb.last.UTC().Format(time.RFC3339),
+			CurrentIssue:  hb.issue,
+		})
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Actor < agents[j].Actor })
+	return agents
+}
@@ -0,0 +1,67 @@
+package nostr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestStaleMonitorTracksLatestHeartbeat(t *testing.T) {
+	m := NewStaleMonitor(&RelayPool{}, time.Minute)
+	now := time.Now()
+
+	heartbeat := func(actor, status string, at time.Time) *nostr.Event {
+		event, err := NewAgentHeartbeatEvent(actor, "gastown", "polecat", status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		event.CreatedAt = nostr.Timestamp(at.Unix())
+		return event
+	}
+
+	m.observe(heartbeat("toast", "working", now.Add(-10*time.Second)))
+	m.observe(heartbeat("toast", "idle", now.Add(-30*time.Second))) // older, ignored
+	m.observe(heartbeat("nux", "working", now.Add(-5*time.Minute)))
+	m.observe(heartbeat("slit", "retiring", now.Add(-5*time.Minute)))
+
+	agents := m.Agents()
+	want := map[string]string{"nux": AgentStatusStale, "slit": "retiring", "toast": "working"}
+	if len(agents) != len(want) {
+		t.Fatalf("agents = %+v", agents)
+	}
+	for _, a := range agents {
+		if a.Status != want[a.Actor] {
+			t.Errorf("%s status = %q, want %q", a.Actor, a.Status, want[a.Actor])
+		}
+	}
+
+	m.checkStale(now)
+	if !m.agents["nux"].reported || m.agents["toast"].reported || m.agents["slit"].reported {
+		t.Error("only nux should be reported stale")
+	}
+}
+
+func TestQueryAgentsReturnsWithoutRelays(t *testing.T) {
+	start := time.Now()
+	agents := QueryAgents(context.Background(), &RelayPool{}, 0)
+	if len(agents) != 0 {
+		t.Errorf("agents = %+v, want none", agents)
+	}
+	if elapsed := time.Since(start); elapsed >= DefaultAgentQueryTimeout {
+		t.Errorf("QueryAgents took %s; it should return at EOSE", elapsed)
+	}
+}
+
+func TestStaleMonitorHistoryWindow(t *testing.T) {
+	now := time.Now()
+	m := NewStaleMonitor(&RelayPool{}, time.Minute)
+	if got, want := m.historySince(now), now.Add(-3*time.Minute); !got.Equal(want) {
+		t.Errorf("default history since = %v, want %v", got, want)
+	}
+	m.SetHistory(0, 0)
+	if got := m.historySince(now); !got.IsZero() {
+		t.Errorf("zero window since = %v, want unbounded", got)
+	}
+}
@@ -0,0 +1,217 @@
+package nostr
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+const (
+	// managedEventBuffer is the buffer size of a managed subscription's
+	// event channel.
+	managedEventBuffer = 256
+
+	// managedDedupeSize is how many recent event IDs a managed subscription
+	// remembers to drop duplicates delivered by several relays.
+	managedDedupeSize = 4096
+)
+
+// SubscribeManaged subscribes to filters on every read relay and merges the
+// results into one stream of events, de-duplicated by ID across relays.
+// Events that fail VerifyEvent are dropped and logged.
+//
+// The second channel is closed once every relay has sent EOSE (or dropped
+// the subscription), i.e. when stored events are drained and everything that
+// follows is live. After Reconnect replaces a relay connection the
+// subscription is transparently re-established; stored events replayed by the
+// new subscription are dropped as duplicates where still remembered.
+//
+// The event channel is closed when ctx is done.
+func (p *RelayPool) SubscribeManaged(ctx context.Context, filters []nostr.Filter) (<-chan *nostr.Event, <-chan struct{}) {
+	events := make(chan *nostr.Event, managedEventBuffer)
+	eose := make(chan struct{})
+	go p.runManagedSubscription(ctx, filters, events, eose)
+	return events, eose
+}
+
+// SubscribeSince is SubscribeManaged with every filter bounded to history
+// the caller can use: stored events created before since are not requested
+// (a zero since leaves the filters unbounded), and with limit > 0 each relay
+// returns at most limit stored events per filter. Live events are
+// unaffected. The caller's filters are not modified.
+//
+// Subscribers should pick a window from what they need to rebuild their
+// state (e.g. a few heartbeat intervals) rather than pulling a relay's whole
+// archive or only what arrives after they start.
+func SubscribeSince(ctx context.Context, pool *RelayPool, filters []nostr.Filter, since time.Time, limit int) (<-chan *nostr.Event, <-chan struct{}) {
+	return pool.SubscribeManaged(ctx, boundFilters(filters, since, limit))
+}
+
+// boundFilters returns copies of filters with Since and Limit applied. A
+// filter's own tighter bounds are kept.
+func boundFilters(filters []nostr.Filter, since time.Time, limit int) []nostr.Filter {
+	bounded := make([]nostr.Filter, len(filters))
+	for i, f := range filters {
+		if !since.IsZero() {
+			if ts := nostr.Timestamp(since.Unix()); ts > f.Since {
+				f.Since = ts
+			}
+		}
+		if limit > 0 && (f.Limit == 0 || limit < f.Limit) {
+			f.Limit = limit
+		}
+		bounded[i] = f
+	}
+	return bounded
+}
+
+// queryStored passes each stored event matching filters to fn and returns
+// once every read relay has sent EOSE, or when ctx is done.
+func (p *RelayPool) queryStored(ctx context.Context, filters []nostr.Filter, fn func(*nostr.Event)) {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, eose := p.SubscribeManaged(subCtx, filters)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			fn(event)
+		case <-eose:
+			// Take whatever is already buffered, then stop.
+			for {
+				select {
+				case event, ok := <-events:
+					if !ok {
+						return
+					}
+					fn(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *RelayPool) runManagedSubscription(ctx context.Context, filters []nostr.Filter, events chan<- *nostr.Event, eose chan struct{}) {
+	defer close(events)
+
+	seen := newEventIDSet(managedDedupeSize)
+	var eoseOnce sync.Once
+	signalEOSE := func() { eoseOnce.Do(func() { close(eose) }) }
+
+	for {
+		reconnected := p.reconnectSignal()
+		subCtx, cancel := context.WithCancel(ctx)
+		subs := p.Subscribe(subCtx, filters)
+		if len(subs) == 0 {
+			signalEOSE()
+		}
+
+		var pending sync.WaitGroup
+		pending.Add(len(subs))
+		go func() {
+			pending.Wait()
+			if subCtx.Err() == nil {
+				signalEOSE()
+			}
+		}()
+
+		var wg sync.WaitGroup
+		for _, sub := range subs {
+			wg.Add(1)
+			go func(sub *nostr.Subscription) {
+				defer wg.Done()
+				forwardSubscription(subCtx, sub, seen, events, pending.Done)
+			}(sub)
+		}
+
+		select {
+		case <-ctx.Done():
+			cancel()
+			wg.Wait()
+			return
+		case <-reconnected:
+			log.Printf("[nostr] resubscribing after relay reconnect")
+			cancel()
+			wg.Wait()
+		}
+	}
+}
+
+// forwardSubscription copies sub's events to out until the subscription ends
+// or ctx is done, calling stored exactly once when stored events are drained.
+func forwardSubscription(ctx context.Context, sub *nostr.Subscription, seen *eventIDSet, out chan<- *nostr.Event, stored func()) {
+	var once sync.Once
+	markStored := func() { once.Do(stored) }
+	defer markStored()
+
+	eose := sub.EndOfStoredEvents
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-eose:
+			markStored()
+			eose = nil
+		case reason := <-sub.ClosedReason:
+			log.Printf("[nostr] subscription on %s closed: %s", sub.Relay.URL, reason)
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := VerifyEvent(&event); err != nil {
+				log.Printf("[nostr] dropping unverified event from %s: %v", sub.Relay.URL, err)
+				continue
+			}
+			if !seen.add(event.ID) {
+				continue
+			}
+			select {
+			case out <- &event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// eventIDSet remembers the most recent event IDs in a fixed-size ring.
+type eventIDSet struct {
+	mu    sync.Mutex
+	ids   map[nostr.ID]struct{}
+	order []nostr.ID
+	next  int
+}
+
+func newEventIDSet(size int) *eventIDSet {
+	return &eventIDSet{
+		ids:   make(map[nostr.ID]struct{}, size),
+		order: make([]nostr.ID, 0, size),
+	}
+}
+
+// add records id and reports whether it was new.
+func (s *eventIDSet) add(id nostr.ID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	if len(s.order) < cap(s.order) {
+		s.order = append(s.order, id)
+	} else {
+		delete(s.ids, s.order[s.next])
+		s.order[s.next] = id
+		s.next = (s.next + 1) % len(s.order)
+	}
+	s.ids[id] = struct{}{}
+	return true
+}
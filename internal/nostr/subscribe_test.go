@@ -0,0 +1,73 @@
+package nostr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestEventIDSetEvictsOldest(t *testing.T) {
+	s := newEventIDSet(2)
+	a, b, c := nostr.ID{1}, nostr.ID{2}, nostr.ID{3}
+
+	if !s.add(a) || !s.add(b) {
+		t.Fatal("new IDs should be added")
+	}
+	if s.add(a) {
+		t.Error("duplicate ID should be rejected")
+	}
+	s.add(c) // evicts a
+	if !s.add(a) {
+		t.Error("evicted ID should be accepted again")
+	}
+	if s.add(c) {
+		t.Error("recent ID should still be remembered")
+	}
+}
+
+func TestSubscribeManagedWithoutRelays(t *testing.T) {
+	pool := &RelayPool{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, eose := pool.SubscribeManaged(ctx, []nostr.Filter{{}})
+	select {
+	case <-eose:
+	case <-time.After(time.Second):
+		t.Fatal("EOSE should be signalled when there are no relays")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("unexpected event")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("events channel should close when ctx is done")
+	}
+}
+
+func TestBoundFiltersAppliesSinceAndLimitWithoutMutating(t *testing.T) {
+	since := time.Unix(1_000_000, 0)
+	filters := []nostr.Filter{
+		{Kinds: []nostr.Kind{1}},
+		{Kinds: []nostr.Kind{2}, Since: 2_000_000, Limit: 5}, // already tighter
+	}
+
+	got := boundFilters(filters, since, 100)
+	if got[0].Since != 1_000_000 || got[0].Limit != 100 {
+		t.Errorf("unbounded filter = %+v", got[0])
+	}
+	if got[1].Since != 2_000_000 || got[1].Limit != 5 {
+		t.Errorf("tighter filter loosened: %+v", got[1])
+	}
+	if filters[0].Since != 0 || filters[0].Limit != 0 {
+		t.Errorf("caller's filter modified: %+v", filters[0])
+	}
+
+	if got := boundFilters(filters, time.Time{}, 0); got[0].Since != 0 || got[0].Limit != 0 {
+		t.Errorf("zero bounds should leave filter alone: %+v", got[0])
+	}
+}
@@ -12,6 +12,8 @@ package nostr
 
 import (
 	"encoding/hex"
+	"strconv"
+	"time"
 
 	"fiatjaf.com/nostr"
 	cascadia "git.sharegap.net/cascadia/cascadia-go"
@@ -74,6 +76,38 @@ func CorrelationTags(issueID, convoyID, beadID, sessionID string) nostr.Tags {
 	return tags
 }
 
+// MergeTags returns optional ["branch", ...] and ["mr", ...] tags for events
+// about a branch or merge request.
+func MergeTags(branch, mergeReq string) nostr.Tags {
+	var tags nostr.Tags
+	if branch != "" {
+		tags = append(tags, nostr.Tag{"branch", branch})
+	}
+	if mergeReq != "" {
+		tags = append(tags, nostr.Tag{"mr", mergeReq})
+	}
+	return tags
+}
+
+// ExpirationTag returns a NIP-40 expiration tag for the given time.
+func ExpirationTag(at time.Time) nostr.Tag {
+	return nostr.Tag{"expiration", strconv.FormatInt(at.Unix(), 10)}
+}
+
+// expiresAt returns the NIP-40 expiration time in tags, if any.
+func expiresAt(tags nostr.Tags) (time.Time, bool) {
+	for _, tag := range tags {
+		if len(tag) >= 2 && tag[0] == "expiration" {
+			ts, err := strconv.ParseInt(tag[1], 10, 64)
+			if err != nil {
+				return time.Time{}, false
+			}
+			return time.Unix(ts, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
 // ReplaceableTag returns a NIP-33 d-tag for an addressable event.
 func ReplaceableTag(d string) nostr.Tag {
 	return nostr.Tag{"d", d}
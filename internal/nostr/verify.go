@@ -0,0 +1,24 @@
+package nostr
+
+import (
+	"fmt"
+
+	"fiatjaf.com/nostr"
+)
+
+// VerifyEvent checks that event's ID is the hash of its contents and that
+// its Schnorr signature is valid for its pubkey. Events received from relays
+// must pass this before being acted on: a relay can otherwise forge events
+// attributed to any pubkey.
+func VerifyEvent(event *nostr.Event) error {
+	if event == nil {
+		return fmt.Errorf("nil event")
+	}
+	if event.GetID() != event.ID {
+		return fmt.Errorf("event %s: id does not match contents", IDToString(event.ID))
+	}
+	if !event.VerifySignature() {
+		return fmt.Errorf("event %s: invalid signature for pubkey %s", IDToString(event.ID), PubKeyToString(event.PubKey))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package nostr
+
+import (
+	"context"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestVerifyEventRejectsTampering(t *testing.T) {
+	signer, err := NewLocalSigner("0000000000000000000000000000000000000000000000000000000000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := func() *nostr.Event {
+		event := &nostr.Event{Kind: 1, Tags: nostr.Tags{TypeTag("MERGE_READY")}, Content: "gt-1 ready"}
+		if err := signer.Sign(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+		return event
+	}
+
+	if err := VerifyEvent(signed()); err != nil {
+		t.Fatalf("VerifyEvent(valid) = %v", err)
+	}
+
+	// Content changed after signing: the ID no longer matches.
+	tampered := signed()
+	tampered.Content = "gt-2 ready"
+	if err := VerifyEvent(tampered); err == nil {
+		t.Error("VerifyEvent should reject tampered content")
+	}
+
+	// Re-attributed with a recomputed ID: the signature no longer matches.
+	forged := signed()
+	forged.PubKey = PubKeyFromHexGT("c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5")
+	forged.ID = forged.GetID()
+	if err := VerifyEvent(forged); err == nil {
+		t.Error("VerifyEvent should reject a forged author")
+	}
+}